@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// AdminGetDatabaseHealth returns the connection pools, the replication lag of the read replicas, the
+// migration status and the last error of the database.
+func (hs *HTTPServer) AdminGetDatabaseHealth(c *contextmodel.ReqContext) response.Response {
+	return response.JSON(http.StatusOK, hs.sqlStore.Health(c.Req.Context()))
+}
+
+// AdminGetDatabasePools returns the settings and statistics of the connection pools of the primary
+// database and of the read replicas.
+func (hs *HTTPServer) AdminGetDatabasePools(c *contextmodel.ReqContext) response.Response {
+	return response.JSON(http.StatusOK, hs.sqlStore.Pools())
+}
+
+// AdminUpdateDatabasePool changes the settings of a connection pool until the next restart.
+func (hs *HTTPServer) AdminUpdateDatabasePool(c *contextmodel.ReqContext) response.Response {
+	update := sqlstore.PoolSettingsUpdate{}
+	if err := web.Bind(c.Req, &update); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	pool, err := hs.sqlStore.UpdatePool(web.Params(c.Req)[":name"], update)
+	if err != nil {
+		if errors.Is(err, sqlstore.ErrPoolNotFound) {
+			return response.Error(http.StatusNotFound, "Connection pool not found", err)
+		}
+		return response.Error(http.StatusBadRequest, "Invalid connection pool settings", err)
+	}
+
+	return response.JSON(http.StatusOK, pool)
+}
+
+// AdminGetDatabaseMigrationPlan returns the migrations that have not been executed yet with the SQL
+// they would execute, without executing them.
+func (hs *HTTPServer) AdminGetDatabaseMigrationPlan(c *contextmodel.ReqContext) response.Response {
+	plan, err := hs.sqlStore.MigrationPlan()
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to plan the migrations", err)
+	}
+	return response.JSON(http.StatusOK, plan)
+}
+
+// AdminVerifyDatabaseMigrations returns the divergences between the migration log and the migrations.
+func (hs *HTTPServer) AdminVerifyDatabaseMigrations(c *contextmodel.ReqContext) response.Response {
+	divergences, err := hs.sqlStore.VerifyMigrations()
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to verify the migrations", err)
+	}
+	return response.JSON(http.StatusOK, divergences)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+func TestAPI_AdminDatabase(t *testing.T) {
+	store := sqlstore.InitTestDB(t)
+	primary := store.Pools()[0]
+	t.Cleanup(func() {
+		_, err := store.UpdatePool(sqlstore.PrimaryPool, sqlstore.PoolSettingsUpdate{
+			MaxOpenConn:     &primary.Settings.MaxOpenConn,
+			MaxIdleConn:     &primary.Settings.MaxIdleConn,
+			ConnMaxLifetime: &primary.Settings.ConnMaxLifetime,
+		})
+		require.NoError(t, err)
+	})
+
+	server := SetupAPITestServer(t, func(hs *HTTPServer) {
+		hs.Cfg = setting.NewCfg()
+		hs.sqlStore = store
+	})
+
+	t.Run("get the pools", func(t *testing.T) {
+		res, err := server.Send(webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/database/pools"), userWithPermissions(1, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionServerStatsRead},
+		})))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var pools []sqlstore.Pool
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&pools))
+		require.NoError(t, res.Body.Close())
+		require.Len(t, pools, 1)
+		assert.Equal(t, sqlstore.PrimaryPool, pools[0].Name)
+	})
+
+	t.Run("get the health", func(t *testing.T) {
+		res, err := server.Send(webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/db-health"), userWithPermissions(1, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionServerStatsRead},
+		})))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var health sqlstore.Health
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&health))
+		require.NoError(t, res.Body.Close())
+		assert.Empty(t, health.PingError)
+		assert.Len(t, health.Pools, 1)
+		assert.Empty(t, health.Migrations.Pending)
+	})
+
+	t.Run("get the migration plan", func(t *testing.T) {
+		res, err := server.Send(webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/database/migrations/plan"), userWithPermissions(1, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionServerStatsRead},
+		})))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var plan []migrator.PlannedMigration
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&plan))
+		require.NoError(t, res.Body.Close())
+		assert.Empty(t, plan)
+	})
+
+	t.Run("verify the migrations", func(t *testing.T) {
+		res, err := server.Send(webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/database/migrations/verify"), userWithPermissions(1, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionServerStatsRead},
+		})))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var divergences []migrator.Divergence
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&divergences))
+		require.NoError(t, res.Body.Close())
+		assert.NotNil(t, divergences)
+	})
+
+	admin := userWithPermissions(1, []accesscontrol.Permission{
+		{Action: accesscontrol.ActionServerDatabasePoolsWrite},
+	})
+	admin.IsGrafanaAdmin = true
+	reader := userWithPermissions(1, []accesscontrol.Permission{
+		{Action: accesscontrol.ActionServerStatsRead},
+	})
+	reader.IsGrafanaAdmin = true
+
+	for _, tt := range []struct {
+		desc         string
+		url          string
+		body         string
+		user         *user.SignedInUser
+		expectedCode int
+	}{
+		{desc: "update the pool", url: "/api/admin/database/pools/primary", body: `{"maxOpenConn": 10}`, user: admin, expectedCode: http.StatusOK},
+		{desc: "invalid settings", url: "/api/admin/database/pools/primary", body: `{"maxIdleConn": 20}`, user: admin, expectedCode: http.StatusBadRequest},
+		{desc: "unknown pool", url: "/api/admin/database/pools/unknown", body: `{"maxOpenConn": 10}`, user: admin, expectedCode: http.StatusNotFound},
+		{desc: "missing permission", url: "/api/admin/database/pools/primary", body: `{"maxOpenConn": 20}`, user: reader, expectedCode: http.StatusForbidden},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			req := server.NewRequest(http.MethodPatch, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			res, err := server.Send(webtest.RequestWithSignedInUser(req, tt.user))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, res.StatusCode)
+			require.NoError(t, res.Body.Close())
+		})
+	}
+
+	assert.Equal(t, 10, store.Pools()[0].Settings.MaxOpenConn)
+}
@@ -8,7 +8,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
-	"github.com/grafana/grafana/pkg/components/apikeygen"
+	apikeygenprefix "github.com/grafana/grafana/pkg/components/apikeygenprefixed"
 	"github.com/grafana/grafana/pkg/services/apikey"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/web"
@@ -127,7 +127,7 @@ func (hs *HTTPServer) AddAPIKey(c *contextmodel.ReqContext) response.Response {
 
 	cmd.OrgID = c.OrgID
 
-	newKeyInfo, err := apikeygen.New(cmd.OrgID, cmd.Name)
+	newKeyInfo, err := apikeygenprefix.New(apikey.ServiceID)
 	if err != nil {
 		return response.Error(500, "Generating API key failed", err)
 	}
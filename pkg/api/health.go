@@ -23,3 +23,59 @@ func (hs *HTTPServer) databaseHealthy(ctx context.Context) bool {
 	hs.CacheService.Set(cacheKey, healthy, time.Second*5)
 	return healthy
 }
+
+func (hs *HTTPServer) remoteCacheHealthy(ctx context.Context) bool {
+	const cacheKey = "remote-cache-healthy"
+
+	if cached, found := hs.CacheService.Get(cacheKey); found {
+		return cached.(bool)
+	}
+
+	_, err := hs.RemoteCacheService.HealthCheck(ctx)
+	healthy := err == nil
+
+	hs.CacheService.Set(cacheKey, healthy, time.Second*5)
+	return healthy
+}
+
+type healthCheckDetails struct {
+	Status    string `json:"status"`
+	Backend   string `json:"backend,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+// healthDetails checks the database and the remote cache and measures their round trip latency.
+// The result is cached like the other health checks, the endpoint does not require authentication.
+func (hs *HTTPServer) healthDetails(ctx context.Context) map[string]healthCheckDetails {
+	const cacheKey = "health-details"
+
+	if cached, found := hs.CacheService.Get(cacheKey); found {
+		return cached.(map[string]healthCheckDetails)
+	}
+
+	details := map[string]healthCheckDetails{}
+
+	start := time.Now()
+	err := hs.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		_, err := session.Exec("SELECT 1")
+		return err
+	})
+	details["database"] = newHealthCheckDetails(time.Since(start), err)
+
+	if hs.RemoteCacheService != nil {
+		latency, err := hs.RemoteCacheService.HealthCheck(ctx)
+		check := newHealthCheckDetails(latency, err)
+		check.Backend = hs.RemoteCacheService.Backend()
+		details["remoteCache"] = check
+	}
+
+	hs.CacheService.Set(cacheKey, details, time.Second*5)
+	return details
+}
+
+func newHealthCheckDetails(latency time.Duration, err error) healthCheckDetails {
+	if err != nil {
+		return healthCheckDetails{Status: "failing"}
+	}
+	return healthCheckDetails{Status: "ok", LatencyMs: latency.Milliseconds()}
+}
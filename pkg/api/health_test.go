@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/infra/db/dbtest"
 	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/web"
 )
@@ -147,6 +149,84 @@ func TestHealthAPI_DatabaseHealthCached(t *testing.T) {
 	require.True(t, healthy.(bool))
 }
 
+func TestHealthAPI_RemoteCache(t *testing.T) {
+	m, hs := setupHealthAPITestEnvironment(t)
+	hs.Cfg.AnonymousHideVersion = true
+	hs.RemoteCacheService = newHealthTestRemoteCache(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	require.Equal(t, 200, rec.Code)
+	expectedBody := `
+		{
+			"database": "ok",
+			"remoteCache": "ok"
+		}
+	`
+	require.JSONEq(t, expectedBody, rec.Body.String())
+
+	// Mock unhealthy remote cache in cache, the status code only depends on the database.
+	hs.CacheService.Set("remote-cache-healthy", false, 5*time.Minute)
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	require.Equal(t, 200, rec.Code)
+	expectedBody = `
+		{
+			"database": "ok",
+			"remoteCache": "failing"
+		}
+	`
+	require.JSONEq(t, expectedBody, rec.Body.String())
+}
+
+func TestHealthAPI_Details(t *testing.T) {
+	m, hs := setupHealthAPITestEnvironment(t)
+	hs.Cfg.AnonymousHideVersion = true
+	hs.RemoteCacheService = newHealthTestRemoteCache(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/health/details", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	require.Equal(t, 200, rec.Code)
+	var body map[string]healthCheckDetails
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, "ok", body["database"].Status)
+	require.Equal(t, "ok", body["remoteCache"].Status)
+	require.Equal(t, "memory", body["remoteCache"].Backend)
+}
+
+func TestHealthAPI_DetailsDatabaseUnhealthy(t *testing.T) {
+	m, hs := setupHealthAPITestEnvironment(t)
+	hs.Cfg.AnonymousHideVersion = true
+	hs.SQLStore.(*dbtest.FakeDB).ExpectedError = errors.New("bad")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/health/details", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	require.Equal(t, 503, rec.Code)
+	expectedBody := `
+		{
+			"database": {"status": "failing", "latencyMs": 0}
+		}
+	`
+	require.JSONEq(t, expectedBody, rec.Body.String())
+}
+
+func newHealthTestRemoteCache(t *testing.T) *remotecache.RemoteCache {
+	t.Helper()
+
+	cache, err := remotecache.ProvideService(&setting.Cfg{
+		RemoteCacheOptions: &setting.RemoteCacheOptions{Name: "memory"},
+	}, nil, nil)
+	require.NoError(t, err)
+	return cache
+}
+
 func setupHealthAPITestEnvironment(t *testing.T, cbs ...func(*setting.Cfg)) (*web.Mux, *HTTPServer) {
 	t.Helper()
 
@@ -162,5 +242,6 @@ func setupHealthAPITestEnvironment(t *testing.T, cbs ...func(*setting.Cfg)) (*we
 	}
 
 	m.Get("/api/health", hs.apiHealthHandler)
+	m.Get("/api/health/details", hs.apiHealthDetailsHandler)
 	return m, hs
 }
@@ -63,6 +63,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/live/pushhttp"
 	"github.com/grafana/grafana/pkg/services/login"
 	loginAttempt "github.com/grafana/grafana/pkg/services/loginattempt"
+	"github.com/grafana/grafana/pkg/services/mfa"
 	"github.com/grafana/grafana/pkg/services/navtree"
 	"github.com/grafana/grafana/pkg/services/ngalert"
 	"github.com/grafana/grafana/pkg/services/notifications"
@@ -114,46 +115,48 @@ type HTTPServer struct {
 	namedMiddlewares []routing.RegisterNamedMiddleware
 	bus              bus.Bus
 
-	PluginContextProvider        *plugincontext.Provider
-	RouteRegister                routing.RouteRegister
-	RenderService                rendering.Service
-	Cfg                          *setting.Cfg
-	Features                     *featuremgmt.FeatureManager
-	SettingsProvider             setting.Provider
-	HooksService                 *hooks.HooksService
-	navTreeService               navtree.Service
-	CacheService                 *localcache.CacheService
-	DataSourceCache              datasources.CacheService
-	AuthTokenService             auth.UserTokenService
-	QuotaService                 quota.Service
-	RemoteCacheService           *remotecache.RemoteCache
-	ProvisioningService          provisioning.ProvisioningService
-	Login                        login.Service
-	License                      licensing.Licensing
-	AccessControl                accesscontrol.AccessControl
-	DataProxy                    *datasourceproxy.DataSourceProxyService
-	PluginRequestValidator       validations.PluginRequestValidator
-	pluginClient                 plugins.Client
-	pluginStore                  plugins.Store
-	pluginInstaller              plugins.Installer
-	pluginDashboardService       plugindashboards.Service
-	pluginStaticRouteResolver    plugins.StaticRouteResolver
-	pluginErrorResolver          plugins.ErrorResolver
-	SearchService                search.Service
-	ShortURLService              shorturls.Service
-	QueryHistoryService          queryhistory.Service
-	CorrelationsService          correlations.Service
-	Live                         *live.GrafanaLive
-	LivePushGateway              *pushhttp.Gateway
-	ThumbService                 thumbs.Service
-	ExportService                export.ExportService
-	StorageService               store.StorageService
-	httpEntityStore              httpentitystore.HTTPEntityStore
-	SearchV2HTTPService          searchV2.SearchHTTPService
-	QueryLibraryHTTPService      querylibrary.HTTPService
-	QueryLibraryService          querylibrary.Service
-	ContextHandler               *contexthandler.ContextHandler
-	SQLStore                     db.DB
+	PluginContextProvider     *plugincontext.Provider
+	RouteRegister             routing.RouteRegister
+	RenderService             rendering.Service
+	Cfg                       *setting.Cfg
+	Features                  *featuremgmt.FeatureManager
+	SettingsProvider          setting.Provider
+	HooksService              *hooks.HooksService
+	navTreeService            navtree.Service
+	CacheService              *localcache.CacheService
+	DataSourceCache           datasources.CacheService
+	AuthTokenService          auth.UserTokenService
+	QuotaService              quota.Service
+	RemoteCacheService        *remotecache.RemoteCache
+	ProvisioningService       provisioning.ProvisioningService
+	Login                     login.Service
+	License                   licensing.Licensing
+	AccessControl             accesscontrol.AccessControl
+	DataProxy                 *datasourceproxy.DataSourceProxyService
+	PluginRequestValidator    validations.PluginRequestValidator
+	pluginClient              plugins.Client
+	pluginStore               plugins.Store
+	pluginInstaller           plugins.Installer
+	pluginDashboardService    plugindashboards.Service
+	pluginStaticRouteResolver plugins.StaticRouteResolver
+	pluginErrorResolver       plugins.ErrorResolver
+	SearchService             search.Service
+	ShortURLService           shorturls.Service
+	QueryHistoryService       queryhistory.Service
+	CorrelationsService       correlations.Service
+	Live                      *live.GrafanaLive
+	LivePushGateway           *pushhttp.Gateway
+	ThumbService              thumbs.Service
+	ExportService             export.ExportService
+	StorageService            store.StorageService
+	httpEntityStore           httpentitystore.HTTPEntityStore
+	SearchV2HTTPService       searchV2.SearchHTTPService
+	QueryLibraryHTTPService   querylibrary.HTTPService
+	QueryLibraryService       querylibrary.Service
+	ContextHandler            *contexthandler.ContextHandler
+	SQLStore                  db.DB
+	// sqlStore is used by the database admin API which is specific to the SQL store
+	sqlStore                     *sqlstore.SQLStore
 	AlertEngine                  *alerting.AlertEngine
 	AlertNG                      *ngalert.AlertNG
 	LibraryPanelService          librarypanels.Service
@@ -213,6 +216,7 @@ type HTTPServer struct {
 	oauthTokenService      oauthtoken.OAuthTokenService
 	statsService           stats.Service
 	authnService           authn.Service
+	mfaService             mfa.Service
 	starApi                *starApi.API
 }
 
@@ -257,7 +261,7 @@ func ProvideHTTPServer(opts ServerOptions, cfg *setting.Cfg, routeRegister routi
 	annotationRepo annotations.Repository, tagService tag.Service, searchv2HTTPService searchV2.SearchHTTPService,
 	queryLibraryHTTPService querylibrary.HTTPService, queryLibraryService querylibrary.Service, oauthTokenService oauthtoken.OAuthTokenService,
 	statsService stats.Service, authnService authn.Service, pluginsCDNService *pluginscdn.Service,
-	starApi *starApi.API,
+	starApi *starApi.API, mfaService mfa.Service,
 ) (*HTTPServer, error) {
 	web.Env = cfg.Env
 	m := web.New()
@@ -271,6 +275,7 @@ func ProvideHTTPServer(opts ServerOptions, cfg *setting.Cfg, routeRegister routi
 		HooksService:                 hooksService,
 		CacheService:                 cacheService,
 		SQLStore:                     sqlStore,
+		sqlStore:                     sqlStore,
 		AlertEngine:                  alertEngine,
 		PluginRequestValidator:       pluginRequestValidator,
 		pluginInstaller:              pluginInstaller,
@@ -363,6 +368,7 @@ func ProvideHTTPServer(opts ServerOptions, cfg *setting.Cfg, routeRegister routi
 		oauthTokenService:            oauthTokenService,
 		statsService:                 statsService,
 		authnService:                 authnService,
+		mfaService:                   mfaService,
 		pluginsCDNService:            pluginsCDNService,
 		starApi:                      starApi,
 	}
@@ -622,6 +628,7 @@ func (hs *HTTPServer) addMiddlewaresAndStaticRoutes() {
 	// and should not be redirected or rejected.
 	m.Use(hs.healthzHandler)
 	m.Use(hs.apiHealthHandler)
+	m.Use(hs.apiHealthDetailsHandler)
 	m.Use(hs.metricsEndpoint)
 	m.Use(hs.pluginMetricsEndpoint)
 	m.Use(hs.frontendLogEndpoints())
@@ -680,7 +687,8 @@ func (hs *HTTPServer) healthzHandler(ctx *web.Context) {
 
 // apiHealthHandler will return ok if Grafana's web server is running and it
 // can access the database. If the database cannot be accessed it will return
-// http status code 503.
+// http status code 503. The status of the remote cache is reported as well,
+// a failing remote cache does not change the status code.
 func (hs *HTTPServer) apiHealthHandler(ctx *web.Context) {
 	notHeadOrGet := ctx.Req.Method != http.MethodGet && ctx.Req.Method != http.MethodHead
 	if notHeadOrGet || ctx.Req.URL.Path != "/api/health" {
@@ -693,6 +701,12 @@ func (hs *HTTPServer) apiHealthHandler(ctx *web.Context) {
 		data.Set("version", hs.Cfg.BuildVersion)
 		data.Set("commit", hs.Cfg.BuildCommit)
 	}
+	if hs.RemoteCacheService != nil {
+		data.Set("remoteCache", "ok")
+		if !hs.remoteCacheHealthy(ctx.Req.Context()) {
+			data.Set("remoteCache", "failing")
+		}
+	}
 
 	if !hs.databaseHealthy(ctx.Req.Context()) {
 		data.Set("database", "failing")
@@ -714,6 +728,41 @@ func (hs *HTTPServer) apiHealthHandler(ctx *web.Context) {
 	}
 }
 
+// apiHealthDetailsHandler reports the status and round trip latency of the database
+// and the remote cache. It returns http status code 503 if any of them is failing.
+func (hs *HTTPServer) apiHealthDetailsHandler(ctx *web.Context) {
+	notHeadOrGet := ctx.Req.Method != http.MethodGet && ctx.Req.Method != http.MethodHead
+	if notHeadOrGet || ctx.Req.URL.Path != "/api/health/details" {
+		return
+	}
+
+	data := simplejson.New()
+	status := http.StatusOK
+	for name, check := range hs.healthDetails(ctx.Req.Context()) {
+		data.Set(name, check)
+		if check.Status != "ok" {
+			status = http.StatusServiceUnavailable
+		}
+	}
+	if !hs.Cfg.AnonymousHideVersion {
+		data.Set("version", hs.Cfg.BuildVersion)
+		data.Set("commit", hs.Cfg.BuildCommit)
+	}
+
+	ctx.Resp.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	ctx.Resp.WriteHeader(status)
+
+	dataBytes, err := data.EncodePretty()
+	if err != nil {
+		hs.log.Error("Failed to encode data", "err", err)
+		return
+	}
+
+	if _, err := ctx.Resp.Write(dataBytes); err != nil {
+		hs.log.Error("Failed to write to response", "err", err)
+	}
+}
+
 func (hs *HTTPServer) mapStatic(m *web.Mux, rootDir string, dir string, prefix string, exclude ...string) {
 	headers := func(c *web.Context) {
 		c.Resp.Header().Set("Cache-Control", "public, max-age=3600")
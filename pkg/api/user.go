@@ -9,6 +9,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/services/authn/clients"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/login"
 	"github.com/grafana/grafana/pkg/services/org"
@@ -380,10 +381,62 @@ func (hs *HTTPServer) UserSetUsingOrg(c *contextmodel.ReqContext) response.Respo
 	if err := hs.userService.SetUsingOrg(c.Req.Context(), &cmd); err != nil {
 		return response.Error(500, "Failed to change active organization", err)
 	}
+	hs.setSessionOrg(c, orgID)
 
 	return response.Success("Active organization changed")
 }
 
+// swagger:route POST /user/session/using/{org_id} signed_in_user userSetSessionOrg
+//
+// Switch organization for the current session.
+//
+// Switch the current session to the given organization without changing the default organization of the user,
+// other sessions of the user are not affected. Requests that target an organization with the `X-Grafana-Org-Id`
+// header or the `targetOrgId` query parameter use that organization regardless of the session.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) UserSetSessionOrg(c *contextmodel.ReqContext) response.Response {
+	orgID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+
+	if c.UserToken == nil {
+		return response.Error(http.StatusBadRequest, "Switching organization for the session requires a session", nil)
+	}
+
+	if !hs.validateUsingOrg(c.Req.Context(), c.UserID, orgID) {
+		return response.Error(401, "Not a valid organization", nil)
+	}
+
+	if err := hs.sessionOrgs().Set(c.Req.Context(), c.UserToken.Id, orgID); err != nil {
+		return response.Error(500, "Failed to change active organization of session", err)
+	}
+
+	return response.Success("Active organization of session changed")
+}
+
+func (hs *HTTPServer) sessionOrgs() *clients.SessionOrgs {
+	return clients.NewSessionOrgs(hs.RemoteCacheService, hs.Cfg.LoginMaxLifetime)
+}
+
+// setSessionOrg switches the current session to the organization, so that a session that has
+// switched organization before follows the default organization of the user when it is changed.
+func (hs *HTTPServer) setSessionOrg(c *contextmodel.ReqContext, orgID int64) {
+	if c.UserToken == nil || hs.RemoteCacheService == nil {
+		return
+	}
+
+	if err := hs.sessionOrgs().Set(c.Req.Context(), c.UserToken.Id, orgID); err != nil {
+		hs.log.Warn("Failed to change active organization of session", "userID", c.UserID, "orgID", orgID, "err", err)
+	}
+}
+
 // GET /profile/switch-org/:id
 func (hs *HTTPServer) ChangeActiveOrgAndRedirectToHome(c *contextmodel.ReqContext) {
 	orgID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
@@ -401,6 +454,7 @@ func (hs *HTTPServer) ChangeActiveOrgAndRedirectToHome(c *contextmodel.ReqContex
 	if err := hs.userService.SetUsingOrg(c.Req.Context(), &cmd); err != nil {
 		hs.NotFoundHandler(c)
 	}
+	hs.setSessionOrg(c, orgID)
 
 	c.Redirect(hs.Cfg.AppSubURL + "/")
 }
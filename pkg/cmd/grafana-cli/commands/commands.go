@@ -58,7 +58,7 @@ func runDbCommand(command func(commandLine utils.CommandLine, sqlStore db.DB) er
 
 		bus := bus.ProvideBus(tracer)
 
-		sqlStore, err := db.ProvideService(cfg, nil, &migrations.OSSMigrations{}, bus, tracer)
+		sqlStore, err := db.ProvideService(cfg, nil, &migrations.OSSMigrations{}, bus, tracer, setting.ProvideProvider(cfg))
 		if err != nil {
 			return fmt.Errorf("%v: %w", "failed to initialize SQL store", err)
 		}
@@ -175,6 +175,21 @@ var adminCommands = []*cli.Command{
 			},
 		},
 	},
+	{
+		Name:   "migrations",
+		Usage:  "Lists the pending database migrations with --dry-run, or checks the migration log for divergences with --verify. Does not change the database.",
+		Action: runMigrationsCommand(migrationsCommand),
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "List the pending migrations and the SQL they would execute",
+			},
+			&cli.BoolFlag{
+				Name:  "verify",
+				Usage: "Check the migration log for failed migrations and migrations executed with another statement",
+			},
+		},
+	},
 	{
 		Name:  "secrets-migration",
 		Usage: "Runs a script that migrates secrets in your database",
@@ -86,7 +86,7 @@ func getSqlStore(cfg *setting.Cfg) (*sqlstore.SQLStore, error) {
 		return nil, fmt.Errorf("%v: %w", "failed to initialize tracer service", err)
 	}
 	bus := bus.ProvideBus(tracer)
-	return sqlstore.ProvideService(cfg, nil, &migrations.OSSMigrations{}, bus, tracer)
+	return sqlstore.ProvideService(cfg, nil, &migrations.OSSMigrations{}, bus, tracer, setting.ProvideProvider(cfg))
 }
 
 func runListConflictUsers() func(context *cli.Context) error {
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrations"
+)
+
+// runMigrationsCommand connects to the database without executing the migrations, so that they
+// can be inspected before upgrading.
+func runMigrationsCommand(command func(commandLine utils.CommandLine, sqlStore *sqlstore.SQLStore) error) func(context *cli.Context) error {
+	return func(context *cli.Context) error {
+		cmd := &utils.ContextCommandLine{Context: context}
+
+		cfg, err := initCfg(cmd)
+		if err != nil {
+			return fmt.Errorf("%v: %w", "failed to load configuration", err)
+		}
+
+		tracer, err := tracing.ProvideService(cfg)
+		if err != nil {
+			return fmt.Errorf("%v: %w", "failed to initialize tracer service", err)
+		}
+
+		sqlStore, err := sqlstore.NewWithoutMigrations(cfg, &migrations.OSSMigrations{}, bus.ProvideBus(tracer), tracer)
+		if err != nil {
+			return fmt.Errorf("%v: %w", "failed to initialize SQL store", err)
+		}
+
+		if err := command(cmd, sqlStore); err != nil {
+			return err
+		}
+
+		logger.Info("\n\n")
+		return nil
+	}
+}
+
+func migrationsCommand(c utils.CommandLine, sqlStore *sqlstore.SQLStore) error {
+	switch {
+	case c.Bool("dry-run") && c.Bool("verify"):
+		return errors.New("--dry-run and --verify can not be used together")
+	case c.Bool("verify"):
+		return verifyMigrations(sqlStore)
+	case c.Bool("dry-run"):
+		return planMigrations(sqlStore)
+	default:
+		return errors.New("one of --dry-run or --verify is required")
+	}
+}
+
+func planMigrations(sqlStore *sqlstore.SQLStore) error {
+	plan, err := sqlStore.MigrationPlan()
+	if err != nil {
+		return fmt.Errorf("%v: %w", "failed to plan the migrations", err)
+	}
+
+	if len(plan) == 0 {
+		logger.Info(color.GreenString("No pending migrations.\n"))
+		return nil
+	}
+
+	logger.Infof("%d pending migrations:\n\n", len(plan))
+	for _, m := range plan {
+		switch {
+		case m.Skipped:
+			logger.Infof("%s %s\n", color.New(color.Bold).Sprint(m.ID), color.YellowString("(skipped, only logged)"))
+		case m.CodeMigration:
+			logger.Infof("%s %s\n", color.New(color.Bold).Sprint(m.ID), color.YellowString("(code migration, statements depend on the data)"))
+		default:
+			logger.Infof("%s\n", color.New(color.Bold).Sprint(m.ID))
+		}
+		logger.Infof("%s\n\n", m.SQL)
+	}
+	return nil
+}
+
+func verifyMigrations(sqlStore *sqlstore.SQLStore) error {
+	divergences, err := sqlStore.VerifyMigrations()
+	if err != nil {
+		return fmt.Errorf("%v: %w", "failed to verify the migrations", err)
+	}
+
+	if len(divergences) == 0 {
+		logger.Info(color.GreenString("The migration log matches the migrations.\n"))
+		return nil
+	}
+
+	for _, d := range divergences {
+		logger.Infof("%s %s: %s\n", color.New(color.Bold).Sprint(d.MigrationID), color.RedString(d.Reason), d.Message)
+	}
+	return fmt.Errorf("the migration log has %d divergences", len(divergences))
+}
@@ -23,3 +23,20 @@ func TestApiKeyGen(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, result.HashedKey, keyHashed)
 }
+
+func TestApiKeyIsValid(t *testing.T) {
+	result, err := New(12, "Cool key")
+	require.NoError(t, err)
+
+	keyInfo, err := Decode(result.ClientSecret)
+	require.NoError(t, err)
+
+	valid, err := IsValid(keyInfo, result.HashedKey)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	keyInfo.Key = "not-the-key"
+	valid, err = IsValid(keyInfo, result.HashedKey)
+	require.NoError(t, err)
+	assert.False(t, valid)
+}
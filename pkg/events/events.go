@@ -78,3 +78,11 @@ type FolderTitleUpdated struct {
 	UID       string    `json:"uid"`
 	OrgID     int64     `json:"org_id"`
 }
+
+// IdentityChanged is published when the roles, teams, permissions or state of a user or
+// service account have changed. A zero UserID means that the change can affect any identity,
+// for example when permissions are granted to a team or a basic role.
+type IdentityChanged struct {
+	Timestamp time.Time `json:"timestamp"`
+	UserID    int64     `json:"user_id"`
+}
@@ -28,6 +28,8 @@ type InitTestDBOpt = sqlstore.InitTestDBOpt
 var InitTestDB = sqlstore.InitTestDB
 var InitTestDBwithCfg = sqlstore.InitTestDBWithCfg
 var ProvideService = sqlstore.ProvideService
+var WithReadReplica = sqlstore.WithReadReplica
+var WithTransientErrorRetries = sqlstore.WithTransientErrorRetries
 
 func IsTestDbSQLite() bool {
 	if db, present := os.LookupEnv("GRAFANA_TEST_DB"); !present || db == "sqlite" {
@@ -2,6 +2,7 @@ package kvstore
 
 import (
 	"context"
+	"time"
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
@@ -23,9 +24,15 @@ func ProvideService(sqlStore db.DB) KVStore {
 type KVStore interface {
 	Get(ctx context.Context, orgId int64, namespace string, key string) (string, bool, error)
 	Set(ctx context.Context, orgId int64, namespace string, key string, value string) error
+	// SetWithTTL sets an item that is not returned anymore once the ttl has passed, a ttl of 0
+	// sets an item that does not expire.
+	SetWithTTL(ctx context.Context, orgId int64, namespace string, key string, value string, ttl time.Duration) error
 	Del(ctx context.Context, orgId int64, namespace string, key string) error
 	Keys(ctx context.Context, orgId int64, namespace string, keyPrefix string) ([]Key, error)
 	GetAll(ctx context.Context, orgId int64, namespace string) (map[int64]map[string]string, error)
+	// DeleteExpired deletes the expired items of all organizations and namespaces, and returns
+	// the number of deleted items.
+	DeleteExpired(ctx context.Context) (int64, error)
 }
 
 // WithNamespace returns a kvstore wrapper with fixed orgId and namespace.
@@ -52,6 +59,10 @@ func (kv *NamespacedKVStore) Set(ctx context.Context, key string, value string)
 	return kv.kvStore.Set(ctx, kv.orgId, kv.namespace, key, value)
 }
 
+func (kv *NamespacedKVStore) SetWithTTL(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return kv.kvStore.SetWithTTL(ctx, kv.orgId, kv.namespace, key, value, ttl)
+}
+
 func (kv *NamespacedKVStore) Del(ctx context.Context, key string) error {
 	return kv.kvStore.Del(ctx, kv.orgId, kv.namespace, key)
 }
@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 func createTestableKVStore(t *testing.T) KVStore {
@@ -287,3 +291,169 @@ func TestGetItems(t *testing.T) {
 		}
 	})
 }
+
+func TestIntegrationKVStoreTTL(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	kv := createTestableKVStore(t)
+	ctx := context.Background()
+
+	expire := func(t *testing.T, key string) {
+		t.Helper()
+		err := kv.(*kvStoreSQL).sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+			_, err := sess.Exec("UPDATE kv_store SET expires = ? WHERE namespace = ? AND "+kv.(*kvStoreSQL).sqlStore.GetDialect().Quote("key")+" = ?",
+				time.Now().Add(-time.Second).Unix(), "ttl", key)
+			return err
+		})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, kv.SetWithTTL(ctx, 1, "ttl", "expired", "value", time.Hour))
+	require.NoError(t, kv.SetWithTTL(ctx, 1, "ttl", "not-expired", "value", time.Hour))
+	require.NoError(t, kv.SetWithTTL(ctx, 1, "ttl", "no-ttl", "value", 0))
+	expire(t, "expired")
+
+	t.Run("expired items are not returned", func(t *testing.T) {
+		_, ok, err := kv.Get(ctx, 1, "ttl", "expired")
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		for _, key := range []string{"not-expired", "no-ttl"} {
+			value, ok, err := kv.Get(ctx, 1, "ttl", key)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, "value", value)
+		}
+
+		keys, err := kv.Keys(ctx, 1, "ttl", "")
+		require.NoError(t, err)
+		require.ElementsMatch(t, []Key{{OrgId: 1, Namespace: "ttl", Key: "not-expired"}, {OrgId: 1, Namespace: "ttl", Key: "no-ttl"}}, keys)
+
+		items, err := kv.GetAll(ctx, 1, "ttl")
+		require.NoError(t, err)
+		require.Equal(t, map[int64]map[string]string{1: {"not-expired": "value", "no-ttl": "value"}}, items)
+	})
+
+	t.Run("setting an expired item again stores it", func(t *testing.T) {
+		require.NoError(t, kv.SetWithTTL(ctx, 1, "ttl", "renewed", "value", time.Hour))
+		expire(t, "renewed")
+		require.NoError(t, kv.SetWithTTL(ctx, 1, "ttl", "renewed", "value", time.Hour))
+
+		_, ok, err := kv.Get(ctx, 1, "ttl", "renewed")
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("set removes the ttl", func(t *testing.T) {
+		require.NoError(t, kv.SetWithTTL(ctx, 1, "ttl", "persisted", "value", time.Hour))
+		require.NoError(t, kv.Set(ctx, 1, "ttl", "persisted", "value"))
+		expire(t, "persisted")
+		require.NoError(t, kv.Set(ctx, 1, "ttl", "persisted", "value"))
+
+		_, ok, err := kv.Get(ctx, 1, "ttl", "persisted")
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("delete expired items", func(t *testing.T) {
+		deleted, err := kv.DeleteExpired(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), deleted)
+
+		keys, err := kv.Keys(ctx, 1, "ttl", "")
+		require.NoError(t, err)
+		require.Len(t, keys, 4)
+	})
+}
+
+func TestIntegrationKVStoreWatch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	type change struct {
+		value string
+		found bool
+	}
+
+	watch := func(t *testing.T, w *Watcher, key string) <-chan change {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		changes := make(chan change, 10)
+		done := make(chan struct{})
+		t.Cleanup(func() {
+			cancel()
+			<-done
+		})
+		go func() {
+			defer close(done)
+			_ = w.Watch(ctx, 1, "watch", key, func(value string, found bool) {
+				changes <- change{value: value, found: found}
+			})
+		}()
+		return changes
+	}
+
+	next := func(t *testing.T, changes <-chan change) change {
+		t.Helper()
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("no change notified")
+			return change{}
+		}
+	}
+
+	t.Run("changes are noticed by polling", func(t *testing.T) {
+		kv := createTestableKVStore(t)
+		w := &Watcher{KVStore: kv, pollInterval: 10 * time.Millisecond, log: log.New("infra.kvstore.watcher")}
+		ctx := context.Background()
+		require.NoError(t, kv.Set(ctx, 1, "watch", "key", "initial"))
+
+		changes := watch(t, w, "key")
+		require.Equal(t, change{value: "initial", found: true}, next(t, changes))
+
+		require.NoError(t, kv.Set(ctx, 1, "watch", "key", "updated"))
+		require.Equal(t, change{value: "updated", found: true}, next(t, changes))
+
+		require.NoError(t, kv.Set(ctx, 1, "watch", "other", "value"))
+		require.NoError(t, kv.Del(ctx, 1, "watch", "key"))
+		require.Equal(t, change{value: "", found: false}, next(t, changes))
+	})
+
+	t.Run("changes made through the watcher are published", func(t *testing.T) {
+		kv := createTestableKVStore(t)
+		cache, err := remotecache.ProvideService(&setting.Cfg{
+			RemoteCacheOptions: &setting.RemoteCacheOptions{Name: "memory"},
+		}, nil, fakes.NewFakeSecretsService())
+		require.NoError(t, err)
+		w := &Watcher{KVStore: kv, pubsub: cache, pollInterval: time.Hour, log: log.New("infra.kvstore.watcher")}
+		ns := w.WithNamespace(1, "watch")
+		ctx := context.Background()
+
+		changes := watch(t, w, "key")
+		require.Equal(t, change{value: "", found: false}, next(t, changes))
+
+		// the subscription starts after the initial value is read, changes made before are missed
+		revision := 0
+		require.Eventually(t, func() bool {
+			revision++
+			require.NoError(t, ns.Set(ctx, "key", fmt.Sprint(revision)))
+			select {
+			case c := <-changes:
+				require.True(t, c.found)
+				return true
+			case <-time.After(50 * time.Millisecond):
+				return false
+			}
+		}, 5*time.Second, time.Millisecond)
+
+		require.NoError(t, ns.SetWithTTL(ctx, "key", "expiring", time.Hour))
+		require.Equal(t, change{value: "expiring", found: true}, next(t, changes))
+
+		require.NoError(t, ns.Del(ctx, "key"))
+		require.Equal(t, change{value: "", found: false}, next(t, changes))
+	})
+}
@@ -14,12 +14,19 @@ type Item struct {
 
 	Created time.Time
 	Updated time.Time
+	// Expires is the unix timestamp in seconds after which the item is not returned anymore,
+	// nil for items that do not expire.
+	Expires *int64
 }
 
 func (i *Item) TableName() string {
 	return "kv_store"
 }
 
+func (i *Item) expired(now time.Time) bool {
+	return i.Expires != nil && *i.Expires <= now.Unix()
+}
+
 type Key struct {
 	OrgId     int64
 	Namespace string
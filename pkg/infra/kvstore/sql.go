@@ -34,16 +34,36 @@ func (kv *kvStoreSQL) Get(ctx context.Context, orgId int64, namespace string, ke
 			kv.log.Debug("kvstore value not found", "orgId", orgId, "namespace", namespace, "key", key)
 			return nil
 		}
+		if item.expired(time.Now()) {
+			kv.log.Debug("kvstore value expired", "orgId", orgId, "namespace", namespace, "key", key)
+			return nil
+		}
 		itemFound = true
 		kv.log.Debug("got kvstore value", "orgId", orgId, "namespace", namespace, "key", key, "value", item.Value)
 		return nil
 	})
 
+	if !itemFound {
+		return "", false, err
+	}
 	return item.Value, itemFound, err
 }
 
 // Set an item in the store
 func (kv *kvStoreSQL) Set(ctx context.Context, orgId int64, namespace string, key string, value string) error {
+	return kv.set(ctx, orgId, namespace, key, value, nil)
+}
+
+// SetWithTTL sets an item in the store that expires after the ttl
+func (kv *kvStoreSQL) SetWithTTL(ctx context.Context, orgId int64, namespace string, key string, value string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return kv.set(ctx, orgId, namespace, key, value, nil)
+	}
+	expires := time.Now().Add(ttl).Unix()
+	return kv.set(ctx, orgId, namespace, key, value, &expires)
+}
+
+func (kv *kvStoreSQL) set(ctx context.Context, orgId int64, namespace string, key string, value string, expires *int64) error {
 	return kv.sqlStore.WithTransactionalDbSession(ctx, func(dbSession *db.Session) error {
 		item := Item{
 			OrgId:     &orgId,
@@ -57,16 +77,17 @@ func (kv *kvStoreSQL) Set(ctx context.Context, orgId int64, namespace string, ke
 			return err
 		}
 
-		if has && item.Value == value {
+		if has && item.Value == value && sameExpiry(item.Expires, expires) {
 			kv.log.Debug("kvstore value not changed", "orgId", orgId, "namespace", namespace, "key", key, "value", value)
 			return nil
 		}
 
 		item.Value = value
 		item.Updated = time.Now()
+		item.Expires = expires
 
 		if has {
-			_, err = dbSession.Exec("UPDATE kv_store SET value = ?, updated = ? WHERE id = ?", item.Value, item.Updated, item.Id)
+			_, err = dbSession.Exec("UPDATE kv_store SET value = ?, updated = ?, expires = ? WHERE id = ?", item.Value, item.Updated, item.Expires, item.Id)
 			if err != nil {
 				kv.log.Debug("error updating kvstore value", "orgId", orgId, "namespace", namespace, "key", key, "value", value, "err", err)
 			} else {
@@ -105,6 +126,7 @@ func (kv *kvStoreSQL) Keys(ctx context.Context, orgId int64, namespace string, k
 		if orgId != AllOrganizations {
 			query.And("org_id = ?", orgId)
 		}
+		query.And("(expires IS NULL OR expires > ?)", time.Now().Unix())
 		return query.Find(&keys)
 	})
 	return keys, err
@@ -120,6 +142,7 @@ func (kv *kvStoreSQL) GetAll(ctx context.Context, orgId int64, namespace string)
 		if orgId != AllOrganizations {
 			query.And("org_id = ?", orgId)
 		}
+		query.And("(expires IS NULL OR expires > ?)", time.Now().Unix())
 
 		return query.Find(&results)
 	})
@@ -134,3 +157,24 @@ func (kv *kvStoreSQL) GetAll(ctx context.Context, orgId int64, namespace string)
 
 	return items, err
 }
+
+// DeleteExpired deletes the expired items of all organizations and namespaces.
+func (kv *kvStoreSQL) DeleteExpired(ctx context.Context) (int64, error) {
+	var affected int64
+	err := kv.sqlStore.WithDbSession(ctx, func(dbSession *db.Session) error {
+		res, err := dbSession.Exec("DELETE FROM kv_store WHERE expires <= ?", time.Now().Unix())
+		if err != nil {
+			return err
+		}
+		affected, err = res.RowsAffected()
+		return err
+	})
+	return affected, err
+}
+
+func sameExpiry(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -4,16 +4,18 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 )
 
 // In memory kv store used for testing
 type FakeKVStore struct {
 	store    map[Key]string
+	expires  map[Key]time.Time
 	delError bool
 }
 
 func NewFakeKVStore() *FakeKVStore {
-	return &FakeKVStore{store: make(map[Key]string)}
+	return &FakeKVStore{store: make(map[Key]string), expires: make(map[Key]time.Time)}
 }
 
 func (f *FakeKVStore) DeletionError(shouldErr bool) {
@@ -21,13 +23,26 @@ func (f *FakeKVStore) DeletionError(shouldErr bool) {
 }
 
 func (f *FakeKVStore) Get(ctx context.Context, orgId int64, namespace string, key string) (string, bool, error) {
-	value := f.store[buildKey(orgId, namespace, key)]
+	k := buildKey(orgId, namespace, key)
+	if expires, ok := f.expires[k]; ok && !time.Now().Before(expires) {
+		return "", false, nil
+	}
+	value := f.store[k]
 	found := value != ""
 	return value, found, nil
 }
 
 func (f *FakeKVStore) Set(ctx context.Context, orgId int64, namespace string, key string, value string) error {
-	f.store[buildKey(orgId, namespace, key)] = value
+	return f.SetWithTTL(ctx, orgId, namespace, key, value, 0)
+}
+
+func (f *FakeKVStore) SetWithTTL(ctx context.Context, orgId int64, namespace string, key string, value string, ttl time.Duration) error {
+	k := buildKey(orgId, namespace, key)
+	f.store[k] = value
+	delete(f.expires, k)
+	if ttl > 0 {
+		f.expires[k] = time.Now().Add(ttl)
+	}
 	return nil
 }
 
@@ -36,9 +51,22 @@ func (f *FakeKVStore) Del(ctx context.Context, orgId int64, namespace string, ke
 		return errors.New("mocked del error")
 	}
 	delete(f.store, buildKey(orgId, namespace, key))
+	delete(f.expires, buildKey(orgId, namespace, key))
 	return nil
 }
 
+func (f *FakeKVStore) DeleteExpired(ctx context.Context) (int64, error) {
+	var deleted int64
+	for k, expires := range f.expires {
+		if !time.Now().Before(expires) {
+			delete(f.store, k)
+			delete(f.expires, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
 // List all keys with an optional filter. If default values are provided, filter is not applied.
 func (f *FakeKVStore) Keys(ctx context.Context, orgId int64, namespace string, keyPrefix string) ([]Key, error) {
 	res := make([]Key, 0)
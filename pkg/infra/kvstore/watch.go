@@ -0,0 +1,147 @@
+package kvstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const defaultWatchPollInterval = 10 * time.Second
+
+// Watcher is a KVStore that notifies watchers of a key when its value changes. Watchers poll
+// the database, and when the remote cache is redis, writes made through the Watcher are also
+// published to the watchers of all instances so that they read the new value right away.
+type Watcher struct {
+	KVStore
+	pubsub       remotecache.PubSub
+	pollInterval time.Duration
+	log          log.Logger
+}
+
+// NewWatcher returns a Watcher for kv. The Watcher is not bound in wire, services that need to
+// watch keys create their own. Writes made directly through the KVStore are only noticed by polling.
+func NewWatcher(cfg *setting.Cfg, kv KVStore, remoteCache *remotecache.RemoteCache) *Watcher {
+	w := &Watcher{
+		KVStore:      kv,
+		pollInterval: defaultWatchPollInterval,
+		log:          log.New("infra.kvstore.watcher"),
+	}
+	// the pub/sub of the other backends is not shared between instances or polls the database itself
+	if cfg.RemoteCacheOptions != nil && cfg.RemoteCacheOptions.Name == "redis" {
+		w.pubsub = remoteCache
+	}
+	return w
+}
+
+// Set an item in the store and notify the watchers of the key
+func (w *Watcher) Set(ctx context.Context, orgId int64, namespace string, key string, value string) error {
+	if err := w.KVStore.Set(ctx, orgId, namespace, key, value); err != nil {
+		return err
+	}
+	w.notify(ctx, orgId, namespace, key)
+	return nil
+}
+
+// SetWithTTL sets an item in the store that expires after the ttl and notifies the watchers of the key
+func (w *Watcher) SetWithTTL(ctx context.Context, orgId int64, namespace string, key string, value string, ttl time.Duration) error {
+	if err := w.KVStore.SetWithTTL(ctx, orgId, namespace, key, value, ttl); err != nil {
+		return err
+	}
+	w.notify(ctx, orgId, namespace, key)
+	return nil
+}
+
+// Del deletes an item from the store and notifies the watchers of the key
+func (w *Watcher) Del(ctx context.Context, orgId int64, namespace string, key string) error {
+	if err := w.KVStore.Del(ctx, orgId, namespace, key); err != nil {
+		return err
+	}
+	w.notify(ctx, orgId, namespace, key)
+	return nil
+}
+
+// Watch calls handler with the current value of the key, and then every time the value changes
+// until the context is done. found is false while the key does not exist or has expired.
+// Changes are noticed by polling, changes that are reverted between two polls can be missed.
+func (w *Watcher) Watch(ctx context.Context, orgId int64, namespace string, key string, handler func(value string, found bool)) error {
+	value, found, err := w.KVStore.Get(ctx, orgId, namespace, key)
+	if err != nil {
+		return err
+	}
+	handler(value, found)
+
+	changed := make(chan struct{}, 1)
+	if w.pubsub != nil {
+		go func() {
+			err := w.pubsub.Subscribe(ctx, watchChannel(orgId, namespace, key), func(message []byte) {
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			})
+			if err != nil && ctx.Err() == nil {
+				w.log.Error("Failed to subscribe to kvstore changes, falling back to polling", "orgId", orgId, "namespace", namespace, "key", key, "error", err)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-changed:
+		}
+
+		newValue, newFound, err := w.KVStore.Get(ctx, orgId, namespace, key)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.log.Error("Failed to get watched kvstore value", "orgId", orgId, "namespace", namespace, "key", key, "error", err)
+			}
+			continue
+		}
+		if newValue == value && newFound == found {
+			continue
+		}
+		value, found = newValue, newFound
+		handler(value, found)
+	}
+}
+
+// WithNamespace returns a wrapper of the Watcher with fixed orgId and namespace.
+func (w *Watcher) WithNamespace(orgId int64, namespace string) *NamespacedWatcher {
+	return &NamespacedWatcher{
+		NamespacedKVStore: WithNamespace(w, orgId, namespace),
+		watcher:           w,
+	}
+}
+
+func (w *Watcher) notify(ctx context.Context, orgId int64, namespace string, key string) {
+	if w.pubsub == nil {
+		return
+	}
+	// the watchers still notice the change with the next poll
+	if err := w.pubsub.Publish(ctx, watchChannel(orgId, namespace, key), nil); err != nil {
+		w.log.Warn("Failed to publish kvstore change", "orgId", orgId, "namespace", namespace, "key", key, "error", err)
+	}
+}
+
+func watchChannel(orgId int64, namespace string, key string) string {
+	return fmt.Sprintf("kvstore:%d:%s:%s", orgId, namespace, key)
+}
+
+// NamespacedWatcher is a Watcher wrapper with fixed orgId and namespace.
+type NamespacedWatcher struct {
+	*NamespacedKVStore
+	watcher *Watcher
+}
+
+func (w *NamespacedWatcher) Watch(ctx context.Context, key string, handler func(value string, found bool)) error {
+	return w.watcher.Watch(ctx, w.orgId, w.namespace, key, handler)
+}
@@ -0,0 +1,157 @@
+package remotecache
+
+import (
+	"context"
+	"time"
+)
+
+type batchOpKind int
+
+const (
+	batchGet batchOpKind = iota
+	batchGetWithTTL
+	batchSet
+	batchTouch
+	batchDelete
+	batchPublish
+)
+
+// BatchResult is the result of an operation of a batch, it is set once the batch ran.
+type BatchResult struct {
+	// Value is the value read by GetByteArray and GetWithTTL
+	Value []byte
+	// TTL is the remaining time to live read by GetWithTTL, 0 for items without expiry
+	TTL time.Duration
+	Err error
+}
+
+// batchOp is an operation of a batch. Wrappers pass copies of the operations to the wrapped
+// cache, the copies share the result with the operation of the caller.
+type batchOp struct {
+	kind batchOpKind
+	// key is the channel of publish operations
+	key    string
+	value  []byte
+	expire time.Duration
+	// pubsub is used by publish operations if the backend cannot pipeline them
+	pubsub PubSub
+	result *BatchResult
+}
+
+func (op *batchOp) withKey(key string) *batchOp {
+	c := *op
+	c.key = key
+	return &c
+}
+
+func (op *batchOp) withValue(value []byte) *batchOp {
+	c := *op
+	c.value = value
+	return &c
+}
+
+// batcher is implemented by caches that run a batch differently than one operation after
+// the other, redis sends the whole batch in a single round trip.
+type batcher interface {
+	runBatch(ctx context.Context, ops []*batchOp)
+}
+
+// execBatch runs the operations with the batch support of the cache, or one by one if the
+// cache has none.
+func execBatch(ctx context.Context, cache CacheStorage, ops []*batchOp) {
+	if len(ops) == 0 {
+		return
+	}
+	if b, ok := cache.(batcher); ok {
+		b.runBatch(ctx, ops)
+		return
+	}
+	for _, op := range ops {
+		r := op.result
+		switch op.kind {
+		case batchGet:
+			r.Value, r.Err = cache.GetByteArray(ctx, op.key)
+		case batchGetWithTTL:
+			r.Value, r.TTL, r.Err = cache.GetWithTTL(ctx, op.key)
+		case batchSet:
+			r.Err = cache.SetByteArray(ctx, op.key, op.value, op.expire)
+		case batchTouch:
+			r.Err = cache.Touch(ctx, op.key, op.expire)
+		case batchDelete:
+			r.Err = cache.Delete(ctx, op.key)
+		case batchPublish:
+			r.Err = op.pubsub.Publish(ctx, op.key, op.value)
+		}
+	}
+}
+
+// Batch queues cache operations and runs them together with Exec. The redis backend sends
+// all operations of the batch in one round trip, the other backends run them one by one.
+// A batch is not a transaction, operations of other clients can run in between.
+type Batch struct {
+	cache *RemoteCache
+	ops   []*batchOp
+}
+
+// NewBatch returns an empty batch
+func (ds *RemoteCache) NewBatch() *Batch {
+	return &Batch{cache: ds}
+}
+
+func (b *Batch) add(op *batchOp) *BatchResult {
+	op.result = &BatchResult{}
+	b.ops = append(b.ops, op)
+	return op.result
+}
+
+// GetByteArray queues reading the value of the key
+func (b *Batch) GetByteArray(key string) *BatchResult {
+	return b.add(&batchOp{kind: batchGet, key: key})
+}
+
+// GetWithTTL queues reading the value and the remaining time to live of the key
+func (b *Batch) GetWithTTL(key string) *BatchResult {
+	return b.add(&batchOp{kind: batchGetWithTTL, key: key})
+}
+
+// SetByteArray queues storing the value of the key
+func (b *Batch) SetByteArray(key string, value []byte, expire time.Duration) *BatchResult {
+	return b.add(&batchOp{kind: batchSet, key: key, value: value, expire: expire})
+}
+
+// Touch queues setting the expiry of the key
+func (b *Batch) Touch(key string, expire time.Duration) *BatchResult {
+	return b.add(&batchOp{kind: batchTouch, key: key, expire: expire})
+}
+
+// Delete queues deleting the key
+func (b *Batch) Delete(key string) *BatchResult {
+	return b.add(&batchOp{kind: batchDelete, key: key})
+}
+
+// Publish queues publishing the message on the channel
+func (b *Batch) Publish(channel string, message []byte) *BatchResult {
+	return b.add(&batchOp{
+		kind:   batchPublish,
+		key:    b.cache.Cfg.RemoteCacheOptions.Prefix + channel,
+		value:  message,
+		pubsub: b.cache.pubsub,
+	})
+}
+
+// Exec runs the queued operations and empties the batch. It returns the first error of the
+// operations other than a cache miss, the result of each operation is set in its BatchResult.
+func (b *Batch) Exec(ctx context.Context) error {
+	ctx, cancel := b.cache.withTimeout(ctx)
+	defer cancel()
+
+	ops := b.ops
+	b.ops = nil
+	execBatch(ctx, b.cache.client, ops)
+	for _, op := range ops {
+		if op.result.Err != nil && !isCacheMiss(op.result.Err) {
+			return op.result.Err
+		}
+	}
+	return nil
+}
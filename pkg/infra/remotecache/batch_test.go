@@ -0,0 +1,130 @@
+package remotecache
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	cache, err := ProvideService(&setting.Cfg{
+		RemoteCacheOptions: &setting.RemoteCacheOptions{
+			Name:                 memoryCacheType,
+			Prefix:               "batch-",
+			Encryption:           true,
+			Compression:          CompressionSnappy,
+			CompressionThreshold: 1,
+			MaxItemSize:          1024,
+		},
+	}, nil, &reversingSecretsService{})
+	require.NoError(t, err)
+
+	pubsub := &recordingPubSub{}
+	cache.pubsub = pubsub
+
+	require.NoError(t, cache.SetByteArray(ctx, "existing", []byte("value"), time.Minute))
+	// random data is not made smaller by the compression
+	large := make([]byte, 2048)
+	_, err = rand.Read(large)
+	require.NoError(t, err)
+
+	b := cache.NewBatch()
+	set := b.SetByteArray("session", []byte("data"), time.Hour)
+	tooLarge := b.SetByteArray("large", large, time.Hour)
+	get := b.GetWithTTL("session")
+	existing := b.GetByteArray("existing")
+	missing := b.GetByteArray("missing")
+	touched := b.Touch("missing", time.Minute)
+	deleted := b.Delete("existing")
+	published := b.Publish("sessions", []byte("session"))
+
+	require.ErrorIs(t, b.Exec(ctx), ErrCacheItemTooLarge)
+	assert.NoError(t, set.Err)
+	assert.ErrorIs(t, tooLarge.Err, ErrCacheItemTooLarge)
+	require.NoError(t, get.Err)
+	assert.Equal(t, "data", string(get.Value))
+	assert.InDelta(t, time.Hour, get.TTL, float64(time.Minute))
+	require.NoError(t, existing.Err)
+	assert.Equal(t, "value", string(existing.Value))
+	assert.ErrorIs(t, missing.Err, ErrCacheItemNotFound)
+	assert.ErrorIs(t, touched.Err, ErrCacheItemNotFound)
+	assert.NoError(t, deleted.Err)
+	assert.NoError(t, published.Err)
+	assert.Equal(t, []string{"session"}, pubsub.published("batch-sessions"))
+
+	// the batch is empty after Exec
+	require.NoError(t, b.Exec(ctx))
+
+	_, err = cache.GetByteArray(ctx, "existing")
+	assert.ErrorIs(t, err, ErrCacheItemNotFound)
+	_, err = cache.GetByteArray(ctx, "large")
+	assert.ErrorIs(t, err, ErrCacheItemNotFound)
+	data, err := cache.GetByteArray(ctx, "session")
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+func TestTieredCacheStorage_Batch(t *testing.T) {
+	ctx := context.Background()
+	remote := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+	pubsub := &recordingPubSub{}
+	tiered := newTieredCacheStorage(remote, &pubSubInvalidator{pubsub: pubsub, channel: invalidationChannel}, &gobCodec{}, time.Minute, 100)
+
+	require.NoError(t, tiered.SetByteArray(ctx, "key", []byte("v1"), 0))
+	assert.Equal(t, []string{"key"}, pubsub.published(invalidationChannel))
+
+	// read into the local cache, then changed behind it
+	_, err := tiered.GetByteArray(ctx, "key")
+	require.NoError(t, err)
+	require.NoError(t, remote.SetByteArray(ctx, "key", []byte("changed behind the cache"), 0))
+
+	get := &batchOp{kind: batchGet, key: "key", result: &BatchResult{}}
+	set := &batchOp{kind: batchSet, key: "key", value: []byte("v2"), result: &BatchResult{}}
+	getAfterSet := &batchOp{kind: batchGet, key: "key", result: &BatchResult{}}
+	execBatch(ctx, tiered, []*batchOp{get, set, getAfterSet})
+
+	// the first read is served locally, the read after the change from the remote cache
+	assert.Equal(t, "v1", string(get.result.Value))
+	assert.NoError(t, set.result.Err)
+	assert.Equal(t, "v2", string(getAfterSet.result.Value))
+	assert.Equal(t, []string{"key", "key"}, pubsub.published(invalidationChannel))
+
+	data, err := tiered.GetByteArray(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+}
+
+// recordingPubSub records the published messages
+type recordingPubSub struct {
+	mu       sync.Mutex
+	messages map[string][]string
+}
+
+func (p *recordingPubSub) Publish(ctx context.Context, channel string, message []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.messages == nil {
+		p.messages = map[string][]string{}
+	}
+	p.messages[channel] = append(p.messages[channel], string(message))
+	return nil
+}
+
+func (p *recordingPubSub) Subscribe(ctx context.Context, channel string, handler func(message []byte)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *recordingPubSub) published(channel string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.messages[channel]
+}
@@ -0,0 +1,252 @@
+package remotecache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/grafana/grafana/pkg/registry"
+)
+
+const (
+	CompressionNone   = "none"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+// compressionMagic starts every value written with a compression header, the second byte
+// identifies the compression. Values without the header are returned as they are, so
+// values stored before compression was enabled and counters stay readable.
+const compressionMagic byte = 0xc7
+
+const (
+	compressionIDNone byte = iota
+	compressionIDSnappy
+	compressionIDZstd
+)
+
+var (
+	// ErrInvalidCompression is returned if the configured compression is unknown
+	ErrInvalidCompression = errors.New("invalid remote cache compression")
+
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// compressedCacheStorage compresses values larger than the threshold before they are stored
+// in the wrapped cache.
+type compressedCacheStorage struct {
+	cache     CacheStorage
+	codec     codec
+	id        byte
+	threshold int
+}
+
+func newCompressedCacheStorage(cache CacheStorage, codec codec, compression string, threshold int) (*compressedCacheStorage, error) {
+	var id byte
+	switch compression {
+	case CompressionSnappy:
+		id = compressionIDSnappy
+	case CompressionZstd:
+		id = compressionIDZstd
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCompression, compression)
+	}
+
+	return &compressedCacheStorage{cache: cache, codec: codec, id: id, threshold: threshold}, nil
+}
+
+func (s *compressedCacheStorage) compress(data []byte) []byte {
+	if len(data) < s.threshold {
+		// small values are stored as they are, unless they could be mistaken for a header
+		if len(data) > 0 && data[0] == compressionMagic {
+			return append([]byte{compressionMagic, compressionIDNone}, data...)
+		}
+		return data
+	}
+
+	header := []byte{compressionMagic, s.id}
+	switch s.id {
+	case compressionIDSnappy:
+		return append(header, snappy.Encode(nil, data)...)
+	default:
+		return zstdEncoder.EncodeAll(data, header)
+	}
+}
+
+func decompress(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != compressionMagic {
+		return data, nil
+	}
+
+	switch data[1] {
+	case compressionIDNone:
+		return data[2:], nil
+	case compressionIDSnappy:
+		return snappy.Decode(nil, data[2:])
+	case compressionIDZstd:
+		return zstdDecoder.DecodeAll(data[2:], nil)
+	default:
+		return nil, fmt.Errorf("unknown compression %d of cached value", data[1])
+	}
+}
+
+func (s *compressedCacheStorage) Get(ctx context.Context, key string) (interface{}, error) {
+	data, err := s.GetByteArray(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &cachedItem{}
+	if err := s.codec.Decode(ctx, data, item); err != nil {
+		return nil, err
+	}
+	return item.Val, nil
+}
+
+func (s *compressedCacheStorage) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	data, err := s.codec.Encode(ctx, &cachedItem{Val: value})
+	if err != nil {
+		return err
+	}
+	return s.SetByteArray(ctx, key, data, expire)
+}
+
+func (s *compressedCacheStorage) GetByteArray(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.cache.GetByteArray(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return decompress(data)
+}
+
+func (s *compressedCacheStorage) SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	return s.cache.SetByteArray(ctx, key, s.compress(value), expire)
+}
+
+func (s *compressedCacheStorage) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	data, ttl, err := s.cache.GetWithTTL(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err = decompress(data)
+	return data, ttl, err
+}
+
+func (s *compressedCacheStorage) Touch(ctx context.Context, key string, expire time.Duration) error {
+	return s.cache.Touch(ctx, key, expire)
+}
+
+func (s *compressedCacheStorage) Delete(ctx context.Context, key string) error {
+	return s.cache.Delete(ctx, key)
+}
+
+func (s *compressedCacheStorage) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	values, err := s.cache.GetMulti(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]byte, len(values))
+	for key, data := range values {
+		value, err := decompress(data)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+func (s *compressedCacheStorage) SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error {
+	compressed := make(map[string][]byte, len(items))
+	for key, value := range items {
+		compressed[key] = s.compress(value)
+	}
+	return s.cache.SetMulti(ctx, compressed, expire)
+}
+
+func (s *compressedCacheStorage) DeleteMulti(ctx context.Context, keys []string) error {
+	return s.cache.DeleteMulti(ctx, keys)
+}
+
+func (s *compressedCacheStorage) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return s.cache.Increment(ctx, key, delta, expire)
+}
+
+func (s *compressedCacheStorage) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return s.cache.Decrement(ctx, key, delta, expire)
+}
+
+func (s *compressedCacheStorage) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	return s.cache.SetIfNotExists(ctx, key, s.compress(value), expire)
+}
+
+// CompareAndSwap compares the decompressed value, since the stored value can have been
+// written with another compression.
+func (s *compressedCacheStorage) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	stored, err := s.cache.GetByteArray(ctx, key)
+	if err != nil {
+		if isCacheMiss(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	current, err := decompress(stored)
+	if err != nil || !bytes.Equal(current, old) {
+		return false, nil
+	}
+
+	return s.cache.CompareAndSwap(ctx, key, stored, s.compress(value), expire)
+}
+
+func (s *compressedCacheStorage) Scan(ctx context.Context, prefix string) ([]string, error) {
+	return s.cache.Scan(ctx, prefix)
+}
+
+func (s *compressedCacheStorage) DeleteByPrefix(ctx context.Context, prefix string) error {
+	return s.cache.DeleteByPrefix(ctx, prefix)
+}
+
+func (s *compressedCacheStorage) Count(ctx context.Context, prefix string) (int64, error) {
+	return s.cache.Count(ctx, prefix)
+}
+
+func (s *compressedCacheStorage) Stats(ctx context.Context) (*Stats, error) {
+	return s.cache.Stats(ctx)
+}
+
+// runBatch compresses the values of the batch before it runs and decompresses the values read by it.
+func (s *compressedCacheStorage) runBatch(ctx context.Context, ops []*batchOp) {
+	compressed := make([]*batchOp, 0, len(ops))
+	for _, op := range ops {
+		if op.kind == batchSet {
+			op = op.withValue(s.compress(op.value))
+		}
+		compressed = append(compressed, op)
+	}
+	execBatch(ctx, s.cache, compressed)
+
+	for _, op := range compressed {
+		r := op.result
+		if (op.kind == batchGet || op.kind == batchGetWithTTL) && r.Err == nil {
+			if r.Value, r.Err = decompress(r.Value); r.Err != nil {
+				r.TTL = 0
+			}
+		}
+	}
+}
+
+// Run runs the background jobs of the wrapped cache.
+func (s *compressedCacheStorage) Run(ctx context.Context) error {
+	if backgroundjob, ok := s.cache.(registry.BackgroundService); ok {
+		return backgroundjob.Run(ctx)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
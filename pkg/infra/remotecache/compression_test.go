@@ -0,0 +1,68 @@
+package remotecache
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressedCacheStorage(t *testing.T) {
+	for _, compression := range []string{CompressionSnappy, CompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			ctx := context.Background()
+			backend := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+			client, err := newCompressedCacheStorage(backend, &gobCodec{}, compression, 64)
+			require.NoError(t, err)
+
+			runTestsForClient(t, client)
+
+			large := bytes.Repeat([]byte("grafana"), 100)
+			require.NoError(t, client.SetByteArray(ctx, "large", large, time.Minute))
+			stored, err := backend.GetByteArray(ctx, "large")
+			require.NoError(t, err)
+			assert.Less(t, len(stored), len(large))
+
+			data, err := client.GetByteArray(ctx, "large")
+			require.NoError(t, err)
+			assert.Equal(t, large, data)
+
+			// small values are stored as they are
+			require.NoError(t, client.SetByteArray(ctx, "small", []byte("value"), time.Minute))
+			stored, err = backend.GetByteArray(ctx, "small")
+			require.NoError(t, err)
+			assert.Equal(t, "value", string(stored))
+		})
+	}
+}
+
+func TestCompressedCacheStorage_ReadsOtherCompressions(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+	snappyClient, err := newCompressedCacheStorage(backend, &gobCodec{}, CompressionSnappy, 0)
+	require.NoError(t, err)
+	zstdClient, err := newCompressedCacheStorage(backend, &gobCodec{}, CompressionZstd, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, snappyClient.SetByteArray(ctx, "key", []byte("value"), time.Minute))
+	data, err := zstdClient.GetByteArray(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(data))
+
+	// a small value starting like a header is not mistaken for a compressed value
+	smallClient, err := newCompressedCacheStorage(backend, &gobCodec{}, CompressionZstd, 64)
+	require.NoError(t, err)
+	value := []byte{compressionMagic, compressionIDSnappy, 'x'}
+	require.NoError(t, smallClient.SetByteArray(ctx, "key", value, time.Minute))
+	data, err = smallClient.GetByteArray(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, value, data)
+}
+
+func TestNewCompressedCacheStorage_Invalid(t *testing.T) {
+	_, err := newCompressedCacheStorage(nil, &gobCodec{}, "lz4", 0)
+	assert.ErrorIs(t, err, ErrInvalidCompression)
+}
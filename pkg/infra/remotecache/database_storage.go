@@ -1,59 +1,245 @@
 package remotecache
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 var getTime = time.Now
 
 const databaseCacheType = "database"
 
+const (
+	defaultDatabaseGCInterval  = 10 * time.Minute
+	defaultDatabaseGCBatchSize = 1000
+
+	// accessResolution is the number of seconds within which repeated reads of a row are
+	// not recorded, the eviction order is approximate
+	accessResolution = 60
+)
+
 type databaseCache struct {
-	SQLStore db.DB
-	codec    codec
-	log      log.Logger
+	SQLStore    db.DB
+	codec       codec
+	log         log.Logger
+	gcInterval  time.Duration
+	gcBatchSize int
+	// maxRows and maxBytes limit the size of the table, 0 disables the limit
+	maxRows  int64
+	maxBytes int64
 }
 
 func newDatabaseCache(sqlstore db.DB, codec codec) *databaseCache {
+	return newDatabaseCacheWithOptions(sqlstore, codec, &setting.RemoteCacheOptions{})
+}
+
+// newDatabaseCacheWithOptions creates a database cache with the garbage collection and size
+// limits of the options, zero values use the defaults.
+func newDatabaseCacheWithOptions(sqlstore db.DB, codec codec, opts *setting.RemoteCacheOptions) *databaseCache {
 	dc := &databaseCache{
-		SQLStore: sqlstore,
-		codec:    codec,
-		log:      log.New("remotecache.database"),
+		SQLStore:    sqlstore,
+		codec:       codec,
+		log:         log.New("remotecache.database"),
+		gcInterval:  opts.DatabaseGCInterval,
+		gcBatchSize: opts.DatabaseGCBatchSize,
+		maxRows:     opts.DatabaseMaxRows,
+		maxBytes:    opts.DatabaseMaxBytes,
 	}
 
 	return dc
 }
 
 func (dc *databaseCache) Run(ctx context.Context) error {
-	ticker := time.NewTicker(time.Minute * 10)
+	interval := dc.gcInterval
+	if interval <= 0 {
+		interval = defaultDatabaseGCInterval
+	}
+
+	ticker := time.NewTicker(interval)
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			dc.internalRunGC()
+			dc.internalRunGC(ctx)
 		}
 	}
 }
 
-func (dc *databaseCache) internalRunGC() {
-	err := dc.SQLStore.WithDbSession(context.Background(), func(session *db.Session) error {
-		now := getTime().Unix()
-		sql := `DELETE FROM cache_data WHERE (? - created_at) >= expires AND expires <> 0`
+// internalRunGC deletes the expired rows in batches. Every batch runs in its own session,
+// so rows are not locked for long on busy databases.
+func (dc *databaseCache) internalRunGC(ctx context.Context) {
+	batchSize := dc.gcBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultDatabaseGCBatchSize
+	}
 
-		_, err := session.Exec(sql, now)
-		return err
-	})
+	start := time.Now()
+	var purged int64
+	for {
+		selected, deleted, err := dc.deleteExpiredBatch(ctx, batchSize)
+		purged += deleted
+		if err != nil {
+			dc.log.Error("failed to run garbage collect", "error", err)
+			break
+		}
+		if selected < batchSize {
+			break
+		}
+	}
 
+	evicted, err := dc.evict(ctx, batchSize)
 	if err != nil {
-		dc.log.Error("failed to run garbage collect", "error", err)
+		dc.log.Error("failed to evict cache items", "error", err)
+	}
+
+	gcPurgedRows.Observe(float64(purged))
+	gcEvictedRows.Add(float64(evicted))
+	gcDuration.Observe(time.Since(start).Seconds())
+}
+
+// evict deletes the least recently accessed rows until the table is within the size limits.
+func (dc *databaseCache) evict(ctx context.Context, batchSize int) (int64, error) {
+	if dc.maxRows <= 0 && dc.maxBytes <= 0 {
+		return 0, nil
+	}
+
+	var evicted int64
+	for {
+		var keys []string
+		err := dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+			var err error
+			keys, err = dc.selectEvictionBatch(session, batchSize)
+			return err
+		})
+		if err != nil || len(keys) == 0 {
+			return evicted, err
+		}
+
+		if err := dc.DeleteMulti(ctx, keys); err != nil {
+			return evicted, err
+		}
+		evicted += int64(len(keys))
 	}
 }
 
+// selectEvictionBatch returns up to batchSize of the least recently accessed keys that have to
+// be deleted to bring the table within the limits, none if it is within the limits.
+func (dc *databaseCache) selectEvictionBatch(session *db.Session, batchSize int) ([]string, error) {
+	type size struct {
+		Items int64
+		Bytes int64
+	}
+	sizes := []size{}
+	sql := `SELECT COUNT(*) AS items, COALESCE(SUM(LENGTH(data)), 0) AS bytes FROM cache_data`
+	if err := session.SQL(sql).Find(&sizes); err != nil || len(sizes) == 0 {
+		return nil, err
+	}
+
+	excessRows, excessBytes := int64(0), int64(0)
+	if dc.maxRows > 0 && sizes[0].Items > dc.maxRows {
+		excessRows = sizes[0].Items - dc.maxRows
+	}
+	if dc.maxBytes > 0 && sizes[0].Bytes > dc.maxBytes {
+		excessBytes = sizes[0].Bytes - dc.maxBytes
+	}
+	if excessRows == 0 && excessBytes == 0 {
+		return nil, nil
+	}
+
+	type candidate struct {
+		CacheKey string
+		Size     int64
+	}
+	candidates := []candidate{}
+	sql = `SELECT cache_key, LENGTH(data) AS size FROM cache_data ORDER BY last_accessed_at` + dc.SQLStore.GetDialect().Limit(int64(batchSize))
+	if err := session.SQL(sql).Find(&candidates); err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, c := range candidates {
+		if int64(len(keys)) >= excessRows && excessBytes <= 0 {
+			break
+		}
+		keys = append(keys, c.CacheKey)
+		excessBytes -= c.Size
+	}
+	return keys, nil
+}
+
+// trackAccess is only needed when the table is limited, rows written are marked as accessed.
+func (dc *databaseCache) trackAccess() bool {
+	return dc.maxRows > 0 || dc.maxBytes > 0
+}
+
+// recordAccess updates the last access time of the rows, at most once per accessResolution to
+// avoid a write for every read.
+func (dc *databaseCache) recordAccess(session *db.Session, rows ...CacheData) {
+	now := getTime().Unix()
+	keys := []string{}
+	for _, row := range rows {
+		if now-row.LastAccessedAt >= accessResolution {
+			keys = append(keys, row.CacheKey)
+		}
+	}
+
+	for _, chunk := range chunkKeys(keys) {
+		args := make([]interface{}, 0, len(chunk)+2)
+		args = append(args, "UPDATE cache_data SET last_accessed_at=? WHERE cache_key IN (?"+strings.Repeat(",?", len(chunk)-1)+")", now)
+		for _, key := range chunk {
+			args = append(args, key)
+		}
+		// a failed update only makes the row more likely to be evicted
+		if _, err := session.Exec(args...); err != nil {
+			dc.log.Debug("Failed to record cache access", "error", err)
+		}
+	}
+}
+
+// deleteExpiredBatch deletes up to batchSize expired rows and returns the number of rows that
+// were selected and deleted. Rows set again in between are left alone.
+func (dc *databaseCache) deleteExpiredBatch(ctx context.Context, batchSize int) (selected int, deleted int64, err error) {
+	err = dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		now := getTime().Unix()
+		keys := []string{}
+		sql := `SELECT cache_key FROM cache_data WHERE (? - created_at) >= expires AND expires <> 0` + dc.SQLStore.GetDialect().Limit(int64(batchSize))
+		if err := session.SQL(sql, now).Find(&keys); err != nil {
+			return err
+		}
+		selected = len(keys)
+
+		for _, chunk := range chunkKeys(keys) {
+			args := make([]interface{}, 0, len(chunk)+2)
+			args = append(args, "DELETE FROM cache_data WHERE cache_key IN (?"+strings.Repeat(",?", len(chunk)-1)+") AND (? - created_at) >= expires AND expires <> 0")
+			for _, key := range chunk {
+				args = append(args, key)
+			}
+			args = append(args, now)
+
+			res, err := session.Exec(args...)
+			if err != nil {
+				return err
+			}
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			deleted += affected
+		}
+		return nil
+	})
+	return selected, deleted, err
+}
+
 func (dc *databaseCache) GetByteArray(ctx context.Context, key string) ([]byte, error) {
 	cacheHit := CacheData{}
 
@@ -79,12 +265,68 @@ func (dc *databaseCache) GetByteArray(ctx context.Context, key string) ([]byte,
 			}
 		}
 
+		if dc.trackAccess() {
+			dc.recordAccess(session, cacheHit)
+		}
 		return nil
 	})
 
 	return cacheHit.Data, err
 }
 
+func (dc *databaseCache) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	row := CacheData{}
+	err := dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		exist, err := session.Where("cache_key = ?", key).Get(&row)
+		if err != nil {
+			return err
+		}
+		if !exist || (row.Expires > 0 && getTime().Unix()-row.CreatedAt >= row.Expires) {
+			return ErrCacheItemNotFound
+		}
+		if dc.trackAccess() {
+			dc.recordAccess(session, row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ttl time.Duration
+	if row.Expires > 0 {
+		ttl = time.Duration(row.CreatedAt+row.Expires-getTime().Unix()) * time.Second
+	}
+	return row.Data, ttl, nil
+}
+
+// Touch restarts the expiry of the item, the expiry is stored relative to created_at.
+func (dc *databaseCache) Touch(ctx context.Context, key string, expire time.Duration) error {
+	return dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		now := getTime().Unix()
+		sql := `UPDATE cache_data SET created_at=?, expires=?, last_accessed_at=? WHERE cache_key=? AND (expires = 0 OR (? - created_at) < expires)`
+		res, err := session.Exec(sql, now, int64(expire/time.Second), now, key, now)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil || affected == 1 {
+			return err
+		}
+
+		// MySQL reports unchanged rows as not affected, which happens when the item is
+		// touched twice within a second
+		exist, err := session.Where("cache_key = ? AND created_at = ?", key, now).Exist(&CacheData{})
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return ErrCacheItemNotFound
+		}
+		return nil
+	})
+}
+
 func (dc *databaseCache) Get(ctx context.Context, key string) (interface{}, error) {
 	bytes, err := dc.GetByteArray(ctx, key)
 	if err != nil {
@@ -107,16 +349,17 @@ func (dc *databaseCache) SetByteArray(ctx context.Context, key string, data []by
 		}
 
 		// attempt to insert the key
-		sql := `INSERT INTO cache_data (cache_key,data,created_at,expires) VALUES(?,?,?,?)`
-		_, err := session.Exec(sql, key, data, getTime().Unix(), expiresInSeconds)
+		sql := `INSERT INTO cache_data (cache_key,data,created_at,expires,last_accessed_at) VALUES(?,?,?,?,?)`
+		now := getTime().Unix()
+		_, err := session.Exec(sql, key, data, now, expiresInSeconds, now)
 		if err != nil {
 			// attempt to update if a unique constrain violation or a deadlock (for MySQL) occurs
 			// if the update fails propagate the error
 			// which eventually will result in a key that is not finally set
 			// but since it's a cache does not harm a lot
 			if dc.SQLStore.GetDialect().IsUniqueConstraintViolation(err) || dc.SQLStore.GetDialect().IsDeadlock(err) {
-				sql := `UPDATE cache_data SET data=?, created_at=?, expires=? WHERE cache_key=?`
-				_, err = session.Exec(sql, data, getTime().Unix(), expiresInSeconds, key)
+				sql := `UPDATE cache_data SET data=?, created_at=?, expires=?, last_accessed_at=? WHERE cache_key=?`
+				_, err = session.Exec(sql, data, now, expiresInSeconds, now, key)
 				if err != nil && dc.SQLStore.GetDialect().IsDeadlock(err) {
 					// most probably somebody else is upserting the key
 					// so it is safe enough not to propagate this error
@@ -148,6 +391,234 @@ func (dc *databaseCache) Delete(ctx context.Context, key string) error {
 	})
 }
 
+// maxKeysPerQuery keeps the number of parameters of a query below the limits of the databases
+const maxKeysPerQuery = 500
+
+func (dc *databaseCache) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	err := dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		now := getTime().Unix()
+		for _, chunk := range chunkKeys(keys) {
+			var rows []CacheData
+			if err := session.In("cache_key", chunk).Find(&rows); err != nil {
+				return err
+			}
+			found := make([]CacheData, 0, len(rows))
+			for _, row := range rows {
+				// expired rows are removed by the garbage collection
+				if row.Expires > 0 && now-row.CreatedAt >= row.Expires {
+					continue
+				}
+				result[row.CacheKey] = row.Data
+				found = append(found, row)
+			}
+			if dc.trackAccess() {
+				dc.recordAccess(session, found...)
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (dc *databaseCache) SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error {
+	for key, data := range items {
+		if err := dc.SetByteArray(ctx, key, data, expire); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dc *databaseCache) DeleteMulti(ctx context.Context, keys []string) error {
+	return dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		for _, chunk := range chunkKeys(keys) {
+			args := make([]interface{}, 0, len(chunk)+1)
+			args = append(args, "DELETE FROM cache_data WHERE cache_key IN (?"+strings.Repeat(",?", len(chunk)-1)+")")
+			for _, key := range chunk {
+				args = append(args, key)
+			}
+			if _, err := session.Exec(args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func chunkKeys(keys []string) [][]string {
+	var chunks [][]string
+	for len(keys) > maxKeysPerQuery {
+		chunks = append(chunks, keys[:maxKeysPerQuery])
+		keys = keys[maxKeysPerQuery:]
+	}
+	if len(keys) > 0 {
+		chunks = append(chunks, keys)
+	}
+	return chunks
+}
+
+// SetIfNotExists inserts the item, an existing but expired item is replaced with an
+// UPDATE conditional on it being expired.
+func (dc *databaseCache) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	var ok bool
+	err := dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		now := getTime().Unix()
+		expiresInSeconds := int64(expire / time.Second)
+
+		sql := `INSERT INTO cache_data (cache_key,data,created_at,expires,last_accessed_at) VALUES(?,?,?,?,?)`
+		_, err := session.Exec(sql, key, value, now, expiresInSeconds, now)
+		if err == nil {
+			ok = true
+			return nil
+		}
+		if !dc.SQLStore.GetDialect().IsUniqueConstraintViolation(err) {
+			return err
+		}
+
+		sql = `UPDATE cache_data SET data=?, created_at=?, expires=?, last_accessed_at=? WHERE cache_key=? AND expires <> 0 AND (? - created_at) >= expires`
+		res, err := session.Exec(sql, value, now, expiresInSeconds, now, key, now)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		ok = affected == 1
+		return err
+	})
+	return ok, err
+}
+
+// CompareAndSwap emulates compare-and-swap with an UPDATE conditional on the current value.
+func (dc *databaseCache) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	var ok bool
+	err := dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		now := getTime().Unix()
+		expiresInSeconds := int64(expire / time.Second)
+
+		sql := `UPDATE cache_data SET data=?, created_at=?, expires=?, last_accessed_at=? WHERE cache_key=? AND data=? AND (expires = 0 OR (? - created_at) < expires)`
+		res, err := session.Exec(sql, value, now, expiresInSeconds, now, key, old, now)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 1 || !bytes.Equal(old, value) {
+			ok = affected == 1
+			return nil
+		}
+
+		// MySQL reports unchanged rows as not affected, which happens when the same
+		// value is swapped in within the same second
+		row := CacheData{}
+		exist, err := session.Where("cache_key = ? AND data = ?", key, value).Get(&row)
+		ok = exist
+		return err
+	})
+	return ok, err
+}
+
+// maxCounterRetries limits how often a counter update is retried when other instances
+// update the same counter concurrently
+const maxCounterRetries = 10
+
+// Increment reads the counter and writes the new value with an UPDATE conditional on the value
+// read, the data column is a blob so the arithmetic cannot be done by the database itself.
+// The update is retried if the counter was changed in between.
+func (dc *databaseCache) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	for i := 0; i < maxCounterRetries; i++ {
+		value, ok, err := dc.tryIncrement(ctx, key, delta, expire)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("failed to update counter %q after %d attempts", key, maxCounterRetries)
+}
+
+func (dc *databaseCache) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return dc.Increment(ctx, key, -delta, expire)
+}
+
+func (dc *databaseCache) tryIncrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, bool, error) {
+	var value int64
+	var ok bool
+	err := dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		now := getTime().Unix()
+		expiresInSeconds := int64(expire / time.Second)
+
+		row := CacheData{}
+		exist, err := session.Where("cache_key = ?", key).Get(&row)
+		if err != nil {
+			return err
+		}
+
+		if !exist {
+			value = delta
+			sql := `INSERT INTO cache_data (cache_key,data,created_at,expires,last_accessed_at) VALUES(?,?,?,?,?)`
+			_, err := session.Exec(sql, key, []byte(strconv.FormatInt(value, 10)), now, expiresInSeconds, now)
+			if err != nil && dc.SQLStore.GetDialect().IsUniqueConstraintViolation(err) {
+				// the counter was created by somebody else, retry with an update
+				return nil
+			}
+			ok = err == nil
+			return err
+		}
+
+		createdAt, expires := row.CreatedAt, row.Expires
+		if row.Expires > 0 && now-row.CreatedAt >= row.Expires {
+			// an expired counter starts over
+			createdAt, expires = now, expiresInSeconds
+		} else {
+			current, err := strconv.ParseInt(string(row.Data), 10, 64)
+			if err != nil {
+				return ErrCacheItemNotCounter
+			}
+			value = current
+		}
+		value += delta
+
+		sql := `UPDATE cache_data SET data=?, created_at=?, expires=?, last_accessed_at=? WHERE cache_key=? AND data=? AND created_at=?`
+		res, err := session.Exec(sql, []byte(strconv.FormatInt(value, 10)), createdAt, expires, now, key, row.Data, row.CreatedAt)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		// MySQL reports unchanged rows as not affected, a zero delta does not change the counter
+		ok = affected == 1 || (delta == 0 && createdAt == row.CreatedAt)
+		return nil
+	})
+	return value, ok, err
+}
+
+func (dc *databaseCache) Scan(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	err := dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		sql := "SELECT cache_key FROM cache_data WHERE cache_key LIKE ? ESCAPE '!' AND (expires = 0 OR (? - created_at) < expires)"
+		return session.SQL(sql, likePrefix(prefix), getTime().Unix()).Find(&keys)
+	})
+	return keys, err
+}
+
+func (dc *databaseCache) DeleteByPrefix(ctx context.Context, prefix string) error {
+	return dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		_, err := session.Exec("DELETE FROM cache_data WHERE cache_key LIKE ? ESCAPE '!'", likePrefix(prefix))
+		return err
+	})
+}
+
+// likePrefix returns a LIKE pattern matching the prefix, '!' is used as the escape character
+// since a backslash has to be escaped differently by every database.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(prefix) + "%"
+}
+
 func (dc *databaseCache) Count(ctx context.Context, prefix string) (int64, error) {
 	res := int64(0)
 	err := dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
@@ -164,10 +635,33 @@ func (dc *databaseCache) Count(ctx context.Context, prefix string) (int64, error
 	return res, err
 }
 
+// Stats counts the items that have not expired and the size of their values.
+func (dc *databaseCache) Stats(ctx context.Context) (*Stats, error) {
+	type stats struct {
+		Items int64
+		Bytes int64
+	}
+
+	rows := []stats{}
+	err := dc.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		sql := `SELECT COUNT(*) AS items, COALESCE(SUM(LENGTH(data)), 0) AS bytes FROM cache_data
+			WHERE expires = 0 OR (? - created_at) < expires`
+		return session.SQL(sql, getTime().Unix()).Find(&rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return &Stats{}, nil
+	}
+	return &Stats{Items: rows[0].Items, Bytes: rows[0].Bytes}, nil
+}
+
 // CacheData is the struct representing the table in the database
 type CacheData struct {
-	CacheKey  string
-	Data      []byte
-	Expires   int64
-	CreatedAt int64
+	CacheKey       string
+	Data           []byte
+	Expires        int64
+	CreatedAt      int64
+	LastAccessedAt int64
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 func TestDatabaseStorageGarbageCollection(t *testing.T) {
@@ -44,7 +45,7 @@ func TestDatabaseStorageGarbageCollection(t *testing.T) {
 	assert.Equal(t, err, nil)
 
 	// run GC
-	db.internalRunGC()
+	db.internalRunGC(context.Background())
 
 	// try to read values
 	_, err = db.Get(context.Background(), "key1")
@@ -60,6 +61,47 @@ func TestDatabaseStorageGarbageCollection(t *testing.T) {
 	assert.Equal(t, err, nil)
 }
 
+func TestDatabaseStorageGarbageCollectionInBatches(t *testing.T) {
+	sqlstore := db.InitTestDB(t)
+	db := newDatabaseCacheWithOptions(sqlstore, &gobCodec{}, &setting.RemoteCacheOptions{DatabaseGCBatchSize: 2})
+
+	getTime = func() time.Time { return time.Now().AddDate(0, 0, -2) }
+	for _, key := range []string{"key1", "key2", "key3", "key4", "key5"} {
+		require.NoError(t, db.SetByteArray(context.Background(), key, []byte("value"), time.Hour))
+	}
+	require.NoError(t, db.SetByteArray(context.Background(), "forever", []byte("value"), 0))
+
+	getTime = time.Now
+	require.NoError(t, db.SetByteArray(context.Background(), "fresh", []byte("value"), time.Hour))
+
+	db.internalRunGC(context.Background())
+
+	n, err := db.Count(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestDatabaseStorageEvictsLeastRecentlyAccessed(t *testing.T) {
+	ctx := context.Background()
+	sqlstore := db.InitTestDB(t)
+	db := newDatabaseCacheWithOptions(sqlstore, &gobCodec{}, &setting.RemoteCacheOptions{DatabaseMaxRows: 2})
+
+	getTime = func() time.Time { return time.Now().Add(-time.Hour) }
+	require.NoError(t, db.SetByteArray(ctx, "old", []byte("value"), 0))
+	require.NoError(t, db.SetByteArray(ctx, "read", []byte("value"), 0))
+
+	getTime = time.Now
+	_, err := db.GetByteArray(ctx, "read")
+	require.NoError(t, err)
+	require.NoError(t, db.SetByteArray(ctx, "new", []byte("value"), 0))
+
+	db.internalRunGC(context.Background())
+
+	keys, err := db.Scan(ctx, "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"read", "new"}, keys)
+}
+
 func TestSecondSet(t *testing.T) {
 	var err error
 	sqlstore := db.InitTestDB(t)
@@ -111,10 +153,44 @@ func TestDatabaseStorageCount(t *testing.T) {
 	require.NoError(t, err)
 
 	// run GC
-	db.internalRunGC()
+	db.internalRunGC(context.Background())
 
 	// try to read values
 	n, errC := db.Count(context.Background(), "pref-")
 	require.NoError(t, errC)
 	assert.Equal(t, int64(2), n)
 }
+
+func TestDatabaseStorageExpiredCounterStartsOver(t *testing.T) {
+	sqlstore := db.InitTestDB(t)
+	db := newDatabaseCache(sqlstore, &gobCodec{})
+
+	getTime = func() time.Time { return time.Now().AddDate(0, 0, -2) }
+	value, err := db.Increment(context.Background(), "counter", 10, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), value)
+
+	getTime = time.Now
+	value, err = db.Increment(context.Background(), "counter", 1, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+}
+
+func TestDatabaseStorageSetIfNotExistsReplacesExpiredItem(t *testing.T) {
+	sqlstore := db.InitTestDB(t)
+	db := newDatabaseCache(sqlstore, &gobCodec{})
+
+	getTime = func() time.Time { return time.Now().AddDate(0, 0, -2) }
+	ok, err := db.SetIfNotExists(context.Background(), "leader", []byte("instance-1"), time.Hour)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	getTime = time.Now
+	ok, err = db.SetIfNotExists(context.Background(), "leader", []byte("instance-2"), time.Hour)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	data, err := db.GetByteArray(context.Background(), "leader")
+	require.NoError(t, err)
+	assert.Equal(t, "instance-2", string(data))
+}
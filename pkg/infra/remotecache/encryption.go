@@ -0,0 +1,259 @@
+package remotecache
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/registry"
+	"github.com/grafana/grafana/pkg/services/secrets"
+)
+
+// encryptedCacheStorage encrypts the values of the cache with data keys of the secrets
+// service, either for all keys or only for keys with one of the configured prefixes.
+// Counters are stored in plaintext since the backends have to do arithmetic on them.
+type encryptedCacheStorage struct {
+	cache          CacheStorage
+	secretsService secrets.Service
+	codec          codec
+	// prefixes of the keys to encrypt, all keys are encrypted when empty
+	prefixes []string
+	// policies that set encryption take precedence over the prefixes
+	policies cachePolicies
+	log      log.Logger
+}
+
+func newEncryptedCacheStorage(cache CacheStorage, secretsService secrets.Service, codec codec, prefixes []string) *encryptedCacheStorage {
+	return &encryptedCacheStorage{
+		cache:          cache,
+		secretsService: secretsService,
+		codec:          codec,
+		prefixes:       prefixes,
+		log:            log.New("remotecache.encryption"),
+	}
+}
+
+func (s *encryptedCacheStorage) shouldEncrypt(key string) bool {
+	if policy, ok := s.policies.lookup(key); ok && policy.Encrypt != nil {
+		return *policy.Encrypt
+	}
+	if len(s.prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *encryptedCacheStorage) encrypt(ctx context.Context, key string, data []byte) ([]byte, error) {
+	if !s.shouldEncrypt(key) {
+		return data, nil
+	}
+	return s.secretsService.Encrypt(ctx, data, secrets.WithoutScope())
+}
+
+// decrypt returns ErrCacheItemNotFound for values that cannot be decrypted, such as values
+// stored before encryption was enabled, so they are treated like any other cache miss.
+func (s *encryptedCacheStorage) decrypt(ctx context.Context, key string, data []byte) ([]byte, error) {
+	if !s.shouldEncrypt(key) {
+		return data, nil
+	}
+	decrypted, err := s.secretsService.Decrypt(ctx, data)
+	if err != nil {
+		// a canceled operation is not a cache miss
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// counters are not encrypted
+		if _, parseErr := strconv.ParseInt(string(data), 10, 64); parseErr == nil {
+			return data, nil
+		}
+		s.log.FromContext(ctx).Debug("Failed to decrypt cached value", "key", key, "error", err)
+		return nil, ErrCacheItemNotFound
+	}
+	return decrypted, nil
+}
+
+func (s *encryptedCacheStorage) Get(ctx context.Context, key string) (interface{}, error) {
+	data, err := s.GetByteArray(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &cachedItem{}
+	if err := s.codec.Decode(ctx, data, item); err != nil {
+		return nil, err
+	}
+	return item.Val, nil
+}
+
+func (s *encryptedCacheStorage) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	data, err := s.codec.Encode(ctx, &cachedItem{Val: value})
+	if err != nil {
+		return err
+	}
+	return s.SetByteArray(ctx, key, data, expire)
+}
+
+func (s *encryptedCacheStorage) GetByteArray(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.cache.GetByteArray(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return s.decrypt(ctx, key, data)
+}
+
+func (s *encryptedCacheStorage) SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	data, err := s.encrypt(ctx, key, value)
+	if err != nil {
+		return err
+	}
+	return s.cache.SetByteArray(ctx, key, data, expire)
+}
+
+func (s *encryptedCacheStorage) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	data, ttl, err := s.cache.GetWithTTL(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err = s.decrypt(ctx, key, data)
+	return data, ttl, err
+}
+
+func (s *encryptedCacheStorage) Touch(ctx context.Context, key string, expire time.Duration) error {
+	return s.cache.Touch(ctx, key, expire)
+}
+
+func (s *encryptedCacheStorage) Delete(ctx context.Context, key string) error {
+	return s.cache.Delete(ctx, key)
+}
+
+func (s *encryptedCacheStorage) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	values, err := s.cache.GetMulti(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]byte, len(values))
+	for key, data := range values {
+		if decrypted, err := s.decrypt(ctx, key, data); err == nil {
+			result[key] = decrypted
+		}
+	}
+	return result, nil
+}
+
+func (s *encryptedCacheStorage) SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error {
+	encrypted := make(map[string][]byte, len(items))
+	for key, value := range items {
+		data, err := s.encrypt(ctx, key, value)
+		if err != nil {
+			return err
+		}
+		encrypted[key] = data
+	}
+	return s.cache.SetMulti(ctx, encrypted, expire)
+}
+
+func (s *encryptedCacheStorage) DeleteMulti(ctx context.Context, keys []string) error {
+	return s.cache.DeleteMulti(ctx, keys)
+}
+
+func (s *encryptedCacheStorage) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return s.cache.Increment(ctx, key, delta, expire)
+}
+
+func (s *encryptedCacheStorage) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return s.cache.Decrement(ctx, key, delta, expire)
+}
+
+func (s *encryptedCacheStorage) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	data, err := s.encrypt(ctx, key, value)
+	if err != nil {
+		return false, err
+	}
+	return s.cache.SetIfNotExists(ctx, key, data, expire)
+}
+
+// CompareAndSwap compares the decrypted value, the encryption is not deterministic so the
+// swap is made against the stored ciphertext instead of encrypting old.
+func (s *encryptedCacheStorage) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	if !s.shouldEncrypt(key) {
+		return s.cache.CompareAndSwap(ctx, key, old, value, expire)
+	}
+
+	stored, err := s.cache.GetByteArray(ctx, key)
+	if err != nil {
+		if isCacheMiss(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	current, err := s.decrypt(ctx, key, stored)
+	if err != nil || !bytes.Equal(current, old) {
+		return false, nil
+	}
+
+	data, err := s.encrypt(ctx, key, value)
+	if err != nil {
+		return false, err
+	}
+	return s.cache.CompareAndSwap(ctx, key, stored, data, expire)
+}
+
+func (s *encryptedCacheStorage) Scan(ctx context.Context, prefix string) ([]string, error) {
+	return s.cache.Scan(ctx, prefix)
+}
+
+func (s *encryptedCacheStorage) DeleteByPrefix(ctx context.Context, prefix string) error {
+	return s.cache.DeleteByPrefix(ctx, prefix)
+}
+
+func (s *encryptedCacheStorage) Count(ctx context.Context, prefix string) (int64, error) {
+	return s.cache.Count(ctx, prefix)
+}
+
+func (s *encryptedCacheStorage) Stats(ctx context.Context) (*Stats, error) {
+	return s.cache.Stats(ctx)
+}
+
+// runBatch encrypts the values of the batch before it runs and decrypts the values read by it.
+func (s *encryptedCacheStorage) runBatch(ctx context.Context, ops []*batchOp) {
+	encrypted := make([]*batchOp, 0, len(ops))
+	for _, op := range ops {
+		if op.kind == batchSet {
+			data, err := s.encrypt(ctx, op.key, op.value)
+			if err != nil {
+				op.result.Err = err
+				continue
+			}
+			op = op.withValue(data)
+		}
+		encrypted = append(encrypted, op)
+	}
+	execBatch(ctx, s.cache, encrypted)
+
+	for _, op := range encrypted {
+		r := op.result
+		if (op.kind == batchGet || op.kind == batchGetWithTTL) && r.Err == nil {
+			if r.Value, r.Err = s.decrypt(ctx, op.key, r.Value); r.Err != nil {
+				r.TTL = 0
+			}
+		}
+	}
+}
+
+// Run runs the background jobs of the wrapped cache.
+func (s *encryptedCacheStorage) Run(ctx context.Context) error {
+	if backgroundjob, ok := s.cache.(registry.BackgroundService); ok {
+		return backgroundjob.Run(ctx)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
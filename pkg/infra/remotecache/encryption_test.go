@@ -0,0 +1,76 @@
+package remotecache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+)
+
+func TestEncryptedCacheStorage(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+	client := newEncryptedCacheStorage(backend, &reversingSecretsService{}, &gobCodec{}, nil)
+
+	runTestsForClient(t, client)
+
+	require.NoError(t, client.SetByteArray(ctx, "key", []byte("secret"), time.Minute))
+	stored, err := backend.GetByteArray(ctx, "key")
+	require.NoError(t, err)
+	assert.NotEqual(t, "secret", string(stored))
+
+	// values stored before encryption was enabled are cache misses
+	require.NoError(t, backend.SetByteArray(ctx, "plain", []byte("value"), time.Minute))
+	_, err = client.GetByteArray(ctx, "plain")
+	assert.ErrorIs(t, err, ErrCacheItemNotFound)
+}
+
+func TestEncryptedCacheStorage_Prefixes(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+	client := newEncryptedCacheStorage(backend, &reversingSecretsService{}, &gobCodec{}, []string{"session-"})
+
+	require.NoError(t, client.SetMulti(ctx, map[string][]byte{"session-1": []byte("secret"), "other": []byte("public")}, time.Minute))
+
+	stored, err := backend.GetMulti(ctx, []string{"session-1", "other"})
+	require.NoError(t, err)
+	assert.NotEqual(t, "secret", string(stored["session-1"]))
+	assert.Equal(t, "public", string(stored["other"]))
+
+	values, err := client.GetMulti(ctx, []string{"session-1", "other"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"session-1": []byte("secret"), "other": []byte("public")}, values)
+}
+
+// reversingSecretsService "encrypts" by reversing the payload behind a marker
+type reversingSecretsService struct {
+	fakes.FakeSecretsService
+}
+
+var encryptedMarker = []byte("encrypted:")
+
+func (s *reversingSecretsService) Encrypt(_ context.Context, payload []byte, _ secrets.EncryptionOptions) ([]byte, error) {
+	return append(append([]byte{}, encryptedMarker...), reverse(payload)...), nil
+}
+
+func (s *reversingSecretsService) Decrypt(_ context.Context, payload []byte) ([]byte, error) {
+	if !bytes.HasPrefix(payload, encryptedMarker) {
+		return nil, errors.New("not encrypted")
+	}
+	return reverse(payload[len(encryptedMarker):]), nil
+}
+
+func reverse(b []byte) []byte {
+	result := make([]byte, len(b))
+	for i := range b {
+		result[len(b)-1-i] = b[i]
+	}
+	return result
+}
@@ -0,0 +1,145 @@
+package remotecache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// Loader loads the value of a key that is missing from the cache
+type Loader func(ctx context.Context) (interface{}, error)
+
+// GetOrSetOptions configure how GetOrSet stores values
+type GetOrSetOptions struct {
+	// StaleTTL keeps values for this long after their ttl. Stale values are returned
+	// while they are refreshed in the background.
+	StaleTTL time.Duration
+	// NegativeError is the error of the loader for values that do not exist, such as a
+	// not found error. It is cached for NegativeTTL and returned without calling the loader.
+	NegativeError error
+	NegativeTTL   time.Duration
+	// LoadTimeout limits how long the loader can run, defaults to 30 seconds. The load is shared
+	// by concurrent callers, so it does not end when the context of one of them is canceled.
+	LoadTimeout time.Duration
+}
+
+const (
+	defaultGetOrSetLoadTimeout = 30 * time.Second
+
+	getOrSetFlagNegative = 1
+	// flags followed by the time the value is fresh until in unix nanoseconds
+	getOrSetHeaderSize = 9
+)
+
+// GetOrSet returns the cached value of the key or stores the value returned by the loader
+// for ttl. Concurrent loads of the same key are deduplicated within this instance.
+func (ds *RemoteCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader Loader) (interface{}, error) {
+	return ds.GetOrSetWithOptions(ctx, key, ttl, loader, GetOrSetOptions{})
+}
+
+// GetOrSetWithOptions is GetOrSet with stale values and cached negative results.
+func (ds *RemoteCache) GetOrSetWithOptions(ctx context.Context, key string, ttl time.Duration, loader Loader, opts GetOrSetOptions) (interface{}, error) {
+	data, err := ds.GetByteArray(ctx, key)
+	if err == nil {
+		value, freshUntil, err := ds.decodeGetOrSetEntry(ctx, data, opts)
+		if err == nil || (opts.NegativeError != nil && errors.Is(err, opts.NegativeError)) {
+			if getTime().After(freshUntil) && opts.StaleTTL > 0 {
+				ds.refresh(key, ttl, loader, opts)
+			}
+			return value, err
+		}
+		// values stored in another format are loaded again
+	} else if !isCacheMiss(err) {
+		// the loader is the source of truth, a failing cache only makes it slower
+		ds.log.FromContext(ctx).Warn("Failed to read from the remote cache", "key", key, "error", err)
+	}
+
+	result := ds.loads.DoChan(key, func() (interface{}, error) {
+		return ds.load(detachedContext{parent: ctx}, key, ttl, loader, opts)
+	})
+	select {
+	case res := <-result:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh loads the value in the background, the request that found the stale value
+// does not wait for it.
+func (ds *RemoteCache) refresh(key string, ttl time.Duration, loader Loader, opts GetOrSetOptions) {
+	ds.loads.DoChan(key, func() (interface{}, error) {
+		return ds.load(context.Background(), key, ttl, loader, opts)
+	})
+}
+
+func (ds *RemoteCache) load(ctx context.Context, key string, ttl time.Duration, loader Loader, opts GetOrSetOptions) (interface{}, error) {
+	timeout := opts.LoadTimeout
+	if timeout <= 0 {
+		timeout = defaultGetOrSetLoadTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	value, err := loader(ctx)
+	if err != nil {
+		if opts.NegativeError != nil && opts.NegativeTTL > 0 && errors.Is(err, opts.NegativeError) {
+			ds.storeGetOrSetEntry(ctx, key, getOrSetFlagNegative, nil, opts.NegativeTTL, 0)
+		}
+		return nil, err
+	}
+
+	data, encodeErr := ds.codec.Encode(ctx, &cachedItem{Val: value})
+	if encodeErr != nil {
+		return nil, encodeErr
+	}
+	ds.storeGetOrSetEntry(ctx, key, 0, data, ttl, opts.StaleTTL)
+	return value, nil
+}
+
+// storeGetOrSetEntry stores the value until the end of ttl and staleTTL. The value is returned
+// anyway if it cannot be stored.
+func (ds *RemoteCache) storeGetOrSetEntry(ctx context.Context, key string, flags byte, data []byte, ttl, staleTTL time.Duration) {
+	entry := make([]byte, getOrSetHeaderSize, getOrSetHeaderSize+len(data))
+	entry[0] = flags
+	binary.BigEndian.PutUint64(entry[1:getOrSetHeaderSize], uint64(getTime().Add(ttl).UnixNano()))
+	entry = append(entry, data...)
+
+	if err := ds.SetByteArray(ctx, key, entry, ttl+staleTTL); err != nil {
+		ds.log.FromContext(ctx).Warn("Failed to store value in the remote cache", "key", key, "error", err)
+	}
+}
+
+func (ds *RemoteCache) decodeGetOrSetEntry(ctx context.Context, data []byte, opts GetOrSetOptions) (interface{}, time.Time, error) {
+	if len(data) < getOrSetHeaderSize {
+		return nil, time.Time{}, ErrCacheItemNotFound
+	}
+	freshUntil := time.Unix(0, int64(binary.BigEndian.Uint64(data[1:getOrSetHeaderSize])))
+	if data[0]&getOrSetFlagNegative != 0 {
+		if opts.NegativeError == nil {
+			return nil, time.Time{}, ErrCacheItemNotFound
+		}
+		return nil, freshUntil, opts.NegativeError
+	}
+
+	item := &cachedItem{}
+	if err := ds.codec.Decode(ctx, data[getOrSetHeaderSize:], item); err != nil {
+		return nil, time.Time{}, err
+	}
+	return item.Val, freshUntil, nil
+}
+
+// detachedContext keeps the values of its parent, such as the tracing span, but is not
+// canceled with it, like context.WithoutCancel which is not available in go 1.19.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (c detachedContext) Done() <-chan struct{} { return nil }
+
+func (c detachedContext) Err() error { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
@@ -0,0 +1,190 @@
+package remotecache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestNotFound = errors.New("user not found")
+
+func TestGetOrSet(t *testing.T) {
+	ctx := context.Background()
+	cache := newNamespaceTestCache(t, "get-or-set-")
+
+	var loads int32
+	loader := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		return "value", nil
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err := cache.GetOrSet(ctx, "key", time.Minute, loader)
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	t.Run("loader errors are not cached", func(t *testing.T) {
+		var calls int32
+		failing := func(ctx context.Context) (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, errors.New("database is down")
+		}
+		for i := 0; i < 2; i++ {
+			_, err := cache.GetOrSet(ctx, "failing", time.Minute, failing)
+			require.Error(t, err)
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestGetOrSet_DeduplicatesConcurrentLoads(t *testing.T) {
+	ctx := context.Background()
+	cache := newNamespaceTestCache(t, "get-or-set-concurrent-")
+
+	var loads int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := cache.GetOrSet(ctx, "key", time.Minute, loader)
+			assert.NoError(t, err)
+			assert.Equal(t, "value", value)
+		}()
+	}
+
+	// give the goroutines time to join the load before it finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+}
+
+func TestGetOrSet_LoadOutlivesCanceledCaller(t *testing.T) {
+	cache := newNamespaceTestCache(t, "get-or-set-canceled-")
+
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, error) {
+		select {
+		case <-release:
+			return "value", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// the first caller gives up, the load it started continues for the others
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := cache.GetOrSet(ctx, "key", time.Minute, loader)
+		first <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	second := make(chan interface{})
+	go func() {
+		value, err := cache.GetOrSet(context.Background(), "key", time.Minute, loader)
+		assert.NoError(t, err)
+		second <- value
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+
+	close(release)
+	assert.Equal(t, "value", <-second)
+
+	t.Run("the shared load has its own timeout", func(t *testing.T) {
+		blocking := func(ctx context.Context) (interface{}, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		_, err := cache.GetOrSetWithOptions(context.Background(), "blocking", time.Minute, blocking, GetOrSetOptions{LoadTimeout: 10 * time.Millisecond})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestGetOrSet_ServesStaleValues(t *testing.T) {
+	ctx := context.Background()
+	cache := newNamespaceTestCache(t, "get-or-set-stale-")
+
+	now := time.Now()
+	getTime = func() time.Time { return now }
+	t.Cleanup(func() { getTime = time.Now })
+
+	refreshed := make(chan struct{})
+	var loads int32
+	loader := func(ctx context.Context) (interface{}, error) {
+		if atomic.AddInt32(&loads, 1) == 1 {
+			return "old", nil
+		}
+		defer close(refreshed)
+		return "new", nil
+	}
+	opts := GetOrSetOptions{StaleTTL: time.Hour}
+
+	value, err := cache.GetOrSetWithOptions(ctx, "key", time.Minute, loader, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "old", value)
+
+	// the value is stale, it is returned while the new value is loaded
+	now = now.Add(2 * time.Minute)
+	value, err = cache.GetOrSetWithOptions(ctx, "key", time.Minute, loader, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "old", value)
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("stale value was not refreshed")
+	}
+	require.Eventually(t, func() bool {
+		value, err := cache.GetOrSetWithOptions(ctx, "key", time.Minute, loader, opts)
+		return err == nil && value == "new"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads))
+}
+
+func TestGetOrSet_CachesNegativeResults(t *testing.T) {
+	ctx := context.Background()
+	cache := newNamespaceTestCache(t, "get-or-set-negative-")
+
+	var loads int32
+	loader := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		return nil, errTestNotFound
+	}
+	opts := GetOrSetOptions{NegativeError: errTestNotFound, NegativeTTL: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		_, err := cache.GetOrSetWithOptions(ctx, "missing-user", time.Hour, loader, opts)
+		require.ErrorIs(t, err, errTestNotFound)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	t.Run("negative results are ignored without a negative error", func(t *testing.T) {
+		value, err := cache.GetOrSet(ctx, "missing-user", time.Hour, func(ctx context.Context) (interface{}, error) {
+			return "created", nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "created", value)
+	})
+}
@@ -0,0 +1,29 @@
+package remotecache
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// healthCheckKey is never written, reading it is a round trip to the backend that misses
+	healthCheckKey     = "health-check"
+	healthCheckTimeout = 5 * time.Second
+)
+
+// HealthCheck verifies that the cache backend can be reached and returns the round trip latency.
+func (ds *RemoteCache) HealthCheck(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := ds.client.GetByteArray(ctx, healthCheckKey); err != nil && !isCacheMiss(err) {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// Backend returns the name of the configured cache backend
+func (ds *RemoteCache) Backend() string {
+	return ds.Cfg.RemoteCacheOptions.Name
+}
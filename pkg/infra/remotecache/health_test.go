@@ -0,0 +1,36 @@
+package remotecache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestHealthCheck(t *testing.T) {
+	cache, err := ProvideService(&setting.Cfg{
+		RemoteCacheOptions: &setting.RemoteCacheOptions{Name: memoryCacheType, StartupHealthCheck: true},
+	}, nil, nil)
+	require.NoError(t, err)
+
+	_, err = cache.HealthCheck(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, memoryCacheType, cache.Backend())
+}
+
+func TestHealthCheck_FailsStartupIfUnreachable(t *testing.T) {
+	opts := &setting.RemoteCacheOptions{Name: redisCacheType, ConnStr: "addr=127.0.0.1:1"}
+
+	// without the startup check Grafana starts with a failing cache
+	cache, err := ProvideService(&setting.Cfg{RemoteCacheOptions: opts}, nil, nil)
+	require.NoError(t, err)
+	_, err = cache.HealthCheck(context.Background())
+	require.Error(t, err)
+
+	opts.StartupHealthCheck = true
+	_, err = ProvideService(&setting.Cfg{RemoteCacheOptions: opts}, nil, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,232 @@
+package remotecache
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/registry"
+)
+
+// instrumentedCacheStorage records hits, misses, sets, deletes, errors and the duration of
+// every operation of the wrapped backend.
+type instrumentedCacheStorage struct {
+	cache   CacheStorage
+	backend string
+	prefix  string
+}
+
+func newInstrumentedCacheStorage(cache CacheStorage, backend, prefix string) *instrumentedCacheStorage {
+	return &instrumentedCacheStorage{cache: cache, backend: backend, prefix: prefix}
+}
+
+// observe records the duration of the operation and counts errors, cache misses are not errors.
+func (s *instrumentedCacheStorage) observe(ctx context.Context, operation string, start time.Time, err error) {
+	namespace := namespaceFromContext(ctx)
+	operationDuration.WithLabelValues(s.backend, s.prefix, namespace, operation).Observe(time.Since(start).Seconds())
+	if err != nil && !isCacheMiss(err) {
+		errorsCounter.WithLabelValues(s.backend, s.prefix, namespace, operation).Inc()
+	}
+}
+
+func (s *instrumentedCacheStorage) lookups(ctx context.Context, hits, misses int) {
+	namespace := namespaceFromContext(ctx)
+	hitsCounter.WithLabelValues(s.backend, s.prefix, namespace).Add(float64(hits))
+	missesCounter.WithLabelValues(s.backend, s.prefix, namespace).Add(float64(misses))
+}
+
+// lookup counts a single key as hit or miss, failed lookups are counted as errors only.
+func (s *instrumentedCacheStorage) lookup(ctx context.Context, err error) {
+	switch {
+	case err == nil:
+		s.lookups(ctx, 1, 0)
+	case isCacheMiss(err):
+		s.lookups(ctx, 0, 1)
+	}
+}
+
+func (s *instrumentedCacheStorage) sets(ctx context.Context, n int, err error) {
+	if err == nil {
+		setsCounter.WithLabelValues(s.backend, s.prefix, namespaceFromContext(ctx)).Add(float64(n))
+	}
+}
+
+func (s *instrumentedCacheStorage) deletes(ctx context.Context, err error) {
+	if err == nil {
+		deletesCounter.WithLabelValues(s.backend, s.prefix, namespaceFromContext(ctx)).Inc()
+	}
+}
+
+func (s *instrumentedCacheStorage) Get(ctx context.Context, key string) (interface{}, error) {
+	start := time.Now()
+	value, err := s.cache.Get(ctx, key)
+	s.observe(ctx, "get", start, err)
+	s.lookup(ctx, err)
+	return value, err
+}
+
+func (s *instrumentedCacheStorage) GetByteArray(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	value, err := s.cache.GetByteArray(ctx, key)
+	s.observe(ctx, "get", start, err)
+	s.lookup(ctx, err)
+	return value, err
+}
+
+func (s *instrumentedCacheStorage) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	start := time.Now()
+	err := s.cache.Set(ctx, key, value, expire)
+	s.observe(ctx, "set", start, err)
+	s.sets(ctx, 1, err)
+	return err
+}
+
+func (s *instrumentedCacheStorage) SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	start := time.Now()
+	err := s.cache.SetByteArray(ctx, key, value, expire)
+	s.observe(ctx, "set", start, err)
+	s.sets(ctx, 1, err)
+	return err
+}
+
+func (s *instrumentedCacheStorage) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	start := time.Now()
+	value, ttl, err := s.cache.GetWithTTL(ctx, key)
+	s.observe(ctx, "get_with_ttl", start, err)
+	s.lookup(ctx, err)
+	return value, ttl, err
+}
+
+func (s *instrumentedCacheStorage) Touch(ctx context.Context, key string, expire time.Duration) error {
+	start := time.Now()
+	err := s.cache.Touch(ctx, key, expire)
+	s.observe(ctx, "touch", start, err)
+	return err
+}
+
+func (s *instrumentedCacheStorage) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.cache.Delete(ctx, key)
+	s.observe(ctx, "delete", start, err)
+	s.deletes(ctx, err)
+	return err
+}
+
+func (s *instrumentedCacheStorage) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	start := time.Now()
+	values, err := s.cache.GetMulti(ctx, keys)
+	s.observe(ctx, "get_multi", start, err)
+	if err == nil {
+		s.lookups(ctx, len(values), len(keys)-len(values))
+	}
+	return values, err
+}
+
+func (s *instrumentedCacheStorage) SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error {
+	start := time.Now()
+	err := s.cache.SetMulti(ctx, items, expire)
+	s.observe(ctx, "set_multi", start, err)
+	s.sets(ctx, len(items), err)
+	return err
+}
+
+func (s *instrumentedCacheStorage) DeleteMulti(ctx context.Context, keys []string) error {
+	start := time.Now()
+	err := s.cache.DeleteMulti(ctx, keys)
+	s.observe(ctx, "delete_multi", start, err)
+	s.deletes(ctx, err)
+	return err
+}
+
+func (s *instrumentedCacheStorage) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	start := time.Now()
+	value, err := s.cache.Increment(ctx, key, delta, expire)
+	s.observe(ctx, "increment", start, err)
+	return value, err
+}
+
+func (s *instrumentedCacheStorage) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	start := time.Now()
+	value, err := s.cache.Decrement(ctx, key, delta, expire)
+	s.observe(ctx, "decrement", start, err)
+	return value, err
+}
+
+func (s *instrumentedCacheStorage) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	start := time.Now()
+	ok, err := s.cache.SetIfNotExists(ctx, key, value, expire)
+	s.observe(ctx, "set_if_not_exists", start, err)
+	if ok {
+		s.sets(ctx, 1, err)
+	}
+	return ok, err
+}
+
+func (s *instrumentedCacheStorage) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	start := time.Now()
+	ok, err := s.cache.CompareAndSwap(ctx, key, old, value, expire)
+	s.observe(ctx, "compare_and_swap", start, err)
+	if ok {
+		s.sets(ctx, 1, err)
+	}
+	return ok, err
+}
+
+func (s *instrumentedCacheStorage) Scan(ctx context.Context, prefix string) ([]string, error) {
+	start := time.Now()
+	keys, err := s.cache.Scan(ctx, prefix)
+	s.observe(ctx, "scan", start, err)
+	return keys, err
+}
+
+func (s *instrumentedCacheStorage) DeleteByPrefix(ctx context.Context, prefix string) error {
+	start := time.Now()
+	err := s.cache.DeleteByPrefix(ctx, prefix)
+	s.observe(ctx, "delete_by_prefix", start, err)
+	s.deletes(ctx, err)
+	return err
+}
+
+func (s *instrumentedCacheStorage) Count(ctx context.Context, prefix string) (int64, error) {
+	start := time.Now()
+	count, err := s.cache.Count(ctx, prefix)
+	s.observe(ctx, "count", start, err)
+	return count, err
+}
+
+func (s *instrumentedCacheStorage) Stats(ctx context.Context) (*Stats, error) {
+	start := time.Now()
+	stats, err := s.cache.Stats(ctx)
+	s.observe(ctx, "stats", start, err)
+	return stats, err
+}
+
+// runBatch records the duration of the whole batch and counts its operations like single operations.
+func (s *instrumentedCacheStorage) runBatch(ctx context.Context, ops []*batchOp) {
+	start := time.Now()
+	execBatch(ctx, s.cache, ops)
+
+	var err error
+	for _, op := range ops {
+		switch op.kind {
+		case batchGet, batchGetWithTTL:
+			s.lookup(ctx, op.result.Err)
+		case batchSet:
+			s.sets(ctx, 1, op.result.Err)
+		case batchDelete:
+			s.deletes(ctx, op.result.Err)
+		}
+		if err == nil && op.result.Err != nil && !isCacheMiss(op.result.Err) {
+			err = op.result.Err
+		}
+	}
+	s.observe(ctx, "batch", start, err)
+}
+
+// Run runs the background jobs of the wrapped cache.
+func (s *instrumentedCacheStorage) Run(ctx context.Context) error {
+	if backgroundjob, ok := s.cache.(registry.BackgroundService); ok {
+		return backgroundjob.Run(ctx)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
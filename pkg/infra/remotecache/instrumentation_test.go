@@ -0,0 +1,51 @@
+package remotecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentedCacheStorage(t *testing.T) {
+	client := newInstrumentedCacheStorage(newMemoryStorageWithLimits(&gobCodec{}, 0, 0), memoryCacheType, "instrumented-")
+	runTestsForClient(t, client)
+}
+
+func TestInstrumentedCacheStorage_Metrics(t *testing.T) {
+	ctx := context.Background()
+	client := newInstrumentedCacheStorage(newMemoryStorageWithLimits(&gobCodec{}, 0, 0), memoryCacheType, "metrics-")
+
+	require.NoError(t, client.SetMulti(ctx, map[string][]byte{"a": []byte("one"), "b": []byte("two")}, time.Minute))
+	_, err := client.GetByteArray(ctx, "a")
+	require.NoError(t, err)
+	_, err = client.GetByteArray(ctx, "missing")
+	require.ErrorIs(t, err, ErrCacheItemNotFound)
+	_, err = client.GetMulti(ctx, []string{"a", "b", "missing"})
+	require.NoError(t, err)
+	require.NoError(t, client.Delete(ctx, "a"))
+
+	// incrementing a value that is not a counter fails
+	_, err = client.Increment(ctx, "b", 1, 0)
+	require.ErrorIs(t, err, ErrCacheItemNotCounter)
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(hitsCounter.WithLabelValues(memoryCacheType, "metrics-", "")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(missesCounter.WithLabelValues(memoryCacheType, "metrics-", "")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(setsCounter.WithLabelValues(memoryCacheType, "metrics-", "")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(deletesCounter.WithLabelValues(memoryCacheType, "metrics-", "")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(errorsCounter.WithLabelValues(memoryCacheType, "metrics-", "", "get")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(errorsCounter.WithLabelValues(memoryCacheType, "metrics-", "", "increment")))
+}
+
+func TestMemoryStorage_Stats(t *testing.T) {
+	ctx := context.Background()
+	client := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+
+	require.NoError(t, client.SetByteArray(ctx, "key", []byte("value"), time.Minute))
+	stats, err := client.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &Stats{Items: 1, Bytes: int64(len("key") + len("value"))}, stats)
+}
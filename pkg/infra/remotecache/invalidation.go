@@ -0,0 +1,28 @@
+package remotecache
+
+import (
+	"context"
+)
+
+const invalidationChannel = "remotecache-invalidation"
+
+// pubSubInvalidator publishes invalidated keys on a pub/sub channel. Lost messages are
+// bounded by the local TTL.
+type pubSubInvalidator struct {
+	pubsub  PubSub
+	channel string
+}
+
+func (i *pubSubInvalidator) Publish(ctx context.Context, key string) error {
+	return i.pubsub.Publish(ctx, i.channel, []byte(key))
+}
+
+func (i *pubSubInvalidator) publishOp(key string) *batchOp {
+	return &batchOp{kind: batchPublish, key: i.channel, value: []byte(key), pubsub: i.pubsub, result: &BatchResult{}}
+}
+
+func (i *pubSubInvalidator) Run(ctx context.Context, invalidate func(key string)) error {
+	return i.pubsub.Subscribe(ctx, i.channel, func(message []byte) {
+		invalidate(string(message))
+	})
+}
@@ -0,0 +1,76 @@
+package remotecache
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+const (
+	GobCodec  = "gob"
+	JSONCodec = "json"
+)
+
+// jsonCodecVersion is stored with every value, values of other versions are cache misses
+const jsonCodecVersion = 1
+
+type jsonEnvelope struct {
+	Version     int             `json:"v"`
+	Type        string          `json:"t,omitempty"`
+	TypeVersion int             `json:"tv,omitempty"`
+	Data        json.RawMessage `json:"d,omitempty"`
+}
+
+// jsonCodec stores values as JSON together with their registered type, so unlike gob it
+// does not fail on fields that were added or removed between versions.
+type jsonCodec struct{}
+
+func (c *jsonCodec) Encode(_ context.Context, item *cachedItem) ([]byte, error) {
+	envelope := jsonEnvelope{Version: jsonCodecVersion}
+	if item.Val != nil {
+		ct, err := cachedTypeOf(item.Val)
+		if err != nil {
+			return nil, err
+		}
+		envelope.Type = ct.name
+		envelope.TypeVersion = ct.version
+
+		data, err := json.Marshal(item.Val)
+		if err != nil {
+			return nil, err
+		}
+		envelope.Data = data
+	}
+	return json.Marshal(envelope)
+}
+
+// Decode returns ErrCacheItemNotFound for values it cannot decode, such as values of another
+// version or of a type this instance does not know.
+func (c *jsonCodec) Decode(_ context.Context, data []byte, out *cachedItem) error {
+	var envelope jsonEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Version != jsonCodecVersion {
+		return decodeMiss(JSONCodec)
+	}
+	if envelope.Type == "" {
+		out.Val = nil
+		return nil
+	}
+
+	ct, ok := cachedTypeByName(envelope.Type)
+	if !ok || ct.version != envelope.TypeVersion {
+		return decodeMiss(JSONCodec)
+	}
+
+	value := reflect.New(ct.t)
+	if err := json.Unmarshal(envelope.Data, value.Interface()); err != nil {
+		return decodeMiss(JSONCodec)
+	}
+
+	out.Val = value.Elem().Interface()
+	return nil
+}
+
+func decodeMiss(codec string) error {
+	decodeErrorsCounter.WithLabelValues(codec).Inc()
+	return ErrCacheItemNotFound
+}
@@ -0,0 +1,76 @@
+package remotecache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONCodec(t *testing.T) {
+	ctx := context.Background()
+	c := &jsonCodec{}
+
+	for _, value := range []interface{}{
+		CacheableStruct{String: "hej", Int64: 2000},
+		&CacheableStruct{String: "pointer"},
+		"string",
+		int64(42),
+		nil,
+	} {
+		data, err := c.Encode(ctx, &cachedItem{Val: value})
+		require.NoError(t, err)
+
+		item := &cachedItem{}
+		require.NoError(t, c.Decode(ctx, data, item))
+		assert.Equal(t, value, item.Val)
+	}
+}
+
+func TestJSONCodec_Mismatch(t *testing.T) {
+	ctx := context.Background()
+	c := &jsonCodec{}
+
+	for name, data := range map[string]string{
+		"not json":       `gob data`,
+		"other version":  `{"v":2,"t":"string","d":"value"}`,
+		"unknown type":   `{"v":1,"t":"example.com/unknown.Type","d":{}}`,
+		"type mismatch":  `{"v":1,"t":"string","d":{"field":1}}`,
+		"struct version": `{"v":1,"t":"` + goTypeName(reflect.TypeOf(versionedStruct{})) + `","tv":1,"d":{}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := c.Decode(ctx, []byte(data), &cachedItem{})
+			assert.ErrorIs(t, err, ErrCacheItemNotFound)
+		})
+	}
+}
+
+func TestJSONCodec_Versioned(t *testing.T) {
+	ctx := context.Background()
+	c := &jsonCodec{}
+
+	data, err := c.Encode(ctx, &cachedItem{Val: versionedStruct{Name: "v2"}})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"tv":2`)
+
+	item := &cachedItem{}
+	require.NoError(t, c.Decode(ctx, data, item))
+	assert.Equal(t, versionedStruct{Name: "v2"}, item.Val)
+}
+
+func TestMemoryStorage_JSONCodec(t *testing.T) {
+	client := newMemoryStorageWithLimits(&jsonCodec{}, 0, 0)
+	runTestsForClient(t, client)
+}
+
+type versionedStruct struct {
+	Name string
+}
+
+func (versionedStruct) CacheVersion() int { return 2 }
+
+func init() {
+	Register(versionedStruct{})
+}
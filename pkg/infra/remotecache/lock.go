@@ -0,0 +1,157 @@
+package remotecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// ErrLockHeld is returned if the lock is held by another holder
+var ErrLockHeld = errors.New("lock is held by another holder")
+
+const (
+	lockKeyPrefix  = "lock:"
+	fenceKeySuffix = ":fence"
+)
+
+// Locker coordinates instances of Grafana with locks stored in the remote cache. The lock
+// is taken with SetIfNotExists, so it uses SET NX PX with redis, add with memcached and
+// a conditional insert with the database.
+type Locker struct {
+	cache CacheStorage
+	log   log.Logger
+}
+
+func NewLocker(cache CacheStorage) *Locker {
+	return &Locker{
+		cache: cache,
+		log:   log.New("remotecache.lock"),
+	}
+}
+
+// Lock is a held lock. The lease is renewed in the background until Unlock is called or
+// the lock is lost, which closes the Lost channel.
+type Lock struct {
+	// FencingToken increases every time the lock is acquired, writes guarded by the lock
+	// should be rejected if a newer token has been seen. Tokens are only monotonic as long
+	// as the backend does not evict the counter.
+	FencingToken int64
+
+	locker *Locker
+	key    string
+	owner  []byte
+	ttl    time.Duration
+
+	stop     chan struct{}
+	lost     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Lock acquires the named lock for ttl, it returns ErrLockHeld if another holder has it.
+func (l *Locker) Lock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	if ttl < time.Second {
+		return nil, fmt.Errorf("lock ttl must be at least a second, got %s", ttl)
+	}
+
+	key := lockKeyPrefix + name
+	owner := []byte(util.GenerateShortUID())
+	// the lease expires ttl after it was taken, not after the call returned
+	acquired := time.Now()
+
+	ok, err := l.cache.SetIfNotExists(ctx, key, owner, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// a released lock is kept as an empty value until it expires
+		ok, err = l.cache.CompareAndSwap(ctx, key, []byte{}, owner, ttl)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		return nil, ErrLockHeld
+	}
+
+	token, err := l.cache.Increment(ctx, key+fenceKeySuffix, 1, 0)
+	if err != nil {
+		_, _ = l.cache.CompareAndSwap(ctx, key, owner, []byte{}, ttl)
+		return nil, err
+	}
+
+	lock := &Lock{
+		FencingToken: token,
+		locker:       l,
+		key:          key,
+		owner:        owner,
+		ttl:          ttl,
+		stop:         make(chan struct{}),
+		lost:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go lock.renew(acquired)
+
+	return lock, nil
+}
+
+// Lost is closed if the lease could not be renewed and another holder may have the lock.
+func (lk *Lock) Lost() <-chan struct{} {
+	return lk.lost
+}
+
+// Unlock stops the renewal and releases the lock if it is still held.
+func (lk *Lock) Unlock(ctx context.Context) error {
+	lk.stopOnce.Do(func() { close(lk.stop) })
+	<-lk.done
+
+	select {
+	case <-lk.lost:
+		return nil
+	default:
+	}
+
+	_, err := lk.locker.cache.CompareAndSwap(ctx, lk.key, lk.owner, []byte{}, lk.ttl)
+	return err
+}
+
+// renew extends the lease every third of the ttl, so a single failed renewal does not lose the lock.
+// If the lease could not be renewed within the ttl it has expired and the lock is lost.
+func (lk *Lock) renew(renewed time.Time) {
+	defer close(lk.done)
+
+	ticker := time.NewTicker(lk.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lk.stop:
+			return
+		case <-ticker.C:
+			attempt := time.Now()
+			ctx, cancel := context.WithTimeout(context.Background(), lk.ttl/3)
+			ok, err := lk.locker.cache.CompareAndSwap(ctx, lk.key, lk.owner, lk.owner, lk.ttl)
+			cancel()
+			if err != nil {
+				lk.locker.log.Warn("Failed to renew lock", "key", lk.key, "error", err)
+				if time.Since(renewed) >= lk.ttl {
+					lk.locker.log.Warn("Lock was lost, it could not be renewed within the ttl", "key", lk.key)
+					close(lk.lost)
+					return
+				}
+				continue
+			}
+			if !ok {
+				lk.locker.log.Warn("Lock was lost", "key", lk.key)
+				close(lk.lost)
+				return
+			}
+			renewed = attempt
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package remotecache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocker(t *testing.T) {
+	ctx := context.Background()
+	locker := NewLocker(newMemoryStorageWithLimits(&gobCodec{}, 0, 0))
+
+	lock, err := locker.Lock(ctx, "job", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), lock.FencingToken)
+
+	_, err = locker.Lock(ctx, "job", time.Minute)
+	assert.ErrorIs(t, err, ErrLockHeld)
+
+	other, err := locker.Lock(ctx, "other-job", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, other.Unlock(ctx))
+
+	require.NoError(t, lock.Unlock(ctx))
+	// unlocking twice is a no-op
+	require.NoError(t, lock.Unlock(ctx))
+
+	next, err := locker.Lock(ctx, "job", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), next.FencingToken)
+	require.NoError(t, next.Unlock(ctx))
+}
+
+func TestLocker_LostLock(t *testing.T) {
+	ctx := context.Background()
+	cache := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+	locker := NewLocker(cache)
+
+	lock, err := locker.Lock(ctx, "job", 3*time.Second)
+	require.NoError(t, err)
+
+	// another holder takes over the lock, the next renewal notices it
+	require.NoError(t, cache.SetByteArray(ctx, lockKeyPrefix+"job", []byte("other"), time.Minute))
+
+	select {
+	case <-lock.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lost lock was not detected")
+	}
+
+	require.NoError(t, lock.Unlock(ctx))
+	data, err := cache.GetByteArray(ctx, lockKeyPrefix+"job")
+	require.NoError(t, err)
+	assert.Equal(t, "other", string(data))
+}
+
+func TestLocker_LostLockWhenRenewalFails(t *testing.T) {
+	ctx := context.Background()
+	cache := &failingRenewalStorage{CacheStorage: newMemoryStorageWithLimits(&gobCodec{}, 0, 0)}
+	locker := NewLocker(cache)
+
+	lock, err := locker.Lock(ctx, "job", 3*time.Second)
+	require.NoError(t, err)
+
+	// the lease expires if it cannot be renewed within the ttl
+	select {
+	case <-lock.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expired lock was not detected")
+	}
+
+	require.NoError(t, lock.Unlock(ctx))
+}
+
+// failingRenewalStorage fails every compare and swap, so the lease cannot be renewed
+type failingRenewalStorage struct {
+	CacheStorage
+}
+
+func (s *failingRenewalStorage) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	return false, errors.New("cache unavailable")
+}
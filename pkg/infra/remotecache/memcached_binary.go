@@ -0,0 +1,428 @@
+package remotecache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// ErrMemcachedAuthFailed is returned if the memcached server rejects the SASL credentials
+var ErrMemcachedAuthFailed = errors.New("memcached sasl authentication failed")
+
+// errNonNumeric matches the error of the text protocol client for incr and decr of a
+// value that is not a counter, memcachedStorage maps both to ErrCacheItemNotCounter
+var errNonNumeric = errors.New("memcache: cannot increment or decrement non-numeric value")
+
+const (
+	binaryMagicRequest  = 0x80
+	binaryMagicResponse = 0x81
+	binaryHeaderLen     = 24
+
+	binaryOpGet       = 0x00
+	binaryOpSet       = 0x01
+	binaryOpAdd       = 0x02
+	binaryOpDelete    = 0x04
+	binaryOpIncrement = 0x05
+	binaryOpDecrement = 0x06
+	binaryOpNoop      = 0x0a
+	binaryOpGetKQ     = 0x0d
+	binaryOpTouch     = 0x1c
+	binaryOpSASLAuth  = 0x21
+
+	binaryStatusOK          = 0x0000
+	binaryStatusKeyNotFound = 0x0001
+	binaryStatusKeyExists   = 0x0002
+	binaryStatusNotStored   = 0x0005
+	binaryStatusNonNumeric  = 0x0006
+	binaryStatusAuthError   = 0x0020
+
+	// binaryNoAutoCreate as expiration of incr and decr fails for missing counters
+	// instead of creating them, like the text protocol does
+	binaryNoAutoCreate = 0xffffffff
+
+	// maxKeyLength is the longest key memcached accepts
+	maxKeyLength = 250
+)
+
+type binaryRequest struct {
+	opcode byte
+	key    string
+	extras []byte
+	value  []byte
+	cas    uint64
+}
+
+type binaryResponse struct {
+	opcode byte
+	status uint16
+	key    string
+	extras []byte
+	value  []byte
+	cas    uint64
+}
+
+// binaryClient is a memcached client for the binary protocol, which memcached requires
+// for SASL authentication. It authenticates every new connection with the PLAIN mechanism
+// and supports the operations used by memcachedStorage.
+type binaryClient struct {
+	selector    memcache.ServerSelector
+	dialContext func(ctx context.Context, network, address string) (net.Conn, error)
+	username    string
+	password    string
+	timeout     time.Duration
+	maxIdle     int
+
+	mu   sync.Mutex
+	idle map[string][]*binaryConn
+}
+
+type binaryConn struct {
+	nc net.Conn
+	rw *bufio.ReadWriter
+}
+
+func newBinaryClient(selector memcache.ServerSelector, dialContext func(ctx context.Context, network, address string) (net.Conn, error), username, password string) *binaryClient {
+	if dialContext == nil {
+		dialContext = (&net.Dialer{}).DialContext
+	}
+	return &binaryClient{
+		selector:    selector,
+		dialContext: dialContext,
+		username:    username,
+		password:    password,
+		timeout:     memcache.DefaultTimeout,
+		maxIdle:     memcache.DefaultMaxIdleConns,
+		idle:        map[string][]*binaryConn{},
+	}
+}
+
+func (c *binaryClient) Get(key string) (*memcache.Item, error) {
+	res, err := c.roundTrip(key, &binaryRequest{opcode: binaryOpGet, key: key})
+	if err != nil {
+		return nil, err
+	}
+	return res.item(key), nil
+}
+
+// GetMulti sends quiet gets, which only answer hits, for the keys of each server followed by a noop
+// that marks the end of the responses.
+func (c *binaryClient) GetMulti(keys []string) (map[string]*memcache.Item, error) {
+	keysByAddr := map[net.Addr][]string{}
+	for _, key := range keys {
+		if err := checkKey(key); err != nil {
+			return nil, err
+		}
+		addr, err := c.selector.PickServer(key)
+		if err != nil {
+			return nil, err
+		}
+		keysByAddr[addr] = append(keysByAddr[addr], key)
+	}
+
+	items := make(map[string]*memcache.Item, len(keys))
+	for addr, keys := range keysByAddr {
+		err := c.withConn(addr, func(cn *binaryConn) error {
+			for _, key := range keys {
+				if err := cn.write(&binaryRequest{opcode: binaryOpGetKQ, key: key}); err != nil {
+					return err
+				}
+			}
+			if err := cn.write(&binaryRequest{opcode: binaryOpNoop}); err != nil {
+				return err
+			}
+			if err := cn.rw.Flush(); err != nil {
+				return err
+			}
+
+			for {
+				res, err := cn.read()
+				if err != nil {
+					return err
+				}
+				if res.opcode == binaryOpNoop {
+					return nil
+				}
+				if res.status == binaryStatusOK {
+					items[res.key] = res.item(res.key)
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func (c *binaryClient) Set(item *memcache.Item) error {
+	_, err := c.roundTrip(item.Key, &binaryRequest{opcode: binaryOpSet, key: item.Key, extras: storeExtras(item), value: item.Value})
+	return err
+}
+
+// Add returns memcache.ErrNotStored if the key exists, as the text protocol client does.
+func (c *binaryClient) Add(item *memcache.Item) error {
+	_, err := c.roundTrip(item.Key, &binaryRequest{opcode: binaryOpAdd, key: item.Key, extras: storeExtras(item), value: item.Value})
+	if errors.Is(err, memcache.ErrCASConflict) {
+		return memcache.ErrNotStored
+	}
+	return err
+}
+
+func (c *binaryClient) Touch(key string, seconds int32) error {
+	extras := make([]byte, 4)
+	binary.BigEndian.PutUint32(extras, uint32(seconds))
+	_, err := c.roundTrip(key, &binaryRequest{opcode: binaryOpTouch, key: key, extras: extras})
+	return err
+}
+
+func (c *binaryClient) Delete(key string) error {
+	_, err := c.roundTrip(key, &binaryRequest{opcode: binaryOpDelete, key: key})
+	return err
+}
+
+func (c *binaryClient) Increment(key string, delta uint64) (uint64, error) {
+	return c.updateCounter(binaryOpIncrement, key, delta)
+}
+
+func (c *binaryClient) Decrement(key string, delta uint64) (uint64, error) {
+	return c.updateCounter(binaryOpDecrement, key, delta)
+}
+
+func (c *binaryClient) updateCounter(opcode byte, key string, delta uint64) (uint64, error) {
+	extras := make([]byte, 20)
+	binary.BigEndian.PutUint64(extras[0:8], delta)
+	binary.BigEndian.PutUint32(extras[16:20], binaryNoAutoCreate)
+	res, err := c.roundTrip(key, &binaryRequest{opcode: opcode, key: key, extras: extras})
+	if err != nil {
+		return 0, err
+	}
+	if len(res.value) != 8 {
+		return 0, fmt.Errorf("memcache: unexpected counter value of %d bytes", len(res.value))
+	}
+	return binary.BigEndian.Uint64(res.value), nil
+}
+
+func (c *binaryClient) compareAndSwap(key string, old []byte, item *memcache.Item) (bool, error) {
+	res, err := c.roundTrip(key, &binaryRequest{opcode: binaryOpGet, key: key})
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(res.value, old) {
+		return false, nil
+	}
+
+	// the set fails if the item was changed or deleted after it was read
+	_, err = c.roundTrip(key, &binaryRequest{opcode: binaryOpSet, key: key, extras: storeExtras(item), value: item.Value, cas: res.cas})
+	if errors.Is(err, memcache.ErrCASConflict) || errors.Is(err, memcache.ErrCacheMiss) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// roundTrip sends the request to the server of the key and returns the error for the status of the response.
+func (c *binaryClient) roundTrip(key string, req *binaryRequest) (*binaryResponse, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	addr, err := c.selector.PickServer(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *binaryResponse
+	err = c.withConn(addr, func(cn *binaryConn) (err error) {
+		res, err = cn.exchange(req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, statusError(res)
+}
+
+// withConn runs fn with a connection to the server, the connection is only reused if fn succeeds
+// since a failed exchange may leave unread responses behind.
+func (c *binaryClient) withConn(addr net.Addr, fn func(*binaryConn) error) error {
+	cn, err := c.getConn(addr)
+	if err != nil {
+		return err
+	}
+	if err := cn.nc.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		_ = cn.nc.Close()
+		return err
+	}
+	if err := fn(cn); err != nil {
+		_ = cn.nc.Close()
+		return err
+	}
+	c.putConn(addr, cn)
+	return nil
+}
+
+func (c *binaryClient) getConn(addr net.Addr) (*binaryConn, error) {
+	c.mu.Lock()
+	if idle := c.idle[addr.String()]; len(idle) > 0 {
+		cn := idle[len(idle)-1]
+		c.idle[addr.String()] = idle[:len(idle)-1]
+		c.mu.Unlock()
+		return cn, nil
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	nc, err := c.dialContext(ctx, addr.Network(), addr.String())
+	if err != nil {
+		return nil, err
+	}
+
+	cn := &binaryConn{nc: nc, rw: bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc))}
+	if c.username != "" {
+		if err := c.authenticate(cn); err != nil {
+			_ = nc.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+func (c *binaryClient) putConn(addr net.Addr, cn *binaryConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.idle[addr.String()]) >= c.maxIdle {
+		_ = cn.nc.Close()
+		return
+	}
+	c.idle[addr.String()] = append(c.idle[addr.String()], cn)
+}
+
+// authenticate authenticates the connection with the SASL PLAIN mechanism.
+func (c *binaryClient) authenticate(cn *binaryConn) error {
+	if err := cn.nc.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return err
+	}
+	res, err := cn.exchange(&binaryRequest{
+		opcode: binaryOpSASLAuth,
+		key:    "PLAIN",
+		value:  []byte("\x00" + c.username + "\x00" + c.password),
+	})
+	if err != nil {
+		return err
+	}
+	return statusError(res)
+}
+
+func (cn *binaryConn) exchange(req *binaryRequest) (*binaryResponse, error) {
+	if err := cn.write(req); err != nil {
+		return nil, err
+	}
+	if err := cn.rw.Flush(); err != nil {
+		return nil, err
+	}
+	return cn.read()
+}
+
+func (cn *binaryConn) write(req *binaryRequest) error {
+	header := make([]byte, binaryHeaderLen)
+	header[0] = binaryMagicRequest
+	header[1] = req.opcode
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(req.key)))
+	header[4] = byte(len(req.extras))
+	binary.BigEndian.PutUint32(header[8:12], uint32(len(req.extras)+len(req.key)+len(req.value)))
+	binary.BigEndian.PutUint64(header[16:24], req.cas)
+
+	if _, err := cn.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := cn.rw.Write(req.extras); err != nil {
+		return err
+	}
+	if _, err := cn.rw.WriteString(req.key); err != nil {
+		return err
+	}
+	_, err := cn.rw.Write(req.value)
+	return err
+}
+
+func (cn *binaryConn) read() (*binaryResponse, error) {
+	header := make([]byte, binaryHeaderLen)
+	if _, err := io.ReadFull(cn.rw, header); err != nil {
+		return nil, err
+	}
+	if header[0] != binaryMagicResponse {
+		return nil, fmt.Errorf("memcache: invalid response magic %#x", header[0])
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(header[2:4]))
+	extrasLen := int(header[4])
+	body := make([]byte, binary.BigEndian.Uint32(header[8:12]))
+	if len(body) < keyLen+extrasLen {
+		return nil, errors.New("memcache: invalid response length")
+	}
+	if _, err := io.ReadFull(cn.rw, body); err != nil {
+		return nil, err
+	}
+
+	return &binaryResponse{
+		opcode: header[1],
+		status: binary.BigEndian.Uint16(header[6:8]),
+		extras: body[:extrasLen],
+		key:    string(body[extrasLen : extrasLen+keyLen]),
+		value:  body[extrasLen+keyLen:],
+		cas:    binary.BigEndian.Uint64(header[16:24]),
+	}, nil
+}
+
+func (res *binaryResponse) item(key string) *memcache.Item {
+	item := &memcache.Item{Key: key, Value: res.value}
+	if len(res.extras) >= 4 {
+		item.Flags = binary.BigEndian.Uint32(res.extras[0:4])
+	}
+	return item
+}
+
+func storeExtras(item *memcache.Item) []byte {
+	extras := make([]byte, 8)
+	binary.BigEndian.PutUint32(extras[0:4], item.Flags)
+	binary.BigEndian.PutUint32(extras[4:8], uint32(item.Expiration))
+	return extras
+}
+
+// statusError returns the errors of the text protocol client for the status of the response.
+func statusError(res *binaryResponse) error {
+	switch res.status {
+	case binaryStatusOK:
+		return nil
+	case binaryStatusKeyNotFound:
+		return memcache.ErrCacheMiss
+	case binaryStatusKeyExists:
+		return memcache.ErrCASConflict
+	case binaryStatusNotStored:
+		return memcache.ErrNotStored
+	case binaryStatusNonNumeric:
+		return errNonNumeric
+	case binaryStatusAuthError:
+		return ErrMemcachedAuthFailed
+	default:
+		return fmt.Errorf("memcache: server error %#x: %s", res.status, res.value)
+	}
+}
+
+func checkKey(key string) error {
+	if len(key) == 0 || len(key) > maxKeyLength {
+		return memcache.ErrMalformedKey
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package remotecache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryClient(t *testing.T) {
+	addr := startFakeBinaryMemcached(t, "grafana", "secret")
+
+	t.Run("should reject invalid credentials", func(t *testing.T) {
+		c := newBinaryClient(newHashRing(addr), nil, "grafana", "wrong")
+		_, err := c.Get("key")
+		assert.ErrorIs(t, err, ErrMemcachedAuthFailed)
+	})
+
+	c := newBinaryClient(newHashRing(addr), nil, "grafana", "secret")
+
+	t.Run("should set and get items", func(t *testing.T) {
+		_, err := c.Get("missing")
+		assert.ErrorIs(t, err, memcache.ErrCacheMiss)
+
+		require.NoError(t, c.Set(&memcache.Item{Key: "a", Value: []byte("1")}))
+		require.NoError(t, c.Set(&memcache.Item{Key: "b", Value: []byte("2")}))
+		item, err := c.Get("a")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("1"), item.Value)
+
+		items, err := c.GetMulti([]string{"a", "b", "missing"})
+		require.NoError(t, err)
+		assert.Len(t, items, 2)
+		assert.Equal(t, []byte("2"), items["b"].Value)
+	})
+
+	t.Run("should not add existing items", func(t *testing.T) {
+		require.NoError(t, c.Add(&memcache.Item{Key: "add", Value: []byte("1")}))
+		assert.ErrorIs(t, c.Add(&memcache.Item{Key: "add", Value: []byte("2")}), memcache.ErrNotStored)
+	})
+
+	t.Run("should only swap unchanged items", func(t *testing.T) {
+		require.NoError(t, c.Set(&memcache.Item{Key: "cas", Value: []byte("old")}))
+
+		swapped, err := c.compareAndSwap("cas", []byte("other"), &memcache.Item{Key: "cas", Value: []byte("new")})
+		require.NoError(t, err)
+		assert.False(t, swapped)
+
+		swapped, err = c.compareAndSwap("cas", []byte("old"), &memcache.Item{Key: "cas", Value: []byte("new")})
+		require.NoError(t, err)
+		assert.True(t, swapped)
+	})
+
+	t.Run("should update existing counters", func(t *testing.T) {
+		_, err := c.Increment("counter", 1)
+		assert.ErrorIs(t, err, memcache.ErrCacheMiss)
+
+		require.NoError(t, c.Add(&memcache.Item{Key: "counter", Value: []byte("5")}))
+		value, err := c.Increment("counter", 2)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(7), value)
+
+		_, err = c.Increment("cas", 1)
+		assert.ErrorIs(t, err, errNonNumeric)
+	})
+
+	t.Run("should delete items", func(t *testing.T) {
+		require.NoError(t, c.Delete("a"))
+		assert.ErrorIs(t, c.Delete("a"), memcache.ErrCacheMiss)
+	})
+}
+
+// startFakeBinaryMemcached serves the binary protocol commands used by binaryClient and
+// requires SASL PLAIN authentication with the credentials.
+func startFakeBinaryMemcached(t *testing.T, username, password string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	var mu sync.Mutex
+	items := map[string][]byte{}
+	cas := map[string]uint64{}
+	var nextCAS uint64
+
+	handle := func(req *binaryRequest, authenticated *bool) (*binaryResponse, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		res := &binaryResponse{opcode: req.opcode}
+		if req.opcode == binaryOpSASLAuth {
+			*authenticated = string(req.value) == "\x00"+username+"\x00"+password
+			if !*authenticated {
+				res.status = binaryStatusAuthError
+			}
+			return res, true
+		}
+		if !*authenticated {
+			res.status = binaryStatusAuthError
+			return res, true
+		}
+
+		value, exists := items[req.key]
+		switch req.opcode {
+		case binaryOpNoop:
+		case binaryOpGet, binaryOpGetKQ:
+			if !exists {
+				res.status = binaryStatusKeyNotFound
+				return res, req.opcode == binaryOpGet
+			}
+			res.key, res.value, res.cas, res.extras = req.key, value, cas[req.key], make([]byte, 4)
+		case binaryOpSet, binaryOpAdd:
+			switch {
+			case req.opcode == binaryOpAdd && exists:
+				res.status = binaryStatusKeyExists
+			case req.cas != 0 && !exists:
+				res.status = binaryStatusKeyNotFound
+			case req.cas != 0 && req.cas != cas[req.key]:
+				res.status = binaryStatusKeyExists
+			default:
+				nextCAS++
+				items[req.key], cas[req.key] = req.value, nextCAS
+			}
+		case binaryOpDelete:
+			if !exists {
+				res.status = binaryStatusKeyNotFound
+			}
+			delete(items, req.key)
+		case binaryOpIncrement:
+			if !exists {
+				res.status = binaryStatusKeyNotFound
+				return res, true
+			}
+			var counter uint64
+			for _, b := range value {
+				if b < '0' || b > '9' {
+					res.status = binaryStatusNonNumeric
+					return res, true
+				}
+				counter = counter*10 + uint64(b-'0')
+			}
+			counter += binary.BigEndian.Uint64(req.extras[0:8])
+			items[req.key] = []byte(strconv.FormatUint(counter, 10))
+			res.value = make([]byte, 8)
+			binary.BigEndian.PutUint64(res.value, counter)
+		default:
+			res.status = 0x0081
+		}
+		return res, true
+	}
+
+	go func() {
+		for {
+			nc, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = nc.Close() }()
+				cn := &binaryConn{nc: nc, rw: bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc))}
+				authenticated := false
+				for {
+					req, err := readFakeRequest(cn)
+					if err != nil {
+						return
+					}
+					res, reply := handle(req, &authenticated)
+					if !reply {
+						continue
+					}
+					if err := writeFakeResponse(cn, res); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func readFakeRequest(cn *binaryConn) (*binaryRequest, error) {
+	header := make([]byte, binaryHeaderLen)
+	if _, err := io.ReadFull(cn.rw, header); err != nil {
+		return nil, err
+	}
+	if header[0] != binaryMagicRequest {
+		return nil, errors.New("invalid request magic")
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(header[2:4]))
+	extrasLen := int(header[4])
+	body := make([]byte, binary.BigEndian.Uint32(header[8:12]))
+	if _, err := io.ReadFull(cn.rw, body); err != nil {
+		return nil, err
+	}
+
+	return &binaryRequest{
+		opcode: header[1],
+		extras: body[:extrasLen],
+		key:    string(body[extrasLen : extrasLen+keyLen]),
+		value:  body[extrasLen+keyLen:],
+		cas:    binary.BigEndian.Uint64(header[16:24]),
+	}, nil
+}
+
+func writeFakeResponse(cn *binaryConn, res *binaryResponse) error {
+	header := make([]byte, binaryHeaderLen)
+	header[0] = binaryMagicResponse
+	header[1] = res.opcode
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(res.key)))
+	header[4] = byte(len(res.extras))
+	binary.BigEndian.PutUint16(header[6:8], res.status)
+	binary.BigEndian.PutUint32(header[8:12], uint32(len(res.extras)+len(res.key)+len(res.value)))
+	binary.BigEndian.PutUint64(header[16:24], res.cas)
+
+	for _, b := range [][]byte{header, res.extras, []byte(res.key), res.value} {
+		if _, err := cn.rw.Write(b); err != nil {
+			return err
+		}
+	}
+	return cn.rw.Flush()
+}
@@ -0,0 +1,73 @@
+package remotecache
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultKeyIndexMaxEntries = 100000
+
+// keyIndex records the keys written by this instance, memcached has no command to list
+// its keys. Keys written by other instances are not known, so scanning is best effort.
+type keyIndex struct {
+	mu         sync.Mutex
+	keys       map[string]time.Time
+	maxEntries int
+}
+
+func newKeyIndex(maxEntries int) *keyIndex {
+	return &keyIndex{keys: map[string]time.Time{}, maxEntries: maxEntries}
+}
+
+func (i *keyIndex) add(key string, expire time.Duration) {
+	var expires time.Time
+	if expire > 0 {
+		expires = getTime().Add(expire)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, ok := i.keys[key]; !ok && len(i.keys) >= i.maxEntries {
+		i.pruneExpired()
+		// keys that do not fit are not indexed rather than evicting indexed keys
+		if len(i.keys) >= i.maxEntries {
+			return
+		}
+	}
+	i.keys[key] = expires
+}
+
+func (i *keyIndex) remove(keys ...string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, key := range keys {
+		delete(i.keys, key)
+	}
+}
+
+// scan returns the indexed keys with the prefix that have not expired.
+func (i *keyIndex) scan(prefix string) []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.pruneExpired()
+	keys := []string{}
+	for key := range i.keys {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (i *keyIndex) pruneExpired() {
+	now := getTime()
+	for key, expires := range i.keys {
+		if !expires.IsZero() && !now.Before(expires) {
+			delete(i.keys, key)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package remotecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyIndex(t *testing.T) {
+	i := newKeyIndex(2)
+	i.add("org-1:a", time.Minute)
+	i.add("org-1:b", time.Second)
+	// the index is full
+	i.add("org-2:a", 0)
+	assert.ElementsMatch(t, []string{"org-1:a", "org-1:b"}, i.scan("org-"))
+
+	// expired keys are pruned to make room
+	getTime = func() time.Time { return time.Now().Add(2 * time.Second) }
+	t.Cleanup(func() { getTime = time.Now })
+	i.add("org-2:a", 0)
+	assert.ElementsMatch(t, []string{"org-1:a", "org-2:a"}, i.scan("org-"))
+
+	i.remove("org-1:a")
+	assert.Equal(t, []string{"org-2:a"}, i.scan(""))
+}
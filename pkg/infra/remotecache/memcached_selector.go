@@ -0,0 +1,99 @@
+package remotecache
+
+import (
+	"hash/fnv"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// pointsPerServer is the number of points each server has on the hash ring,
+// more points spread the keys more evenly between the servers.
+const pointsPerServer = 160
+
+var _ memcache.ServerSelector = new(hashRing)
+
+// hashRing distributes keys over the memcached servers using consistent hashing,
+// so adding or losing a server only moves the keys of that server instead of
+// most keys as with the modulo distribution of memcache.ServerList.
+type hashRing struct {
+	addrs  []net.Addr
+	points []ringPoint
+}
+
+type ringPoint struct {
+	hash uint32
+	addr net.Addr
+}
+
+// newHashRing creates a hash ring for the comma separated list of servers.
+func newHashRing(servers string) *hashRing {
+	r := &hashRing{}
+	for _, server := range strings.Split(servers, ",") {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+
+		addr := newServerAddr(server)
+		r.addrs = append(r.addrs, addr)
+		for i := 0; i < pointsPerServer; i++ {
+			r.points = append(r.points, ringPoint{hash: hashKey(server + "-" + strconv.Itoa(i)), addr: addr})
+		}
+	}
+
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i].hash < r.points[j].hash })
+	return r
+}
+
+// PickServer returns the server owning the first point on the ring after the hash of the key.
+func (r *hashRing) PickServer(key string) (net.Addr, error) {
+	if len(r.points) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+	if len(r.addrs) == 1 {
+		return r.addrs[0], nil
+	}
+
+	h := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].addr, nil
+}
+
+func (r *hashRing) Each(f func(net.Addr) error) error {
+	for _, addr := range r.addrs {
+		if err := f(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// serverAddr is the address of a memcached server. The host is resolved when
+// connecting, so servers that are not resolvable yet at startup or change
+// their ip address are still reached.
+type serverAddr struct {
+	network, addr string
+}
+
+func newServerAddr(server string) net.Addr {
+	if strings.Contains(server, "/") {
+		return &serverAddr{network: "unix", addr: server}
+	}
+	return &serverAddr{network: "tcp", addr: server}
+}
+
+func (a *serverAddr) Network() string { return a.network }
+func (a *serverAddr) String() string  { return a.addr }
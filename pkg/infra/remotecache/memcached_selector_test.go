@@ -0,0 +1,48 @@
+package remotecache
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashRing(t *testing.T) {
+	t.Run("should fail without servers", func(t *testing.T) {
+		_, err := newHashRing("").PickServer("key")
+		assert.ErrorIs(t, err, memcache.ErrNoServers)
+	})
+
+	t.Run("should parse tcp and unix servers", func(t *testing.T) {
+		r := newHashRing("10.0.0.1:11211, /var/run/memcached.sock")
+		require.Len(t, r.addrs, 2)
+		assert.Equal(t, "tcp", r.addrs[0].Network())
+		assert.Equal(t, "10.0.0.1:11211", r.addrs[0].String())
+		assert.Equal(t, "unix", r.addrs[1].Network())
+		assert.Equal(t, "/var/run/memcached.sock", r.addrs[1].String())
+	})
+
+	t.Run("should only move keys of a removed server", func(t *testing.T) {
+		before := newHashRing("10.0.0.1:11211,10.0.0.2:11211,10.0.0.3:11211")
+		after := newHashRing("10.0.0.1:11211,10.0.0.3:11211")
+
+		used := map[string]int{}
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			a, err := before.PickServer(key)
+			require.NoError(t, err)
+			b, err := after.PickServer(key)
+			require.NoError(t, err)
+
+			used[a.String()]++
+			if a.String() != "10.0.0.2:11211" {
+				assert.Equal(t, a.String(), b.String(), "key %s moved between remaining servers", key)
+			}
+		}
+
+		// every server should get a share of the keys
+		assert.Len(t, used, 3)
+	})
+}
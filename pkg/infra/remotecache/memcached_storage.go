@@ -1,8 +1,13 @@
 package remotecache
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
@@ -15,14 +20,105 @@ const memcachedCacheType = "memcached"
 var ErrNotImplemented = errors.New("count not implemented")
 
 type memcachedStorage struct {
-	c     *memcache.Client
+	c     memcachedClient
 	codec codec
+	// index of the keys written by this instance, used by Scan and DeleteByPrefix
+	index *keyIndex
 }
 
-func newMemcachedStorage(opts *setting.RemoteCacheOptions, codec codec) *memcachedStorage {
+// memcachedClient is the part of the memcached client used by the storage. It is implemented
+// by textClient, and by binaryClient when SASL authentication is configured.
+type memcachedClient interface {
+	Get(key string) (*memcache.Item, error)
+	GetMulti(keys []string) (map[string]*memcache.Item, error)
+	Set(item *memcache.Item) error
+	Add(item *memcache.Item) error
+	Touch(key string, seconds int32) error
+	Delete(key string) error
+	Increment(key string, delta uint64) (uint64, error)
+	Decrement(key string, delta uint64) (uint64, error)
+	// compareAndSwap stores the item if the value of its key is still old.
+	compareAndSwap(key string, old []byte, item *memcache.Item) (bool, error)
+}
+
+func newMemcachedStorage(opts *setting.RemoteCacheOptions, codec codec) (*memcachedStorage, error) {
+	tlsConfig, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialContext func(ctx context.Context, network, address string) (net.Conn, error)
+	if tlsConfig != nil {
+		// without a server name the host of each server is used to verify its certificate
+		dialContext = (&tls.Dialer{Config: tlsConfig}).DialContext
+	}
+
+	selector := newHashRing(opts.ConnStr)
+	var c memcachedClient
+	if opts.MemcachedUsername != "" {
+		// memcached only accepts SASL authentication over the binary protocol
+		c = newBinaryClient(selector, dialContext, opts.MemcachedUsername, opts.MemcachedPassword)
+	} else {
+		tc := memcache.NewFromSelector(selector)
+		tc.DialContext = dialContext
+		c = textClient{Client: tc}
+	}
+
 	return &memcachedStorage{
-		c:     memcache.New(opts.ConnStr),
+		c:     c,
 		codec: codec,
+		index: newKeyIndex(defaultKeyIndexMaxEntries),
+	}, nil
+}
+
+// textClient is the gomemcache client, which speaks the text protocol.
+type textClient struct {
+	*memcache.Client
+}
+
+// compareAndSwap reads the item to get its cas unique and swaps it with the memcached cas
+// command, which fails if the item was changed after it was read.
+func (c textClient) compareAndSwap(key string, old []byte, item *memcache.Item) (bool, error) {
+	stored, err := c.Get(key)
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(stored.Value, old) {
+		return false, nil
+	}
+
+	stored.Value = item.Value
+	stored.Expiration = item.Expiration
+	err = c.CompareAndSwap(stored)
+	if errors.Is(err, memcache.ErrCASConflict) || errors.Is(err, memcache.ErrNotStored) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// do runs a call of the memcached client, which does not support contexts, and returns the
+// context error as soon as ctx is done. The call itself is bounded by the timeout of the
+// client and finishes in the background, so fn must not write anything read after an error.
+func (s *memcachedStorage) do(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Done() == nil {
+		return fn()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
@@ -53,7 +149,11 @@ func (s *memcachedStorage) SetByteArray(ctx context.Context, key string, data []
 	}
 
 	memcachedItem := newItem(key, data, int32(expiresInSeconds))
-	return s.c.Set(memcachedItem)
+	if err := s.do(ctx, func() error { return s.c.Set(memcachedItem) }); err != nil {
+		return err
+	}
+	s.index.add(key, expires)
+	return nil
 }
 
 // Get gets value by given key in the cache.
@@ -74,7 +174,11 @@ func (s *memcachedStorage) Get(ctx context.Context, key string) (interface{}, er
 
 // GetByteArray returns the cached value as an byte array
 func (s *memcachedStorage) GetByteArray(ctx context.Context, key string) ([]byte, error) {
-	memcachedItem, err := s.c.Get(key)
+	var memcachedItem *memcache.Item
+	err := s.do(ctx, func() (err error) {
+		memcachedItem, err = s.c.Get(key)
+		return err
+	})
 	if errors.Is(err, memcache.ErrCacheMiss) {
 		return nil, ErrCacheItemNotFound
 	}
@@ -86,11 +190,178 @@ func (s *memcachedStorage) GetByteArray(ctx context.Context, key string) ([]byte
 	return memcachedItem.Value, nil
 }
 
+// GetWithTTL is not supported, memcached does not return the expiry of items.
+func (s *memcachedStorage) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	return nil, 0, ErrTTLNotSupported
+}
+
+func (s *memcachedStorage) Touch(ctx context.Context, key string, expire time.Duration) error {
+	err := s.do(ctx, func() error { return s.c.Touch(key, int32(expire/time.Second)) })
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return ErrCacheItemNotFound
+	}
+	return err
+}
+
+func (s *memcachedStorage) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	var items map[string]*memcache.Item
+	err := s.do(ctx, func() (err error) {
+		items, err = s.c.GetMulti(keys)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for key, item := range items {
+		result[key] = item.Value
+	}
+	return result, nil
+}
+
+// SetMulti stores the byte arrays in the cache, the memcached protocol has no
+// multi-set so the items are set one by one.
+func (s *memcachedStorage) SetMulti(ctx context.Context, items map[string][]byte, expires time.Duration) error {
+	for key, data := range items {
+		if err := s.SetByteArray(ctx, key, data, expires); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memcachedStorage) DeleteMulti(ctx context.Context, keys []string) error {
+	s.index.remove(keys...)
+	for _, key := range keys {
+		key := key
+		if err := s.do(ctx, func() error { return s.c.Delete(key) }); err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Increment uses incr, a missing counter is created with add. If another instance creates
+// the counter in between, the increment is retried.
+func (s *memcachedStorage) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	if delta < 0 {
+		return s.Decrement(ctx, key, -delta, expire)
+	}
+	return s.updateCounter(ctx, key, delta, delta, expire, s.c.Increment)
+}
+
+// Decrement uses decr, memcached counters cannot go below zero.
+func (s *memcachedStorage) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	if delta < 0 {
+		return s.Increment(ctx, key, -delta, expire)
+	}
+	return s.updateCounter(ctx, key, delta, 0, expire, s.c.Decrement)
+}
+
+// updateCounter applies the update and creates the counter with the initial value if it is missing.
+func (s *memcachedStorage) updateCounter(ctx context.Context, key string, delta, initial int64, expire time.Duration, update func(string, uint64) (uint64, error)) (int64, error) {
+	for {
+		var value uint64
+		err := s.do(ctx, func() (err error) {
+			value, err = update(key, uint64(delta))
+			return err
+		})
+		if err == nil {
+			return int64(value), nil
+		}
+		if !errors.Is(err, memcache.ErrCacheMiss) {
+			if strings.Contains(err.Error(), "non-numeric value") {
+				return 0, ErrCacheItemNotCounter
+			}
+			return 0, err
+		}
+
+		err = s.do(ctx, func() error {
+			return s.c.Add(newItem(key, []byte(strconv.FormatInt(initial, 10)), int32(expire/time.Second)))
+		})
+		if err == nil {
+			s.index.add(key, expire)
+			return initial, nil
+		}
+		if !errors.Is(err, memcache.ErrNotStored) {
+			return 0, err
+		}
+	}
+}
+
+func (s *memcachedStorage) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	err := s.do(ctx, func() error { return s.c.Add(newItem(key, value, int32(expire/time.Second))) })
+	if errors.Is(err, memcache.ErrNotStored) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.index.add(key, expire)
+	return true, nil
+}
+
+// CompareAndSwap swaps the value with the cas of memcached, which fails if the item was changed
+// after it was compared.
+func (s *memcachedStorage) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	var swapped bool
+	err := s.do(ctx, func() (err error) {
+		swapped, err = s.c.compareAndSwap(key, old, newItem(key, value, int32(expire/time.Second)))
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return swapped, nil
+}
+
+// Scan returns the keys written by this instance that still exist.
+func (s *memcachedStorage) Scan(ctx context.Context, prefix string) ([]string, error) {
+	candidates := s.index.scan(prefix)
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+
+	var items map[string]*memcache.Item
+	err := s.do(ctx, func() (err error) {
+		items, err = s.c.GetMulti(candidates)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(items))
+	for _, key := range candidates {
+		if _, ok := items[key]; ok {
+			keys = append(keys, key)
+		} else {
+			s.index.remove(key)
+		}
+	}
+	return keys, nil
+}
+
+// DeleteByPrefix deletes the keys written by this instance.
+func (s *memcachedStorage) DeleteByPrefix(ctx context.Context, prefix string) error {
+	return s.DeleteMulti(ctx, s.index.scan(prefix))
+}
+
 func (s *memcachedStorage) Count(ctx context.Context, prefix string) (int64, error) {
 	return 0, ErrNotImplemented
 }
 
+// Stats is not supported by the memcached client, the server stats are not exposed.
+func (s *memcachedStorage) Stats(ctx context.Context) (*Stats, error) {
+	return &Stats{Items: -1, Bytes: -1}, nil
+}
+
 // Delete delete a key from the cache
 func (s *memcachedStorage) Delete(ctx context.Context, key string) error {
-	return s.c.Delete(key)
+	s.index.remove(key)
+	return s.do(ctx, func() error { return s.c.Delete(key) })
 }
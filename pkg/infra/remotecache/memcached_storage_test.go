@@ -0,0 +1,34 @@
+package remotecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestMemcachedStorage_HonorsContext(t *testing.T) {
+	s, err := newMemcachedStorage(&setting.RemoteCacheOptions{ConnStr: "127.0.0.1:1"}, &gobCodec{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.GetByteArray(ctx, "key")
+	require.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrCacheItemNotFound)
+
+	// a call that does not finish in time returns the deadline error
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	err = s.do(ctx, func() error {
+		<-release
+		return nil
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,378 @@
+package remotecache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const memoryCacheType = "memory"
+
+const (
+	defaultMemoryMaxEntries = 100000
+	defaultMemoryMaxBytes   = 128 * 1024 * 1024
+)
+
+// memoryStorage keeps the cache in the memory of the Grafana process. The least recently
+// used items are evicted once the maximum number of entries or bytes is reached.
+type memoryStorage struct {
+	codec      codec
+	maxEntries int
+	maxBytes   int64
+	now        func() time.Time
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	bytes int64
+}
+
+type memoryItem struct {
+	key     string
+	data    []byte
+	expires time.Time
+}
+
+func (i *memoryItem) size() int64 {
+	return int64(len(i.key) + len(i.data))
+}
+
+func (i *memoryItem) expired(now time.Time) bool {
+	return !i.expires.IsZero() && !now.Before(i.expires)
+}
+
+// parseMemoryConnStr parses k=v pairs in csv, max_entries and max_bytes set to 0 disable the limit.
+func parseMemoryConnStr(connStr string) (maxEntries int, maxBytes int64, err error) {
+	maxEntries, maxBytes = defaultMemoryMaxEntries, defaultMemoryMaxBytes
+	if connStr == "" {
+		return maxEntries, maxBytes, nil
+	}
+
+	for _, rawKeyValue := range strings.Split(connStr, ",") {
+		keyValueTuple := strings.SplitN(rawKeyValue, "=", 2)
+		if len(keyValueTuple) != 2 {
+			return 0, 0, fmt.Errorf("incorrect memory cache connection string format detected for '%v', format is key=value,key=value", rawKeyValue)
+		}
+		switch keyValueTuple[0] {
+		case "max_entries":
+			maxEntries, err = strconv.Atoi(keyValueTuple[1])
+			if err != nil || maxEntries < 0 {
+				return 0, 0, fmt.Errorf("value for max_entries in memory cache connection string must be a positive number")
+			}
+		case "max_bytes":
+			maxBytes, err = strconv.ParseInt(keyValueTuple[1], 10, 64)
+			if err != nil || maxBytes < 0 {
+				return 0, 0, fmt.Errorf("value for max_bytes in memory cache connection string must be a positive number")
+			}
+		default:
+			return 0, 0, fmt.Errorf("unrecognized option '%v' in memory cache connection string", keyValueTuple[0])
+		}
+	}
+	return maxEntries, maxBytes, nil
+}
+
+func newMemoryStorage(connStr string, codec codec) (*memoryStorage, error) {
+	maxEntries, maxBytes, err := parseMemoryConnStr(connStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMemoryStorageWithLimits(codec, maxEntries, maxBytes), nil
+}
+
+func newMemoryStorageWithLimits(codec codec, maxEntries int, maxBytes int64) *memoryStorage {
+	return &memoryStorage{
+		codec:      codec,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		now:        getTime,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+	}
+}
+
+func (s *memoryStorage) Get(ctx context.Context, key string) (interface{}, error) {
+	data, err := s.GetByteArray(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &cachedItem{}
+	if err := s.codec.Decode(ctx, data, item); err != nil {
+		return nil, err
+	}
+	return item.Val, nil
+}
+
+func (s *memoryStorage) GetByteArray(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, err := s.get(key)
+	if err != nil {
+		return nil, err
+	}
+	return item.data, nil
+}
+
+// get returns the item and marks it as recently used, the caller must hold the lock.
+func (s *memoryStorage) get(key string) (*memoryItem, error) {
+	el, ok := s.items[key]
+	if !ok {
+		return nil, ErrCacheItemNotFound
+	}
+
+	item := el.Value.(*memoryItem)
+	if item.expired(s.now()) {
+		s.remove(el)
+		return nil, ErrCacheItemNotFound
+	}
+
+	s.ll.MoveToFront(el)
+	return item, nil
+}
+
+func (s *memoryStorage) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	data, err := s.codec.Encode(ctx, &cachedItem{Val: value})
+	if err != nil {
+		return err
+	}
+	return s.SetByteArray(ctx, key, data, expire)
+}
+
+func (s *memoryStorage) SetByteArray(ctx context.Context, key string, data []byte, expire time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		s.remove(el)
+	}
+
+	s.add(key, data, expire)
+	return nil
+}
+
+func (s *memoryStorage) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, err := s.get(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ttl time.Duration
+	if !item.expires.IsZero() {
+		ttl = item.expires.Sub(s.now())
+	}
+	return item.data, ttl, nil
+}
+
+func (s *memoryStorage) Touch(ctx context.Context, key string, expire time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, err := s.get(key)
+	if err != nil {
+		return err
+	}
+
+	item.expires = time.Time{}
+	if expire > 0 {
+		item.expires = s.now().Add(expire)
+	}
+	return nil
+}
+
+func (s *memoryStorage) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		s.remove(el)
+	}
+	return nil
+}
+
+func (s *memoryStorage) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if data, err := s.GetByteArray(ctx, key); err == nil {
+			result[key] = data
+		}
+	}
+	return result, nil
+}
+
+func (s *memoryStorage) SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error {
+	for key, data := range items {
+		if err := s.SetByteArray(ctx, key, data, expire); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryStorage) DeleteMulti(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		if err := s.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryStorage) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var value int64
+	item := &memoryItem{key: key}
+	if expire > 0 {
+		item.expires = s.now().Add(expire)
+	}
+	if el, ok := s.items[key]; ok {
+		existing := el.Value.(*memoryItem)
+		if !existing.expired(s.now()) {
+			var err error
+			if value, err = strconv.ParseInt(string(existing.data), 10, 64); err != nil {
+				return 0, ErrCacheItemNotCounter
+			}
+			item.expires = existing.expires
+		}
+		s.remove(el)
+	}
+
+	value += delta
+	item.data = []byte(strconv.FormatInt(value, 10))
+	s.push(item)
+	return value, nil
+}
+
+func (s *memoryStorage) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return s.Increment(ctx, key, -delta, expire)
+}
+
+func (s *memoryStorage) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		if !el.Value.(*memoryItem).expired(s.now()) {
+			return false, nil
+		}
+		s.remove(el)
+	}
+
+	s.add(key, value, expire)
+	return true, nil
+}
+
+func (s *memoryStorage) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[key]
+	if !ok {
+		return false, nil
+	}
+	item := el.Value.(*memoryItem)
+	if item.expired(s.now()) || !bytes.Equal(item.data, old) {
+		return false, nil
+	}
+
+	s.remove(el)
+	s.add(key, value, expire)
+	return true, nil
+}
+
+func (s *memoryStorage) Scan(ctx context.Context, prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	keys := []string{}
+	for key, el := range s.items {
+		if strings.HasPrefix(key, prefix) && !el.Value.(*memoryItem).expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (s *memoryStorage) DeleteByPrefix(ctx context.Context, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, el := range s.items {
+		if strings.HasPrefix(key, prefix) {
+			s.remove(el)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStorage) Count(ctx context.Context, prefix string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var count int64
+	for key, el := range s.items {
+		if strings.HasPrefix(key, prefix) && !el.Value.(*memoryItem).expired(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Stats counts the items that have not expired, the bytes include expired items that
+// have not been evicted yet.
+func (s *memoryStorage) Stats(ctx context.Context) (*Stats, error) {
+	count, err := s.Count(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &Stats{Items: count, Bytes: s.bytes}, nil
+}
+
+// add inserts a new item, the caller must hold the lock and have removed the previous item.
+func (s *memoryStorage) add(key string, data []byte, expire time.Duration) {
+	item := &memoryItem{key: key, data: data}
+	if expire > 0 {
+		item.expires = s.now().Add(expire)
+	}
+	s.push(item)
+}
+
+func (s *memoryStorage) push(item *memoryItem) {
+	// an item larger than the cache would evict everything and still not fit
+	if s.maxBytes > 0 && item.size() > s.maxBytes {
+		return
+	}
+
+	s.items[item.key] = s.ll.PushFront(item)
+	s.bytes += item.size()
+	s.evict()
+}
+
+// evict removes the least recently used items until the cache is within its limits.
+func (s *memoryStorage) evict() {
+	for (s.maxEntries > 0 && s.ll.Len() > s.maxEntries) || (s.maxBytes > 0 && s.bytes > s.maxBytes) {
+		s.remove(s.ll.Back())
+	}
+}
+
+func (s *memoryStorage) remove(el *list.Element) {
+	item := s.ll.Remove(el).(*memoryItem)
+	delete(s.items, item.key)
+	s.bytes -= item.size()
+}
@@ -0,0 +1,112 @@
+package remotecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestMemoryCacheStorage(t *testing.T) {
+	opts := &setting.RemoteCacheOptions{Name: memoryCacheType}
+	client := createTestClient(t, opts, nil)
+	runTestsForClient(t, client)
+	runCountTestsForClient(t, opts, nil)
+}
+
+func TestMemoryStorage_Evict(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should evict least recently used entries", func(t *testing.T) {
+		s, err := newMemoryStorage("max_entries=2,max_bytes=0", &gobCodec{})
+		require.NoError(t, err)
+
+		require.NoError(t, s.SetByteArray(ctx, "a", []byte("1"), 0))
+		require.NoError(t, s.SetByteArray(ctx, "b", []byte("2"), 0))
+		// reading a makes b the least recently used entry
+		_, err = s.GetByteArray(ctx, "a")
+		require.NoError(t, err)
+		require.NoError(t, s.SetByteArray(ctx, "c", []byte("3"), 0))
+
+		_, err = s.GetByteArray(ctx, "b")
+		assert.ErrorIs(t, err, ErrCacheItemNotFound)
+		_, err = s.GetByteArray(ctx, "a")
+		assert.NoError(t, err)
+		_, err = s.GetByteArray(ctx, "c")
+		assert.NoError(t, err)
+	})
+
+	t.Run("should evict when max bytes is reached", func(t *testing.T) {
+		s, err := newMemoryStorage("max_entries=0,max_bytes=9", &gobCodec{})
+		require.NoError(t, err)
+
+		require.NoError(t, s.SetByteArray(ctx, "a", []byte("1234"), 0))
+		require.NoError(t, s.SetByteArray(ctx, "b", []byte("1234"), 0))
+		assert.Equal(t, int64(5), s.bytes)
+
+		_, err = s.GetByteArray(ctx, "a")
+		assert.ErrorIs(t, err, ErrCacheItemNotFound)
+
+		// items larger than the cache are not stored
+		require.NoError(t, s.SetByteArray(ctx, "c", []byte("12345678901"), 0))
+		_, err = s.GetByteArray(ctx, "c")
+		assert.ErrorIs(t, err, ErrCacheItemNotFound)
+		_, err = s.GetByteArray(ctx, "b")
+		assert.NoError(t, err)
+	})
+
+	t.Run("should expire entries", func(t *testing.T) {
+		now := time.Now()
+		getTime = func() time.Time { return now }
+		t.Cleanup(func() { getTime = time.Now })
+
+		s, err := newMemoryStorage("", &gobCodec{})
+		require.NoError(t, err)
+		require.NoError(t, s.SetByteArray(ctx, "a", []byte("1"), time.Minute))
+
+		now = now.Add(time.Minute)
+		count, err := s.Count(ctx, "")
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+
+		_, err = s.GetByteArray(ctx, "a")
+		assert.ErrorIs(t, err, ErrCacheItemNotFound)
+		assert.Equal(t, int64(0), s.bytes)
+	})
+}
+
+func TestParseMemoryConnStr(t *testing.T) {
+	maxEntries, maxBytes, err := parseMemoryConnStr("")
+	require.NoError(t, err)
+	assert.Equal(t, defaultMemoryMaxEntries, maxEntries)
+	assert.Equal(t, int64(defaultMemoryMaxBytes), maxBytes)
+
+	maxEntries, maxBytes, err = parseMemoryConnStr("max_entries=10,max_bytes=2048")
+	require.NoError(t, err)
+	assert.Equal(t, 10, maxEntries)
+	assert.Equal(t, int64(2048), maxBytes)
+
+	for _, connStr := range []string{"max_entries=ten", "max_bytes=-1", "size=10", "max_entries"} {
+		_, _, err := parseMemoryConnStr(connStr)
+		assert.Error(t, err, connStr)
+	}
+}
+
+func TestNewFakeMemoryStore(t *testing.T) {
+	now := time.Now()
+	cache := NewFakeMemoryStore(t, func() time.Time { return now })
+	ctx := context.Background()
+
+	require.NoError(t, cache.SetByteArray(ctx, "key", []byte("value"), time.Minute))
+	ok, err := cache.SetIfNotExists(ctx, "key", []byte("other"), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	now = now.Add(time.Minute)
+	_, err = cache.GetByteArray(ctx, "key")
+	assert.ErrorIs(t, err, ErrCacheItemNotFound)
+}
@@ -0,0 +1,124 @@
+package remotecache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/infra/metrics"
+)
+
+var decodeErrorsCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metrics.ExporterName,
+		Subsystem: "remote_cache",
+		Name:      "decode_errors_total",
+		Help:      "Number of cached values that could not be decoded and were treated as cache misses",
+	},
+	[]string{"codec"},
+)
+
+// the operation metrics are labeled with the backend, the configured key prefix and the
+// namespace, so instances sharing a backend with different prefixes can be told apart
+var (
+	hitsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.ExporterName,
+			Subsystem: "remote_cache",
+			Name:      "hits_total",
+			Help:      "Number of keys that were found in the cache",
+		},
+		[]string{"backend", "prefix", "namespace"},
+	)
+
+	missesCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.ExporterName,
+			Subsystem: "remote_cache",
+			Name:      "misses_total",
+			Help:      "Number of keys that were not found in the cache",
+		},
+		[]string{"backend", "prefix", "namespace"},
+	)
+
+	setsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.ExporterName,
+			Subsystem: "remote_cache",
+			Name:      "sets_total",
+			Help:      "Number of keys that were written to the cache",
+		},
+		[]string{"backend", "prefix", "namespace"},
+	)
+
+	deletesCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.ExporterName,
+			Subsystem: "remote_cache",
+			Name:      "deletes_total",
+			Help:      "Number of delete operations on the cache",
+		},
+		[]string{"backend", "prefix", "namespace"},
+	)
+
+	errorsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.ExporterName,
+			Subsystem: "remote_cache",
+			Name:      "errors_total",
+			Help:      "Number of cache operations that failed, cache misses are not counted as errors",
+		},
+		[]string{"backend", "prefix", "namespace", "operation"},
+	)
+
+	operationDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metrics.ExporterName,
+			Subsystem: "remote_cache",
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of cache operations",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 9),
+		},
+		[]string{"backend", "prefix", "namespace", "operation"},
+	)
+)
+
+var (
+	gcPurgedRows = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metrics.ExporterName,
+			Subsystem: "remote_cache",
+			Name:      "database_gc_purged_rows",
+			Help:      "Number of expired rows deleted per garbage collection run of the database cache",
+			Buckets:   prometheus.ExponentialBuckets(1, 10, 7),
+		},
+	)
+
+	gcEvictedRows = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metrics.ExporterName,
+			Subsystem: "remote_cache",
+			Name:      "database_evicted_rows_total",
+			Help:      "Number of rows evicted from the database cache to stay within its size limits",
+		},
+	)
+
+	gcDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metrics.ExporterName,
+			Subsystem: "remote_cache",
+			Name:      "database_gc_duration_seconds",
+			Help:      "Duration of the garbage collection runs of the database cache",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 8),
+		},
+	)
+)
+
+var itemsTooLargeCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metrics.ExporterName,
+		Subsystem: "remote_cache",
+		Name:      "items_too_large_total",
+		Help:      "Number of values that were not stored because they exceed the maximum item size",
+	},
+	[]string{"backend"},
+)
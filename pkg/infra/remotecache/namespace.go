@@ -0,0 +1,125 @@
+package remotecache
+
+import (
+	"context"
+	"time"
+)
+
+// NamespaceOptions are the defaults of a cache namespace
+type NamespaceOptions struct {
+	// DefaultTTL is used for items set without an expiry, 0 keeps the defaults of RemoteCache
+	DefaultTTL time.Duration
+	// Encrypt encrypts the values of the namespace with the secrets service. It has no
+	// effect if encryption is enabled for the whole remote cache.
+	Encrypt bool
+}
+
+type namespaceContextKey struct{}
+
+// namespaceFromContext returns the namespace of the operation, used to label the metrics
+func namespaceFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(namespaceContextKey{}).(string)
+	return name
+}
+
+// ProvideNamespace returns a cache whose keys are prefixed with the name of the namespace,
+// so services sharing the remote cache do not have to prefix their keys themselves. The
+// metrics of the remote cache are labeled with the namespace.
+func (ds *RemoteCache) ProvideNamespace(name string, opts NamespaceOptions) CacheStorage {
+	var cache CacheStorage = &prefixCacheStorage{cache: ds, prefix: name + ":"}
+	if opts.Encrypt && !ds.Cfg.RemoteCacheOptions.Encryption {
+		cache = newEncryptedCacheStorage(cache, ds.secretsService, ds.codec, nil)
+	}
+	return &namespaceCacheStorage{cache: cache, name: name, defaultTTL: opts.DefaultTTL}
+}
+
+// namespaceCacheStorage applies the default TTL of the namespace and adds the namespace to
+// the context of every operation.
+type namespaceCacheStorage struct {
+	cache      CacheStorage
+	name       string
+	defaultTTL time.Duration
+}
+
+func (s *namespaceCacheStorage) context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, namespaceContextKey{}, s.name)
+}
+
+func (s *namespaceCacheStorage) expire(expire time.Duration) time.Duration {
+	if expire == 0 {
+		return s.defaultTTL
+	}
+	return expire
+}
+
+func (s *namespaceCacheStorage) Get(ctx context.Context, key string) (interface{}, error) {
+	return s.cache.Get(s.context(ctx), key)
+}
+
+func (s *namespaceCacheStorage) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	return s.cache.Set(s.context(ctx), key, value, s.expire(expire))
+}
+
+func (s *namespaceCacheStorage) GetByteArray(ctx context.Context, key string) ([]byte, error) {
+	return s.cache.GetByteArray(s.context(ctx), key)
+}
+
+func (s *namespaceCacheStorage) SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	return s.cache.SetByteArray(s.context(ctx), key, value, s.expire(expire))
+}
+
+func (s *namespaceCacheStorage) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	return s.cache.GetWithTTL(s.context(ctx), key)
+}
+
+func (s *namespaceCacheStorage) Touch(ctx context.Context, key string, expire time.Duration) error {
+	return s.cache.Touch(s.context(ctx), key, s.expire(expire))
+}
+
+func (s *namespaceCacheStorage) Delete(ctx context.Context, key string) error {
+	return s.cache.Delete(s.context(ctx), key)
+}
+
+func (s *namespaceCacheStorage) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	return s.cache.GetMulti(s.context(ctx), keys)
+}
+
+func (s *namespaceCacheStorage) SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error {
+	return s.cache.SetMulti(s.context(ctx), items, s.expire(expire))
+}
+
+func (s *namespaceCacheStorage) DeleteMulti(ctx context.Context, keys []string) error {
+	return s.cache.DeleteMulti(s.context(ctx), keys)
+}
+
+func (s *namespaceCacheStorage) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return s.cache.Increment(s.context(ctx), key, delta, s.expire(expire))
+}
+
+func (s *namespaceCacheStorage) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return s.cache.Decrement(s.context(ctx), key, delta, s.expire(expire))
+}
+
+func (s *namespaceCacheStorage) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	return s.cache.SetIfNotExists(s.context(ctx), key, value, s.expire(expire))
+}
+
+func (s *namespaceCacheStorage) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	return s.cache.CompareAndSwap(s.context(ctx), key, old, value, s.expire(expire))
+}
+
+func (s *namespaceCacheStorage) Scan(ctx context.Context, prefix string) ([]string, error) {
+	return s.cache.Scan(s.context(ctx), prefix)
+}
+
+func (s *namespaceCacheStorage) DeleteByPrefix(ctx context.Context, prefix string) error {
+	return s.cache.DeleteByPrefix(s.context(ctx), prefix)
+}
+
+func (s *namespaceCacheStorage) Count(ctx context.Context, prefix string) (int64, error) {
+	return s.cache.Count(s.context(ctx), prefix)
+}
+
+func (s *namespaceCacheStorage) Stats(ctx context.Context) (*Stats, error) {
+	return s.cache.Stats(s.context(ctx))
+}
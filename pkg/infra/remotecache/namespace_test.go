@@ -0,0 +1,64 @@
+package remotecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func newNamespaceTestCache(t *testing.T, prefix string) *RemoteCache {
+	t.Helper()
+
+	cache, err := ProvideService(&setting.Cfg{
+		RemoteCacheOptions: &setting.RemoteCacheOptions{Name: memoryCacheType, Prefix: prefix},
+	}, nil, &reversingSecretsService{})
+	require.NoError(t, err)
+	return cache
+}
+
+func TestNamespace(t *testing.T) {
+	ctx := context.Background()
+	cache := newNamespaceTestCache(t, "namespace-test-")
+	runTestsForClient(t, cache.ProvideNamespace("tests", NamespaceOptions{}))
+
+	ns := cache.ProvideNamespace("authn", NamespaceOptions{})
+
+	require.NoError(t, ns.SetByteArray(ctx, "key", []byte("value"), time.Minute))
+	data, err := cache.GetByteArray(ctx, "authn:key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(data))
+
+	_, err = ns.GetByteArray(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(setsCounter.WithLabelValues(memoryCacheType, "namespace-test-", "authn")))
+
+	n, err := ns.Count(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestNamespace_Options(t *testing.T) {
+	ctx := context.Background()
+	cache := newNamespaceTestCache(t, "")
+	ns := cache.ProvideNamespace("render", NamespaceOptions{DefaultTTL: time.Minute, Encrypt: true})
+
+	require.NoError(t, ns.SetByteArray(ctx, "key", []byte("secret"), 0))
+
+	_, ttl, err := ns.GetWithTTL(ctx, "key")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	stored, err := cache.GetByteArray(ctx, "render:key")
+	require.NoError(t, err)
+	assert.NotEqual(t, "secret", string(stored))
+
+	data, err := ns.GetByteArray(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(data))
+}
@@ -0,0 +1,169 @@
+package remotecache
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/registry"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// cachePolicies are the policies of the remote cache sorted by the length of their prefix,
+// so the most specific policy is found first.
+type cachePolicies []setting.RemoteCachePolicy
+
+func newCachePolicies(policies []setting.RemoteCachePolicy, keyPrefix string) cachePolicies {
+	sorted := make(cachePolicies, 0, len(policies))
+	for _, policy := range policies {
+		policy.Prefix = keyPrefix + policy.Prefix
+		sorted = append(sorted, policy)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Prefix) > len(sorted[j].Prefix)
+	})
+	return sorted
+}
+
+// lookup returns the policy of the key, the second value is false if no policy applies.
+func (p cachePolicies) lookup(key string) (setting.RemoteCachePolicy, bool) {
+	for _, policy := range p {
+		if strings.HasPrefix(key, policy.Prefix) {
+			return policy, true
+		}
+	}
+	return setting.RemoteCachePolicy{}, false
+}
+
+// expire returns the default TTL of the policy of the key for items stored without an expiry.
+func (p cachePolicies) expire(key string, expire time.Duration) time.Duration {
+	if expire != 0 {
+		return expire
+	}
+	if policy, ok := p.lookup(key); ok {
+		return policy.DefaultTTL
+	}
+	return 0
+}
+
+// localCache returns false for keys whose policy keeps them out of the local cache.
+func (p cachePolicies) localCache(key string) bool {
+	policy, ok := p.lookup(key)
+	return !ok || policy.LocalCache
+}
+
+// policyCacheStorage applies the default TTL of the policies to items stored without an expiry.
+type policyCacheStorage struct {
+	cache    CacheStorage
+	policies cachePolicies
+}
+
+func (s *policyCacheStorage) Get(ctx context.Context, key string) (interface{}, error) {
+	return s.cache.Get(ctx, key)
+}
+
+func (s *policyCacheStorage) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	return s.cache.Set(ctx, key, value, s.policies.expire(key, expire))
+}
+
+func (s *policyCacheStorage) GetByteArray(ctx context.Context, key string) ([]byte, error) {
+	return s.cache.GetByteArray(ctx, key)
+}
+
+func (s *policyCacheStorage) SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	return s.cache.SetByteArray(ctx, key, value, s.policies.expire(key, expire))
+}
+
+func (s *policyCacheStorage) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	return s.cache.GetWithTTL(ctx, key)
+}
+
+func (s *policyCacheStorage) Touch(ctx context.Context, key string, expire time.Duration) error {
+	return s.cache.Touch(ctx, key, s.policies.expire(key, expire))
+}
+
+func (s *policyCacheStorage) Delete(ctx context.Context, key string) error {
+	return s.cache.Delete(ctx, key)
+}
+
+func (s *policyCacheStorage) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	return s.cache.GetMulti(ctx, keys)
+}
+
+// SetMulti stores the items in one call per TTL, keys of different policies can have
+// different TTLs.
+func (s *policyCacheStorage) SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error {
+	byExpire := map[time.Duration]map[string][]byte{}
+	for key, value := range items {
+		e := s.policies.expire(key, expire)
+		if byExpire[e] == nil {
+			byExpire[e] = map[string][]byte{}
+		}
+		byExpire[e][key] = value
+	}
+	for e, items := range byExpire {
+		if err := s.cache.SetMulti(ctx, items, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *policyCacheStorage) DeleteMulti(ctx context.Context, keys []string) error {
+	return s.cache.DeleteMulti(ctx, keys)
+}
+
+func (s *policyCacheStorage) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return s.cache.Increment(ctx, key, delta, s.policies.expire(key, expire))
+}
+
+func (s *policyCacheStorage) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return s.cache.Decrement(ctx, key, delta, s.policies.expire(key, expire))
+}
+
+func (s *policyCacheStorage) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	return s.cache.SetIfNotExists(ctx, key, value, s.policies.expire(key, expire))
+}
+
+func (s *policyCacheStorage) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	return s.cache.CompareAndSwap(ctx, key, old, value, s.policies.expire(key, expire))
+}
+
+func (s *policyCacheStorage) Scan(ctx context.Context, prefix string) ([]string, error) {
+	return s.cache.Scan(ctx, prefix)
+}
+
+func (s *policyCacheStorage) DeleteByPrefix(ctx context.Context, prefix string) error {
+	return s.cache.DeleteByPrefix(ctx, prefix)
+}
+
+func (s *policyCacheStorage) Count(ctx context.Context, prefix string) (int64, error) {
+	return s.cache.Count(ctx, prefix)
+}
+
+func (s *policyCacheStorage) Stats(ctx context.Context) (*Stats, error) {
+	return s.cache.Stats(ctx)
+}
+
+func (s *policyCacheStorage) runBatch(ctx context.Context, ops []*batchOp) {
+	withTTL := make([]*batchOp, 0, len(ops))
+	for _, op := range ops {
+		if op.kind == batchSet || op.kind == batchTouch {
+			c := *op
+			c.expire = s.policies.expire(op.key, op.expire)
+			op = &c
+		}
+		withTTL = append(withTTL, op)
+	}
+	execBatch(ctx, s.cache, withTTL)
+}
+
+// Run runs the background jobs of the wrapped cache.
+func (s *policyCacheStorage) Run(ctx context.Context) error {
+	if backgroundjob, ok := s.cache.(registry.BackgroundService); ok {
+		return backgroundjob.Run(ctx)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
@@ -0,0 +1,120 @@
+package remotecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestCachePolicies(t *testing.T) {
+	encrypt := true
+	policies := newCachePolicies([]setting.RemoteCachePolicy{
+		{Prefix: "authn/", DefaultTTL: time.Hour, LocalCache: true},
+		{Prefix: "authn/jwks/", DefaultTTL: time.Minute, Encrypt: &encrypt},
+	}, "grafana-")
+
+	policy, ok := policies.lookup("grafana-authn/jwks/key")
+	require.True(t, ok)
+	assert.Equal(t, "grafana-authn/jwks/", policy.Prefix)
+
+	_, ok = policies.lookup("authn/jwks/key")
+	assert.False(t, ok)
+
+	assert.Equal(t, time.Hour, policies.expire("grafana-authn/user", 0))
+	assert.Equal(t, time.Second, policies.expire("grafana-authn/user", time.Second))
+	assert.Equal(t, time.Duration(0), policies.expire("grafana-session/1", 0))
+
+	// the most specific policy applies on its own
+	assert.False(t, policies.localCache("grafana-authn/jwks/key"))
+	assert.True(t, policies.localCache("grafana-authn/user"))
+	assert.True(t, policies.localCache("grafana-session/1"))
+}
+
+func TestPolicies(t *testing.T) {
+	ctx := context.Background()
+	enabled, disabled := true, false
+	policies := []setting.RemoteCachePolicy{
+		{Prefix: "authn/", DefaultTTL: time.Hour, Encrypt: &enabled},
+		{Prefix: "public/", Encrypt: &disabled},
+	}
+
+	t.Run("default TTL", func(t *testing.T) {
+		cache, err := ProvideService(&setting.Cfg{
+			RemoteCacheOptions: &setting.RemoteCacheOptions{Name: memoryCacheType, Policies: policies},
+		}, nil, &reversingSecretsService{})
+		require.NoError(t, err)
+		runTestsForClient(t, cache)
+
+		require.NoError(t, cache.Set(ctx, "authn/user", "value", 0))
+		require.NoError(t, cache.SetByteArray(ctx, "authn/session", []byte("value"), 0))
+		require.NoError(t, cache.SetMulti(ctx, map[string][]byte{"authn/a": []byte("a"), "other": []byte("b")}, 0))
+		require.NoError(t, cache.SetByteArray(ctx, "authn/short", []byte("value"), time.Minute))
+
+		for key, expected := range map[string]time.Duration{
+			"authn/user":    time.Hour,
+			"authn/session": time.Hour,
+			"authn/a":       time.Hour,
+			"other":         0,
+			"authn/short":   time.Minute,
+		} {
+			_, ttl, err := cache.GetWithTTL(ctx, key)
+			require.NoError(t, err)
+			assert.InDelta(t, expected, ttl, float64(time.Second), key)
+		}
+	})
+
+	t.Run("encryption", func(t *testing.T) {
+		for name, opts := range map[string]*setting.RemoteCacheOptions{
+			"enabled by policy":  {Name: memoryCacheType, Policies: policies},
+			"disabled by policy": {Name: memoryCacheType, Encryption: true, Policies: policies},
+		} {
+			t.Run(name, func(t *testing.T) {
+				backend := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+				client := newEncryptedCacheStorage(backend, &reversingSecretsService{}, &gobCodec{}, nil)
+				client.policies = newCachePolicies(opts.Policies, "")
+				if !opts.Encryption {
+					client.prefixes = []string{"authn/"}
+				}
+
+				for _, key := range []string{"authn/user", "public/key", "other"} {
+					require.NoError(t, client.SetByteArray(ctx, key, []byte("secret"), 0))
+				}
+				for key, encrypted := range map[string]bool{
+					"authn/user": true,
+					"public/key": false,
+					"other":      opts.Encryption,
+				} {
+					stored, err := backend.GetByteArray(ctx, key)
+					require.NoError(t, err)
+					assert.Equal(t, encrypted, string(stored) != "secret", key)
+				}
+			})
+		}
+	})
+
+	t.Run("local cache", func(t *testing.T) {
+		remote := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+		tiered := newTieredCacheStorage(remote, (&fakeInvalidationBus{}).invalidator(), &gobCodec{}, time.Minute, 100)
+		tiered.policies = newCachePolicies([]setting.RemoteCachePolicy{{Prefix: "session/", LocalCache: false}}, "")
+
+		for _, key := range []string{"session/1", "other"} {
+			require.NoError(t, tiered.SetByteArray(ctx, key, []byte("v1"), 0))
+			_, err := tiered.GetByteArray(ctx, key)
+			require.NoError(t, err)
+			require.NoError(t, remote.SetByteArray(ctx, key, []byte("v2"), 0))
+		}
+
+		// keys of the policy are always read from the remote cache
+		data, err := tiered.GetByteArray(ctx, "session/1")
+		require.NoError(t, err)
+		assert.Equal(t, "v2", string(data))
+		data, err = tiered.GetByteArray(ctx, "other")
+		require.NoError(t, err)
+		assert.Equal(t, "v1", string(data))
+	})
+}
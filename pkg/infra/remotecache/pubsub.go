@@ -0,0 +1,197 @@
+package remotecache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+// PubSub broadcasts messages to all Grafana instances sharing the remote cache. Delivery is
+// best effort, messages published while an instance is disconnected can be lost.
+type PubSub interface {
+	// Publish sends the message to all subscribers of the channel, including this instance.
+	Publish(ctx context.Context, channel string, message []byte) error
+	// Subscribe calls handler for every message published on the channel until the context is done.
+	Subscribe(ctx context.Context, channel string, handler func(message []byte)) error
+}
+
+// newPubSub returns redis pub/sub for the redis backend, an in-process pub/sub for the
+// memory backend and polls the database otherwise.
+func newPubSub(backend CacheStorage, sqlstore db.DB) PubSub {
+	switch b := backend.(type) {
+	case *redisStorage:
+		return &redisPubSub{c: b.c}
+	case *memoryStorage:
+		return newMemoryPubSub()
+	default:
+		return newDatabasePubSub(sqlstore)
+	}
+}
+
+type redisPubSub struct {
+	c redis.UniversalClient
+}
+
+func (p *redisPubSub) Publish(ctx context.Context, channel string, message []byte) error {
+	return p.c.Publish(ctx, channel, message).Err()
+}
+
+func (p *redisPubSub) Subscribe(ctx context.Context, channel string, handler func(message []byte)) error {
+	sub := p.c.Subscribe(ctx, channel)
+	defer func() { _ = sub.Close() }()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			handler([]byte(msg.Payload))
+		}
+	}
+}
+
+// memoryPubSub delivers messages within the process, the memory backend is not shared
+// with other instances.
+type memoryPubSub struct {
+	mu       sync.RWMutex
+	handlers map[string]map[*func(message []byte)]struct{}
+}
+
+func newMemoryPubSub() *memoryPubSub {
+	return &memoryPubSub{handlers: map[string]map[*func(message []byte)]struct{}{}}
+}
+
+func (p *memoryPubSub) Publish(ctx context.Context, channel string, message []byte) error {
+	p.mu.RLock()
+	handlers := make([]func(message []byte), 0, len(p.handlers[channel]))
+	for handler := range p.handlers[channel] {
+		handlers = append(handlers, *handler)
+	}
+	p.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+	return nil
+}
+
+func (p *memoryPubSub) Subscribe(ctx context.Context, channel string, handler func(message []byte)) error {
+	p.mu.Lock()
+	if p.handlers[channel] == nil {
+		p.handlers[channel] = map[*func(message []byte)]struct{}{}
+	}
+	p.handlers[channel][&handler] = struct{}{}
+	p.mu.Unlock()
+
+	<-ctx.Done()
+
+	p.mu.Lock()
+	delete(p.handlers[channel], &handler)
+	p.mu.Unlock()
+	return ctx.Err()
+}
+
+// CacheMessage is the struct representing the table used to publish messages when the
+// remote cache has no pub/sub support
+type CacheMessage struct {
+	ID        int64  `xorm:"pk autoincr 'id'"`
+	Channel   string `xorm:"channel"`
+	Payload   []byte `xorm:"payload"`
+	CreatedAt int64  `xorm:"created_at"`
+}
+
+// databasePubSub publishes messages by inserting them into the cache_message table which
+// every subscriber polls. Rows of transactions that commit out of id order can be missed
+// by the poll.
+type databasePubSub struct {
+	SQLStore     db.DB
+	pollInterval time.Duration
+	retention    time.Duration
+	log          log.Logger
+}
+
+func newDatabasePubSub(sqlStore db.DB) *databasePubSub {
+	return &databasePubSub{
+		SQLStore:     sqlStore,
+		pollInterval: 2 * time.Second,
+		retention:    10 * time.Minute,
+		log:          log.New("remotecache.pubsub"),
+	}
+}
+
+func (p *databasePubSub) Publish(ctx context.Context, channel string, message []byte) error {
+	return p.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		_, err := session.Insert(&CacheMessage{Channel: channel, Payload: message, CreatedAt: getTime().Unix()})
+		return err
+	})
+}
+
+func (p *databasePubSub) Subscribe(ctx context.Context, channel string, handler func(message []byte)) error {
+	lastID, err := p.lastID(ctx)
+	if err != nil {
+		return err
+	}
+
+	poll := time.NewTicker(p.pollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(time.Minute)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-poll.C:
+			lastID, err = p.poll(ctx, channel, lastID, handler)
+			if err != nil {
+				p.log.Error("Failed to poll cache messages", "channel", channel, "error", err)
+			}
+		case <-cleanup.C:
+			if err := p.cleanup(ctx); err != nil {
+				p.log.Error("Failed to clean up cache messages", "error", err)
+			}
+		}
+	}
+}
+
+func (p *databasePubSub) lastID(ctx context.Context) (int64, error) {
+	var id int64
+	err := p.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		_, err := session.SQL("SELECT COALESCE(MAX(id), 0) FROM cache_message").Get(&id)
+		return err
+	})
+	return id, err
+}
+
+// poll calls handler for all messages of the channel after lastID and returns the id of the last one.
+func (p *databasePubSub) poll(ctx context.Context, channel string, lastID int64, handler func(message []byte)) (int64, error) {
+	var rows []CacheMessage
+	err := p.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		return session.Where("id > ? AND channel = ?", lastID, channel).Asc("id").Find(&rows)
+	})
+	if err != nil {
+		return lastID, err
+	}
+
+	for _, row := range rows {
+		handler(row.Payload)
+		lastID = row.ID
+	}
+	return lastID, nil
+}
+
+func (p *databasePubSub) cleanup(ctx context.Context) error {
+	return p.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		_, err := session.Exec("DELETE FROM cache_message WHERE created_at < ?", getTime().Add(-p.retention).Unix())
+		return err
+	})
+}
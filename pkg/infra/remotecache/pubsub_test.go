@@ -0,0 +1,69 @@
+package remotecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+func TestDatabasePubSub(t *testing.T) {
+	ctx := context.Background()
+	p := newDatabasePubSub(db.InitTestDB(t))
+
+	require.NoError(t, p.Publish(ctx, "channel", []byte("old")))
+	lastID, err := p.lastID(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, p.Publish(ctx, "channel", []byte("message1")))
+	require.NoError(t, p.Publish(ctx, "other-channel", []byte("other")))
+	require.NoError(t, p.Publish(ctx, "channel", []byte("message2")))
+
+	var messages []string
+	handler := func(message []byte) { messages = append(messages, string(message)) }
+	lastID, err = p.poll(ctx, "channel", lastID, handler)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"message1", "message2"}, messages)
+
+	// already seen messages are not returned again
+	messages = nil
+	_, err = p.poll(ctx, "channel", lastID, handler)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	getTime = func() time.Time { return time.Now().Add(time.Hour) }
+	t.Cleanup(func() { getTime = time.Now })
+	require.NoError(t, p.cleanup(ctx))
+	id, err := p.lastID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), id)
+}
+
+func TestMemoryPubSub(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := newMemoryPubSub()
+
+	received := make(chan string, 1)
+	done := make(chan error)
+	go func() {
+		done <- p.Subscribe(ctx, "channel", func(message []byte) { received <- string(message) })
+	}()
+
+	require.Eventually(t, func() bool {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		return len(p.handlers["channel"]) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, p.Publish(ctx, "other-channel", []byte("other")))
+	require.NoError(t, p.Publish(ctx, "channel", []byte("message")))
+	assert.Equal(t, "message", <-received)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Empty(t, p.handlers["channel"])
+}
@@ -3,9 +3,13 @@ package remotecache
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -16,21 +20,43 @@ import (
 const redisCacheType = "redis"
 
 type redisStorage struct {
-	c     *redis.Client
+	c     redis.UniversalClient
 	codec codec
 }
 
+// redisOptions are the options parsed from the redis connection string.
+// When MasterName is set Addrs are the addresses of the Sentinels monitoring the master.
+type redisOptions struct {
+	redis.UniversalOptions
+	// Cluster connects to a Redis Cluster, using Addrs as the seed nodes.
+	Cluster bool
+}
+
+func (o *redisOptions) newClient() redis.UniversalClient {
+	if o.Cluster {
+		// the cluster client follows MOVED and ASK redirects to the node owning the slot
+		return redis.NewClusterClient(o.UniversalOptions.Cluster())
+	}
+	if o.MasterName != "" {
+		// the failover client asks the Sentinels for the current master and reconnects on failover
+		return redis.NewFailoverClient(o.UniversalOptions.Failover())
+	}
+	return redis.NewClient(o.UniversalOptions.Simple())
+}
+
 // parseRedisConnStr parses k=v pairs in csv and builds a redis Options object
-func parseRedisConnStr(connStr string) (*redis.Options, error) {
+func parseRedisConnStr(connStr string) (*redisOptions, error) {
 	keyValueCSV := strings.Split(connStr, ",")
-	options := &redis.Options{Network: "tcp"}
+	options := &redisOptions{}
 	setTLSIsTrue := false
 	for _, rawKeyValue := range keyValueCSV {
 		keyValueTuple := strings.SplitN(rawKeyValue, "=", 2)
 		if len(keyValueTuple) != 2 {
+			// don't log the passwords
 			if strings.HasPrefix(rawKeyValue, "password") {
-				// don't log the password
 				rawKeyValue = "password" + setting.RedactedPassword
+			} else if strings.HasPrefix(rawKeyValue, "sentinel_password") {
+				rawKeyValue = "sentinel_password" + setting.RedactedPassword
 			}
 			return nil, fmt.Errorf("incorrect redis connection string format detected for '%v', format is key=value,key=value", rawKeyValue)
 		}
@@ -38,7 +64,12 @@ func parseRedisConnStr(connStr string) (*redis.Options, error) {
 		connVal := keyValueTuple[1]
 		switch connKey {
 		case "addr":
-			options.Addr = connVal
+			// addr can be repeated to list the seed nodes of a cluster or the Sentinels
+			options.Addrs = append(options.Addrs, connVal)
+		case "master_name":
+			options.MasterName = connVal
+		case "sentinel_password":
+			options.SentinelPassword = connVal
 		case "password":
 			options.Password = connVal
 		case "db":
@@ -53,6 +84,12 @@ func parseRedisConnStr(connStr string) (*redis.Options, error) {
 				return nil, fmt.Errorf("%v: %w", "value for pool_size in redis connection string must be a number", err)
 			}
 			options.PoolSize = i
+		case "cluster":
+			cluster, err := strconv.ParseBool(connVal)
+			if err != nil {
+				return nil, fmt.Errorf("cluster must be set to 'true' or 'false' when present")
+			}
+			options.Cluster = cluster
 		case "ssl":
 			if connVal != "true" && connVal != "false" && connVal != "insecure" {
 				return nil, fmt.Errorf("ssl must be set to 'true', 'false', or 'insecure' when present")
@@ -67,13 +104,30 @@ func parseRedisConnStr(connStr string) (*redis.Options, error) {
 			return nil, fmt.Errorf("unrecognized option '%v' in redis connection string", connKey)
 		}
 	}
+	if len(options.Addrs) > 1 && !options.Cluster && options.MasterName == "" {
+		return nil, fmt.Errorf("multiple addr in redis connection string are only supported with cluster=true or master_name")
+	}
+	if options.Cluster && options.MasterName != "" {
+		return nil, fmt.Errorf("cluster=true and master_name can not be combined in redis connection string")
+	}
+	if options.SentinelPassword != "" && options.MasterName == "" {
+		return nil, fmt.Errorf("sentinel_password in redis connection string requires master_name")
+	}
+	if options.Cluster && options.DB != 0 {
+		return nil, fmt.Errorf("db in redis connection string is not supported with cluster=true")
+	}
 	if setTLSIsTrue {
-		// Get hostname from the Addr property and set it on the configuration for TLS
-		sp := strings.Split(options.Addr, ":")
-		if len(sp) < 1 {
-			return nil, fmt.Errorf("unable to get hostname from the addr field, expected host:port, got '%v'", options.Addr)
+		// Get hostname from the Addr property and set it on the configuration for TLS,
+		// the nodes of a cluster are expected to share the certificate of the first addr
+		var addr string
+		if len(options.Addrs) > 0 {
+			addr = options.Addrs[0]
 		}
-		options.TLSConfig = &tls.Config{ServerName: sp[0]}
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get hostname from the addr field, expected host:port, got '%v': %w", addr, err)
+		}
+		options.TLSConfig = &tls.Config{ServerName: host}
 	}
 	return options, nil
 }
@@ -83,7 +137,24 @@ func newRedisStorage(opts *setting.RemoteCacheOptions, codec codec) (*redisStora
 	if err != nil {
 		return nil, err
 	}
-	return &redisStorage{c: redis.NewClient(opt), codec: codec}, nil
+
+	tlsConfig, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		// TLS settings of the remote cache take precedence over ssl in the connection string
+		if tlsConfig.ServerName == "" && len(opt.Addrs) > 0 {
+			host, _, err := net.SplitHostPort(opt.Addrs[0])
+			if err != nil {
+				return nil, fmt.Errorf("unable to get hostname from the addr field, expected host:port, got '%v': %w", opt.Addrs[0], err)
+			}
+			tlsConfig.ServerName = host
+		}
+		opt.TLSConfig = tlsConfig
+	}
+
+	return &redisStorage{c: opt.newClient(), codec: codec}, nil
 }
 
 // Set sets value to given key in session.
@@ -129,14 +200,353 @@ func (s *redisStorage) GetByteArray(ctx context.Context, key string) ([]byte, er
 	return s.c.Get(ctx, key).Bytes()
 }
 
+func (s *redisStorage) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	var get *redis.StringCmd
+	var pttl *redis.DurationCmd
+	_, err := s.c.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, 0, ErrCacheItemNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// PTTL returns a negative duration for keys without expiry
+	ttl := pttl.Val()
+	if ttl < 0 {
+		ttl = 0
+	}
+	return []byte(get.Val()), ttl, nil
+}
+
+func (s *redisStorage) Touch(ctx context.Context, key string, expire time.Duration) error {
+	var cmd *redis.BoolCmd
+	if expire > 0 {
+		cmd = s.c.PExpire(ctx, key, expire)
+	} else {
+		cmd = s.c.Persist(ctx, key)
+	}
+	ok, err := cmd.Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// PERSIST also returns false for an existing key without expiry
+		if expire <= 0 {
+			if n, err := s.c.Exists(ctx, key).Result(); err == nil && n == 1 {
+				return nil
+			}
+		}
+		return ErrCacheItemNotFound
+	}
+	return nil
+}
+
 // Delete delete a key from session.
 func (s *redisStorage) Delete(ctx context.Context, key string) error {
 	cmd := s.c.Del(ctx, key)
 	return cmd.Err()
 }
 
+func (s *redisStorage) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	// MGET fails for keys in different slots of a cluster, the cluster pipeline
+	// sends the commands to the node owning each key instead
+	if _, ok := s.c.(*redis.ClusterClient); ok {
+		cmds := make([]*redis.StringCmd, len(keys))
+		_, err := s.c.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				cmds[i] = pipe.Get(ctx, key)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		for i, cmd := range cmds {
+			if data, err := cmd.Bytes(); err == nil {
+				result[keys[i]] = data
+			}
+		}
+		return result, nil
+	}
+
+	values, err := s.c.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		// missing keys are returned as nil
+		if str, ok := value.(string); ok {
+			result[keys[i]] = []byte(str)
+		}
+	}
+	return result, nil
+}
+
+func (s *redisStorage) SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	_, err := s.c.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, data := range items {
+			pipe.Set(ctx, key, data, expire)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *redisStorage) DeleteMulti(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// DEL with multiple keys fails for keys in different slots of a cluster
+	if _, ok := s.c.(*redis.ClusterClient); ok {
+		_, err := s.c.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				pipe.Del(ctx, key)
+			}
+			return nil
+		})
+		return err
+	}
+
+	return s.c.Del(ctx, keys...).Err()
+}
+
+// runBatch sends the whole batch in one pipeline, the cluster client splits the pipeline
+// by the node owning each key.
+func (s *redisStorage) runBatch(ctx context.Context, ops []*batchOp) {
+	cmds := make([]redis.Cmder, len(ops))
+	// the second command of GetWithTTL and of Touch without expiry
+	extra := make([]redis.Cmder, len(ops))
+	// the error of the pipeline is the error of its first failed command, the error of
+	// every command is read below
+	_, _ = s.c.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, op := range ops {
+			switch op.kind {
+			case batchGet:
+				cmds[i] = pipe.Get(ctx, op.key)
+			case batchGetWithTTL:
+				cmds[i] = pipe.Get(ctx, op.key)
+				extra[i] = pipe.PTTL(ctx, op.key)
+			case batchSet:
+				cmds[i] = pipe.Set(ctx, op.key, op.value, op.expire)
+			case batchTouch:
+				if op.expire > 0 {
+					cmds[i] = pipe.PExpire(ctx, op.key, op.expire)
+				} else {
+					// PERSIST also returns false for an existing key without expiry
+					cmds[i] = pipe.Persist(ctx, op.key)
+					extra[i] = pipe.Exists(ctx, op.key)
+				}
+			case batchDelete:
+				cmds[i] = pipe.Del(ctx, op.key)
+			case batchPublish:
+				cmds[i] = pipe.Publish(ctx, op.key, op.value)
+			}
+		}
+		return nil
+	})
+
+	for i, op := range ops {
+		r := op.result
+		if err := cmds[i].Err(); err != nil {
+			if errors.Is(err, redis.Nil) {
+				err = ErrCacheItemNotFound
+			}
+			r.Err = err
+			continue
+		}
+
+		switch op.kind {
+		case batchGet:
+			r.Value, r.Err = cmds[i].(*redis.StringCmd).Bytes()
+		case batchGetWithTTL:
+			r.Value, r.Err = cmds[i].(*redis.StringCmd).Bytes()
+			// PTTL returns a negative duration for keys without expiry
+			if ttl := extra[i].(*redis.DurationCmd).Val(); ttl > 0 {
+				r.TTL = ttl
+			}
+		case batchTouch:
+			touched := cmds[i].(*redis.BoolCmd).Val()
+			if !touched && (extra[i] == nil || extra[i].(*redis.IntCmd).Val() != 1) {
+				r.Err = ErrCacheItemNotFound
+			}
+		}
+	}
+}
+
+// incrementScript increments the counter and sets the expiry only when the counter was
+// created, running it as a script keeps INCRBY and PEXPIRE atomic.
+var incrementScript = redis.NewScript(`
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call("PTTL", KEYS[1]) == -1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return value
+`)
+
+func (s *redisStorage) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	value, err := incrementScript.Run(ctx, s.c, []string{key}, delta, expire.Milliseconds()).Int64()
+	if err != nil && strings.Contains(err.Error(), "not an integer") {
+		return 0, ErrCacheItemNotCounter
+	}
+	return value, err
+}
+
+func (s *redisStorage) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return s.Increment(ctx, key, -delta, expire)
+}
+
+func (s *redisStorage) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	return s.c.SetNX(ctx, key, value, expire).Result()
+}
+
+// compareAndSwapScript sets the value only if the current value matches, GET returns
+// false for a missing key which never equals the expected value.
+var compareAndSwapScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+func (s *redisStorage) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	swapped, err := compareAndSwapScript.Run(ctx, s.c, []string{key}, old, value, expire.Milliseconds()).Int()
+	return swapped == 1, err
+}
+
+// Scan uses SCAN, which unlike KEYS does not block the server while iterating the keys.
+func (s *redisStorage) Scan(ctx context.Context, prefix string) ([]string, error) {
+	var mu sync.Mutex
+	keys := []string{}
+	err := s.forEachNode(ctx, func(ctx context.Context, c redis.UniversalClient) error {
+		nodeKeys, err := scanKeys(ctx, c, prefix)
+		if err != nil {
+			return err
+		}
+		// the masters of a cluster are scanned concurrently
+		mu.Lock()
+		keys = append(keys, nodeKeys...)
+		mu.Unlock()
+		return nil
+	})
+	return keys, err
+}
+
+// DeleteByPrefix deletes the keys found by SCAN one by one, the keys of a cluster node
+// can belong to different slots which cannot be deleted with a single command.
+func (s *redisStorage) DeleteByPrefix(ctx context.Context, prefix string) error {
+	return s.forEachNode(ctx, func(ctx context.Context, c redis.UniversalClient) error {
+		keys, err := scanKeys(ctx, c, prefix)
+		if err != nil || len(keys) == 0 {
+			return err
+		}
+		_, err = c.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				pipe.Del(ctx, key)
+			}
+			return nil
+		})
+		return err
+	})
+}
+
+// forEachNode calls fn for every master of a cluster, or once for other clients.
+func (s *redisStorage) forEachNode(ctx context.Context, fn func(ctx context.Context, c redis.UniversalClient) error) error {
+	if cluster, ok := s.c.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return fn(ctx, client)
+		})
+	}
+	return fn(ctx, s.c)
+}
+
+func scanKeys(ctx context.Context, c redis.UniversalClient, prefix string) ([]string, error) {
+	var keys []string
+	iter := c.Scan(ctx, 0, escapeGlob(prefix)+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// escapeGlob escapes the characters with a special meaning in redis patterns
+func escapeGlob(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(s)
+}
+
 func (s *redisStorage) Count(ctx context.Context, prefix string) (int64, error) {
-	cmd := s.c.Keys(ctx, prefix+"*")
+	// keys are spread over the master nodes of a cluster, so every master has to be asked
+	if cluster, ok := s.c.(*redis.ClusterClient); ok {
+		var count int64
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			n, err := countKeys(ctx, client, prefix)
+			atomic.AddInt64(&count, n)
+			return err
+		})
+		return count, err
+	}
+
+	return countKeys(ctx, s.c, prefix)
+}
+
+// Stats sums the keys and used memory of all masters, the memory includes the overhead of redis.
+func (s *redisStorage) Stats(ctx context.Context) (*Stats, error) {
+	var mu sync.Mutex
+	stats := &Stats{}
+	err := s.forEachNode(ctx, func(ctx context.Context, c redis.UniversalClient) error {
+		items, err := c.DBSize(ctx).Result()
+		if err != nil {
+			return err
+		}
+		info, err := c.Info(ctx, "memory").Result()
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		stats.Items += items
+		stats.Bytes += parseRedisUsedMemory(info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// parseRedisUsedMemory returns the used_memory field of the INFO memory reply, 0 if it is missing.
+func parseRedisUsedMemory(info string) int64 {
+	for _, line := range strings.Split(info, "\n") {
+		if name, value, ok := strings.Cut(strings.TrimSpace(line), ":"); ok && name == "used_memory" {
+			bytes, _ := strconv.ParseInt(value, 10, 64)
+			return bytes
+		}
+	}
+	return 0
+}
+
+func countKeys(ctx context.Context, c redis.Cmdable, prefix string) (int64, error) {
+	cmd := c.Keys(ctx, prefix+"*")
 	if cmd.Err() != nil {
 		return 0, cmd.Err()
 	}
@@ -12,48 +12,110 @@ import (
 func Test_parseRedisConnStr(t *testing.T) {
 	cases := map[string]struct {
 		InputConnStr  string
-		OutputOptions *redis.Options
+		OutputOptions *redisOptions
 		ShouldErr     bool
 	}{
 		"all redis options should parse": {
 			"addr=127.0.0.1:6379,pool_size=100,db=1,password=grafanaRocks,ssl=false",
-			&redis.Options{
-				Addr:      "127.0.0.1:6379",
+			&redisOptions{UniversalOptions: redis.UniversalOptions{
+				Addrs:     []string{"127.0.0.1:6379"},
 				PoolSize:  100,
 				DB:        1,
 				Password:  "grafanaRocks",
-				Network:   "tcp",
 				TLSConfig: nil,
-			},
+			}},
 			false,
 		},
 		"subset of redis options should parse": {
 			"addr=127.0.0.1:6379,pool_size=100",
-			&redis.Options{
-				Addr:     "127.0.0.1:6379",
+			&redisOptions{UniversalOptions: redis.UniversalOptions{
+				Addrs:    []string{"127.0.0.1:6379"},
 				PoolSize: 100,
-				Network:  "tcp",
-			},
+			}},
 			false,
 		},
 		"ssl set to true should result in default TLS configuration with tls set to addr's host": {
 			"addr=grafana.com:6379,ssl=true",
-			&redis.Options{
-				Addr:      "grafana.com:6379",
-				Network:   "tcp",
+			&redisOptions{UniversalOptions: redis.UniversalOptions{
+				Addrs:     []string{"grafana.com:6379"},
 				TLSConfig: &tls.Config{ServerName: "grafana.com"},
-			},
+			}},
+			false,
+		},
+		"ssl set to true should use the host of an IPv6 addr": {
+			"addr=[::1]:6379,ssl=true",
+			&redisOptions{UniversalOptions: redis.UniversalOptions{
+				Addrs:     []string{"[::1]:6379"},
+				TLSConfig: &tls.Config{ServerName: "::1"},
+			}},
 			false,
 		},
 		"ssl to insecure should result in TLS configuration with InsecureSkipVerify": {
 			"addr=127.0.0.1:6379,ssl=insecure",
-			&redis.Options{
-				Addr:      "127.0.0.1:6379",
-				Network:   "tcp",
+			&redisOptions{UniversalOptions: redis.UniversalOptions{
+				Addrs:     []string{"127.0.0.1:6379"},
 				TLSConfig: &tls.Config{InsecureSkipVerify: true},
+			}},
+			false,
+		},
+		"cluster should parse multiple addresses": {
+			"addr=10.0.0.1:7000,addr=10.0.0.2:7000,addr=10.0.0.3:7000,pool_size=10,cluster=true",
+			&redisOptions{
+				UniversalOptions: redis.UniversalOptions{
+					Addrs:    []string{"10.0.0.1:7000", "10.0.0.2:7000", "10.0.0.3:7000"},
+					PoolSize: 10,
+				},
+				Cluster: true,
 			},
 			false,
 		},
+		"cluster with ssl set to true should use the host of the first addr": {
+			"addr=node1.grafana.com:7000,addr=node2.grafana.com:7000,cluster=true,ssl=true",
+			&redisOptions{
+				UniversalOptions: redis.UniversalOptions{
+					Addrs:     []string{"node1.grafana.com:7000", "node2.grafana.com:7000"},
+					TLSConfig: &tls.Config{ServerName: "node1.grafana.com"},
+				},
+				Cluster: true,
+			},
+			false,
+		},
+		"sentinel should parse master name and sentinel addresses": {
+			"addr=10.0.0.1:26379,addr=10.0.0.2:26379,master_name=mymaster,password=grafanaRocks,sentinel_password=sentinelRocks,db=1",
+			&redisOptions{UniversalOptions: redis.UniversalOptions{
+				Addrs:            []string{"10.0.0.1:26379", "10.0.0.2:26379"},
+				MasterName:       "mymaster",
+				Password:         "grafanaRocks",
+				SentinelPassword: "sentinelRocks",
+				DB:               1,
+			}},
+			false,
+		},
+		"cluster combined with master name should err": {
+			"addr=10.0.0.1:7000,cluster=true,master_name=mymaster",
+			nil,
+			true,
+		},
+		"sentinel password without master name should err": {
+			"addr=10.0.0.1:26379,sentinel_password=sentinelRocks",
+			nil,
+			true,
+		},
+		"multiple addresses without cluster should err": {
+			"addr=10.0.0.1:7000,addr=10.0.0.2:7000",
+			nil,
+			true,
+		},
+		"db in cluster mode should err": {
+			"addr=10.0.0.1:7000,cluster=true,db=1",
+			nil,
+			true,
+		},
+		"invalid cluster value should err": {
+			"addr=10.0.0.1:7000,cluster=maybe",
+			nil,
+			true,
+		},
 		"invalid SSL option should err": {
 			"addr=127.0.0.1:6379,ssl=dragons",
 			nil,
@@ -97,3 +159,23 @@ func Test_parseRedisConnStr(t *testing.T) {
 		assert.EqualValues(t, testCase.OutputOptions, options, reason)
 	}
 }
+
+func Test_redisOptions_newClient(t *testing.T) {
+	options, err := parseRedisConnStr("addr=127.0.0.1:6379")
+	assert.NoError(t, err)
+	assert.IsType(t, &redis.Client{}, options.newClient())
+
+	options, err = parseRedisConnStr("addr=127.0.0.1:7000,addr=127.0.0.1:7001,cluster=true")
+	assert.NoError(t, err)
+	assert.IsType(t, &redis.ClusterClient{}, options.newClient())
+
+	options, err = parseRedisConnStr("addr=127.0.0.1:26379,master_name=mymaster")
+	assert.NoError(t, err)
+	assert.IsType(t, &redis.Client{}, options.newClient())
+}
+
+func Test_parseRedisUsedMemory(t *testing.T) {
+	info := "# Memory\r\nused_memory:1048576\r\nused_memory_human:1.00M\r\nused_memory_rss:2097152\r\n"
+	assert.Equal(t, int64(1048576), parseRedisUsedMemory(info))
+	assert.Equal(t, int64(0), parseRedisUsedMemory("# Memory\r\n"))
+}
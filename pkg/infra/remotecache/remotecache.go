@@ -5,9 +5,14 @@ import (
 	"context"
 	"encoding/gob"
 	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
-	"github.com/go-kit/log"
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	glog "github.com/grafana/grafana/pkg/infra/log"
@@ -23,6 +28,21 @@ var (
 	// ErrInvalidCacheType is returned if the type is invalid
 	ErrInvalidCacheType = errors.New("invalid remote cache name")
 
+	// ErrLocalCacheNotSupported is returned if the local cache is enabled for the memory backend
+	ErrLocalCacheNotSupported = errors.New("local cache is not supported for the memory remote cache")
+
+	// ErrInvalidCodec is returned if the codec is invalid
+	ErrInvalidCodec = errors.New("invalid remote cache codec")
+
+	// ErrTTLNotSupported is returned by GetWithTTL for backends that cannot report the expiry of items
+	ErrTTLNotSupported = errors.New("ttl inspection is not supported by the remote cache")
+
+	// ErrCacheItemNotCounter is returned if a counter operation is used on an item that is not a counter
+	ErrCacheItemNotCounter = errors.New("cache item is not a counter")
+
+	// ErrCacheItemTooLarge is returned if a value is larger than the configured maximum item size
+	ErrCacheItemTooLarge = errors.New("cache item is too large")
+
 	defaultMaxCacheExpiration = time.Hour * 24
 )
 
@@ -32,12 +52,16 @@ const (
 
 func ProvideService(cfg *setting.Cfg, sqlStore db.DB, secretsService secrets.Service) (*RemoteCache, error) {
 	var codec codec
-	if cfg.RemoteCacheOptions.Encryption {
-		codec = &encryptionCodec{secretsService}
-	} else {
+	switch cfg.RemoteCacheOptions.Codec {
+	case "", GobCodec:
 		codec = &gobCodec{}
+	case JSONCodec:
+		codec = &jsonCodec{}
+	default:
+		return nil, ErrInvalidCodec
 	}
-	client, err := createClient(cfg.RemoteCacheOptions, sqlStore, codec)
+
+	client, pubsub, err := createClient(cfg.RemoteCacheOptions, sqlStore, secretsService, codec)
 	if err != nil {
 		return nil, err
 	}
@@ -46,14 +70,27 @@ func ProvideService(cfg *setting.Cfg, sqlStore db.DB, secretsService secrets.Ser
 		Cfg:      cfg,
 		log:      glog.New("cache.remote"),
 		client:   client,
+		pubsub:   pubsub,
+
+		operationTimeout: cfg.RemoteCacheOptions.OperationTimeout,
+		secretsService:   secretsService,
+		codec:            codec,
+		policies:         newCachePolicies(cfg.RemoteCacheOptions.Policies, ""),
+	}
+
+	if cfg.RemoteCacheOptions.StartupHealthCheck {
+		if _, err := s.HealthCheck(context.Background()); err != nil {
+			return nil, fmt.Errorf("remote cache %q is unreachable: %w", cfg.RemoteCacheOptions.Name, err)
+		}
 	}
 	return s, nil
 }
 
 // CacheStorage allows the caller to set, get and delete items in the cache.
-// Cached items are stored as byte arrays and marshalled using "encoding/gob"
-// so any struct added to the cache needs to be registered with `remotecache.Register`
-// ex `remotecache.Register(CacheableStruct{})`
+// Values passed to Set are encoded by the configured codec together with the name of
+// their type, types should be registered with a stable name using `remotecache.RegisterType`
+// ex `remotecache.RegisterType("cacheable-struct", 1, CacheableStruct{})`. Byte arrays are
+// stored as they are and do not depend on the codec.
 type CacheStorage interface {
 	// Get reads object from Cache
 	Get(ctx context.Context, key string) (interface{}, error)
@@ -67,56 +104,235 @@ type CacheStorage interface {
 	// SetByteArray saves the value as an byte array. if `expire` is set to zero it will default to 24h
 	SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error
 
+	// GetWithTTL gets the cache value as an byte array together with the time until it expires,
+	// zero for items that do not expire.
+	GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error)
+
+	// Touch sets the expiry of the item without rewriting its value, returns ErrCacheItemNotFound
+	// if the item does not exist. if `expire` is set to zero the item does not expire.
+	Touch(ctx context.Context, key string, expire time.Duration) error
+
 	// Delete object from cache
 	Delete(ctx context.Context, key string) error
 
+	// GetMulti gets the cache values of the keys as byte arrays in as few round trips as
+	// the backend allows. Missing and expired keys are left out of the result.
+	GetMulti(ctx context.Context, keys []string) (map[string][]byte, error)
+
+	// SetMulti saves the values as byte arrays, all with the same expiry.
+	SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error
+
+	// DeleteMulti deletes the keys from the cache, missing keys are ignored.
+	DeleteMulti(ctx context.Context, keys []string) error
+
+	// Increment atomically adds delta to the counter stored at key and returns the new value.
+	// A missing counter starts at zero and expires after `expire`, later increments do not
+	// extend the expiry. Counters are stored as decimal strings and can be read with GetByteArray.
+	Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error)
+
+	// Decrement atomically subtracts delta from the counter stored at key, see Increment.
+	// Memcached counters cannot go below zero.
+	Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error)
+
+	// SetIfNotExists saves the value as a byte array only if the key does not exist yet.
+	// It returns false if the key exists. if `expire` is set to zero the item does not expire.
+	SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error)
+
+	// CompareAndSwap replaces the value of the key only if its current value equals `old`.
+	// It returns false if the key is missing or has a different value.
+	CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error)
+
+	// Scan returns the keys that start with the prefix. Memcached cannot list keys, so only
+	// the keys written by this instance are returned for it.
+	Scan(ctx context.Context, prefix string) ([]string, error)
+
+	// DeleteByPrefix deletes all keys that start with the prefix, with the same limitation as Scan.
+	DeleteByPrefix(ctx context.Context, prefix string) error
+
 	// Count returns the number of items in the cache.
 	// Optionaly a prefix can be provided to only count items with that prefix
 	Count(ctx context.Context, prefix string) (int64, error)
+
+	// Stats returns the number of items and bytes stored by the backend, regardless of
+	// the prefix. Values the backend cannot report are -1.
+	Stats(ctx context.Context) (*Stats, error)
+}
+
+// Stats describes the size of the cache backend
+type Stats struct {
+	Items int64 `json:"items"`
+	Bytes int64 `json:"bytes"`
 }
 
 // RemoteCache allows Grafana to cache data outside its own process
 type RemoteCache struct {
-	log      log.Logger
+	log      glog.Logger
 	client   CacheStorage
+	pubsub   PubSub
 	SQLStore db.DB
 	Cfg      *setting.Cfg
+
+	// timeout of every cache operation, 0 leaves the deadline to the caller
+	operationTimeout time.Duration
+	// used to encrypt namespaces
+	secretsService secrets.Service
+	codec          codec
+	// deduplicates concurrent loads of GetOrSet
+	loads    singleflight.Group
+	policies cachePolicies
 }
 
 // Get reads object from Cache
 func (ds *RemoteCache) Get(ctx context.Context, key string) (interface{}, error) {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
 	return ds.client.Get(ctx, key)
 }
 
 // GetByteArray returns the cached value as an byte array
 func (ds *RemoteCache) GetByteArray(ctx context.Context, key string) ([]byte, error) {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
 	return ds.client.GetByteArray(ctx, key)
 }
 
 // SetByteArray stored the byte array in the cache
 func (ds *RemoteCache) SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
 	return ds.client.SetByteArray(ctx, key, value, expire)
 }
 
 // Set sets an object into the cache. if `expire` is set to zero it will default to 24h
 func (ds *RemoteCache) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
-	if expire == 0 {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	// the default TTL of a policy takes precedence
+	if expire == 0 && ds.policies.expire(key, 0) == 0 {
 		expire = defaultMaxCacheExpiration
 	}
 
 	return ds.client.Set(ctx, key, value, expire)
 }
 
+// GetWithTTL returns the cached value as an byte array and the time until it expires
+func (ds *RemoteCache) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.client.GetWithTTL(ctx, key)
+}
+
+// Touch sets the expiry of the item
+func (ds *RemoteCache) Touch(ctx context.Context, key string, expire time.Duration) error {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.client.Touch(ctx, key, expire)
+}
+
 // Delete object from cache
 func (ds *RemoteCache) Delete(ctx context.Context, key string) error {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
 	return ds.client.Delete(ctx, key)
 }
 
+// GetMulti returns the cached values of the keys as byte arrays
+func (ds *RemoteCache) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.client.GetMulti(ctx, keys)
+}
+
+// SetMulti stores the byte arrays in the cache
+func (ds *RemoteCache) SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.client.SetMulti(ctx, items, expire)
+}
+
+// DeleteMulti deletes the keys from the cache
+func (ds *RemoteCache) DeleteMulti(ctx context.Context, keys []string) error {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.client.DeleteMulti(ctx, keys)
+}
+
+// Increment atomically adds delta to the counter stored at key
+func (ds *RemoteCache) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.client.Increment(ctx, key, delta, expire)
+}
+
+// Decrement atomically subtracts delta from the counter stored at key
+func (ds *RemoteCache) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.client.Decrement(ctx, key, delta, expire)
+}
+
+// SetIfNotExists stores the byte array in the cache if the key does not exist
+func (ds *RemoteCache) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.client.SetIfNotExists(ctx, key, value, expire)
+}
+
+// CompareAndSwap replaces the cached value if it equals old
+func (ds *RemoteCache) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.client.CompareAndSwap(ctx, key, old, value, expire)
+}
+
+// Scan returns the keys that start with the prefix
+func (ds *RemoteCache) Scan(ctx context.Context, prefix string) ([]string, error) {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.client.Scan(ctx, prefix)
+}
+
+// DeleteByPrefix deletes all keys that start with the prefix
+func (ds *RemoteCache) DeleteByPrefix(ctx context.Context, prefix string) error {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.client.DeleteByPrefix(ctx, prefix)
+}
+
 // Count returns the number of items in the cache.
 func (ds *RemoteCache) Count(ctx context.Context, prefix string) (int64, error) {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
 	return ds.client.Count(ctx, prefix)
 }
 
+// Stats returns the number of items and bytes stored by the cache backend
+func (ds *RemoteCache) Stats(ctx context.Context) (*Stats, error) {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.client.Stats(ctx)
+}
+
+// Publish sends the message to the subscribers of the channel on all instances
+func (ds *RemoteCache) Publish(ctx context.Context, channel string, message []byte) error {
+	ctx, cancel := ds.withTimeout(ctx)
+	defer cancel()
+	return ds.pubsub.Publish(ctx, ds.Cfg.RemoteCacheOptions.Prefix+channel, message)
+}
+
+// Subscribe calls handler for every message published on the channel until the context is done
+func (ds *RemoteCache) Subscribe(ctx context.Context, channel string, handler func(message []byte)) error {
+	return ds.pubsub.Subscribe(ctx, ds.Cfg.RemoteCacheOptions.Prefix+channel, handler)
+}
+
+// withTimeout applies the operation timeout to ctx, an earlier deadline of ctx is kept.
+func (ds *RemoteCache) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ds.operationTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, ds.operationTimeout)
+}
+
 // Run starts the backend processes for cache clients.
 func (ds *RemoteCache) Run(ctx context.Context) error {
 	// create new interface if more clients need GC jobs
@@ -129,34 +345,87 @@ func (ds *RemoteCache) Run(ctx context.Context) error {
 	return ctx.Err()
 }
 
-func createClient(opts *setting.RemoteCacheOptions, sqlstore db.DB, codec codec) (cache CacheStorage, err error) {
+func createClient(opts *setting.RemoteCacheOptions, sqlstore db.DB, secretsService secrets.Service, codec codec) (cache CacheStorage, pubsub PubSub, err error) {
 	switch opts.Name {
 	case redisCacheType:
 		cache, err = newRedisStorage(opts, codec)
 	case memcachedCacheType:
-		cache = newMemcachedStorage(opts, codec)
+		cache, err = newMemcachedStorage(opts, codec)
 	case databaseCacheType:
-		cache = newDatabaseCache(sqlstore, codec)
+		cache = newDatabaseCacheWithOptions(sqlstore, codec, opts)
+	case memoryCacheType:
+		cache, err = newMemoryStorage(opts.ConnStr, codec)
 	default:
-		return nil, ErrInvalidCacheType
+		return nil, nil, ErrInvalidCacheType
 	}
 	if err != nil {
-		return cache, err
+		return nil, nil, err
+	}
+	pubsub = newPubSub(cache, sqlstore)
+	backend := cache
+	cache = newInstrumentedCacheStorage(cache, opts.Name, opts.Prefix)
+	if opts.MaxItemSize > 0 {
+		// the limit applies to the stored values, after compression and encryption
+		cache = newSizeLimitedCacheStorage(cache, codec, opts.Name, opts.MaxItemSize)
+	}
+	// the wrapped caches see the prefixed keys
+	prefixedPolicies := newCachePolicies(opts.Policies, opts.Prefix)
+	prefixes := make([]string, 0, len(opts.EncryptionPrefixes))
+	if !opts.Encryption {
+		for _, prefix := range opts.EncryptionPrefixes {
+			prefixes = append(prefixes, opts.Prefix+prefix)
+		}
+		for _, policy := range prefixedPolicies {
+			if policy.Encrypt != nil && *policy.Encrypt {
+				prefixes = append(prefixes, policy.Prefix)
+			}
+		}
+	}
+	if opts.Encryption || len(prefixes) > 0 {
+		encrypted := newEncryptedCacheStorage(cache, secretsService, codec, prefixes)
+		encrypted.policies = prefixedPolicies
+		cache = encrypted
+	}
+	if opts.Compression != "" && opts.Compression != CompressionNone {
+		// values are compressed before they are encrypted, encrypted data does not compress
+		if cache, err = newCompressedCacheStorage(cache, codec, opts.Compression, opts.CompressionThreshold); err != nil {
+			return nil, nil, err
+		}
 	}
 	if opts.Prefix != "" {
 		cache = &prefixCacheStorage{cache: cache, prefix: opts.Prefix}
 	}
-	return cache, nil
+	if opts.LocalCacheTTL > 0 {
+		if _, ok := backend.(*memoryStorage); ok {
+			return nil, nil, ErrLocalCacheNotSupported
+		}
+		inv := &pubSubInvalidator{pubsub: pubsub, channel: opts.Prefix + invalidationChannel}
+		tiered := newTieredCacheStorage(cache, inv, codec, opts.LocalCacheTTL, opts.LocalCacheMaxEntries)
+		tiered.policies = newCachePolicies(opts.Policies, "")
+		cache = tiered
+	}
+	if len(opts.Policies) > 0 {
+		cache = &policyCacheStorage{cache: cache, policies: newCachePolicies(opts.Policies, "")}
+	}
+	return cache, pubsub, nil
+}
+
+// isCacheMiss returns true for the errors the backends return for missing keys
+func isCacheMiss(err error) bool {
+	return errors.Is(err, ErrCacheItemNotFound) || errors.Is(err, redis.Nil) || errors.Is(err, memcache.ErrCacheMiss)
 }
 
-// Register records a type, identified by a value for that type, under its
-// internal type name. That name will identify the concrete type of a value
-// sent or received as an interface variable. Only types that will be
-// transferred as implementations of interface values need to be registered.
-// Expecting to be used only during initialization, it panics if the mapping
-// between types and names is not a bijection.
+// Register registers the type of value under its Go type name, or with the version of
+// Versioned types.
+//
+// Deprecated: use RegisterType, which stores values under a name that does not change when
+// the type is moved or renamed.
 func Register(value interface{}) {
-	gob.Register(value)
+	version := 0
+	if versioned, ok := value.(Versioned); ok {
+		version = versioned.CacheVersion()
+	}
+	_ = registerType(goTypeName(reflect.TypeOf(value)), version, reflect.TypeOf(value))
 }
 
 type cachedItem struct {
@@ -168,39 +437,57 @@ type codec interface {
 	Decode(context.Context, []byte, *cachedItem) error
 }
 
+// gobEnvelope stores the registered type with the value. The value is encoded as its
+// concrete type, so it does not have to be registered with gob.
+type gobEnvelope struct {
+	Type    string
+	Version int
+	Data    []byte
+}
+
 type gobCodec struct{}
 
 func (c *gobCodec) Encode(_ context.Context, item *cachedItem) ([]byte, error) {
+	var envelope gobEnvelope
+	if item.Val != nil {
+		ct, err := cachedTypeOf(item.Val)
+		if err != nil {
+			return nil, err
+		}
+		data := bytes.NewBuffer(nil)
+		if err := gob.NewEncoder(data).Encode(item.Val); err != nil {
+			return nil, err
+		}
+		envelope = gobEnvelope{Type: ct.name, Version: ct.version, Data: data.Bytes()}
+	}
+
 	buf := bytes.NewBuffer(nil)
-	err := gob.NewEncoder(buf).Encode(item)
+	err := gob.NewEncoder(buf).Encode(&envelope)
 	return buf.Bytes(), err
 }
 
+// Decode returns ErrCacheItemNotFound for values it cannot decode, such as values of
+// types that changed since they were stored or of another version.
 func (c *gobCodec) Decode(_ context.Context, data []byte, out *cachedItem) error {
-	buf := bytes.NewBuffer(data)
-	return gob.NewDecoder(buf).Decode(&out)
-}
-
-type encryptionCodec struct {
-	secretsService secrets.Service
-}
-
-func (c *encryptionCodec) Encode(ctx context.Context, item *cachedItem) ([]byte, error) {
-	buf := bytes.NewBuffer(nil)
-	err := gob.NewEncoder(buf).Encode(item)
-	if err != nil {
-		return nil, err
+	var envelope gobEnvelope
+	if err := gob.NewDecoder(bytes.NewBuffer(data)).Decode(&envelope); err != nil {
+		return decodeMiss(GobCodec)
+	}
+	if envelope.Type == "" {
+		out.Val = nil
+		return nil
 	}
-	return c.secretsService.Encrypt(ctx, buf.Bytes(), secrets.WithoutScope())
-}
 
-func (c *encryptionCodec) Decode(ctx context.Context, data []byte, out *cachedItem) error {
-	decrypted, err := c.secretsService.Decrypt(ctx, data)
-	if err != nil {
-		return err
+	ct, ok := cachedTypeByName(envelope.Type)
+	if !ok || ct.version != envelope.Version {
+		return decodeMiss(GobCodec)
+	}
+	value := reflect.New(ct.t)
+	if err := gob.NewDecoder(bytes.NewBuffer(envelope.Data)).Decode(value.Interface()); err != nil {
+		return decodeMiss(GobCodec)
 	}
-	buf := bytes.NewBuffer(decrypted)
-	return gob.NewDecoder(buf).Decode(&out)
+	out.Val = value.Elem().Interface()
+	return nil
 }
 
 type prefixCacheStorage struct {
@@ -220,10 +507,103 @@ func (pcs *prefixCacheStorage) Set(ctx context.Context, key string, value interf
 func (pcs *prefixCacheStorage) SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error {
 	return pcs.cache.SetByteArray(ctx, pcs.prefix+key, value, expire)
 }
+func (pcs *prefixCacheStorage) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	return pcs.cache.GetWithTTL(ctx, pcs.prefix+key)
+}
+func (pcs *prefixCacheStorage) Touch(ctx context.Context, key string, expire time.Duration) error {
+	return pcs.cache.Touch(ctx, pcs.prefix+key, expire)
+}
 func (pcs *prefixCacheStorage) Delete(ctx context.Context, key string) error {
 	return pcs.cache.Delete(ctx, pcs.prefix+key)
 }
 
+func (pcs *prefixCacheStorage) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, pcs.prefix+key)
+	}
+
+	values, err := pcs.cache.GetMulti(ctx, prefixed)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]byte, len(values))
+	for key, value := range values {
+		result[strings.TrimPrefix(key, pcs.prefix)] = value
+	}
+	return result, nil
+}
+func (pcs *prefixCacheStorage) SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error {
+	prefixed := make(map[string][]byte, len(items))
+	for key, value := range items {
+		prefixed[pcs.prefix+key] = value
+	}
+	return pcs.cache.SetMulti(ctx, prefixed, expire)
+}
+func (pcs *prefixCacheStorage) DeleteMulti(ctx context.Context, keys []string) error {
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, pcs.prefix+key)
+	}
+	return pcs.cache.DeleteMulti(ctx, prefixed)
+}
+
+func (pcs *prefixCacheStorage) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return pcs.cache.Increment(ctx, pcs.prefix+key, delta, expire)
+}
+func (pcs *prefixCacheStorage) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return pcs.cache.Decrement(ctx, pcs.prefix+key, delta, expire)
+}
+
+func (pcs *prefixCacheStorage) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	return pcs.cache.SetIfNotExists(ctx, pcs.prefix+key, value, expire)
+}
+func (pcs *prefixCacheStorage) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	return pcs.cache.CompareAndSwap(ctx, pcs.prefix+key, old, value, expire)
+}
+
+func (pcs *prefixCacheStorage) Scan(ctx context.Context, prefix string) ([]string, error) {
+	keys, err := pcs.cache.Scan(ctx, pcs.prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, pcs.prefix)
+	}
+	return keys, nil
+}
+func (pcs *prefixCacheStorage) DeleteByPrefix(ctx context.Context, prefix string) error {
+	return pcs.cache.DeleteByPrefix(ctx, pcs.prefix+prefix)
+}
+
 func (pcs *prefixCacheStorage) Count(ctx context.Context, prefix string) (int64, error) {
-	return pcs.cache.Count(ctx, pcs.prefix)
+	return pcs.cache.Count(ctx, pcs.prefix+prefix)
+}
+
+func (pcs *prefixCacheStorage) Stats(ctx context.Context) (*Stats, error) {
+	return pcs.cache.Stats(ctx)
+}
+
+// Run runs the background jobs of the wrapped cache.
+func (pcs *prefixCacheStorage) Run(ctx context.Context) error {
+	if backgroundjob, ok := pcs.cache.(registry.BackgroundService); ok {
+		return backgroundjob.Run(ctx)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// runBatch prefixes the keys of the batch, the channels of publish operations are prefixed
+// by RemoteCache already.
+func (pcs *prefixCacheStorage) runBatch(ctx context.Context, ops []*batchOp) {
+	prefixed := make([]*batchOp, 0, len(ops))
+	for _, op := range ops {
+		if op.kind == batchPublish {
+			prefixed = append(prefixed, op)
+			continue
+		}
+		prefixed = append(prefixed, op.withKey(pcs.prefix+op.key))
+	}
+	execBatch(ctx, pcs.cache, prefixed)
 }
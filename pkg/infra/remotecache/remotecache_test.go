@@ -2,6 +2,7 @@ package remotecache
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -48,13 +49,18 @@ func TestCachedBasedOnConfig(t *testing.T) {
 }
 
 func TestInvalidCacheTypeReturnsError(t *testing.T) {
-	_, err := createClient(&setting.RemoteCacheOptions{Name: "invalid"}, nil, &gobCodec{})
+	_, _, err := createClient(&setting.RemoteCacheOptions{Name: "invalid"}, nil, nil, &gobCodec{})
 	assert.Equal(t, err, ErrInvalidCacheType)
 }
 
 func runTestsForClient(t *testing.T, client CacheStorage) {
 	canPutGetAndDeleteCachedObjects(t, client)
 	canNotFetchExpiredItems(t, client)
+	canGetSetAndDeleteMultipleItems(t, client)
+	canIncrementAndDecrementCounters(t, client)
+	canSetIfNotExistsAndCompareAndSwap(t, client)
+	canGetTTLAndTouchItems(t, client)
+	canScanAndDeleteByPrefix(t, client)
 }
 
 func runCountTestsForClient(t *testing.T, opts *setting.RemoteCacheOptions, sqlstore db.DB) {
@@ -86,6 +92,18 @@ func runCountTestsForClient(t *testing.T, opts *setting.RemoteCacheOptions, sqls
 		require.NoError(t, errC)
 		assert.Equal(t, int64(2), n)
 	})
+
+	t.Run("can report stats", func(t *testing.T) {
+		stats, err := client.Stats(context.Background())
+		require.NoError(t, err)
+		if opts.Name == memcachedCacheType {
+			assert.Equal(t, &Stats{Items: -1, Bytes: -1}, stats)
+			return
+		}
+
+		assert.GreaterOrEqual(t, stats.Items, int64(3))
+		assert.Greater(t, stats.Bytes, int64(0))
+	})
 }
 
 func canPutGetAndDeleteCachedObjects(t *testing.T, client CacheStorage) {
@@ -109,6 +127,148 @@ func canPutGetAndDeleteCachedObjects(t *testing.T, client CacheStorage) {
 	assert.Equal(t, err, ErrCacheItemNotFound)
 }
 
+func canGetSetAndDeleteMultipleItems(t *testing.T, client CacheStorage) {
+	ctx := context.Background()
+
+	err := client.SetMulti(ctx, map[string][]byte{"multi1": []byte("1"), "multi2": []byte("2")}, time.Minute)
+	require.NoError(t, err)
+
+	values, err := client.GetMulti(ctx, []string{"multi1", "multi2", "multi-missing"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"multi1": []byte("1"), "multi2": []byte("2")}, values)
+
+	err = client.DeleteMulti(ctx, []string{"multi1", "multi-missing"})
+	require.NoError(t, err)
+
+	values, err = client.GetMulti(ctx, []string{"multi1", "multi2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"multi2": []byte("2")}, values)
+
+	values, err = client.GetMulti(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func canIncrementAndDecrementCounters(t *testing.T, client CacheStorage) {
+	ctx := context.Background()
+	_ = client.Delete(ctx, "counter")
+
+	value, err := client.Increment(ctx, "counter", 5, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), value)
+
+	value, err = client.Increment(ctx, "counter", 2, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), value)
+
+	value, err = client.Decrement(ctx, "counter", 3, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), value)
+
+	data, err := client.GetByteArray(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, "4", string(data))
+
+	err = client.SetByteArray(ctx, "not-a-counter", []byte("value"), time.Minute)
+	require.NoError(t, err)
+	_, err = client.Increment(ctx, "not-a-counter", 1, time.Minute)
+	assert.ErrorIs(t, err, ErrCacheItemNotCounter)
+
+	require.NoError(t, client.DeleteMulti(ctx, []string{"counter", "not-a-counter"}))
+}
+
+func canSetIfNotExistsAndCompareAndSwap(t *testing.T, client CacheStorage) {
+	ctx := context.Background()
+	_ = client.Delete(ctx, "conditional")
+
+	ok, err := client.SetIfNotExists(ctx, "conditional", []byte("first"), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = client.SetIfNotExists(ctx, "conditional", []byte("second"), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = client.CompareAndSwap(ctx, "conditional", []byte("other"), []byte("second"), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = client.CompareAndSwap(ctx, "conditional", []byte("first"), []byte("second"), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	data, err := client.GetByteArray(ctx, "conditional")
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+
+	ok, err = client.CompareAndSwap(ctx, "conditional-missing", []byte("first"), []byte("second"), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, client.Delete(ctx, "conditional"))
+}
+
+func canGetTTLAndTouchItems(t *testing.T, client CacheStorage) {
+	ctx := context.Background()
+
+	err := client.SetByteArray(ctx, "ttl", []byte("value"), time.Minute)
+	require.NoError(t, err)
+
+	err = client.Touch(ctx, "ttl", time.Hour)
+	require.NoError(t, err)
+
+	err = client.Touch(ctx, "ttl-missing", time.Hour)
+	assert.ErrorIs(t, err, ErrCacheItemNotFound)
+
+	data, ttl, err := client.GetWithTTL(ctx, "ttl")
+	if errors.Is(err, ErrTTLNotSupported) {
+		require.NoError(t, client.Delete(ctx, "ttl"))
+		return
+	}
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(data))
+	assert.Greater(t, ttl, 59*time.Minute)
+	assert.LessOrEqual(t, ttl, time.Hour)
+
+	err = client.Touch(ctx, "ttl", 0)
+	require.NoError(t, err)
+	_, ttl, err = client.GetWithTTL(ctx, "ttl")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	_, _, err = client.GetWithTTL(ctx, "ttl-missing")
+	assert.ErrorIs(t, err, ErrCacheItemNotFound)
+
+	require.NoError(t, client.Delete(ctx, "ttl"))
+}
+
+func canScanAndDeleteByPrefix(t *testing.T, client CacheStorage) {
+	ctx := context.Background()
+
+	err := client.SetMulti(ctx, map[string][]byte{"scan:1": []byte("1"), "scan:2": []byte("2"), "scan_x": []byte("x"), "other": []byte("o")}, time.Minute)
+	require.NoError(t, err)
+
+	keys, err := client.Scan(ctx, "scan:")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"scan:1", "scan:2"}, keys)
+
+	// characters with a special meaning in patterns are matched literally
+	keys, err = client.Scan(ctx, "scan_")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"scan_x"}, keys)
+
+	require.NoError(t, client.DeleteByPrefix(ctx, "scan:"))
+	keys, err = client.Scan(ctx, "scan")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"scan_x"}, keys)
+
+	values, err := client.GetMulti(ctx, []string{"scan:1", "other"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"other": []byte("o")}, values)
+
+	require.NoError(t, client.DeleteMulti(ctx, []string{"scan_x", "other"}))
+}
+
 func canNotFetchExpiredItems(t *testing.T, client CacheStorage) {
 	cacheableStruct := CacheableStruct{String: "hej", Int64: 2000}
 
@@ -146,4 +306,62 @@ func TestCachePrefix(t *testing.T) {
 	// Get a value directly from the underlying cache without a prefix, should not be there
 	_, err = cache.Get(context.Background(), "foo")
 	require.Error(t, err)
+
+	// Multi operations add and strip the prefix
+	err = prefixCache.SetMulti(context.Background(), map[string][]byte{"multi": []byte("1")}, time.Hour)
+	require.NoError(t, err)
+	values, err := prefixCache.GetMulti(context.Background(), []string{"multi"})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"multi": []byte("1")}, values)
+	values, err = cache.GetMulti(context.Background(), []string{"test/multi"})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"test/multi": []byte("1")}, values)
+}
+
+func TestCachePrefixRunsDatabaseGC(t *testing.T) {
+	sqlStore := db.InitTestDB(t)
+	opts := &setting.RemoteCacheOptions{Name: databaseCacheType, Prefix: "test/", DatabaseGCInterval: 10 * time.Millisecond}
+	cache, _, err := createClient(opts, sqlStore, nil, &gobCodec{})
+	require.NoError(t, err)
+
+	getTime = func() time.Time { return time.Now().AddDate(0, 0, -2) }
+	require.NoError(t, cache.SetByteArray(context.Background(), "expired", []byte("value"), time.Hour))
+	getTime = time.Now
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- (&RemoteCache{client: cache}).Run(ctx)
+	}()
+
+	// the rows are counted directly, including the expired ones
+	assert.Eventually(t, func() bool {
+		n, err := cache.Count(context.Background(), "")
+		return err == nil && n == 0
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestRemoteCacheOperationTimeout(t *testing.T) {
+	ds := &RemoteCache{operationTimeout: time.Second}
+	ctx, cancel := ds.withTimeout(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+
+	// an earlier deadline of the caller is kept
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancelParent()
+	ctx, cancel = ds.withTimeout(parent)
+	defer cancel()
+	deadline, _ = ctx.Deadline()
+	parentDeadline, _ := parent.Deadline()
+	assert.Equal(t, parentDeadline, deadline)
+
+	ctx, cancel = (&RemoteCache{}).withTimeout(context.Background())
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
 }
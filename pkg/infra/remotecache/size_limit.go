@@ -0,0 +1,146 @@
+package remotecache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/registry"
+)
+
+// sizeLimitedCacheStorage rejects values larger than maxItemSize with ErrCacheItemTooLarge.
+// Memcached drops items above its item size limit and redis accepts values of up to 512MB,
+// so large values are rejected before they reach the backend.
+type sizeLimitedCacheStorage struct {
+	cache       CacheStorage
+	codec       codec
+	backend     string
+	maxItemSize int
+}
+
+func newSizeLimitedCacheStorage(cache CacheStorage, codec codec, backend string, maxItemSize int) *sizeLimitedCacheStorage {
+	return &sizeLimitedCacheStorage{cache: cache, codec: codec, backend: backend, maxItemSize: maxItemSize}
+}
+
+func (s *sizeLimitedCacheStorage) check(key string, value []byte) error {
+	if len(value) <= s.maxItemSize {
+		return nil
+	}
+	itemsTooLargeCounter.WithLabelValues(s.backend).Inc()
+	return fmt.Errorf("%w: %q is %d bytes, the maximum is %d bytes", ErrCacheItemTooLarge, key, len(value), s.maxItemSize)
+}
+
+func (s *sizeLimitedCacheStorage) Get(ctx context.Context, key string) (interface{}, error) {
+	return s.cache.Get(ctx, key)
+}
+
+func (s *sizeLimitedCacheStorage) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	data, err := s.codec.Encode(ctx, &cachedItem{Val: value})
+	if err != nil {
+		return err
+	}
+	return s.SetByteArray(ctx, key, data, expire)
+}
+
+func (s *sizeLimitedCacheStorage) GetByteArray(ctx context.Context, key string) ([]byte, error) {
+	return s.cache.GetByteArray(ctx, key)
+}
+
+func (s *sizeLimitedCacheStorage) SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	if err := s.check(key, value); err != nil {
+		return err
+	}
+	return s.cache.SetByteArray(ctx, key, value, expire)
+}
+
+func (s *sizeLimitedCacheStorage) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	return s.cache.GetWithTTL(ctx, key)
+}
+
+func (s *sizeLimitedCacheStorage) Touch(ctx context.Context, key string, expire time.Duration) error {
+	return s.cache.Touch(ctx, key, expire)
+}
+
+func (s *sizeLimitedCacheStorage) Delete(ctx context.Context, key string) error {
+	return s.cache.Delete(ctx, key)
+}
+
+func (s *sizeLimitedCacheStorage) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	return s.cache.GetMulti(ctx, keys)
+}
+
+// SetMulti stores none of the items if one of them is too large.
+func (s *sizeLimitedCacheStorage) SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error {
+	for key, value := range items {
+		if err := s.check(key, value); err != nil {
+			return err
+		}
+	}
+	return s.cache.SetMulti(ctx, items, expire)
+}
+
+func (s *sizeLimitedCacheStorage) DeleteMulti(ctx context.Context, keys []string) error {
+	return s.cache.DeleteMulti(ctx, keys)
+}
+
+func (s *sizeLimitedCacheStorage) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return s.cache.Increment(ctx, key, delta, expire)
+}
+
+func (s *sizeLimitedCacheStorage) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return s.cache.Decrement(ctx, key, delta, expire)
+}
+
+func (s *sizeLimitedCacheStorage) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	if err := s.check(key, value); err != nil {
+		return false, err
+	}
+	return s.cache.SetIfNotExists(ctx, key, value, expire)
+}
+
+func (s *sizeLimitedCacheStorage) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	if err := s.check(key, value); err != nil {
+		return false, err
+	}
+	return s.cache.CompareAndSwap(ctx, key, old, value, expire)
+}
+
+func (s *sizeLimitedCacheStorage) Scan(ctx context.Context, prefix string) ([]string, error) {
+	return s.cache.Scan(ctx, prefix)
+}
+
+func (s *sizeLimitedCacheStorage) DeleteByPrefix(ctx context.Context, prefix string) error {
+	return s.cache.DeleteByPrefix(ctx, prefix)
+}
+
+func (s *sizeLimitedCacheStorage) Count(ctx context.Context, prefix string) (int64, error) {
+	return s.cache.Count(ctx, prefix)
+}
+
+func (s *sizeLimitedCacheStorage) Stats(ctx context.Context) (*Stats, error) {
+	return s.cache.Stats(ctx)
+}
+
+// runBatch fails the sets of values that are too large, the other operations of the batch run.
+func (s *sizeLimitedCacheStorage) runBatch(ctx context.Context, ops []*batchOp) {
+	allowed := make([]*batchOp, 0, len(ops))
+	for _, op := range ops {
+		if op.kind == batchSet {
+			if err := s.check(op.key, op.value); err != nil {
+				op.result.Err = err
+				continue
+			}
+		}
+		allowed = append(allowed, op)
+	}
+	execBatch(ctx, s.cache, allowed)
+}
+
+// Run runs the background jobs of the wrapped cache.
+func (s *sizeLimitedCacheStorage) Run(ctx context.Context) error {
+	if backgroundjob, ok := s.cache.(registry.BackgroundService); ok {
+		return backgroundjob.Run(ctx)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
@@ -0,0 +1,46 @@
+package remotecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizeLimitedCacheStorage(t *testing.T) {
+	backend := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+	client := newSizeLimitedCacheStorage(backend, &gobCodec{}, "size-limit-test", 1024)
+	runTestsForClient(t, client)
+}
+
+func TestSizeLimitedCacheStorage_RejectsLargeItems(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+	client := newSizeLimitedCacheStorage(backend, &gobCodec{}, "size-limit-reject", 8)
+
+	require.NoError(t, client.SetByteArray(ctx, "small", []byte("12345678"), time.Minute))
+
+	err := client.SetByteArray(ctx, "large", []byte("123456789"), time.Minute)
+	require.ErrorIs(t, err, ErrCacheItemTooLarge)
+	_, err = backend.GetByteArray(ctx, "large")
+	require.ErrorIs(t, err, ErrCacheItemNotFound)
+
+	err = client.Set(ctx, "struct", CacheableStruct{String: "does not fit"}, time.Minute)
+	require.ErrorIs(t, err, ErrCacheItemTooLarge)
+
+	// none of the items are stored if one is too large
+	err = client.SetMulti(ctx, map[string][]byte{"a": []byte("1"), "b": []byte("123456789")}, time.Minute)
+	require.ErrorIs(t, err, ErrCacheItemTooLarge)
+	_, err = backend.GetByteArray(ctx, "a")
+	require.ErrorIs(t, err, ErrCacheItemNotFound)
+
+	_, err = client.SetIfNotExists(ctx, "large", []byte("123456789"), time.Minute)
+	require.ErrorIs(t, err, ErrCacheItemTooLarge)
+	_, err = client.CompareAndSwap(ctx, "small", []byte("12345678"), []byte("123456789"), time.Minute)
+	require.ErrorIs(t, err, ErrCacheItemTooLarge)
+
+	assert.Equal(t, 5.0, testutil.ToFloat64(itemsTooLargeCounter.WithLabelValues("size-limit-reject")))
+}
@@ -2,10 +2,12 @@ package remotecache
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/infra/db"
+	glog "github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/secrets/fakes"
 	"github.com/grafana/grafana/pkg/setting"
 )
@@ -28,3 +30,22 @@ func NewFakeStore(t *testing.T) *RemoteCache {
 
 	return dc
 }
+
+// NewFakeMemoryStore creates an in-memory store for testing, items expire according to now
+// so that tests can move the time forward instead of waiting.
+func NewFakeMemoryStore(t *testing.T, now func() time.Time) *RemoteCache {
+	t.Helper()
+
+	storage := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+	if now != nil {
+		storage.now = now
+	}
+
+	return &RemoteCache{
+		log:      glog.New("cache.remote"),
+		client:   storage,
+		Cfg:      &setting.Cfg{RemoteCacheOptions: &setting.RemoteCacheOptions{Name: memoryCacheType}},
+		codec:    &gobCodec{},
+		policies: newCachePolicies(nil, ""),
+	}
+}
@@ -0,0 +1,303 @@
+package remotecache
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/registry"
+)
+
+// invalidator notifies all Grafana instances about keys that changed in the remote cache.
+type invalidator interface {
+	// Publish notifies all instances, including this one, that the key changed.
+	Publish(ctx context.Context, key string) error
+	// Run calls invalidate for every published key until the context is done.
+	Run(ctx context.Context, invalidate func(key string)) error
+}
+
+// batchInvalidator is implemented by invalidators whose notifications can be sent in the
+// batch that changes the keys, saving a round trip to redis.
+type batchInvalidator interface {
+	publishOp(key string) *batchOp
+}
+
+// tieredCacheStorage keeps recently used items in a local cache in front of the remote cache.
+// Changes are published through the invalidator so other instances drop their local copy,
+// the local TTL bounds how long an instance can serve a stale item if a notification is lost.
+type tieredCacheStorage struct {
+	local       *memoryStorage
+	remote      CacheStorage
+	invalidator invalidator
+	codec       codec
+	localTTL    time.Duration
+	// policies can keep keys out of the local cache
+	policies cachePolicies
+	log      log.Logger
+}
+
+func newTieredCacheStorage(remote CacheStorage, invalidator invalidator, codec codec, localTTL time.Duration, maxEntries int) *tieredCacheStorage {
+	return &tieredCacheStorage{
+		local:       newMemoryStorageWithLimits(codec, maxEntries, defaultMemoryMaxBytes),
+		remote:      remote,
+		invalidator: invalidator,
+		codec:       codec,
+		localTTL:    localTTL,
+		log:         log.New("remotecache.tiered"),
+	}
+}
+
+func (s *tieredCacheStorage) Get(ctx context.Context, key string) (interface{}, error) {
+	data, err := s.GetByteArray(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &cachedItem{}
+	if err := s.codec.Decode(ctx, data, item); err != nil {
+		return nil, err
+	}
+	return item.Val, nil
+}
+
+func (s *tieredCacheStorage) GetByteArray(ctx context.Context, key string) ([]byte, error) {
+	if !s.policies.localCache(key) {
+		return s.remote.GetByteArray(ctx, key)
+	}
+	if data, err := s.local.GetByteArray(ctx, key); err == nil {
+		return data, nil
+	}
+
+	data, err := s.remote.GetByteArray(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.local.SetByteArray(ctx, key, data, s.localTTL)
+	return data, nil
+}
+
+func (s *tieredCacheStorage) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	data, err := s.codec.Encode(ctx, &cachedItem{Val: value})
+	if err != nil {
+		return err
+	}
+	return s.SetByteArray(ctx, key, data, expire)
+}
+
+// SetByteArray sends the value and the invalidation in one batch.
+func (s *tieredCacheStorage) SetByteArray(ctx context.Context, key string, data []byte, expire time.Duration) error {
+	op := &batchOp{kind: batchSet, key: key, value: data, expire: expire, result: &BatchResult{}}
+	s.runBatch(ctx, []*batchOp{op})
+	return op.result.Err
+}
+
+// GetWithTTL reads from the remote cache, the local copy does not know the expiry of the item.
+func (s *tieredCacheStorage) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	return s.remote.GetWithTTL(ctx, key)
+}
+
+// Touch does not invalidate the local copies since the value does not change.
+func (s *tieredCacheStorage) Touch(ctx context.Context, key string, expire time.Duration) error {
+	return s.remote.Touch(ctx, key, expire)
+}
+
+func (s *tieredCacheStorage) Delete(ctx context.Context, key string) error {
+	op := &batchOp{kind: batchDelete, key: key, result: &BatchResult{}}
+	s.runBatch(ctx, []*batchOp{op})
+	return op.result.Err
+}
+
+func (s *tieredCacheStorage) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result, _ := s.local.GetMulti(ctx, keys)
+	missing := make([]string, 0, len(keys)-len(result))
+	for _, key := range keys {
+		if _, ok := result[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	values, err := s.remote.GetMulti(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range values {
+		if s.policies.localCache(key) {
+			_ = s.local.SetByteArray(ctx, key, value, s.localTTL)
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+func (s *tieredCacheStorage) SetMulti(ctx context.Context, items map[string][]byte, expire time.Duration) error {
+	if err := s.remote.SetMulti(ctx, items, expire); err != nil {
+		return err
+	}
+	for key := range items {
+		s.invalidate(ctx, key)
+	}
+	return nil
+}
+
+func (s *tieredCacheStorage) DeleteMulti(ctx context.Context, keys []string) error {
+	if err := s.remote.DeleteMulti(ctx, keys); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		s.invalidate(ctx, key)
+	}
+	return nil
+}
+
+// Increment is always sent to the remote cache since the counter is shared by all instances.
+func (s *tieredCacheStorage) Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	value, err := s.remote.Increment(ctx, key, delta, expire)
+	if err != nil {
+		return 0, err
+	}
+	s.invalidate(ctx, key)
+	return value, nil
+}
+
+func (s *tieredCacheStorage) Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	value, err := s.remote.Decrement(ctx, key, delta, expire)
+	if err != nil {
+		return 0, err
+	}
+	s.invalidate(ctx, key)
+	return value, nil
+}
+
+// SetIfNotExists and CompareAndSwap are conditional on the shared value, so they are
+// always sent to the remote cache.
+func (s *tieredCacheStorage) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	ok, err := s.remote.SetIfNotExists(ctx, key, value, expire)
+	if err != nil || !ok {
+		return ok, err
+	}
+	s.invalidate(ctx, key)
+	return true, nil
+}
+
+func (s *tieredCacheStorage) CompareAndSwap(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	ok, err := s.remote.CompareAndSwap(ctx, key, old, value, expire)
+	if err != nil || !ok {
+		return ok, err
+	}
+	s.invalidate(ctx, key)
+	return true, nil
+}
+
+func (s *tieredCacheStorage) Scan(ctx context.Context, prefix string) ([]string, error) {
+	return s.remote.Scan(ctx, prefix)
+}
+
+// DeleteByPrefix invalidates the deleted keys one by one, the invalidation channel only
+// carries keys.
+func (s *tieredCacheStorage) DeleteByPrefix(ctx context.Context, prefix string) error {
+	keys, err := s.remote.Scan(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	if err := s.remote.DeleteByPrefix(ctx, prefix); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		s.invalidate(ctx, key)
+	}
+	return nil
+}
+
+func (s *tieredCacheStorage) Count(ctx context.Context, prefix string) (int64, error) {
+	return s.remote.Count(ctx, prefix)
+}
+
+// Stats returns the stats of the remote cache, the local copies are not included.
+func (s *tieredCacheStorage) Stats(ctx context.Context) (*Stats, error) {
+	return s.remote.Stats(ctx)
+}
+
+// invalidate drops the local copy and notifies the other instances. A failed notification
+// is not returned since the remote cache has been updated, other instances catch up once
+// their local copy expires.
+func (s *tieredCacheStorage) invalidate(ctx context.Context, key string) {
+	_ = s.local.Delete(ctx, key)
+	if err := s.invalidator.Publish(ctx, key); err != nil {
+		s.log.Warn("Failed to publish cache invalidation", "key", key, "error", err)
+	}
+}
+
+// runBatch serves reads from the local copies where possible and appends the invalidations
+// of the changed keys to the batch. Invalidations sent in the batch are published even if the
+// change failed, which only drops the local copies of other instances.
+func (s *tieredCacheStorage) runBatch(ctx context.Context, ops []*batchOp) {
+	remote := make([]*batchOp, 0, len(ops))
+	changed := map[string]bool{}
+	var changes []*batchOp
+	for _, op := range ops {
+		switch op.kind {
+		case batchGet:
+			// keys changed earlier in the batch are read from the remote cache
+			if !changed[op.key] && s.policies.localCache(op.key) {
+				if data, err := s.local.GetByteArray(ctx, op.key); err == nil {
+					op.result.Value = data
+					continue
+				}
+			}
+		case batchSet, batchDelete:
+			changed[op.key] = true
+			changes = append(changes, op)
+		}
+		remote = append(remote, op)
+	}
+
+	inv, pipelined := s.invalidator.(batchInvalidator)
+	notifications := make([]*batchOp, len(changes))
+	if pipelined {
+		for i, op := range changes {
+			notifications[i] = inv.publishOp(op.key)
+			remote = append(remote, notifications[i])
+		}
+	}
+	execBatch(ctx, s.remote, remote)
+
+	for _, op := range remote {
+		if op.kind == batchGet && op.result.Err == nil && s.policies.localCache(op.key) {
+			_ = s.local.SetByteArray(ctx, op.key, op.result.Value, s.localTTL)
+		}
+	}
+	for i, op := range changes {
+		switch {
+		case !pipelined:
+			if op.result.Err == nil {
+				s.invalidate(ctx, op.key)
+			}
+		case notifications[i].result.Err != nil:
+			_ = s.local.Delete(ctx, op.key)
+			s.log.Warn("Failed to publish cache invalidation", "key", op.key, "error", notifications[i].result.Err)
+		default:
+			_ = s.local.Delete(ctx, op.key)
+		}
+	}
+}
+
+// Run listens for invalidations of other instances and runs the background jobs of the remote cache.
+func (s *tieredCacheStorage) Run(ctx context.Context) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return s.invalidator.Run(ctx, func(key string) {
+			_ = s.local.Delete(ctx, key)
+		})
+	})
+	if backgroundjob, ok := s.remote.(registry.BackgroundService); ok {
+		g.Go(func() error { return backgroundjob.Run(ctx) })
+	}
+	return g.Wait()
+}
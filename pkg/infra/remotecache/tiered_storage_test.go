@@ -0,0 +1,97 @@
+package remotecache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+func TestTieredCacheStorage(t *testing.T) {
+	ctx := context.Background()
+	remote := newMemoryStorageWithLimits(&gobCodec{}, 0, 0)
+	bus := &fakeInvalidationBus{}
+
+	instance1 := newTieredCacheStorage(remote, bus.invalidator(), &gobCodec{}, time.Minute, 100)
+	instance2 := newTieredCacheStorage(remote, bus.invalidator(), &gobCodec{}, time.Minute, 100)
+	bus.subscribe(instance1)
+	bus.subscribe(instance2)
+
+	require.NoError(t, instance1.Set(ctx, "key", "v1", 0))
+	v, err := instance2.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", v)
+
+	// served from the local cache
+	require.NoError(t, remote.SetByteArray(ctx, "key", []byte("changed behind the cache"), 0))
+	data, err := instance2.GetByteArray(ctx, "key")
+	require.NoError(t, err)
+	assert.NotEqual(t, []byte("changed behind the cache"), data)
+
+	// changes through the cache invalidate the local copies of all instances
+	require.NoError(t, instance1.Set(ctx, "key", "v2", 0))
+	v, err = instance2.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", v)
+
+	require.NoError(t, instance1.Delete(ctx, "key"))
+	_, err = instance2.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrCacheItemNotFound)
+}
+
+func TestDatabaseInvalidator(t *testing.T) {
+	ctx := context.Background()
+	pubsub := newDatabasePubSub(db.InitTestDB(t))
+	i := &pubSubInvalidator{pubsub: pubsub, channel: invalidationChannel}
+
+	require.NoError(t, i.Publish(ctx, "old"))
+	lastID, err := pubsub.lastID(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, i.Publish(ctx, "key1"))
+	require.NoError(t, i.Publish(ctx, "key2"))
+
+	var keys []string
+	_, err = pubsub.poll(ctx, invalidationChannel, lastID, func(message []byte) { keys = append(keys, string(message)) })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key1", "key2"}, keys)
+}
+
+// fakeInvalidationBus delivers invalidations synchronously to all subscribed instances
+type fakeInvalidationBus struct {
+	mu        sync.Mutex
+	instances []*tieredCacheStorage
+}
+
+func (b *fakeInvalidationBus) subscribe(s *tieredCacheStorage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.instances = append(b.instances, s)
+}
+
+func (b *fakeInvalidationBus) invalidator() invalidator {
+	return &fakeInvalidator{bus: b}
+}
+
+type fakeInvalidator struct {
+	bus *fakeInvalidationBus
+}
+
+func (i *fakeInvalidator) Publish(ctx context.Context, key string) error {
+	i.bus.mu.Lock()
+	defer i.bus.mu.Unlock()
+	for _, s := range i.bus.instances {
+		_ = s.local.Delete(ctx, key)
+	}
+	return nil
+}
+
+func (i *fakeInvalidator) Run(ctx context.Context, invalidate func(key string)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
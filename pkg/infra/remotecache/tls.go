@@ -0,0 +1,49 @@
+package remotecache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// newTLSConfig builds the TLS configuration used to connect to the cache servers,
+// it returns nil if TLS is not enabled for the remote cache.
+func newTLSConfig(opts *setting.RemoteCacheOptions) (*tls.Config, error) {
+	if !opts.TLSEnabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         opts.TLSServerName,
+		InsecureSkipVerify: opts.TLSSkipVerify,
+	}
+
+	if opts.TLSCACertPath != "" {
+		pem, err := os.ReadFile(opts.TLSCACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not read remote cache CA cert path %q: %w", opts.TLSCACertPath, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in remote cache CA cert path %q", opts.TLSCACertPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (opts.TLSClientCertPath == "") != (opts.TLSClientKeyPath == "") {
+		return nil, errors.New("both tls_client_cert_path and tls_client_key_path must be set for remote cache mutual TLS")
+	}
+	if opts.TLSClientCertPath != "" {
+		// load the certificate on every handshake so rotated certificates are picked up without a restart
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(opts.TLSClientCertPath, opts.TLSClientKeyPath)
+			return &cert, err
+		}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,61 @@
+package remotecache
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	t.Run("should return nil when tls is disabled", func(t *testing.T) {
+		tlsConfig, err := newTLSConfig(&setting.RemoteCacheOptions{TLSServerName: "redis"})
+		require.NoError(t, err)
+		assert.Nil(t, tlsConfig)
+	})
+
+	t.Run("should use server name and skip verify", func(t *testing.T) {
+		tlsConfig, err := newTLSConfig(&setting.RemoteCacheOptions{TLSEnabled: true, TLSServerName: "redis", TLSSkipVerify: true})
+		require.NoError(t, err)
+		assert.Equal(t, "redis", tlsConfig.ServerName)
+		assert.True(t, tlsConfig.InsecureSkipVerify)
+		assert.Nil(t, tlsConfig.GetClientCertificate)
+	})
+
+	t.Run("should fail for missing ca cert", func(t *testing.T) {
+		_, err := newTLSConfig(&setting.RemoteCacheOptions{TLSEnabled: true, TLSCACertPath: filepath.Join(t.TempDir(), "ca.pem")})
+		assert.Error(t, err)
+	})
+
+	t.Run("should require both client cert and key", func(t *testing.T) {
+		_, err := newTLSConfig(&setting.RemoteCacheOptions{TLSEnabled: true, TLSClientCertPath: "client.pem"})
+		assert.Error(t, err)
+	})
+}
+
+func TestRedisStorage_TLS(t *testing.T) {
+	storage, err := newRedisStorage(&setting.RemoteCacheOptions{ConnStr: "addr=redis.example.com:6379", TLSEnabled: true}, &gobCodec{})
+	require.NoError(t, err)
+
+	client, ok := storage.c.(*redis.Client)
+	require.True(t, ok)
+	require.NotNil(t, client.Options().TLSConfig)
+	assert.Equal(t, "redis.example.com", client.Options().TLSConfig.ServerName)
+}
+
+func TestMemcachedStorage_TLS(t *testing.T) {
+	storage, err := newMemcachedStorage(&setting.RemoteCacheOptions{ConnStr: "localhost:11211"}, &gobCodec{})
+	require.NoError(t, err)
+	assert.Nil(t, storage.c.(textClient).DialContext)
+
+	storage, err = newMemcachedStorage(&setting.RemoteCacheOptions{ConnStr: "localhost:11211", TLSEnabled: true}, &gobCodec{})
+	require.NoError(t, err)
+	assert.NotNil(t, storage.c.(textClient).DialContext)
+
+	_, err = newMemcachedStorage(&setting.RemoteCacheOptions{ConnStr: "localhost:11211", TLSEnabled: true, TLSClientCertPath: "client.pem"}, &gobCodec{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package remotecache
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrTypeConflict is returned by RegisterType if the name or the type is already registered
+// with another type or name
+var ErrTypeConflict = errors.New("cache type conflicts with a registered type")
+
+// Versioned can be implemented by cached types that are not registered with RegisterType to
+// invalidate values stored by older versions of Grafana during rolling upgrades, bump the
+// version when the struct changes in an incompatible way.
+type Versioned interface {
+	CacheVersion() int
+}
+
+// cachedType is a type whose values can be stored with Set
+type cachedType struct {
+	name    string
+	version int
+	t       reflect.Type
+}
+
+var cachedTypes = struct {
+	sync.RWMutex
+	byName map[string]*cachedType
+	byType map[reflect.Type]*cachedType
+}{byName: map[string]*cachedType{}, byType: map[reflect.Type]*cachedType{}}
+
+func init() {
+	for _, value := range []interface{}{
+		"", false, 0, int32(0), int64(0), uint(0), uint64(0), float64(0), []byte{}, []string{},
+		map[string]string{}, map[string]interface{}{}, []interface{}{},
+	} {
+		t := reflect.TypeOf(value)
+		_ = registerType(goTypeName(t), 0, t)
+	}
+}
+
+// RegisterType registers the type of value under a name that is stored with its values in
+// place of the Go type name, so the type can be moved or renamed without invalidating the
+// values stored by other versions of Grafana. Values of another version are cache misses,
+// bump the version when the type changes in an incompatible way. Registering a type again
+// with the same name and version does nothing.
+//
+// Types that are not registered are stored under their Go type name, instances that have
+// not stored or registered the type yet cannot read them.
+func RegisterType(name string, version int, value interface{}) error {
+	return registerType(name, version, reflect.TypeOf(value))
+}
+
+func registerType(name string, version int, t reflect.Type) error {
+	cachedTypes.Lock()
+	defer cachedTypes.Unlock()
+
+	if existing, ok := cachedTypes.byName[name]; ok && existing.t != t {
+		return fmt.Errorf("%w: %q is registered for %s", ErrTypeConflict, name, existing.t)
+	}
+	if existing, ok := cachedTypes.byType[t]; ok {
+		if existing.name != name || existing.version != version {
+			return fmt.Errorf("%w: %s is registered as %q version %d", ErrTypeConflict, t, existing.name, existing.version)
+		}
+		return nil
+	}
+
+	ct := &cachedType{name: name, version: version, t: t}
+	cachedTypes.byName[name] = ct
+	cachedTypes.byType[t] = ct
+	return nil
+}
+
+// cachedTypeOf returns the registered type of value, types that are not registered yet are
+// registered under their Go type name.
+func cachedTypeOf(value interface{}) (*cachedType, error) {
+	t := reflect.TypeOf(value)
+	if ct, ok := cachedTypeByType(t); ok {
+		return ct, nil
+	}
+
+	version := 0
+	if versioned, ok := value.(Versioned); ok {
+		version = versioned.CacheVersion()
+	}
+	err := registerType(goTypeName(t), version, t)
+	// the type can have been registered by another goroutine in the meantime
+	if ct, ok := cachedTypeByType(t); ok {
+		return ct, nil
+	}
+	return nil, err
+}
+
+func cachedTypeByType(t reflect.Type) (*cachedType, bool) {
+	cachedTypes.RLock()
+	defer cachedTypes.RUnlock()
+	ct, ok := cachedTypes.byType[t]
+	return ct, ok
+}
+
+func cachedTypeByName(name string) (*cachedType, bool) {
+	cachedTypes.RLock()
+	defer cachedTypes.RUnlock()
+	ct, ok := cachedTypes.byName[name]
+	return ct, ok
+}
+
+// goTypeName includes the package path, so types with the same name in different
+// packages do not collide
+func goTypeName(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		return "*" + goTypeName(t.Elem())
+	}
+	if t.PkgPath() == "" {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
@@ -0,0 +1,98 @@
+package remotecache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type registeredStruct struct {
+	Name string
+}
+
+type renamedStruct struct {
+	Name string
+}
+
+func TestRegisterType(t *testing.T) {
+	require.NoError(t, RegisterType("remotecache-test/registered", 1, registeredStruct{}))
+	// registering the same type again does nothing
+	require.NoError(t, RegisterType("remotecache-test/registered", 1, registeredStruct{}))
+
+	err := RegisterType("remotecache-test/registered", 1, renamedStruct{})
+	assert.ErrorIs(t, err, ErrTypeConflict)
+	err = RegisterType("remotecache-test/other-name", 1, registeredStruct{})
+	assert.ErrorIs(t, err, ErrTypeConflict)
+	err = RegisterType("remotecache-test/registered", 2, registeredStruct{})
+	assert.ErrorIs(t, err, ErrTypeConflict)
+}
+
+func TestCodecs_RegisteredTypes(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, RegisterType("remotecache-test/stable-name", 3, stableNameStruct{}))
+
+	for name, c := range map[string]codec{GobCodec: &gobCodec{}, JSONCodec: &jsonCodec{}} {
+		t.Run(name, func(t *testing.T) {
+			for _, value := range []interface{}{
+				stableNameStruct{Name: "registered"},
+				CacheableStruct{String: "hej", Int64: 2000},
+				&CacheableStruct{String: "pointer"},
+				"string",
+				int64(42),
+				// types that are not registered are registered when they are stored
+				unregisteredStruct{Count: 1},
+				nil,
+			} {
+				data, err := c.Encode(ctx, &cachedItem{Val: value})
+				require.NoError(t, err)
+
+				item := &cachedItem{}
+				require.NoError(t, c.Decode(ctx, data, item))
+				assert.Equal(t, value, item.Val)
+			}
+
+			// the registered name is stored in place of the Go type name
+			data, err := c.Encode(ctx, &cachedItem{Val: stableNameStruct{}})
+			require.NoError(t, err)
+			assert.Contains(t, string(data), "remotecache-test/stable-name")
+		})
+	}
+}
+
+func TestGobCodec_Mismatch(t *testing.T) {
+	ctx := context.Background()
+	c := &gobCodec{}
+
+	encode := func(value interface{}) []byte {
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, gob.NewEncoder(buf).Encode(value))
+		return buf.Bytes()
+	}
+
+	for name, data := range map[string][]byte{
+		"not gob":        []byte(`{"v":1}`),
+		"unknown type":   encode(&gobEnvelope{Type: "example.com/unknown.Type", Data: encode("value")}),
+		"type mismatch":  encode(&gobEnvelope{Type: "string", Data: encode(CacheableStruct{String: "value"})}),
+		"struct version": encode(&gobEnvelope{Type: goTypeName(reflect.TypeOf(versionedStruct{})), Version: 1, Data: encode(versionedStruct{})}),
+		// values stored before types were registered
+		"previous format": encode(&cachedItem{Val: "value"}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := c.Decode(ctx, data, &cachedItem{})
+			assert.ErrorIs(t, err, ErrCacheItemNotFound)
+		})
+	}
+}
+
+type stableNameStruct struct {
+	Name string
+}
+
+type unregisteredStruct struct {
+	Count int
+}
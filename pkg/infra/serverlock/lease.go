@@ -0,0 +1,213 @@
+package serverlock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+// Lease is a lock held by this server that is renewed in the background until it is released.
+// The fencing token increases with every acquisition of the lock, so that the token of a server
+// that lost the lease, ex because it was paused for longer than the ttl, is lower than the token
+// of the next holder. Resources written by the holder can store the token to reject the writes
+// of previous holders.
+type Lease struct {
+	sl         *ServerLockService
+	actionName string
+	id         int64
+	token      int64
+	ttl        time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	// stopped is closed when the renewal has stopped
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// FencingToken returns the token of the lease.
+func (l *Lease) FencingToken() int64 {
+	return l.token
+}
+
+// Done returns a channel that is closed when the lease is lost or released. Work done on behalf
+// of the lease must stop when the channel is closed, another server can hold the lease after the ttl.
+func (l *Lease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Release stops the renewal of the lease and releases the lock, so that other servers can acquire
+// it without waiting for the ttl. Releasing a lease that was lost is not an error.
+func (l *Lease) Release(ctx context.Context) error {
+	l.cancel()
+	<-l.stopped
+
+	ctx, span := l.sl.tracer.Start(ctx, "ServerLockService.releaseLease")
+	defer span.End()
+
+	err := l.sl.SQLStore.WithDbSession(ctx, func(dbSession *db.Session) error {
+		_, err := dbSession.Exec("UPDATE server_lock SET last_execution = 0 WHERE id = ? AND version = ?", l.id, l.token)
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+func (l *Lease) lost() {
+	l.once.Do(func() { close(l.done) })
+}
+
+// AcquireLease tries to acquire the lock of actionName, and returns a ServerLockExistsError if another
+// server holds it. The lease is renewed every third of the ttl until it is released or ctx is done,
+// and is lost if it could not be renewed within the ttl. The lock is expired for the other servers
+// once it has not been renewed for the ttl.
+// The lock is stored with a precision of seconds, the ttl should be a few seconds at least.
+// Leases use the same table as LockAndExecute, don't use the same actionName for both of them.
+func (sl *ServerLockService) AcquireLease(ctx context.Context, actionName string, ttl time.Duration) (*Lease, error) {
+	ctx, span := sl.tracer.Start(ctx, "ServerLockService.AcquireLease")
+	span.SetAttributes("serverlock.actionName", actionName, attribute.Key("serverlock.actionName").String(actionName))
+	defer span.End()
+
+	if ttl <= 0 {
+		return nil, errors.New("the ttl of a lease must be positive")
+	}
+
+	rowLock, err := sl.getOrCreate(ctx, actionName)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if sl.isLockWithinInterval(rowLock, ttl) {
+		return nil, &ServerLockExistsError{actionName: actionName}
+	}
+
+	acquiredLock, err := sl.acquireLock(ctx, rowLock)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if !acquiredLock {
+		return nil, &ServerLockExistsError{actionName: actionName}
+	}
+
+	renewCtx, cancel := context.WithCancel(ctx)
+	lease := &Lease{
+		sl:         sl,
+		actionName: actionName,
+		id:         rowLock.Id,
+		token:      rowLock.Version + 1,
+		ttl:        ttl,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	go lease.renew(renewCtx)
+
+	sl.log.FromContext(ctx).Debug("Lease acquired", "actionName", actionName, "fencingToken", lease.token)
+	return lease, nil
+}
+
+// renew renews the lease until ctx is done, and marks it lost when it could not be renewed within the ttl.
+func (l *Lease) renew(ctx context.Context) {
+	defer close(l.stopped)
+	defer l.lost()
+
+	ctxLogger := l.sl.log.FromContext(ctx)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ok, err := l.sl.renewLease(ctx, l.id, l.token)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return
+		case err != nil:
+			ctxLogger.Warn("Failed to renew lease", "actionName", l.actionName, "fencingToken", l.token, "error", err)
+			if time.Since(renewed) >= l.ttl {
+				ctxLogger.Error("Lease lost, it could not be renewed within the ttl", "actionName", l.actionName, "fencingToken", l.token)
+				return
+			}
+		case !ok:
+			ctxLogger.Error("Lease lost, it was acquired by another server", "actionName", l.actionName, "fencingToken", l.token)
+			return
+		default:
+			renewed = time.Now()
+		}
+	}
+}
+
+// renewLease updates the last execution of the lock if it is still held with the fencing token.
+func (sl *ServerLockService) renewLease(ctx context.Context, id int64, token int64) (bool, error) {
+	ctx, span := sl.tracer.Start(ctx, "ServerLockService.renewLease")
+	defer span.End()
+	var result bool
+
+	err := sl.SQLStore.WithDbSession(ctx, func(dbSession *db.Session) error {
+		res, err := dbSession.Exec("UPDATE server_lock SET last_execution = ? WHERE id = ? AND version = ? AND last_execution <> 0", time.Now().Unix(), id, token)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil || affected == 1 {
+			result = affected == 1
+			return err
+		}
+
+		// MySQL does not count the row if the last execution is unchanged, ex when renewed within the same second
+		lockRows := []*serverLock{}
+		if err := dbSession.Where("id = ? AND version = ? AND last_execution <> 0", id, token).Find(&lockRows); err != nil {
+			return err
+		}
+		result = len(lockRows) == 1
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+	}
+	return result, err
+}
+
+// LockExecuteWithLease acquires a lease of actionName and executes fn while holding it. The context
+// of fn is canceled when the lease is lost, and the lease is released once fn returns. It returns a
+// ServerLockExistsError without executing fn if another server holds the lease.
+func (sl *ServerLockService) LockExecuteWithLease(ctx context.Context, actionName string, ttl time.Duration, fn func(ctx context.Context, fencingToken int64)) error {
+	lease, err := sl.AcquireLease(ctx, actionName, ttl)
+	if err != nil {
+		return err
+	}
+
+	fnCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lease.Done():
+			cancel()
+		case <-fnCtx.Done():
+		}
+	}()
+
+	sl.executeFunc(fnCtx, actionName, func(ctx context.Context) {
+		fn(ctx, lease.FencingToken())
+	})
+
+	if err := lease.Release(ctx); err != nil {
+		sl.log.FromContext(ctx).Error("Failed to release the lease", "actionName", actionName, "error", err)
+	}
+	return nil
+}
@@ -21,7 +21,7 @@ func ProvideService(sqlStore db.DB, tracer tracing.Tracer) *ServerLockService {
 
 // ServerLockService allows servers in HA mode to claim a lock and execute a function if the server was granted the lock
 // It exposes 2 services LockAndExecute and LockExecuteAndRelease, which are intended to be used independently, don't mix
-// them up (ie, use the same actionName for both of them).
+// them up (ie, use the same actionName for both of them). Long running jobs can hold a Lease instead, see AcquireLease.
 type ServerLockService struct {
 	SQLStore db.DB
 	tracer   tracing.Tracer
@@ -62,3 +62,29 @@ func TestIntegrationServerLock_LockExecuteAndRelease(t *testing.T) {
 
 	require.Equal(t, 4, counter)
 }
+
+func TestIntegrationServerLock_LockExecuteWithLease(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	sl := createTestableServerLock(t)
+
+	var tokens []int64
+	fn := func(ctx context.Context, fencingToken int64) {
+		tokens = append(tokens, fencingToken)
+
+		// the lease is held while fn is executed
+		err := sl.LockExecuteWithLease(ctx, "test-operation", time.Hour, func(context.Context, int64) {
+			t.Fatal("the lease should be held")
+		})
+		var existsErr *ServerLockExistsError
+		require.ErrorAs(t, err, &existsErr)
+	}
+	ctx := context.Background()
+
+	require.NoError(t, sl.LockExecuteWithLease(ctx, "test-operation", time.Hour, fn))
+	require.NoError(t, sl.LockExecuteWithLease(ctx, "test-operation", time.Hour, fn))
+
+	require.Len(t, tokens, 2)
+	require.Greater(t, tokens[1], tokens[0])
+}
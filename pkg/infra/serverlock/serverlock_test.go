@@ -132,3 +132,74 @@ func TestLockAndRelease(t *testing.T) {
 		require.NoError(t, err3)
 	})
 }
+
+func TestLease(t *testing.T) {
+	operationUID := "test-operation-lease"
+	ctx := context.Background()
+
+	t.Run("acquire a lease, release it and acquire it again", func(t *testing.T) {
+		sl := createTestableServerLock(t)
+
+		lease, err := sl.AcquireLease(ctx, operationUID, time.Hour)
+		require.NoError(t, err)
+
+		_, err = sl.AcquireLease(ctx, operationUID, time.Hour)
+		var existsErr *ServerLockExistsError
+		require.ErrorAs(t, err, &existsErr)
+
+		require.NoError(t, lease.Release(ctx))
+		<-lease.Done()
+
+		next, err := sl.AcquireLease(ctx, operationUID, time.Hour)
+		require.NoError(t, err)
+		assert.Greater(t, next.FencingToken(), lease.FencingToken())
+		require.NoError(t, next.Release(ctx))
+	})
+
+	t.Run("releasing a lease that was lost does not release the lock of the new holder", func(t *testing.T) {
+		sl := createTestableServerLock(t)
+
+		lease, err := sl.AcquireLease(ctx, operationUID, time.Hour)
+		require.NoError(t, err)
+		// another server acquires the lock
+		err = sl.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+			_, err := sess.Exec("UPDATE server_lock SET version = version + 1 WHERE operation_uid = ?", operationUID)
+			return err
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, lease.Release(ctx))
+		_, err = sl.AcquireLease(ctx, operationUID, time.Hour)
+		require.Error(t, err)
+	})
+
+	t.Run("a lease is renewed and lost when another server holds the lock", func(t *testing.T) {
+		sl := createTestableServerLock(t)
+
+		lease, err := sl.AcquireLease(ctx, operationUID, 30*time.Millisecond)
+		require.NoError(t, err)
+
+		renewed, err := sl.renewLease(ctx, lease.id, lease.FencingToken())
+		require.NoError(t, err)
+		require.True(t, renewed)
+
+		err = sl.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+			_, err := sess.Exec("UPDATE server_lock SET version = version + 1 WHERE operation_uid = ?", operationUID)
+			return err
+		})
+		require.NoError(t, err)
+
+		select {
+		case <-lease.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("the lease was not lost")
+		}
+		require.NoError(t, lease.Release(ctx))
+	})
+
+	t.Run("a lease needs a ttl", func(t *testing.T) {
+		sl := createTestableServerLock(t)
+		_, err := sl.AcquireLease(ctx, operationUID, 0)
+		require.Error(t, err)
+	})
+}
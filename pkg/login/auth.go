@@ -7,6 +7,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/ldap"
+	"github.com/grafana/grafana/pkg/services/ldap/service"
 	"github.com/grafana/grafana/pkg/services/login"
 	"github.com/grafana/grafana/pkg/services/loginattempt"
 	"github.com/grafana/grafana/pkg/services/user"
@@ -37,16 +38,18 @@ type AuthenticatorService struct {
 	loginService        login.Service
 	loginAttemptService loginattempt.Service
 	userService         user.Service
+	ldapService         service.LDAP
 	cfg                 *setting.Cfg
 }
 
 func ProvideService(store db.DB, loginService login.Service,
 	loginAttemptService loginattempt.Service,
-	userService user.Service, cfg *setting.Cfg) *AuthenticatorService {
+	userService user.Service, ldapService service.LDAP, cfg *setting.Cfg) *AuthenticatorService {
 	a := &AuthenticatorService{
 		loginService:        loginService,
 		loginAttemptService: loginAttemptService,
 		userService:         userService,
+		ldapService:         ldapService,
 		cfg:                 cfg,
 	}
 	return a
@@ -54,7 +57,7 @@ func ProvideService(store db.DB, loginService login.Service,
 
 // AuthenticateUser authenticates the user via username & password
 func (a *AuthenticatorService) AuthenticateUser(ctx context.Context, query *login.LoginUserQuery) error {
-	ok, err := a.loginAttemptService.Validate(ctx, query.Username)
+	ok, err := a.loginAttemptService.Validate(ctx, query.Username, query.IpAddress)
 	if err != nil {
 		return err
 	}
@@ -78,7 +81,7 @@ func (a *AuthenticatorService) AuthenticateUser(ctx context.Context, query *logi
 		return err
 	}
 
-	ldapEnabled, ldapErr := loginUsingLDAP(ctx, query, a.loginService, a.cfg)
+	ldapEnabled, ldapErr := loginUsingLDAP(ctx, query, a.loginService, a.ldapService, a.cfg)
 	if ldapEnabled {
 		query.AuthModule = login.LDAPAuthModule
 		if ldapErr == nil || !errors.Is(ldapErr, ldap.ErrInvalidCredentials) {
@@ -3,38 +3,27 @@ package login
 import (
 	"context"
 	"errors"
-	"fmt"
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/ldap"
-	"github.com/grafana/grafana/pkg/services/ldap/multildap"
+	"github.com/grafana/grafana/pkg/services/ldap/service"
 	"github.com/grafana/grafana/pkg/services/login"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
-// getLDAPConfig gets LDAP config
-var getLDAPConfig = multildap.GetConfig
-
-// newLDAP creates multiple LDAP instance
-var newLDAP = multildap.New
-
 // logger for the LDAP auth
 var ldapLogger = log.New("login.ldap")
 
 // loginUsingLDAP logs in user using LDAP. It returns whether LDAP is enabled and optional error and query arg will be
-// populated with the logged in user if successful.
+// populated with the logged in user if successful. The shared client of the LDAP service is used so that
+// its pooled connections are reused between logins.
 var loginUsingLDAP = func(ctx context.Context, query *login.LoginUserQuery,
-	loginService login.Service, cfg *setting.Cfg) (bool, error) {
+	loginService login.Service, ldapService service.LDAP, cfg *setting.Cfg) (bool, error) {
 	if !cfg.LDAPEnabled {
 		return false, nil
 	}
 
-	config, err := getLDAPConfig(query.Cfg)
-	if err != nil {
-		return true, fmt.Errorf("%v: %w", "Failed to get LDAP config", err)
-	}
-
-	externalUser, err := newLDAP(config.Servers, cfg).Login(query)
+	externalUser, err := ldapService.Login(query)
 	if err != nil {
 		if errors.Is(err, ldap.ErrCouldNotFindUser) {
 			// Ignore the error since user might not be present anyway
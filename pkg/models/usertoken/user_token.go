@@ -3,6 +3,7 @@ package usertoken
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 var ErrInvalidSessionToken = errors.New("invalid session token")
@@ -35,3 +36,13 @@ type UserToken struct {
 	RevokedAt     int64
 	UnhashedToken string
 }
+
+// TimeToLive returns the time left until the token reaches the maximum lifetime of a session.
+// Sessions are extended on activity, but never beyond the maximum lifetime counted from when the token was created.
+// A maxLifetime of zero or less means there is no maximum lifetime and is returned unchanged.
+func (t *UserToken) TimeToLive(maxLifetime time.Duration) time.Duration {
+	if maxLifetime <= 0 {
+		return maxLifetime
+	}
+	return time.Until(time.Unix(t.CreatedAt, 0).Add(maxLifetime))
+}
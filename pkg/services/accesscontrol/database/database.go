@@ -4,7 +4,9 @@ import (
 	"context"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/grafana/grafana/pkg/events"
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 )
@@ -168,7 +170,9 @@ func (s *AccessControlStore) GetUsersBasicRoles(ctx context.Context, userFilter
 }
 
 func (s *AccessControlStore) DeleteUserPermissions(ctx context.Context, orgID, userID int64) error {
-	err := s.sql.WithDbSession(ctx, func(sess *db.Session) error {
+	err := s.sql.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: userID})
+
 		roleDeleteQuery := "DELETE FROM user_role WHERE user_id = ?"
 		roleDeleteParams := []interface{}{roleDeleteQuery, userID}
 		if orgID != accesscontrol.GlobalOrgID {
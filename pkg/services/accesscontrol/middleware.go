@@ -232,6 +232,11 @@ func LoadPermissionsMiddleware(service Service) web.Handler {
 			return
 		}
 
+		// permissions can already be resolved together with the identity, see authn identity cache
+		if _, ok := c.SignedInUser.Permissions[c.OrgID]; ok {
+			return
+		}
+
 		permissions, err := service.GetUserPermissions(c.Req.Context(), c.SignedInUser,
 			Options{ReloadCache: false})
 		if err != nil {
@@ -306,7 +306,8 @@ const (
 	ActionLDAPConfigReload = "ldap.config:reload"
 
 	// Server actions
-	ActionServerStatsRead = "server.stats:read"
+	ActionServerStatsRead          = "server.stats:read"
+	ActionServerDatabasePoolsWrite = "server.database.pools:write"
 
 	// Settings actions
 	ActionSettingsRead = "settings:read"
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/grafana/grafana/pkg/events"
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/org"
@@ -82,6 +83,7 @@ func (s *store) setUserResourcePermission(
 		}
 	}
 
+	sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: user.ID})
 	return permission, nil
 }
 
@@ -121,6 +123,8 @@ func (s *store) setTeamResourcePermission(
 		}
 	}
 
+	// the permission applies to every member of the team
+	sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now()})
 	return permission, nil
 }
 
@@ -164,6 +168,8 @@ func (s *store) setBuiltInResourcePermission(
 		}
 	}
 
+	// the permission applies to every user with the role
+	sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now()})
 	return permission, nil
 }
 
@@ -103,6 +103,18 @@ var (
 		},
 	}
 
+	databasePoolsWriterRole = RoleDTO{
+		Name:        "fixed:database.pools:writer",
+		DisplayName: "Database pool writer",
+		Description: "Update the connection pool settings of the Grafana database.",
+		Group:       "Statistics",
+		Permissions: []Permission{
+			{
+				Action: ActionServerDatabasePoolsWrite,
+			},
+		},
+	}
+
 	usersReaderRole = RoleDTO{
 		Name:        "fixed:users:reader",
 		DisplayName: "User reader",
@@ -199,6 +211,10 @@ func DeclareFixedRoles(service Service) error {
 		Role:   statsReaderRole,
 		Grants: []string{RoleGrafanaAdmin},
 	}
+	databasePoolsWriter := RoleRegistration{
+		Role:   databasePoolsWriterRole,
+		Grants: []string{RoleGrafanaAdmin},
+	}
 	usersReader := RoleRegistration{
 		Role:   usersReaderRole,
 		Grants: []string{RoleGrafanaAdmin},
@@ -209,7 +225,7 @@ func DeclareFixedRoles(service Service) error {
 	}
 
 	return service.DeclareFixedRoles(ldapReader, ldapWriter, orgUsersReader, orgUsersWriter,
-		settingsReader, statsReader, usersReader, usersWriter)
+		settingsReader, statsReader, databasePoolsWriter, usersReader, usersWriter)
 }
 
 func ConcatPermissions(permissions ...[]Permission) []Permission {
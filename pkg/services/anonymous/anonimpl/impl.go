@@ -9,16 +9,21 @@ import (
 	"strings"
 	"time"
 
+	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/localcache"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/network"
 	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
 	"github.com/grafana/grafana/pkg/infra/usagestats"
+	"github.com/grafana/grafana/pkg/services/anonymous"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/web"
 )
 
 const thirtyDays = 30 * 24 * time.Hour
 const anonCachePrefix = "anon-session"
+const anonDeviceCachePrefix = "anon-device"
 
 type AnonSession struct {
 	ip        string
@@ -39,13 +44,19 @@ func (a *AnonSession) Key() (string, error) {
 }
 
 type AnonSessionService struct {
+	cfg         *setting.Cfg
+	store       store
+	lock        *serverlock.ServerLockService
 	remoteCache remotecache.CacheStorage
 	log         log.Logger
 	localCache  *localcache.CacheService
 }
 
-func ProvideAnonymousSessionService(remoteCache remotecache.CacheStorage, usageStats usagestats.Service) *AnonSessionService {
+func ProvideAnonymousSessionService(cfg *setting.Cfg, sqlStore db.DB, lock *serverlock.ServerLockService, remoteCache remotecache.CacheStorage, usageStats usagestats.Service) *AnonSessionService {
 	a := &AnonSessionService{
+		cfg:         cfg,
+		store:       &xormStore{db: sqlStore},
+		lock:        lock,
 		remoteCache: remoteCache,
 		log:         log.New("anonymous-session-service"),
 		localCache:  localcache.New(29*time.Minute, 15*time.Minute),
@@ -68,18 +79,11 @@ func (a *AnonSessionService) UsageStatFn(ctx context.Context) (map[string]interf
 }
 
 func (a *AnonSessionService) TagSession(ctx context.Context, httpReq *http.Request) error {
-	addr := web.RemoteAddr(httpReq)
-	ip, err := network.GetIPFromAddress(addr)
+	clientIPStr, err := a.clientIP(httpReq)
 	if err != nil {
-		a.log.Debug("failed to parse ip from address", "addr", addr)
 		return nil
 	}
 
-	clientIPStr := ip.String()
-	if len(ip) == 0 {
-		clientIPStr = ""
-	}
-
 	anonSession := &AnonSession{
 		ip:        clientIPStr,
 		userAgent: httpReq.UserAgent(),
@@ -98,3 +102,105 @@ func (a *AnonSessionService) TagSession(ctx context.Context, httpReq *http.Reque
 
 	return a.remoteCache.Set(ctx, key, key, thirtyDays)
 }
+
+func (a *AnonSessionService) TagDevice(ctx context.Context, httpReq *http.Request, orgID int64, deviceID string) error {
+	if deviceID == "" {
+		return nil
+	}
+
+	key := fmt.Sprintf("%s:%d:%s", anonDeviceCachePrefix, orgID, deviceID)
+	if _, ok := a.localCache.Get(key); ok {
+		return nil
+	}
+
+	now := time.Now()
+	clientIPStr, _ := a.clientIP(httpReq)
+	device := &anonymous.Device{
+		OrgID:     orgID,
+		DeviceID:  deviceID,
+		ClientIP:  clientIPStr,
+		UserAgent: truncate(httpReq.UserAgent(), 255),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := a.store.CreateOrUpdateDevice(ctx, device, a.cfg.AnonymousDeviceLimit, now.Add(-thirtyDays)); err != nil {
+		return err
+	}
+
+	a.localCache.SetDefault(key, struct{}{})
+	return nil
+}
+
+func (a *AnonSessionService) CheckDeviceLimit(ctx context.Context, orgID int64) error {
+	if a.cfg.AnonymousDeviceLimit <= 0 {
+		return nil
+	}
+
+	count, err := a.store.CountDevices(ctx, orgID, time.Now().Add(-thirtyDays))
+	if err != nil {
+		return err
+	}
+
+	if count >= a.cfg.AnonymousDeviceLimit {
+		return errDeviceLimitReached(orgID, a.cfg.AnonymousDeviceLimit)
+	}
+
+	return nil
+}
+
+func (a *AnonSessionService) clientIP(httpReq *http.Request) (string, error) {
+	addr := web.RemoteAddr(httpReq)
+	ip, err := network.GetIPFromAddress(addr)
+	if err != nil {
+		a.log.Debug("failed to parse ip from address", "addr", addr)
+		return "", err
+	}
+
+	if len(ip) == 0 {
+		return "", nil
+	}
+
+	return ip.String(), nil
+}
+
+// Run periodically removes anonymous devices that have not been active for thirty days.
+func (a *AnonSessionService) Run(ctx context.Context) error {
+	if !a.cfg.AnonymousEnabled {
+		return nil
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.cleanup(ctx)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (a *AnonSessionService) cleanup(ctx context.Context) {
+	err := a.lock.LockAndExecute(ctx, "delete old anonymous devices", time.Hour, func(context.Context) {
+		deleted, err := a.store.DeleteDevicesOlderThan(ctx, time.Now().Add(-thirtyDays))
+		if err != nil {
+			a.log.Error("Problem deleting inactive anonymous devices", "error", err)
+			return
+		}
+		a.log.Debug("Deleted inactive anonymous devices", "rows affected", deleted)
+	})
+
+	if err != nil {
+		a.log.Error("failed to lock and execute cleanup of anonymous devices", "error", err)
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,141 @@
+package anonimpl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/anonymous"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestAnonSessionService_TagDevice(t *testing.T) {
+	type device struct {
+		orgID    int64
+		deviceID string
+	}
+
+	type testCase struct {
+		desc        string
+		limit       int64
+		devices     []device
+		expectedErr error
+		expectedLen int
+	}
+
+	tests := []testCase{
+		{
+			desc:        "should track devices without limit",
+			devices:     []device{{1, "a"}, {1, "b"}, {1, "c"}},
+			expectedLen: 3,
+		},
+		{
+			desc:        "should reject new device when limit is reached",
+			limit:       2,
+			devices:     []device{{1, "a"}, {1, "b"}, {1, "c"}},
+			expectedErr: anonymous.ErrDeviceLimitReached,
+			expectedLen: 2,
+		},
+		{
+			desc:        "should allow known device when limit is reached",
+			limit:       2,
+			devices:     []device{{1, "a"}, {1, "b"}, {1, "a"}},
+			expectedLen: 2,
+		},
+		{
+			desc:        "should apply limit per org",
+			limit:       1,
+			devices:     []device{{1, "a"}, {2, "a"}, {2, "b"}},
+			expectedErr: anonymous.ErrDeviceLimitReached,
+			expectedLen: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			store := &fakeStore{}
+			s := &AnonSessionService{
+				cfg:        &setting.Cfg{AnonymousDeviceLimit: tt.limit},
+				store:      store,
+				log:        log.NewNopLogger(),
+				localCache: localcache.New(time.Minute, time.Minute),
+			}
+
+			var err error
+			for _, d := range tt.devices {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				if err = s.TagDevice(context.Background(), req, d.orgID, d.deviceID); err != nil {
+					break
+				}
+			}
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Len(t, store.devices, tt.expectedLen)
+		})
+	}
+}
+
+func TestAnonSessionService_CheckDeviceLimit(t *testing.T) {
+	store := &fakeStore{devices: []*anonymous.Device{
+		{OrgID: 1, DeviceID: "a", UpdatedAt: time.Now()},
+		{OrgID: 1, DeviceID: "b", UpdatedAt: time.Now().Add(-2 * thirtyDays)},
+	}}
+	s := &AnonSessionService{
+		cfg:   &setting.Cfg{AnonymousDeviceLimit: 1},
+		store: store,
+	}
+
+	assert.ErrorIs(t, s.CheckDeviceLimit(context.Background(), 1), anonymous.ErrDeviceLimitReached)
+	assert.NoError(t, s.CheckDeviceLimit(context.Background(), 2))
+
+	s.cfg.AnonymousDeviceLimit = 2
+	assert.NoError(t, s.CheckDeviceLimit(context.Background(), 1))
+}
+
+type fakeStore struct {
+	devices []*anonymous.Device
+}
+
+func (f *fakeStore) CreateOrUpdateDevice(ctx context.Context, device *anonymous.Device, limit int64, since time.Time) error {
+	for _, d := range f.devices {
+		if d.OrgID == device.OrgID && d.DeviceID == device.DeviceID && !d.UpdatedAt.Before(since) {
+			d.UpdatedAt = device.UpdatedAt
+			return nil
+		}
+	}
+
+	if limit > 0 {
+		count, _ := f.CountDevices(ctx, device.OrgID, since)
+		if count >= limit {
+			return errDeviceLimitReached(device.OrgID, limit)
+		}
+	}
+
+	f.devices = append(f.devices, device)
+	return nil
+}
+
+func (f *fakeStore) CountDevices(ctx context.Context, orgID int64, since time.Time) (int64, error) {
+	var count int64
+	for _, d := range f.devices {
+		if d.OrgID == orgID && !d.UpdatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeStore) DeleteDevicesOlderThan(ctx context.Context, olderThan time.Time) (int64, error) {
+	return 0, nil
+}
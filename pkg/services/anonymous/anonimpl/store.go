@@ -0,0 +1,103 @@
+package anonimpl
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/anonymous"
+)
+
+type store interface {
+	// CreateOrUpdateDevice updates the device or adds it if it does not exist. When limit is positive,
+	// a device that has not been active since the given time is only added while the org has less than
+	// limit active devices, ErrDeviceLimitReached is returned otherwise.
+	CreateOrUpdateDevice(ctx context.Context, device *anonymous.Device, limit int64, since time.Time) error
+	CountDevices(ctx context.Context, orgID int64, since time.Time) (int64, error)
+	DeleteDevicesOlderThan(ctx context.Context, olderThan time.Time) (int64, error)
+}
+
+type xormStore struct {
+	db db.DB
+}
+
+func (s *xormStore) CreateOrUpdateDevice(ctx context.Context, device *anonymous.Device, limit int64, since time.Time) error {
+	if limit <= 0 {
+		return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+			return s.createOrUpdateDevice(sess, device)
+		})
+	}
+
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		// Locking the org row serializes the admission of new devices to the org across instances,
+		// so concurrent requests cannot exceed the limit.
+		if _, err := sess.Exec("UPDATE org SET version = version WHERE id = ?", device.OrgID); err != nil {
+			return err
+		}
+
+		active, err := sess.Where("org_id = ? AND device_id = ? AND updated_at >= ?", device.OrgID, device.DeviceID, since).Exist(&anonymous.Device{})
+		if err != nil {
+			return err
+		}
+
+		if !active {
+			count, err := sess.Where("org_id = ? AND updated_at >= ?", device.OrgID, since).Count(&anonymous.Device{})
+			if err != nil {
+				return err
+			}
+			if count >= limit {
+				return errDeviceLimitReached(device.OrgID, limit)
+			}
+		}
+
+		return s.createOrUpdateDevice(sess, device)
+	})
+}
+
+// createOrUpdateDevice looks the device up instead of relying on the rows affected by the update,
+// MySQL does not count rows whose values did not change. A device inserted concurrently is updated.
+func (s *xormStore) createOrUpdateDevice(sess *db.Session, device *anonymous.Device) error {
+	exists, err := sess.Where("org_id = ? AND device_id = ?", device.OrgID, device.DeviceID).Exist(&anonymous.Device{})
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		_, err = sess.Insert(device)
+		if err == nil || !s.db.GetDialect().IsUniqueConstraintViolation(err) {
+			return err
+		}
+	}
+
+	_, err = sess.Where("org_id = ? AND device_id = ?", device.OrgID, device.DeviceID).
+		Cols("client_ip", "user_agent", "updated_at").
+		Update(device)
+	return err
+}
+
+func (s *xormStore) CountDevices(ctx context.Context, orgID int64, since time.Time) (int64, error) {
+	var count int64
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		count, err = sess.Where("org_id = ? AND updated_at >= ?", orgID, since).Count(&anonymous.Device{})
+		return err
+	})
+	return count, err
+}
+
+func (s *xormStore) DeleteDevicesOlderThan(ctx context.Context, olderThan time.Time) (int64, error) {
+	var deleted int64
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("DELETE FROM anon_device WHERE updated_at < ?", olderThan)
+		if err != nil {
+			return err
+		}
+		deleted, err = res.RowsAffected()
+		return err
+	})
+	return deleted, err
+}
+
+func errDeviceLimitReached(orgID, limit int64) error {
+	return anonymous.ErrDeviceLimitReached.Errorf("org %d has reached the limit of %d anonymous devices", orgID, limit)
+}
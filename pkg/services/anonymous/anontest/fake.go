@@ -6,8 +6,20 @@ import (
 )
 
 type FakeAnonymousSessionService struct {
+	ExpectedTagDeviceError        error
+	ExpectedCheckDeviceLimitError error
+	TaggedDeviceIDs               []string
 }
 
 func (f *FakeAnonymousSessionService) TagSession(ctx context.Context, httpReq *http.Request) error {
 	return nil
 }
+
+func (f *FakeAnonymousSessionService) TagDevice(ctx context.Context, httpReq *http.Request, orgID int64, deviceID string) error {
+	f.TaggedDeviceIDs = append(f.TaggedDeviceIDs, deviceID)
+	return f.ExpectedTagDeviceError
+}
+
+func (f *FakeAnonymousSessionService) CheckDeviceLimit(ctx context.Context, orgID int64) error {
+	return f.ExpectedCheckDeviceLimitError
+}
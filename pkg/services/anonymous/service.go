@@ -3,8 +3,35 @@ package anonymous
 import (
 	"context"
 	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/util/errutil"
 )
 
+// DeviceIDCookieName is the name of the cookie used to identify anonymous devices
+const DeviceIDCookieName = "grafana_device_id"
+
+var ErrDeviceLimitReached = errutil.NewBase(errutil.StatusForbidden, "anonymous.device-limit-reached", errutil.WithPublicMessage("Anonymous device limit reached. Contact your Grafana administrator."))
+
 type Service interface {
 	TagSession(context.Context, *http.Request) error
+	// TagDevice marks the anonymous device as active in the organization.
+	// ErrDeviceLimitReached is returned for new devices when the organization
+	// already has the maximum number of active anonymous devices.
+	TagDevice(ctx context.Context, httpReq *http.Request, orgID int64, deviceID string) error
+	// CheckDeviceLimit returns ErrDeviceLimitReached when the organization already has
+	// the maximum number of active anonymous devices.
+	CheckDeviceLimit(ctx context.Context, orgID int64) error
 }
+
+type Device struct {
+	ID        int64     `xorm:"pk autoincr 'id'"`
+	OrgID     int64     `xorm:"org_id"`
+	DeviceID  string    `xorm:"device_id"`
+	ClientIP  string    `xorm:"client_ip"`
+	UserAgent string    `xorm:"user_agent"`
+	CreatedAt time.Time `xorm:"created_at"`
+	UpdatedAt time.Time `xorm:"updated_at"`
+}
+
+func (d Device) TableName() string { return "anon_device" }
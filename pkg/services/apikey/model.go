@@ -16,6 +16,10 @@ var (
 	ErrDuplicate         = errors.New("API key, organization ID and name must be unique")
 )
 
+// ServiceID is the prefix identifier for API keys, new keys are generated in the format glak_<secret>_<checksum>
+// and only the hash of the key is stored.
+const ServiceID = "ak"
+
 type APIKey struct {
 	ID               int64        `db:"id" xorm:"pk autoincr 'id'"`
 	OrgID            int64        `db:"org_id" xorm:"org_id"`
@@ -148,6 +148,21 @@ func (s *UserAuthTokenService) LookupToken(ctx context.Context, unhashedToken st
 		}
 	}
 
+	// Current incoming token is the previous auth token in the DB and the rotated token has already been seen,
+	// when reuse detection is enabled we treat this as the token being stolen and revoke the session.
+	if s.isReusedToken(&model, hashedToken) {
+		ctxLogger.Warn("rotated out user token was reused, revoking session", "tokenId", model.Id, "userId", model.UserId, "clientIP", model.ClientIp, "userAgent", model.UserAgent)
+
+		if err := s.revokeRotationChain(ctx, &model); err != nil {
+			return nil, err
+		}
+
+		return nil, &auth.TokenRevokedError{
+			UserID:  model.UserId,
+			TokenID: model.Id,
+		}
+	}
+
 	// Current incoming token is the previous auth token in the DB and the auth_token_seen is true
 	if model.AuthToken != hashedToken && model.PrevAuthToken == hashedToken && model.AuthTokenSeen {
 		modelCopy := model
@@ -496,6 +511,34 @@ func (s *UserAuthTokenService) reportActiveTokenCount(ctx context.Context, _ *qu
 	return u, err
 }
 
+// isReusedToken returns true if reuse detection is enabled and the incoming token was rotated out
+// and the token replacing it was first seen more than the grace period ago.
+func (s *UserAuthTokenService) isReusedToken(model *userAuthToken, hashedToken string) bool {
+	if !s.cfg.TokenRotationReuseDetection {
+		return false
+	}
+
+	if model.AuthToken == hashedToken || model.PrevAuthToken != hashedToken || !model.AuthTokenSeen {
+		return false
+	}
+
+	// seen_at is reset when the token is rotated and set when the new token is first seen
+	return model.SeenAt > 0 && model.SeenAt < getTime().Add(-s.cfg.TokenRotationReuseGracePeriod).Unix()
+}
+
+// revokeRotationChain revokes the session of a reused token together with every token that was
+// rotated from it, so that neither the stolen token nor the tokens replacing it can be used.
+func (s *UserAuthTokenService) revokeRotationChain(ctx context.Context, model *userAuthToken) error {
+	model.RevokedAt = getTime().Unix()
+	return s.sqlStore.WithTransactionalDbSession(ctx, func(dbSession *db.Session) error {
+		_, err := dbSession.Exec(
+			"UPDATE user_auth_token SET revoked_at = ? WHERE revoked_at = 0 AND (id = ? OR auth_token IN (?, ?) OR prev_auth_token IN (?, ?))",
+			model.RevokedAt, model.Id, model.AuthToken, model.PrevAuthToken, model.AuthToken, model.PrevAuthToken,
+		)
+		return err
+	})
+}
+
 func (s *UserAuthTokenService) createdAfterParam() int64 {
 	return getTime().Add(-s.cfg.LoginMaxLifetime).Unix()
 }
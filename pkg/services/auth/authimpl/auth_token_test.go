@@ -370,6 +370,72 @@ func TestUserAuthToken(t *testing.T) {
 		require.NotNil(t, prevUserToken)
 	})
 
+	t.Run("revokes session when rotated out token is reused and reuse detection is enabled", func(t *testing.T) {
+		ctx.tokenService.cfg.TokenRotationReuseDetection = true
+		ctx.tokenService.cfg.TokenRotationReuseGracePeriod = time.Minute
+		defer func() { ctx.tokenService.cfg.TokenRotationReuseDetection = false }()
+
+		getTime = func() time.Time { return now }
+		userToken, err := ctx.tokenService.CreateToken(context.Background(), user,
+			net.ParseIP("192.168.10.11"), "some user agent")
+		require.Nil(t, err)
+
+		_, err = ctx.tokenService.LookupToken(context.Background(), userToken.UnhashedToken)
+		require.Nil(t, err)
+
+		getTime = func() time.Time { return now.Add(10 * time.Minute) }
+		prevToken := userToken.UnhashedToken
+		rotated, newToken, err := ctx.tokenService.TryRotateToken(context.Background(), userToken,
+			net.ParseIP("1.1.1.1"), "firefox")
+		require.Nil(t, err)
+		require.True(t, rotated)
+
+		// the rotated out token is accepted until the new token has been seen
+		_, err = ctx.tokenService.LookupToken(context.Background(), prevToken)
+		require.Nil(t, err)
+
+		_, err = ctx.tokenService.LookupToken(context.Background(), newToken.UnhashedToken)
+		require.Nil(t, err)
+
+		getTime = func() time.Time { return now.Add(20 * time.Minute) }
+		_, err = ctx.tokenService.LookupToken(context.Background(), prevToken)
+		var revokedErr *auth.TokenRevokedError
+		require.ErrorAs(t, err, &revokedErr)
+
+		_, err = ctx.tokenService.LookupToken(context.Background(), newToken.UnhashedToken)
+		require.ErrorAs(t, err, &revokedErr)
+	})
+
+	t.Run("measures the reuse grace period from when the rotated token was first seen", func(t *testing.T) {
+		ctx.tokenService.cfg.TokenRotationReuseDetection = true
+		ctx.tokenService.cfg.TokenRotationReuseGracePeriod = time.Minute
+		defer func() { ctx.tokenService.cfg.TokenRotationReuseDetection = false }()
+
+		getTime = func() time.Time { return now }
+		userToken, err := ctx.tokenService.CreateToken(context.Background(), user,
+			net.ParseIP("192.168.10.11"), "some user agent")
+		require.Nil(t, err)
+
+		_, err = ctx.tokenService.LookupToken(context.Background(), userToken.UnhashedToken)
+		require.Nil(t, err)
+
+		getTime = func() time.Time { return now.Add(10 * time.Minute) }
+		prevToken := userToken.UnhashedToken
+		rotated, newToken, err := ctx.tokenService.TryRotateToken(context.Background(), userToken,
+			net.ParseIP("1.1.1.1"), "firefox")
+		require.Nil(t, err)
+		require.True(t, rotated)
+
+		// the new token is first seen long after the rotation
+		getTime = func() time.Time { return now.Add(15 * time.Minute) }
+		_, err = ctx.tokenService.LookupToken(context.Background(), newToken.UnhashedToken)
+		require.Nil(t, err)
+
+		getTime = func() time.Time { return now.Add(15*time.Minute + 30*time.Second) }
+		_, err = ctx.tokenService.LookupToken(context.Background(), prevToken)
+		require.Nil(t, err)
+	})
+
 	t.Run("will not mark token unseen when prev and current are the same", func(t *testing.T) {
 		userToken, err := ctx.tokenService.CreateToken(context.Background(), user,
 			net.ParseIP("192.168.10.11"), "some user agent")
@@ -27,6 +27,7 @@ const (
 	ClientForm      = "auth.client.form"
 	ClientProxy     = "auth.client.proxy"
 	ClientSAML      = "auth.client.saml"
+	ClientWebAuthn  = "auth.client.webauthn"
 )
 
 const (
@@ -62,8 +63,14 @@ type Service interface {
 	// Authenticate authenticates a request
 	Authenticate(ctx context.Context, r *Request) (*Identity, error)
 	// RegisterPostAuthHook registers a hook with a priority that is called after a successful authentication.
-	// A lower number means higher priority.
+	// A lower number means higher priority. Hooks are called in order and can modify the identity,
+	// if a hook returns an error the authentication fails and the remaining hooks are not called.
 	RegisterPostAuthHook(hook PostAuthHookFn, priority uint)
+	// RegisterPostAuthObserver registers a hook with a priority that is called once a request has been
+	// fully authenticated, after all post auth hooks and the client hook have been called.
+	// A lower number means higher priority. Observers should not modify the identity,
+	// errors returned from an observer are logged and will not fail the authentication.
+	RegisterPostAuthObserver(hook PostAuthHookFn, priority uint)
 	// Login authenticates a request and creates a session on successful authentication.
 	Login(ctx context.Context, client string, r *Request) (*Identity, error)
 	// RegisterPostLoginHook registers a hook that that is called after a login request.
@@ -71,10 +78,41 @@ type Service interface {
 	RegisterPostLoginHook(hook PostLoginHookFn, priority uint)
 	// RedirectURL will generate url that we can use to initiate auth flow for supported clients.
 	RedirectURL(ctx context.Context, client string, r *Request) (*Redirect, error)
-	// RegisterClient will register a new authn.Client that can be used for authentication
+	// RegisterClient will register a new authn.Client that can be used for authentication.
+	// Clients implementing ContextAwareClient will also be tried during request authentication.
+	// Registering a client with the same name as an already registered client replaces it.
 	RegisterClient(c Client)
+	// RegisterContextAwareClient registers a client that does not implement ContextAwareClient
+	// so that it will be tried during request authentication with given priority whenever test returns true.
+	// A lower number means higher priority.
+	RegisterContextAwareClient(c Client, priority uint, test TestFn)
+	// RegisterIdentityEnricher registers an enricher that is called after a client has authenticated a request.
+	// Results are cached per identity for cacheTTL, a cacheTTL of 0 disables caching.
+	RegisterIdentityEnricher(e IdentityEnricher, cacheTTL time.Duration)
+	// InvalidateIdentity removes cached identities with given namespaced id in all organizations.
+	// It should be called when roles, team memberships or the disabled state of the entity change.
+	InvalidateIdentity(ctx context.Context, namespaceID string) error
+	// RegisterIdentityResolver registers a resolver for identities in the namespace, e.g. "user".
+	// Registering a resolver for a namespace that already has one replaces it.
+	RegisterIdentityResolver(namespace string, resolver IdentityResolverFn)
+	// ResolveIdentity resolves the identity for a namespaced id, e.g. "user:1", in the organization
+	// using the resolver registered for the namespace.
+	ResolveIdentity(ctx context.Context, orgID int64, namespaceID string) (*Identity, error)
+}
+
+// IdentityResolver resolves identities from their namespaced id, clients use it so they do not
+// need to know how the identities of a namespace are built.
+type IdentityResolver interface {
+	ResolveIdentity(ctx context.Context, orgID int64, namespaceID string) (*Identity, error)
 }
 
+// IdentityResolverFn resolves the identity with id in a namespace for the organization.
+// The id is the part of the namespaced id following the namespace.
+type IdentityResolverFn func(ctx context.Context, orgID int64, id string) (*Identity, error)
+
+// TestFn should return true if a client can be used to authenticate the request
+type TestFn func(ctx context.Context, r *Request) bool
+
 type Client interface {
 	// Name returns the name of a client
 	Name() string
@@ -108,6 +146,23 @@ type RedirectClient interface {
 	RedirectURL(ctx context.Context, r *Request) (*Redirect, error)
 }
 
+// IdentityEnricher can be used to add information from external systems, e.g. groups from a directory service,
+// to an identity after it has been authenticated by a client.
+type IdentityEnricher interface {
+	// Name returns the name of the enricher
+	Name() string
+	// Enrich returns the additional information for the identity
+	Enrich(ctx context.Context, identity *Identity, r *Request) (*Enrichment, error)
+}
+
+// Enrichment is the additional information returned by an IdentityEnricher.
+// Groups and Labels are appended to the identity. Teams are not, because they grant permissions,
+// use team sync to add users to teams based on their groups instead.
+type Enrichment struct {
+	Groups []string          `json:"groups,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
 type PasswordClient interface {
 	AuthenticatePassword(ctx context.Context, r *Request, username, password string) (*Identity, error)
 }
@@ -167,6 +222,27 @@ const (
 	NamespaceUser           = "user"
 	NamespaceAPIKey         = "api-key"
 	NamespaceServiceAccount = "service-account"
+	NamespaceRenderService  = "render"
+	NamespaceAnonymous      = "anonymous"
+	NamespaceProvisioning   = "provisioning"
+)
+
+// AssuranceLevel describes how strongly an identity has been authenticated.
+type AssuranceLevel int
+
+const (
+	AssuranceLevelNone AssuranceLevel = iota
+	// AssuranceLevelSingleFactor is used for identities authenticated with a single factor, e.g. a password.
+	AssuranceLevelSingleFactor
+	// AssuranceLevelMultiFactor is used for identities that completed a second factor, e.g. a TOTP code.
+	AssuranceLevelMultiFactor
+)
+
+// Authentication method references (amr), see RFC 8176.
+const (
+	AMROTP = "otp"
+	// AMRHardwareKey is used for proof-of-possession of a hardware-secured key, e.g. a WebAuthn credential.
+	AMRHardwareKey = "hwk"
 )
 
 type Identity struct {
@@ -210,6 +286,8 @@ type Identity struct {
 	// idP Groups that the entity is a member of. This is only populated if the
 	// identity provider supports groups.
 	Groups []string
+	// Labels are additional key value pairs attached to the identity, e.g. by an IdentityEnricher.
+	Labels map[string]string
 	// OAuthToken is the OAuth token used to authenticate the entity.
 	OAuthToken *oauth2.Token
 	// SessionToken is the session token used to authenticate the entity.
@@ -217,6 +295,13 @@ type Identity struct {
 	// ClientParams are hints for the auth service on how to handle the identity.
 	// Set by the authenticating client.
 	ClientParams ClientParams
+	// AuthenticationMethods are the authentication method references (amr) used to authenticate the entity.
+	AuthenticationMethods []string
+	// AssuranceLevel is the level of assurance reached by the authentication methods.
+	AssuranceLevel AssuranceLevel
+	// Permissions is the permissions of the entity per organization.
+	// Only populated when the identity was resolved through the identity cache.
+	Permissions map[int64]map[string][]string
 }
 
 // Role returns the role of the identity in the active organization.
@@ -240,6 +325,15 @@ func (i *Identity) NamespacedID() (string, int64) {
 	return split[0], id
 }
 
+// ParseNamespaceID splits a namespaced id, e.g. "user:1", into the namespace and the id.
+func ParseNamespaceID(namespaceID string) (string, string, error) {
+	namespace, id, ok := strings.Cut(namespaceID, ":")
+	if !ok || namespace == "" || id == "" {
+		return "", "", ErrInvalidNamespaceID.Errorf("expected namespaced id in format <namespace>:<id> but got: %s", namespaceID)
+	}
+	return namespace, id, nil
+}
+
 // NamespacedID builds a namespaced ID from a namespace and an ID.
 func NamespacedID(namespace string, id int64) string {
 	return fmt.Sprintf("%s:%d", namespace, id)
@@ -269,6 +363,7 @@ func (i *Identity) SignedInUser() *user.SignedInUser {
 		HelpFlags1:         i.HelpFlags1,
 		LastSeenAt:         i.LastSeenAt,
 		Teams:              i.Teams,
+		Permissions:        i.Permissions,
 	}
 
 	namespace, id := i.NamespacedID()
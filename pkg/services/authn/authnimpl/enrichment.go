@@ -0,0 +1,125 @@
+package authnimpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/authn"
+)
+
+const enrichmentCachePrefix = "authn-enrichment"
+
+type enrichmentCache interface {
+	GetByteArray(ctx context.Context, key string) ([]byte, error)
+	SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error
+}
+
+type identityEnricher struct {
+	authn.IdentityEnricher
+	cacheTTL time.Duration
+}
+
+func (s *Service) RegisterIdentityEnricher(e authn.IdentityEnricher, cacheTTL time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enrichers = append(s.enrichers, identityEnricher{IdentityEnricher: e, cacheTTL: cacheTTL})
+}
+
+// enrichIdentityHook is a post auth hook that appends the results from all registered enrichers to the identity.
+// Failing enrichers are logged and skipped.
+func (s *Service) enrichIdentityHook(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+	s.mu.RLock()
+	enrichers := s.enrichers
+	s.mu.RUnlock()
+
+	if len(enrichers) == 0 {
+		return nil
+	}
+
+	key := enrichmentKey(identity)
+	if key == "" {
+		return nil
+	}
+
+	for _, e := range enrichers {
+		enrichment, err := s.getEnrichment(ctx, e, key, identity, r)
+		if err != nil {
+			s.log.FromContext(ctx).Warn("Failed to enrich identity", "enricher", e.Name(), "id", key, "error", err)
+			continue
+		}
+		applyEnrichment(identity, enrichment)
+	}
+
+	return nil
+}
+
+func (s *Service) getEnrichment(ctx context.Context, e identityEnricher, key string, identity *authn.Identity, r *authn.Request) (*authn.Enrichment, error) {
+	cacheKey := fmt.Sprintf("%s-%s-%s", enrichmentCachePrefix, e.Name(), key)
+	if e.cacheTTL > 0 && s.cache != nil {
+		if data, err := s.cache.GetByteArray(ctx, cacheKey); err == nil {
+			enrichment := &authn.Enrichment{}
+			if err := json.Unmarshal(data, enrichment); err == nil {
+				return enrichment, nil
+			}
+		}
+	}
+
+	enrichment, err := e.Enrich(ctx, identity, r)
+	if err != nil || enrichment == nil {
+		return enrichment, err
+	}
+
+	if e.cacheTTL > 0 && s.cache != nil {
+		data, err := json.Marshal(enrichment)
+		if err == nil {
+			err = s.cache.SetByteArray(ctx, cacheKey, data, e.cacheTTL)
+		}
+		if err != nil {
+			s.log.FromContext(ctx).Warn("Failed to cache identity enrichment", "enricher", e.Name(), "id", key, "error", err)
+		}
+	}
+
+	return enrichment, nil
+}
+
+// enrichmentKey returns a key that identifies the identity, external identities that
+// are not yet synced to the database are identified by their auth module and auth id.
+func enrichmentKey(identity *authn.Identity) string {
+	if identity.ID != "" {
+		return identity.ID
+	}
+	if identity.AuthModule != "" && identity.AuthID != "" {
+		return identity.AuthModule + ":" + identity.AuthID
+	}
+	return ""
+}
+
+func applyEnrichment(identity *authn.Identity, enrichment *authn.Enrichment) {
+	if enrichment == nil {
+		return
+	}
+
+	for _, group := range enrichment.Groups {
+		if !containsValue(identity.Groups, group) {
+			identity.Groups = append(identity.Groups, group)
+		}
+	}
+
+	if len(enrichment.Labels) > 0 && identity.Labels == nil {
+		identity.Labels = make(map[string]string, len(enrichment.Labels))
+	}
+	for k, v := range enrichment.Labels {
+		identity.Labels[k] = v
+	}
+}
+
+func containsValue[T comparable](values []T, v T) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package authnimpl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/authn"
+)
+
+func TestService_EnrichIdentityHook(t *testing.T) {
+	type testCase struct {
+		desc             string
+		identity         *authn.Identity
+		enrichment       *authn.Enrichment
+		enrichErr        error
+		expectedIdentity *authn.Identity
+	}
+
+	tests := []testCase{
+		{
+			desc:     "should append groups and labels",
+			identity: &authn.Identity{ID: "user:1", Groups: []string{"a"}, Teams: []int64{1}},
+			enrichment: &authn.Enrichment{
+				Groups: []string{"a", "b"},
+				Labels: map[string]string{"department": "engineering"},
+			},
+			expectedIdentity: &authn.Identity{
+				ID:     "user:1",
+				Groups: []string{"a", "b"},
+				Teams:  []int64{1},
+				Labels: map[string]string{"department": "engineering"},
+			},
+		},
+		{
+			desc:             "should use auth module and auth id for identities not synced yet",
+			identity:         &authn.Identity{AuthModule: "oauth_generic", AuthID: "abc"},
+			enrichment:       &authn.Enrichment{Groups: []string{"a"}},
+			expectedIdentity: &authn.Identity{AuthModule: "oauth_generic", AuthID: "abc", Groups: []string{"a"}},
+		},
+		{
+			desc:             "should skip identity that cannot be identified",
+			identity:         &authn.Identity{},
+			enrichment:       &authn.Enrichment{Groups: []string{"a"}},
+			expectedIdentity: &authn.Identity{},
+		},
+		{
+			desc:             "should not fail authentication when enricher fails",
+			identity:         &authn.Identity{ID: "user:1"},
+			enrichErr:        errors.New("directory unavailable"),
+			expectedIdentity: &authn.Identity{ID: "user:1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			s := setupTests(t, func(svc *Service) {
+				svc.RegisterIdentityEnricher(&fakeEnricher{enrichment: tt.enrichment, err: tt.enrichErr}, 0)
+			})
+
+			err := s.enrichIdentityHook(context.Background(), tt.identity, &authn.Request{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedIdentity, tt.identity)
+		})
+	}
+}
+
+func TestService_EnrichIdentityHook_Cache(t *testing.T) {
+	now := time.Now()
+	enricher := &fakeEnricher{enrichment: &authn.Enrichment{Groups: []string{"a"}}}
+	s := setupTests(t, func(svc *Service) {
+		svc.cache = remotecache.NewFakeMemoryStore(t, func() time.Time { return now })
+		svc.RegisterIdentityEnricher(enricher, time.Minute)
+	})
+
+	enrich := func() {
+		identity := &authn.Identity{ID: "user:1"}
+		require.NoError(t, s.enrichIdentityHook(context.Background(), identity, &authn.Request{}))
+		assert.Equal(t, []string{"a"}, identity.Groups)
+	}
+
+	for i := 0; i < 3; i++ {
+		enrich()
+	}
+	assert.Equal(t, 1, enricher.calls)
+
+	// the enrichment is loaded again once the cached one has expired
+	now = now.Add(time.Minute)
+	enrich()
+	assert.Equal(t, 2, enricher.calls)
+}
+
+type fakeEnricher struct {
+	enrichment *authn.Enrichment
+	err        error
+	calls      int
+}
+
+func (f *fakeEnricher) Name() string {
+	return "fake"
+}
+
+func (f *fakeEnricher) Enrich(ctx context.Context, identity *authn.Identity, r *authn.Request) (*authn.Enrichment, error) {
+	f.calls++
+	return f.enrichment, f.err
+}
@@ -0,0 +1,210 @@
+package authnimpl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/pkg/events"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+const (
+	identityCachePrefix = "authn-identity"
+	// allIdentities is used in place of a namespaced id for the generation of all cached identities
+	allIdentities = "all"
+)
+
+// cachedIdentity holds the parts of an identity that are resolved from the database
+// together with the permissions of the identity in the organization.
+type cachedIdentity struct {
+	Login          string                        `json:"login"`
+	Name           string                        `json:"name"`
+	Email          string                        `json:"email"`
+	OrgID          int64                         `json:"orgId"`
+	OrgName        string                        `json:"orgName"`
+	OrgCount       int                           `json:"orgCount"`
+	OrgRoles       map[int64]org.RoleType        `json:"orgRoles"`
+	IsGrafanaAdmin bool                          `json:"isGrafanaAdmin"`
+	IsDisabled     bool                          `json:"isDisabled"`
+	HelpFlags1     user.HelpFlags1               `json:"helpFlags1"`
+	LastSeenAt     time.Time                     `json:"lastSeenAt"`
+	Teams          []int64                       `json:"teams"`
+	Permissions    map[int64]map[string][]string `json:"permissions"`
+}
+
+// identityCacheHook wraps the hook resolving the identity from the database. Resolved identities
+// and their permissions are cached per identity and organization so they are not assembled on every request.
+func (s *Service) identityCacheHook(fetch authn.PostAuthHookFn) authn.PostAuthHookFn {
+	return func(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+		if s.cfg.IdentityCacheTTL <= 0 || s.cache == nil || s.accessControl == nil {
+			return fetch(ctx, identity, r)
+		}
+
+		namespace, _ := identity.NamespacedID()
+		if namespace != authn.NamespaceUser && namespace != authn.NamespaceServiceAccount {
+			return fetch(ctx, identity, r)
+		}
+
+		key, err := s.identityCacheKey(ctx, identity.ID, r.OrgID)
+		if err != nil {
+			s.log.FromContext(ctx).Warn("Failed to get identity cache key", "id", identity.ID, "error", err)
+			return fetch(ctx, identity, r)
+		}
+
+		// identities that were synced during this request can have changed, so refresh the cached entry
+		if !identity.ClientParams.SyncUser {
+			if cached, ok := s.getCachedIdentity(ctx, key); ok {
+				applyCachedIdentity(identity, cached)
+				return nil
+			}
+		}
+
+		if err := fetch(ctx, identity, r); err != nil {
+			return err
+		}
+
+		permissions, err := s.accessControl.GetUserPermissions(ctx, identity.SignedInUser(), accesscontrol.Options{ReloadCache: false})
+		if err != nil {
+			// permissions are loaded per request when they are missing from the identity
+			s.log.FromContext(ctx).Warn("Failed to load permissions for identity", "id", identity.ID, "error", err)
+			return nil
+		}
+		identity.Permissions = map[int64]map[string][]string{identity.OrgID: accesscontrol.GroupScopesByAction(permissions)}
+
+		s.setCachedIdentity(ctx, key, identity)
+		return nil
+	}
+}
+
+func (s *Service) InvalidateIdentity(ctx context.Context, namespaceID string) error {
+	if s.cfg.IdentityCacheTTL <= 0 || s.cache == nil {
+		return nil
+	}
+
+	// cached entries are keyed by generation, so a new generation invalidates the entries for all organizations
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	return s.cache.SetByteArray(ctx, identityGenerationKey(namespaceID), []byte(generation), s.cfg.IdentityCacheTTL)
+}
+
+// invalidateAllIdentities removes all cached identities, it is used for changes
+// that can affect many identities such as permissions granted to a team.
+func (s *Service) invalidateAllIdentities(ctx context.Context) error {
+	if s.cfg.IdentityCacheTTL <= 0 || s.cache == nil {
+		return nil
+	}
+
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	return s.cache.SetByteArray(ctx, identityGenerationKey(allIdentities), []byte(generation), s.cfg.IdentityCacheTTL)
+}
+
+// handleIdentityChanged invalidates the cached identities affected by changes
+// to users, service accounts, teams and permissions made in their stores.
+func (s *Service) handleIdentityChanged(ctx context.Context, e *events.IdentityChanged) error {
+	if e.UserID == 0 {
+		return s.invalidateAllIdentities(ctx)
+	}
+
+	// users and service accounts share ids
+	for _, namespace := range []string{authn.NamespaceUser, authn.NamespaceServiceAccount} {
+		if err := s.InvalidateIdentity(ctx, authn.NamespacedID(namespace, e.UserID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) identityCacheKey(ctx context.Context, namespaceID string, orgID int64) (string, error) {
+	global, err := s.identityGeneration(ctx, allIdentities)
+	if err != nil {
+		return "", err
+	}
+
+	generation, err := s.identityGeneration(ctx, namespaceID)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s-%s-%d-%s-%s", identityCachePrefix, namespaceID, orgID, global, generation), nil
+}
+
+func (s *Service) identityGeneration(ctx context.Context, namespaceID string) (string, error) {
+	data, err := s.cache.GetByteArray(ctx, identityGenerationKey(namespaceID))
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return "0", nil
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+func identityGenerationKey(namespaceID string) string {
+	return fmt.Sprintf("%s-generation-%s", identityCachePrefix, namespaceID)
+}
+
+func (s *Service) getCachedIdentity(ctx context.Context, key string) (*cachedIdentity, bool) {
+	data, err := s.cache.GetByteArray(ctx, key)
+	if err != nil {
+		return nil, false
+	}
+
+	cached := &cachedIdentity{}
+	if err := json.Unmarshal(data, cached); err != nil {
+		return nil, false
+	}
+	return cached, true
+}
+
+func (s *Service) setCachedIdentity(ctx context.Context, key string, identity *authn.Identity) {
+	var isGrafanaAdmin bool
+	if identity.IsGrafanaAdmin != nil {
+		isGrafanaAdmin = *identity.IsGrafanaAdmin
+	}
+
+	data, err := json.Marshal(&cachedIdentity{
+		Login:          identity.Login,
+		Name:           identity.Name,
+		Email:          identity.Email,
+		OrgID:          identity.OrgID,
+		OrgName:        identity.OrgName,
+		OrgCount:       identity.OrgCount,
+		OrgRoles:       identity.OrgRoles,
+		IsGrafanaAdmin: isGrafanaAdmin,
+		IsDisabled:     identity.IsDisabled,
+		HelpFlags1:     identity.HelpFlags1,
+		// updating the last seen time invalidates the cached identity
+		LastSeenAt:  identity.LastSeenAt,
+		Teams:       identity.Teams,
+		Permissions: identity.Permissions,
+	})
+	if err == nil {
+		err = s.cache.SetByteArray(ctx, key, data, s.cfg.IdentityCacheTTL)
+	}
+	if err != nil {
+		s.log.FromContext(ctx).Warn("Failed to cache identity", "id", identity.ID, "error", err)
+	}
+}
+
+func applyCachedIdentity(identity *authn.Identity, cached *cachedIdentity) {
+	identity.Login = cached.Login
+	identity.Name = cached.Name
+	identity.Email = cached.Email
+	identity.OrgID = cached.OrgID
+	identity.OrgName = cached.OrgName
+	identity.OrgCount = cached.OrgCount
+	identity.OrgRoles = cached.OrgRoles
+	identity.IsGrafanaAdmin = &cached.IsGrafanaAdmin
+	identity.IsDisabled = cached.IsDisabled
+	identity.HelpFlags1 = cached.HelpFlags1
+	identity.LastSeenAt = cached.LastSeenAt
+	identity.Teams = cached.Teams
+	identity.Permissions = cached.Permissions
+}
@@ -0,0 +1,126 @@
+package authnimpl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/events"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/actest"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/org"
+)
+
+func TestService_IdentityCacheHook(t *testing.T) {
+	var fetches int
+	fetch := func(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+		fetches++
+		identity.Login = "test"
+		identity.OrgID = 2
+		identity.OrgRoles = map[int64]org.RoleType{2: org.RoleEditor}
+		return nil
+	}
+
+	s := setupTests(t, func(svc *Service) {
+		svc.cfg.IdentityCacheTTL = time.Minute
+		svc.cache = remotecache.NewFakeMemoryStore(t, nil)
+		svc.accessControl = actest.FakeService{ExpectedPermissions: []accesscontrol.Permission{
+			{Action: "dashboards:read", Scope: "dashboards:*"},
+		}}
+	})
+	hook := s.identityCacheHook(fetch)
+
+	resolve := func() *authn.Identity {
+		identity := &authn.Identity{ID: "user:1", ClientParams: authn.ClientParams{FetchSyncedUser: true}}
+		require.NoError(t, hook(context.Background(), identity, &authn.Request{OrgID: 2}))
+		return identity
+	}
+
+	for i := 0; i < 3; i++ {
+		identity := resolve()
+		assert.Equal(t, "test", identity.Login)
+		assert.Equal(t, org.RoleEditor, identity.Role())
+		assert.Equal(t, map[int64]map[string][]string{2: {"dashboards:read": {"dashboards:*"}}}, identity.Permissions)
+	}
+	assert.Equal(t, 1, fetches)
+
+	require.NoError(t, s.InvalidateIdentity(context.Background(), "user:1"))
+	resolve()
+	assert.Equal(t, 2, fetches)
+
+	// synced identities always refresh the cached entry
+	identity := &authn.Identity{ID: "user:1", ClientParams: authn.ClientParams{FetchSyncedUser: true, SyncUser: true}}
+	require.NoError(t, hook(context.Background(), identity, &authn.Request{OrgID: 2}))
+	assert.Equal(t, 3, fetches)
+}
+
+func TestService_IdentityCacheHook_Disabled(t *testing.T) {
+	var fetches int
+	fetch := func(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+		fetches++
+		return nil
+	}
+
+	s := setupTests(t, func(svc *Service) {
+		svc.cache = remotecache.NewFakeMemoryStore(t, nil)
+		svc.accessControl = actest.FakeService{}
+	})
+	hook := s.identityCacheHook(fetch)
+
+	for i := 0; i < 2; i++ {
+		identity := &authn.Identity{ID: "user:1", ClientParams: authn.ClientParams{FetchSyncedUser: true}}
+		require.NoError(t, hook(context.Background(), identity, &authn.Request{}))
+		assert.Nil(t, identity.Permissions)
+	}
+	assert.Equal(t, 2, fetches)
+}
+
+func TestService_HandleIdentityChanged(t *testing.T) {
+	var fetches int
+	lastSeen := time.Now().Add(-time.Hour)
+	fetch := func(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+		fetches++
+		identity.LastSeenAt = lastSeen
+		return nil
+	}
+
+	s := setupTests(t, func(svc *Service) {
+		svc.cfg.IdentityCacheTTL = time.Minute
+		svc.cache = remotecache.NewFakeMemoryStore(t, nil)
+		svc.accessControl = actest.FakeService{}
+	})
+	hook := s.identityCacheHook(fetch)
+
+	resolve := func(id string) *authn.Identity {
+		identity := &authn.Identity{ID: id, ClientParams: authn.ClientParams{FetchSyncedUser: true}}
+		require.NoError(t, hook(context.Background(), identity, &authn.Request{OrgID: 1}))
+		return identity
+	}
+
+	resolve("user:1")
+	resolve("service-account:2")
+	assert.Equal(t, 2, fetches)
+
+	// the last seen time is kept so that it is still updated for cached identities
+	assert.Equal(t, lastSeen.Unix(), resolve("user:1").LastSeenAt.Unix())
+	assert.Equal(t, 2, fetches)
+
+	t.Run("should invalidate the changed identity", func(t *testing.T) {
+		require.NoError(t, s.handleIdentityChanged(context.Background(), &events.IdentityChanged{UserID: 1}))
+		resolve("user:1")
+		resolve("service-account:2")
+		assert.Equal(t, 3, fetches)
+	})
+
+	t.Run("should invalidate all identities for changes without an identity", func(t *testing.T) {
+		require.NoError(t, s.handleIdentityChanged(context.Background(), &events.IdentityChanged{}))
+		resolve("user:1")
+		resolve("service-account:2")
+		assert.Equal(t, 5, fetches)
+	})
+}
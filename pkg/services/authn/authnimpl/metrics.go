@@ -0,0 +1,28 @@
+package authnimpl
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	metricsNamespace = "grafana"
+	metricsSubsystem = "authn"
+)
+
+var (
+	authenticationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "authentications_total",
+		Help:      "Number of authentication attempts per client and result",
+	}, []string{"client", "result"})
+
+	authenticationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "authentication_duration_seconds",
+		Help:      "Duration of authentication attempts per client, including post auth hooks",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"client"})
+)
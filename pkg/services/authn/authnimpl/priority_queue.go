@@ -32,3 +32,23 @@ func (q *queue[T]) insert(v T, p uint) {
 	// item did not have higher priority then what is in the queue currently, so we need to add it to the end
 	q.items = append(q.items, queueItem[T]{v, p})
 }
+
+// remove deletes all items from the queue where fn returns true
+func (q *queue[T]) remove(fn func(v T) bool) {
+	items := make([]queueItem[T], 0, len(q.items))
+	for _, item := range q.items {
+		if !fn(item.v) {
+			items = append(items, item)
+		}
+	}
+	q.items = items
+}
+
+// values returns a copy of all values in the queue ordered by priority
+func (q *queue[T]) values() []T {
+	values := make([]T, 0, len(q.items))
+	for _, item := range q.items {
+		values = append(values, item.v)
+	}
+	return values
+}
@@ -0,0 +1,167 @@
+package authnimpl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/apikey"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func (s *Service) RegisterIdentityResolver(namespace string, resolver authn.IdentityResolverFn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.resolvers[namespace]; ok {
+		s.log.Warn("Replacing already registered identity resolver", "namespace", namespace)
+	}
+	s.resolvers[namespace] = resolver
+}
+
+func (s *Service) ResolveIdentity(ctx context.Context, orgID int64, namespaceID string) (*authn.Identity, error) {
+	namespace, id, err := authn.ParseNamespaceID(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	resolver, ok := s.resolvers[namespace]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, authn.ErrUnsupportedIdentity.Errorf("no resolver registered for namespace: %s", namespace)
+	}
+
+	return resolver(ctx, orgID, id)
+}
+
+// identityResolvers resolves identities for the namespaces known to Grafana
+type identityResolvers struct {
+	cfg           *setting.Cfg
+	userService   user.Service
+	apikeyService apikey.Service
+	orgService    org.Service
+}
+
+func (r *identityResolvers) register(s *Service) {
+	s.RegisterIdentityResolver(authn.NamespaceUser, r.resolveUser)
+	s.RegisterIdentityResolver(authn.NamespaceServiceAccount, r.resolveServiceAccount)
+	s.RegisterIdentityResolver(authn.NamespaceAPIKey, r.resolveAPIKey)
+	s.RegisterIdentityResolver(authn.NamespaceRenderService, r.resolveRenderService)
+	// provisioning identities are not resolved, they would have the admin role in any organization
+	if r.cfg.AnonymousEnabled {
+		s.RegisterIdentityResolver(authn.NamespaceAnonymous, r.resolveAnonymous)
+	}
+}
+
+func (r *identityResolvers) resolveUser(ctx context.Context, orgID int64, id string) (*authn.Identity, error) {
+	usr, err := r.getSignedInUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if usr.IsServiceAccount {
+		return nil, authn.ErrUnsupportedIdentity.Errorf("user %d is a service account", usr.UserID)
+	}
+
+	return authn.IdentityFromSignedInUser(authn.NamespacedID(authn.NamespaceUser, usr.UserID), usr, authn.ClientParams{}), nil
+}
+
+func (r *identityResolvers) resolveServiceAccount(ctx context.Context, orgID int64, id string) (*authn.Identity, error) {
+	usr, err := r.getSignedInUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !usr.IsServiceAccount {
+		return nil, authn.ErrUnsupportedIdentity.Errorf("user %d is not a service account", usr.UserID)
+	}
+
+	return authn.IdentityFromSignedInUser(authn.NamespacedID(authn.NamespaceServiceAccount, usr.UserID), usr, authn.ClientParams{}), nil
+}
+
+func (r *identityResolvers) getSignedInUser(ctx context.Context, orgID int64, id string) (*user.SignedInUser, error) {
+	userID, err := parseNumericID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.userService.GetSignedInUserWithCacheCtx(ctx, &user.GetSignedInUserQuery{UserID: userID, OrgID: orgID})
+}
+
+func (r *identityResolvers) resolveAPIKey(ctx context.Context, orgID int64, id string) (*authn.Identity, error) {
+	keyID, err := parseNumericID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &apikey.GetByIDQuery{ApiKeyID: keyID}
+	if err := r.apikeyService.GetApiKeyById(ctx, query); err != nil {
+		return nil, err
+	}
+
+	key := query.Result
+	if key.Expires != nil && *key.Expires <= time.Now().Unix() {
+		return nil, authn.ErrUnsupportedIdentity.Errorf("api key %d has expired", keyID)
+	}
+	if key.IsRevoked != nil && *key.IsRevoked {
+		return nil, authn.ErrUnsupportedIdentity.Errorf("api key %d is revoked", keyID)
+	}
+
+	if orgID > 0 && key.OrgID != orgID {
+		return nil, authn.ErrUnsupportedIdentity.Errorf("api key %d does not belong to organization %d", keyID, orgID)
+	}
+
+	// api keys belonging to a service account are resolved as the service account
+	if key.ServiceAccountId != nil && *key.ServiceAccountId > 0 {
+		return r.resolveServiceAccount(ctx, key.OrgID, strconv.FormatInt(*key.ServiceAccountId, 10))
+	}
+
+	return &authn.Identity{
+		ID:       authn.NamespacedID(authn.NamespaceAPIKey, key.ID),
+		OrgID:    key.OrgID,
+		OrgRoles: map[int64]org.RoleType{key.OrgID: key.Role},
+	}, nil
+}
+
+// resolveRenderService resolves the identity used by the image renderer when rendering
+// without a user, it has the viewer role in the organization.
+func (r *identityResolvers) resolveRenderService(ctx context.Context, orgID int64, id string) (*authn.Identity, error) {
+	return &authn.Identity{
+		ID:       authn.NamespacedID(authn.NamespaceRenderService, 0),
+		OrgID:    orgID,
+		OrgRoles: map[int64]org.RoleType{orgID: org.RoleViewer},
+	}, nil
+}
+
+// resolveAnonymous resolves the anonymous identity, it can only be resolved in the organization
+// configured for anonymous access.
+func (r *identityResolvers) resolveAnonymous(ctx context.Context, orgID int64, id string) (*authn.Identity, error) {
+	o, err := r.orgService.GetByName(ctx, &org.GetOrgByNameQuery{Name: r.cfg.AnonymousOrgName})
+	if err != nil {
+		return nil, err
+	}
+
+	if orgID > 0 && o.ID != orgID {
+		return nil, authn.ErrUnsupportedIdentity.Errorf("anonymous access is not enabled for organization %d", orgID)
+	}
+
+	return &authn.Identity{
+		ID:          authn.NamespacedID(authn.NamespaceAnonymous, 0),
+		IsAnonymous: true,
+		OrgID:       o.ID,
+		OrgName:     o.Name,
+		OrgRoles:    map[int64]org.RoleType{o.ID: org.RoleType(r.cfg.AnonymousOrgRole)},
+	}, nil
+}
+
+func parseNumericID(id string) (int64, error) {
+	v, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, authn.ErrInvalidNamespaceID.Errorf("expected numeric id but got: %s", id)
+	}
+	return v, nil
+}
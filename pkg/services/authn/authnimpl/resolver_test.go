@@ -0,0 +1,143 @@
+package authnimpl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/apikey"
+	"github.com/grafana/grafana/pkg/services/apikey/apikeytest"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+func TestService_ResolveIdentity(t *testing.T) {
+	type testCase struct {
+		desc             string
+		namespaceID      string
+		signedInUser     *user.SignedInUser
+		apiKey           *apikey.APIKey
+		expectedIdentity *authn.Identity
+		expectedErr      error
+	}
+
+	tests := []testCase{
+		{
+			desc:         "should resolve user",
+			namespaceID:  "user:1",
+			signedInUser: &user.SignedInUser{UserID: 1, OrgID: 2, OrgRole: org.RoleEditor, Login: "test"},
+			expectedIdentity: &authn.Identity{
+				ID:             "user:1",
+				OrgID:          2,
+				OrgRoles:       map[int64]org.RoleType{2: org.RoleEditor},
+				Login:          "test",
+				IsGrafanaAdmin: boolPtr(false),
+			},
+		},
+		{
+			desc:         "should resolve service account",
+			namespaceID:  "service-account:1",
+			signedInUser: &user.SignedInUser{UserID: 1, OrgID: 2, OrgRole: org.RoleViewer, Login: "sa", IsServiceAccount: true},
+			expectedIdentity: &authn.Identity{
+				ID:             "service-account:1",
+				OrgID:          2,
+				OrgRoles:       map[int64]org.RoleType{2: org.RoleViewer},
+				Login:          "sa",
+				IsGrafanaAdmin: boolPtr(false),
+			},
+		},
+		{
+			desc:         "should not resolve service account as user",
+			namespaceID:  "user:1",
+			signedInUser: &user.SignedInUser{UserID: 1, OrgID: 2, IsServiceAccount: true},
+			expectedErr:  authn.ErrUnsupportedIdentity,
+		},
+		{
+			desc:        "should resolve api key",
+			namespaceID: "api-key:1",
+			apiKey:      &apikey.APIKey{ID: 1, OrgID: 2, Role: org.RoleViewer},
+			expectedIdentity: &authn.Identity{
+				ID:       "api-key:1",
+				OrgID:    2,
+				OrgRoles: map[int64]org.RoleType{2: org.RoleViewer},
+			},
+		},
+		{
+			desc:        "should not resolve expired api key",
+			namespaceID: "api-key:1",
+			apiKey:      &apikey.APIKey{ID: 1, OrgID: 2, Role: org.RoleViewer, Expires: int64Ptr(0)},
+			expectedErr: authn.ErrUnsupportedIdentity,
+		},
+		{
+			desc:        "should not resolve revoked api key",
+			namespaceID: "api-key:1",
+			apiKey:      &apikey.APIKey{ID: 1, OrgID: 2, Role: org.RoleViewer, IsRevoked: boolPtr(true)},
+			expectedErr: authn.ErrUnsupportedIdentity,
+		},
+		{
+			desc:        "should not resolve provisioning identity",
+			namespaceID: "provisioning:0",
+			expectedErr: authn.ErrUnsupportedIdentity,
+		},
+		{
+			desc:        "should fail for namespace without resolver",
+			namespaceID: "workload:1",
+			expectedErr: authn.ErrUnsupportedIdentity,
+		},
+		{
+			desc:        "should fail for invalid namespaced id",
+			namespaceID: "user",
+			expectedErr: authn.ErrInvalidNamespaceID,
+		},
+		{
+			desc:        "should fail for non numeric user id",
+			namespaceID: "user:abc",
+			expectedErr: authn.ErrInvalidNamespaceID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			s := setupTests(t, func(svc *Service) {
+				resolvers := &identityResolvers{
+					cfg:           svc.cfg,
+					userService:   &usertest.FakeUserService{ExpectedSignedInUser: tt.signedInUser},
+					apikeyService: &apikeytest.Service{ExpectedAPIKey: tt.apiKey},
+				}
+				resolvers.register(svc)
+			})
+
+			identity, err := s.ResolveIdentity(context.Background(), 2, tt.namespaceID)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedIdentity, identity)
+		})
+	}
+}
+
+func TestService_RegisterIdentityResolver(t *testing.T) {
+	s := setupTests(t)
+	s.RegisterIdentityResolver("workload", func(ctx context.Context, orgID int64, id string) (*authn.Identity, error) {
+		return &authn.Identity{ID: authn.NamespacedID("workload", 0), OrgID: orgID, Login: id}, nil
+	})
+
+	identity, err := s.ResolveIdentity(context.Background(), 1, "workload:payments")
+	require.NoError(t, err)
+	assert.Equal(t, &authn.Identity{ID: "workload:0", OrgID: 1, Login: "payments"}, identity)
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func int64Ptr(n int64) *int64 {
+	return &n
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/network"
 	"github.com/grafana/grafana/pkg/infra/remotecache"
@@ -19,7 +22,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/apikey"
 	"github.com/grafana/grafana/pkg/services/auth"
 	"github.com/grafana/grafana/pkg/services/authn"
-	"github.com/grafana/grafana/pkg/services/authn/authnimpl/sync"
+	authnsync "github.com/grafana/grafana/pkg/services/authn/authnimpl/sync"
 	"github.com/grafana/grafana/pkg/services/authn/clients"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/ldap/service"
@@ -60,26 +63,33 @@ func ProvideService(
 	authInfoService login.AuthInfoService, renderService rendering.Service,
 	features *featuremgmt.FeatureManager, oauthTokenService oauthtoken.OAuthTokenService,
 	socialService social.Service, cache *remotecache.RemoteCache,
-	ldapService service.LDAP,
+	ldapService service.LDAP, bus bus.Bus,
 ) *Service {
 	s := &Service{
-		log:            log.New("authn.service"),
-		cfg:            cfg,
-		clients:        make(map[string]authn.Client),
-		clientQueue:    newQueue[authn.ContextAwareClient](),
-		tracer:         tracer,
-		sessionService: sessionService,
-		postAuthHooks:  newQueue[authn.PostAuthHookFn](),
-		postLoginHooks: newQueue[authn.PostLoginHookFn](),
+		log:               log.New("authn.service"),
+		cfg:               cfg,
+		clients:           make(map[string]authn.Client),
+		clientQueue:       newQueue[authn.ContextAwareClient](),
+		resolvers:         make(map[string]authn.IdentityResolverFn),
+		tracer:            tracer,
+		cache:             cache,
+		sessionService:    sessionService,
+		accessControl:     accessControlService,
+		postAuthHooks:     newQueue[authn.PostAuthHookFn](),
+		postAuthObservers: newQueue[authn.PostAuthHookFn](),
+		postLoginHooks:    newQueue[authn.PostLoginHookFn](),
 	}
 
 	usageStats.RegisterMetricsFunc(s.getUsageStats)
 
-	s.RegisterClient(clients.ProvideRender(userService, renderService))
-	s.RegisterClient(clients.ProvideAPIKey(apikeyService, userService))
+	resolvers := &identityResolvers{cfg: cfg, userService: userService, apikeyService: apikeyService, orgService: orgService}
+	resolvers.register(s)
+
+	s.RegisterClient(clients.ProvideRender(s, renderService))
+	s.RegisterClient(clients.ProvideAPIKey(apikeyService, s))
 
 	if cfg.LoginCookieName != "" {
-		s.RegisterClient(clients.ProvideSession(sessionService, userService, cfg.LoginCookieName, cfg.LoginMaxLifetime))
+		s.RegisterClient(clients.ProvideSession(sessionService, userService, clients.NewSessionOrgs(cache, cfg.LoginMaxLifetime), cfg.LoginCookieName, cfg.LoginMaxLifetime))
 	}
 
 	if s.cfg.AnonymousEnabled {
@@ -140,19 +150,23 @@ func ProvideService(
 		}
 	}
 
+	// enrichment needs to run before the user is synced so that groups from enrichers are synced as well
+	s.RegisterPostAuthHook(s.enrichIdentityHook, 5)
+
 	// FIXME (jguer): move to User package
-	userSyncService := sync.ProvideUserSync(userService, userProtectionService, authInfoService, quotaService)
-	orgUserSyncService := sync.ProvideOrgSync(userService, orgService, accessControlService)
+	userSyncService := authnsync.ProvideUserSync(userService, userProtectionService, authInfoService, quotaService)
+	orgUserSyncService := authnsync.ProvideOrgSync(userService, orgService, accessControlService)
 	s.RegisterPostAuthHook(userSyncService.SyncUserHook, 10)
 	s.RegisterPostAuthHook(userSyncService.EnableDisabledUserHook, 20)
 	s.RegisterPostAuthHook(orgUserSyncService.SyncOrgRolesHook, 30)
-	s.RegisterPostAuthHook(userSyncService.SyncLastSeenHook, 40)
+	s.RegisterPostAuthObserver(userSyncService.SyncLastSeenHook, 10)
 
 	if features.IsEnabled(featuremgmt.FlagAccessTokenExpirationCheck) {
-		s.RegisterPostAuthHook(sync.ProvideOAuthTokenSync(oauthTokenService, sessionService).SyncOauthTokenHook, 60)
+		s.RegisterPostAuthHook(authnsync.ProvideOAuthTokenSync(oauthTokenService, sessionService).SyncOauthTokenHook, 60)
 	}
 
-	s.RegisterPostAuthHook(userSyncService.FetchSyncedUserHook, 100)
+	s.RegisterPostAuthHook(s.identityCacheHook(userSyncService.FetchSyncedUserHook), 100)
+	bus.AddEventListener(s.handleIdentityChanged)
 
 	return s
 }
@@ -161,14 +175,25 @@ type Service struct {
 	log log.Logger
 	cfg *setting.Cfg
 
+	// mu guards clients, clientQueue, resolvers and the hook queues, they can be registered after the service has been initialized
+	mu          sync.RWMutex
 	clients     map[string]authn.Client
 	clientQueue *queue[authn.ContextAwareClient]
+	// resolvers resolve identities from namespaced ids per namespace
+	resolvers map[string]authn.IdentityResolverFn
 
 	tracer         tracing.Tracer
+	cache          enrichmentCache
 	sessionService auth.UserTokenService
+	accessControl  accesscontrol.Service
+
+	// enrichers are used to add information from external systems to authenticated identities
+	enrichers []identityEnricher
 
 	// postAuthHooks are called after a successful authentication. They can modify the identity.
 	postAuthHooks *queue[authn.PostAuthHookFn]
+	// postAuthObservers are called after a request is fully authenticated. Errors are only logged.
+	postAuthObservers *queue[authn.PostAuthHookFn]
 	// postLoginHooks are called after a login request is performed, both for failing and successful requests.
 	postLoginHooks *queue[authn.PostLoginHookFn]
 }
@@ -178,9 +203,9 @@ func (s *Service) Authenticate(ctx context.Context, r *authn.Request) (*authn.Id
 	defer span.End()
 
 	var authErr error
-	for _, item := range s.clientQueue.items {
-		if item.v.Test(ctx, r) {
-			identity, err := s.authenticate(ctx, item.v, r)
+	for _, client := range s.contextAwareClients() {
+		if client.Test(ctx, r) {
+			identity, err := s.authenticate(ctx, client, r)
 			if err != nil {
 				authErr = multierror.Append(authErr, err)
 				// try next
@@ -200,16 +225,26 @@ func (s *Service) Authenticate(ctx context.Context, r *authn.Request) (*authn.Id
 	return nil, errCantAuthenticateReq.Errorf("cannot authenticate request")
 }
 
-func (s *Service) authenticate(ctx context.Context, c authn.Client, r *authn.Request) (*authn.Identity, error) {
+func (s *Service) authenticate(ctx context.Context, c authn.Client, r *authn.Request) (identity *authn.Identity, err error) {
+	start := time.Now()
+	defer func() {
+		result := "success"
+		if err != nil {
+			result = "failure"
+		}
+		authenticationsTotal.WithLabelValues(c.Name(), result).Inc()
+		authenticationDuration.WithLabelValues(c.Name()).Observe(time.Since(start).Seconds())
+	}()
+
 	r.OrgID = orgIDFromRequest(r)
-	identity, err := c.Authenticate(ctx, r)
+	identity, err = c.Authenticate(ctx, r)
 	if err != nil {
 		s.log.FromContext(ctx).Warn("Failed to authenticate request", "client", c.Name(), "error", err)
 		return nil, err
 	}
 
-	for _, hook := range s.postAuthHooks.items {
-		if err := hook.v(ctx, identity, r); err != nil {
+	for _, hook := range s.hooks(s.postAuthHooks) {
+		if err := hook(ctx, identity, r); err != nil {
 			s.log.FromContext(ctx).Warn("Failed to run post auth hook", "client", c.Name(), "id", identity.ID, "error", err)
 			return nil, err
 		}
@@ -226,21 +261,42 @@ func (s *Service) authenticate(ctx context.Context, c authn.Client, r *authn.Req
 		}
 	}
 
+	for _, observer := range s.hooks(s.postAuthObservers) {
+		if err := observer(ctx, identity, r); err != nil {
+			s.log.FromContext(ctx).Warn("Failed to run post auth observer", "client", c.Name(), "id", identity.ID, "error", err)
+		}
+	}
+
 	return identity, nil
 }
 
 func (s *Service) RegisterPostAuthHook(hook authn.PostAuthHookFn, priority uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.postAuthHooks.insert(hook, priority)
 }
 
+func (s *Service) RegisterPostAuthObserver(hook authn.PostAuthHookFn, priority uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.postAuthObservers.insert(hook, priority)
+}
+
+// hooks returns a copy of the hooks in q so they can be called without holding s.mu.
+func (s *Service) hooks(q *queue[authn.PostAuthHookFn]) []authn.PostAuthHookFn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return q.values()
+}
+
 func (s *Service) Login(ctx context.Context, client string, r *authn.Request) (identity *authn.Identity, err error) {
 	defer func() {
-		for _, hook := range s.postLoginHooks.items {
-			hook.v(ctx, identity, r, err)
+		for _, hook := range s.loginHooks() {
+			hook(ctx, identity, r, err)
 		}
 	}()
 
-	c, ok := s.clients[client]
+	c, ok := s.getClient(client)
 	if !ok {
 		return nil, authn.ErrClientNotConfigured.Errorf("client not configured: %s", client)
 	}
@@ -274,15 +330,23 @@ func (s *Service) Login(ctx context.Context, client string, r *authn.Request) (i
 }
 
 func (s *Service) RegisterPostLoginHook(hook authn.PostLoginHookFn, priority uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.postLoginHooks.insert(hook, priority)
 }
 
+func (s *Service) loginHooks() []authn.PostLoginHookFn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.postLoginHooks.values()
+}
+
 func (s *Service) RedirectURL(ctx context.Context, client string, r *authn.Request) (*authn.Redirect, error) {
 	ctx, span := s.tracer.Start(ctx, "authn.RedirectURL")
 	defer span.End()
 	span.SetAttributes(attributeKeyClient, client, attribute.Key(attributeKeyClient).String(client))
 
-	c, ok := s.clients[client]
+	c, ok := s.getClient(client)
 	if !ok {
 		return nil, authn.ErrClientNotConfigured.Errorf("client not configured: %s", client)
 	}
@@ -296,10 +360,104 @@ func (s *Service) RedirectURL(ctx context.Context, client string, r *authn.Reque
 }
 
 func (s *Service) RegisterClient(c authn.Client) {
-	s.clients[c.Name()] = c
+	if s.isClientDisabled(c.Name()) {
+		s.log.Debug("Skipping registration of disabled client", "client", c.Name())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.register(c)
 	if cac, ok := c.(authn.ContextAwareClient); ok {
-		s.clientQueue.insert(cac, cac.Priority())
+		s.clientQueue.insert(cac, s.clientPriority(cac.Name(), cac.Priority()))
+	}
+}
+
+func (s *Service) RegisterContextAwareClient(c authn.Client, priority uint, test authn.TestFn) {
+	if s.isClientDisabled(c.Name()) {
+		s.log.Debug("Skipping registration of disabled client", "client", c.Name())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.register(c)
+	s.clientQueue.insert(&contextAwareClient{Client: c, priority: priority, test: test}, s.clientPriority(c.Name(), priority))
+}
+
+// isClientDisabled returns true if the client is listed in [auth.clients] disabled
+func (s *Service) isClientDisabled(name string) bool {
+	for _, disabled := range s.cfg.AuthClientsDisabled {
+		if authn.ClientWithPrefix(disabled) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// clientPriority returns the priority configured for a client through [auth.clients] order.
+// Clients not listed keep their default priority but are tried after all listed clients.
+func (s *Service) clientPriority(name string, priority uint) uint {
+	for i, ordered := range s.cfg.AuthClientsOrder {
+		if authn.ClientWithPrefix(ordered) == name {
+			return uint(i)
+		}
+	}
+	return uint(len(s.cfg.AuthClientsOrder)) + priority
+}
+
+// register adds the client to the set of known clients, replacing any client that
+// was previously registered with the same name. Caller must hold s.mu.
+func (s *Service) register(c authn.Client) {
+	name := c.Name()
+	if _, ok := s.clients[name]; ok {
+		s.log.Warn("Replacing already registered client", "client", name)
+		s.clientQueue.remove(func(v authn.ContextAwareClient) bool { return v.Name() == name })
+	}
+	s.clients[name] = c
+}
+
+func (s *Service) getClient(name string) (authn.Client, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.clients[name]
+	return c, ok
+}
+
+func (s *Service) contextAwareClients() []authn.ContextAwareClient {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.clientQueue.values()
+}
+
+var _ authn.ContextAwareClient = new(contextAwareClient)
+var _ authn.HookClient = new(contextAwareClient)
+
+// contextAwareClient wraps a client registered through RegisterContextAwareClient
+type contextAwareClient struct {
+	authn.Client
+	priority uint
+	test     authn.TestFn
+}
+
+func (c *contextAwareClient) Test(ctx context.Context, r *authn.Request) bool {
+	if c.test == nil {
+		return false
+	}
+	return c.test(ctx, r)
+}
+
+func (c *contextAwareClient) Priority() uint {
+	return c.priority
+}
+
+func (c *contextAwareClient) Hook(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+	if hc, ok := c.Client.(authn.HookClient); ok {
+		return hc.Hook(ctx, identity, r)
 	}
+	return nil
 }
 
 func orgIDFromRequest(r *authn.Request) int64 {
@@ -6,8 +6,10 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"sync"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -108,6 +110,20 @@ func TestService_Authenticate(t *testing.T) {
 	}
 }
 
+func TestService_AuthenticateMetrics(t *testing.T) {
+	svc := setupTests(t, func(svc *Service) {
+		svc.RegisterClient(&authntest.FakeClient{ExpectedName: "metrics-ok", ExpectedPriority: 1, ExpectedTest: true, ExpectedIdentity: &authn.Identity{ID: "user:1"}})
+		svc.RegisterClient(&authntest.FakeClient{ExpectedName: "metrics-failing", ExpectedPriority: 0, ExpectedTest: true, ExpectedErr: errors.New("failed")})
+	})
+
+	_, err := svc.Authenticate(context.Background(), &authn.Request{})
+	require.NoError(t, err)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(authenticationsTotal.WithLabelValues("metrics-ok", "success")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(authenticationsTotal.WithLabelValues("metrics-ok", "failure")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(authenticationsTotal.WithLabelValues("metrics-failing", "failure")))
+}
+
 func TestService_OrgID(t *testing.T) {
 	type TestCase struct {
 		desc          string
@@ -191,6 +207,89 @@ func TestService_HookClient(t *testing.T) {
 	require.True(t, hookCalled)
 }
 
+func TestService_PostAuthHooks(t *testing.T) {
+	t.Run("should stop calling hooks and fail authentication when a hook returns an error", func(t *testing.T) {
+		var called []string
+		s := setupTests(t, func(svc *Service) {
+			svc.RegisterClient(&authntest.FakeClient{ExpectedTest: true, ExpectedIdentity: &authn.Identity{ID: "user:1"}})
+			svc.RegisterPostAuthHook(func(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+				called = append(called, "2")
+				return nil
+			}, 2)
+			svc.RegisterPostAuthHook(func(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+				called = append(called, "1")
+				return errors.New("hook failed")
+			}, 1)
+			svc.RegisterPostAuthObserver(func(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+				called = append(called, "observer")
+				return nil
+			}, 1)
+		})
+
+		identity, err := s.Authenticate(context.Background(), &authn.Request{})
+		assert.Error(t, err)
+		assert.Nil(t, identity)
+		assert.Equal(t, []string{"1"}, called)
+	})
+
+	t.Run("should call observers in order and ignore their errors", func(t *testing.T) {
+		var called []string
+		s := setupTests(t, func(svc *Service) {
+			svc.RegisterClient(&authntest.FakeClient{ExpectedTest: true, ExpectedIdentity: &authn.Identity{ID: "user:1"}})
+			svc.RegisterPostAuthObserver(func(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+				called = append(called, "2")
+				return nil
+			}, 2)
+			svc.RegisterPostAuthObserver(func(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+				called = append(called, "1")
+				return errors.New("observer failed")
+			}, 1)
+		})
+
+		identity, err := s.Authenticate(context.Background(), &authn.Request{})
+		require.NoError(t, err)
+		assert.Equal(t, "user:1", identity.ID)
+		assert.Equal(t, []string{"1", "2"}, called)
+	})
+
+	t.Run("should not call observers for disabled identity", func(t *testing.T) {
+		called := false
+		s := setupTests(t, func(svc *Service) {
+			svc.RegisterClient(&authntest.FakeClient{ExpectedTest: true, ExpectedIdentity: &authn.Identity{ID: "user:1", IsDisabled: true}})
+			svc.RegisterPostAuthObserver(func(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+				called = true
+				return nil
+			}, 1)
+		})
+
+		_, err := s.Authenticate(context.Background(), &authn.Request{})
+		assert.ErrorIs(t, err, errDisabledIdentity)
+		assert.False(t, called)
+	})
+
+	t.Run("should allow registering hooks while authenticating", func(t *testing.T) {
+		s := setupTests(t, func(svc *Service) {
+			svc.RegisterClient(&authntest.FakeClient{ExpectedTest: true, ExpectedIdentity: &authn.Identity{ID: "user:1"}})
+		})
+		noop := func(ctx context.Context, identity *authn.Identity, r *authn.Request) error { return nil }
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				s.RegisterPostAuthHook(noop, uint(i))
+				s.RegisterPostAuthObserver(noop, uint(i))
+			}
+		}()
+		for i := 0; i < 100; i++ {
+			_, err := s.Authenticate(context.Background(), &authn.Request{})
+			require.NoError(t, err)
+		}
+		wg.Wait()
+	})
+}
+
 func TestService_Login(t *testing.T) {
 	type TestCase struct {
 		desc   string
@@ -299,6 +398,83 @@ func TestService_RedirectURL(t *testing.T) {
 	}
 }
 
+func TestService_RegisterClient(t *testing.T) {
+	t.Run("should replace already registered client with the same name", func(t *testing.T) {
+		s := setupTests(t, func(svc *Service) {
+			svc.RegisterClient(&authntest.FakeClient{ExpectedName: "fake", ExpectedTest: true, ExpectedIdentity: &authn.Identity{ID: "user:1"}})
+			svc.RegisterClient(&authntest.FakeClient{ExpectedName: "fake", ExpectedTest: true, ExpectedIdentity: &authn.Identity{ID: "user:2"}})
+		})
+
+		require.Len(t, s.clientQueue.items, 1)
+		identity, err := s.Authenticate(context.Background(), &authn.Request{})
+		require.NoError(t, err)
+		assert.Equal(t, "user:2", identity.ID)
+	})
+
+	t.Run("should try context aware client registered with test function", func(t *testing.T) {
+		hookCalled := false
+		s := setupTests(t, func(svc *Service) {
+			svc.RegisterClient(&authntest.FakeClient{ExpectedName: "1", ExpectedPriority: 1, ExpectedTest: false})
+			svc.RegisterContextAwareClient(authntest.MockClient{
+				NameFunc: func() string { return "custom" },
+				AuthenticateFunc: func(ctx context.Context, r *authn.Request) (*authn.Identity, error) {
+					return &authn.Identity{ID: "user:3"}, nil
+				},
+				HookFunc: func(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+					hookCalled = true
+					return nil
+				},
+			}, 2, func(ctx context.Context, r *authn.Request) bool { return true })
+		})
+
+		identity, err := s.Authenticate(context.Background(), &authn.Request{})
+		require.NoError(t, err)
+		assert.Equal(t, "user:3", identity.ID)
+		assert.True(t, hookCalled)
+
+		_, ok := s.getClient("custom")
+		assert.True(t, ok)
+	})
+
+	t.Run("should skip context aware client when test function returns false", func(t *testing.T) {
+		s := setupTests(t, func(svc *Service) {
+			svc.RegisterContextAwareClient(&authntest.FakeClient{ExpectedName: "custom", ExpectedIdentity: &authn.Identity{ID: "user:1"}}, 1,
+				func(ctx context.Context, r *authn.Request) bool { return false })
+		})
+
+		_, err := s.Authenticate(context.Background(), &authn.Request{})
+		assert.ErrorIs(t, err, errCantAuthenticateReq)
+	})
+}
+
+func TestService_ClientsConfig(t *testing.T) {
+	t.Run("should not register disabled client", func(t *testing.T) {
+		s := setupTests(t, func(svc *Service) {
+			svc.cfg.AuthClientsDisabled = []string{"session"}
+			svc.RegisterClient(&authntest.FakeClient{ExpectedName: authn.ClientSession, ExpectedTest: true})
+		})
+
+		_, ok := s.getClient(authn.ClientSession)
+		assert.False(t, ok)
+		assert.Empty(t, s.clientQueue.items)
+	})
+
+	t.Run("should try clients in configured order before other clients", func(t *testing.T) {
+		s := setupTests(t, func(svc *Service) {
+			svc.cfg.AuthClientsOrder = []string{"session", "jwt"}
+			svc.RegisterClient(&authntest.FakeClient{ExpectedName: authn.ClientRender, ExpectedPriority: 10})
+			svc.RegisterClient(&authntest.FakeClient{ExpectedName: authn.ClientJWT, ExpectedPriority: 20})
+			svc.RegisterClient(&authntest.FakeClient{ExpectedName: authn.ClientSession, ExpectedPriority: 60})
+		})
+
+		var order []string
+		for _, c := range s.contextAwareClients() {
+			order = append(order, c.Name())
+		}
+		assert.Equal(t, []string{authn.ClientSession, authn.ClientJWT, authn.ClientRender}, order)
+	})
+}
+
 func mustParseURL(s string) *url.URL {
 	u, err := url.Parse(s)
 	if err != nil {
@@ -311,13 +487,15 @@ func setupTests(t *testing.T, opts ...func(svc *Service)) *Service {
 	t.Helper()
 
 	s := &Service{
-		log:            log.NewNopLogger(),
-		cfg:            setting.NewCfg(),
-		clients:        map[string]authn.Client{},
-		clientQueue:    newQueue[authn.ContextAwareClient](),
-		tracer:         tracing.InitializeTracerForTest(),
-		postAuthHooks:  newQueue[authn.PostAuthHookFn](),
-		postLoginHooks: newQueue[authn.PostLoginHookFn](),
+		log:               log.NewNopLogger(),
+		cfg:               setting.NewCfg(),
+		clients:           map[string]authn.Client{},
+		clientQueue:       newQueue[authn.ContextAwareClient](),
+		resolvers:         map[string]authn.IdentityResolverFn{},
+		tracer:            tracing.InitializeTracerForTest(),
+		postAuthHooks:     newQueue[authn.PostAuthHookFn](),
+		postAuthObservers: newQueue[authn.PostAuthHookFn](),
+		postLoginHooks:    newQueue[authn.PostLoginHookFn](),
 	}
 
 	for _, o := range opts {
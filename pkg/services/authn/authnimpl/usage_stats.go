@@ -38,7 +38,14 @@ func (s *Service) getUsageStats(ctx context.Context) (map[string]interface{}, er
 		m["stats.authz.editors_can_admin.count"] = 1
 	}
 
+	s.mu.RLock()
+	clients := make([]authn.Client, 0, len(s.clients))
 	for _, client := range s.clients {
+		clients = append(clients, client)
+	}
+	s.mu.RUnlock()
+
+	for _, client := range clients {
 		if usac, ok := client.(authn.UsageStatClient); ok {
 			clientStats, err := usac.UsageStatFn(ctx)
 			if err != nil {
@@ -10,6 +10,10 @@ type FakeService struct {
 	authn.Service
 }
 
+func (f *FakeService) InvalidateIdentity(ctx context.Context, namespaceID string) error {
+	return nil
+}
+
 var _ authn.ContextAwareClient = new(FakeClient)
 
 type FakeClient struct {
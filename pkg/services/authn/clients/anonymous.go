@@ -5,12 +5,17 @@ import (
 	"strings"
 
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/middleware/cookies"
 	"github.com/grafana/grafana/pkg/services/anonymous"
 	"github.com/grafana/grafana/pkg/services/authn"
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
 )
 
+// deviceCookieMaxAge is the lifetime of the device id cookie in seconds
+const deviceCookieMaxAge = 365 * 24 * 60 * 60
+
 var _ authn.ContextAwareClient = new(Anonymous)
 
 func ProvideAnonymous(cfg *setting.Cfg, orgService org.Service, anonSessionService anonymous.Service) *Anonymous {
@@ -51,6 +56,12 @@ func (a *Anonymous) Authenticate(ctx context.Context, r *authn.Request) (*authn.
 		}
 	}()
 
+	if r.HTTPRequest != nil {
+		if err := a.tagDevice(ctx, r, o.ID); err != nil {
+			return nil, err
+		}
+	}
+
 	return &authn.Identity{
 		IsAnonymous:  true,
 		OrgID:        o.ID,
@@ -60,6 +71,33 @@ func (a *Anonymous) Authenticate(ctx context.Context, r *authn.Request) (*authn.
 	}, nil
 }
 
+// tagDevice marks the device that presented a device cookie as active. Requests without a valid
+// cookie get a new device id, but the device is only tracked once it presents the cookie again so
+// clients that do not keep cookies cannot fill up the device limit.
+func (a *Anonymous) tagDevice(ctx context.Context, r *authn.Request, orgID int64) error {
+	if deviceID, ok := a.getDeviceID(r); ok {
+		return a.anonSessionService.TagDevice(ctx, r.HTTPRequest, orgID, deviceID)
+	}
+
+	if err := a.anonSessionService.CheckDeviceLimit(ctx, orgID); err != nil {
+		return err
+	}
+
+	if r.Resp != nil {
+		cookies.WriteCookie(r.Resp, anonymous.DeviceIDCookieName, util.GenerateShortUID(), deviceCookieMaxAge, nil)
+	}
+	return nil
+}
+
+// getDeviceID returns the device id stored in the device cookie and false if the cookie is missing or invalid.
+func (a *Anonymous) getDeviceID(r *authn.Request) (string, bool) {
+	cookie, err := r.HTTPRequest.Cookie(anonymous.DeviceIDCookieName)
+	if err != nil || cookie.Value == "" || !util.IsValidShortUID(cookie.Value) || util.IsShortUIDTooLong(cookie.Value) {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
 func (a *Anonymous) Test(ctx context.Context, r *authn.Request) bool {
 	// If anonymous client is register it can always be used for authentication
 	return true
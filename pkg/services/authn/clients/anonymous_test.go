@@ -3,17 +3,21 @@ package clients
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/anonymous"
 	"github.com/grafana/grafana/pkg/services/anonymous/anontest"
 	"github.com/grafana/grafana/pkg/services/authn"
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/org/orgtest"
 	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
 )
 
 func TestAnonymous_Authenticate(t *testing.T) {
@@ -67,3 +71,85 @@ func TestAnonymous_Authenticate(t *testing.T) {
 		})
 	}
 }
+
+func TestAnonymous_DeviceID(t *testing.T) {
+	type TestCase struct {
+		desc              string
+		cookie            *http.Cookie
+		tagDeviceErr      error
+		deviceLimitErr    error
+		expectedErr       error
+		expectCookieWrite bool
+		expectedDeviceID  string
+	}
+
+	tests := []TestCase{
+		{
+			desc:              "should generate and write device id when cookie is missing",
+			expectCookieWrite: true,
+		},
+		{
+			desc:             "should use device id from cookie",
+			cookie:           &http.Cookie{Name: anonymous.DeviceIDCookieName, Value: "device-1"},
+			expectedDeviceID: "device-1",
+		},
+		{
+			desc:              "should replace invalid device id",
+			cookie:            &http.Cookie{Name: anonymous.DeviceIDCookieName, Value: "not a valid id!"},
+			expectCookieWrite: true,
+		},
+		{
+			desc:         "should fail when device limit is reached",
+			cookie:       &http.Cookie{Name: anonymous.DeviceIDCookieName, Value: "device-1"},
+			tagDeviceErr: anonymous.ErrDeviceLimitReached.Errorf("limit reached"),
+			expectedErr:  anonymous.ErrDeviceLimitReached,
+		},
+		{
+			desc:           "should fail for new device when device limit is reached",
+			deviceLimitErr: anonymous.ErrDeviceLimitReached.Errorf("limit reached"),
+			expectedErr:    anonymous.ErrDeviceLimitReached,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			anonSessionService := &anontest.FakeAnonymousSessionService{
+				ExpectedTagDeviceError:        tt.tagDeviceErr,
+				ExpectedCheckDeviceLimitError: tt.deviceLimitErr,
+			}
+			c := Anonymous{
+				cfg:                &setting.Cfg{AnonymousOrgName: "some org", AnonymousOrgRole: "Viewer"},
+				log:                log.NewNopLogger(),
+				orgService:         &orgtest.FakeOrgService{ExpectedOrg: &org.Org{ID: 1, Name: "some org"}},
+				anonSessionService: anonSessionService,
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			rec := httptest.NewRecorder()
+			r := &authn.Request{HTTPRequest: req, Resp: web.NewResponseWriter(http.MethodGet, rec)}
+
+			_, err := c.Authenticate(context.Background(), r)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			written := rec.Result().Cookies()
+			if !tt.expectCookieWrite {
+				assert.Empty(t, written)
+				assert.Equal(t, []string{tt.expectedDeviceID}, anonSessionService.TaggedDeviceIDs)
+				return
+			}
+
+			// new devices are only tracked once they present the cookie
+			assert.Empty(t, anonSessionService.TaggedDeviceIDs)
+			require.Len(t, written, 1)
+			assert.Equal(t, anonymous.DeviceIDCookieName, written[0].Name)
+			assert.NotEmpty(t, written[0].Value)
+		})
+	}
+}
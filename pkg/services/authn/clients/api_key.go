@@ -12,7 +12,6 @@ import (
 	"github.com/grafana/grafana/pkg/services/apikey"
 	"github.com/grafana/grafana/pkg/services/authn"
 	"github.com/grafana/grafana/pkg/services/org"
-	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/util"
 	"github.com/grafana/grafana/pkg/util/errutil"
 )
@@ -26,17 +25,17 @@ var (
 var _ authn.HookClient = new(APIKey)
 var _ authn.ContextAwareClient = new(APIKey)
 
-func ProvideAPIKey(apiKeyService apikey.Service, userService user.Service) *APIKey {
+func ProvideAPIKey(apiKeyService apikey.Service, resolver authn.IdentityResolver) *APIKey {
 	return &APIKey{
 		log:           log.New(authn.ClientAPIKey),
-		userService:   userService,
+		resolver:      resolver,
 		apiKeyService: apiKeyService,
 	}
 }
 
 type APIKey struct {
 	log           log.Logger
-	userService   user.Service
+	resolver      authn.IdentityResolver
 	apiKeyService apikey.Service
 }
 
@@ -70,16 +69,7 @@ func (s *APIKey) Authenticate(ctx context.Context, r *authn.Request) (*authn.Ide
 		}, nil
 	}
 
-	usr, err := s.userService.GetSignedInUserWithCacheCtx(ctx, &user.GetSignedInUserQuery{
-		UserID: *apiKey.ServiceAccountId,
-		OrgID:  apiKey.OrgID,
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	return authn.IdentityFromSignedInUser(authn.NamespacedID(authn.NamespaceServiceAccount, usr.UserID), usr, authn.ClientParams{}), nil
+	return s.resolver.ResolveIdentity(ctx, apiKey.OrgID, authn.NamespacedID(authn.NamespaceServiceAccount, *apiKey.ServiceAccountId))
 }
 
 func (s *APIKey) getAPIKey(ctx context.Context, token string) (*apikey.APIKey, error) {
@@ -16,12 +16,12 @@ import (
 	"github.com/grafana/grafana/pkg/services/authn"
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/user"
-	"github.com/grafana/grafana/pkg/services/user/usertest"
 )
 
 var (
-	revoked      = true
-	secret, hash = genApiKey(false)
+	revoked                  = true
+	secret, hash             = genApiKey(false)
+	legacySecret, legacyHash = genApiKey(true)
 )
 
 func TestAPIKey_Authenticate(t *testing.T) {
@@ -84,6 +84,32 @@ func TestAPIKey_Authenticate(t *testing.T) {
 				IsGrafanaAdmin: boolPtr(false),
 			},
 		},
+		{
+			desc: "should success for valid legacy token",
+			req:  &authn.Request{HTTPRequest: &http.Request{Header: map[string][]string{"Authorization": {"Bearer " + legacySecret}}}},
+			expectedKey: &apikey.APIKey{
+				ID:    1,
+				OrgID: 1,
+				Key:   legacyHash,
+				Role:  org.RoleAdmin,
+			},
+			expectedIdentity: &authn.Identity{
+				ID:       "api-key:1",
+				OrgID:    1,
+				OrgRoles: map[int64]org.RoleType{1: org.RoleAdmin},
+			},
+		},
+		{
+			desc: "should fail for legacy token not matching stored hash",
+			req:  &authn.Request{HTTPRequest: &http.Request{Header: map[string][]string{"Authorization": {"Bearer " + legacySecret}}}},
+			expectedKey: &apikey.APIKey{
+				ID:    1,
+				OrgID: 1,
+				Key:   hash,
+				Role:  org.RoleAdmin,
+			},
+			expectedErr: errAPIKeyInvalid,
+		},
 		{
 			desc: "should fail for expired api key",
 			req:  &authn.Request{HTTPRequest: &http.Request{Header: map[string][]string{"Authorization": {"Bearer " + secret}}}},
@@ -108,9 +134,7 @@ func TestAPIKey_Authenticate(t *testing.T) {
 		t.Run(tt.desc, func(t *testing.T) {
 			c := ProvideAPIKey(&apikeytest.Service{
 				ExpectedAPIKey: tt.expectedKey,
-			}, &usertest.FakeUserService{
-				ExpectedSignedInUser: tt.expectedUser,
-			})
+			}, fakeIdentityResolver{usr: tt.expectedUser})
 
 			identity, err := c.Authenticate(context.Background(), tt.req)
 			if tt.expectedErr != nil {
@@ -175,12 +199,24 @@ func TestAPIKey_Test(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
-			c := ProvideAPIKey(&apikeytest.Service{}, usertest.NewUserServiceFake())
+			c := ProvideAPIKey(&apikeytest.Service{}, fakeIdentityResolver{})
 			assert.Equal(t, tt.expected, c.Test(context.Background(), tt.req))
 		})
 	}
 }
 
+// fakeIdentityResolver resolves users and service accounts to the identity of usr
+type fakeIdentityResolver struct {
+	usr *user.SignedInUser
+}
+
+func (f fakeIdentityResolver) ResolveIdentity(ctx context.Context, orgID int64, namespaceID string) (*authn.Identity, error) {
+	if f.usr == nil {
+		return nil, user.ErrUserNotFound
+	}
+	return authn.IdentityFromSignedInUser(namespaceID, f.usr, authn.ClientParams{}), nil
+}
+
 func intPtr(n int64) *int64 {
 	return &n
 }
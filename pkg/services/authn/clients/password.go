@@ -35,7 +35,7 @@ type Password struct {
 func (c *Password) AuthenticatePassword(ctx context.Context, r *authn.Request, username, password string) (*authn.Identity, error) {
 	r.SetMeta(authn.MetaKeyUsername, username)
 
-	ok, err := c.loginAttempts.Validate(ctx, username)
+	ok, err := c.loginAttempts.Validate(ctx, username, web.RemoteAddr(r.HTTPRequest))
 	if err != nil {
 		return nil, err
 	}
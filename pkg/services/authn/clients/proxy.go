@@ -2,11 +2,14 @@ package clients
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"hash/fnv"
 	"net"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +28,8 @@ const (
 	proxyFieldRole   = "Role"
 	proxyFieldGroups = "Groups"
 	proxyCachePrefix = "auth-proxy-sync-ttl"
+	// proxySignatureCachePrefix is used to remember signatures that have already been used
+	proxySignatureCachePrefix = "auth-proxy-signature"
 )
 
 var proxyFields = [...]string{proxyFieldName, proxyFieldEmail, proxyFieldLogin, proxyFieldRole, proxyFieldGroups}
@@ -33,6 +38,7 @@ var (
 	errNotAcceptedIP      = errutil.NewBase(errutil.StatusUnauthorized, "auth-proxy.invalid-ip")
 	errEmptyProxyHeader   = errutil.NewBase(errutil.StatusUnauthorized, "auth-proxy.empty-header")
 	errInvalidProxyHeader = errutil.NewBase(errutil.StatusInternal, "auth-proxy.invalid-proxy-header")
+	errInvalidSignature   = errutil.NewBase(errutil.StatusUnauthorized, "auth-proxy.invalid-signature")
 )
 
 var (
@@ -51,6 +57,7 @@ func ProvideProxy(cfg *setting.Cfg, cache proxyCache, userSrv user.Service, clie
 type proxyCache interface {
 	Get(ctx context.Context, key string) (interface{}, error)
 	Set(ctx context.Context, key string, value interface{}, expire time.Duration) error
+	SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error)
 }
 
 type Proxy struct {
@@ -76,6 +83,12 @@ func (c *Proxy) Authenticate(ctx context.Context, r *authn.Request) (*authn.Iden
 		return nil, errEmptyProxyHeader.Errorf("no username provided in auth proxy header")
 	}
 
+	if c.cfg.AuthProxySignatureSecret != "" {
+		if err := c.validateSignature(ctx, r, username); err != nil {
+			return nil, err
+		}
+	}
+
 	additional := getAdditionalProxyHeaders(r, c.cfg)
 
 	cacheKey, ok := getProxyCacheKey(username, additional)
@@ -140,6 +153,56 @@ func (c *Proxy) Hook(ctx context.Context, identity *authn.Identity, r *authn.Req
 	return nil
 }
 
+// validateSignature checks that the request carries a valid HMAC-SHA256 signature of the username
+// and timestamp, that the timestamp is within the configured max age and that the signature has not been used before.
+func (c *Proxy) validateSignature(ctx context.Context, r *authn.Request, username string) error {
+	signature := r.HTTPRequest.Header.Get(c.cfg.AuthProxySignatureHeaderName)
+	timestamp := r.HTTPRequest.Header.Get(c.cfg.AuthProxyTimestampHeaderName)
+	if signature == "" || timestamp == "" {
+		return errInvalidSignature.Errorf("missing signature or timestamp header")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errInvalidSignature.Errorf("invalid timestamp: %w", err)
+	}
+
+	age := time.Since(time.UnixMilli(ts))
+	if age > c.cfg.AuthProxySignatureMaxAge || age < -c.cfg.AuthProxySignatureMaxAge {
+		return errInvalidSignature.Errorf("timestamp is outside of the allowed window")
+	}
+
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		return errInvalidSignature.Errorf("invalid signature encoding: %w", err)
+	}
+
+	if !hmac.Equal(decoded, computeProxySignature(c.cfg.AuthProxySignatureSecret, username, timestamp)) {
+		return errInvalidSignature.Errorf("signature does not match")
+	}
+
+	// hex decoding is case insensitive, so the key is built from the decoded signature
+	// to recognize a replayed signature with different casing.
+	key := strings.Join([]string{proxySignatureCachePrefix, hex.EncodeToString(decoded)}, ":")
+
+	// Keep the signature for the whole window the timestamp is accepted in, in both directions
+	stored, err := c.cache.SetIfNotExists(ctx, key, []byte{1}, 2*c.cfg.AuthProxySignatureMaxAge)
+	if err != nil {
+		return fmt.Errorf("failed to store proxy signature: %w", err)
+	}
+	if !stored {
+		return errInvalidSignature.Errorf("signature has already been used")
+	}
+
+	return nil
+}
+
+func computeProxySignature(secret, username, timestamp string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(username + ":" + timestamp))
+	return mac.Sum(nil)
+}
+
 func (c *Proxy) isAllowedIP(r *authn.Request) bool {
 	if len(c.acceptedIPs) == 0 {
 		return true
@@ -2,14 +2,18 @@ package clients
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/infra/remotecache"
 	"github.com/grafana/grafana/pkg/services/authn"
 	"github.com/grafana/grafana/pkg/services/authn/authntest"
 	"github.com/grafana/grafana/pkg/services/user/usertest"
@@ -123,6 +127,140 @@ func TestProxy_Authenticate(t *testing.T) {
 	}
 }
 
+func TestProxy_AuthenticateSignature(t *testing.T) {
+	const secret = "secret"
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	old := strconv.FormatInt(time.Now().Add(-2*time.Minute).UnixMilli(), 10)
+	sign := func(username, timestamp string) string {
+		return hex.EncodeToString(computeProxySignature(secret, username, timestamp))
+	}
+
+	type testCase struct {
+		desc    string
+		headers map[string][]string
+		// usedSignature is sent in a request before the one of the test case
+		usedSignature string
+		expectedErr   error
+	}
+
+	tests := []testCase{
+		{
+			desc: "should authenticate request with valid signature",
+			headers: map[string][]string{
+				"X-Username":                {"username"},
+				"X-Grafana-Proxy-Signature": {sign("username", now)},
+				"X-Grafana-Proxy-Timestamp": {now},
+			},
+		},
+		{
+			desc:        "should fail when signature is missing",
+			headers:     map[string][]string{"X-Username": {"username"}},
+			expectedErr: errInvalidSignature,
+		},
+		{
+			desc: "should fail when signature was computed for another user",
+			headers: map[string][]string{
+				"X-Username":                {"admin"},
+				"X-Grafana-Proxy-Signature": {sign("username", now)},
+				"X-Grafana-Proxy-Timestamp": {now},
+			},
+			expectedErr: errInvalidSignature,
+		},
+		{
+			desc: "should fail when timestamp is too old",
+			headers: map[string][]string{
+				"X-Username":                {"username"},
+				"X-Grafana-Proxy-Signature": {sign("username", old)},
+				"X-Grafana-Proxy-Timestamp": {old},
+			},
+			expectedErr: errInvalidSignature,
+		},
+		{
+			desc: "should fail when signature is replayed",
+			headers: map[string][]string{
+				"X-Username":                {"username"},
+				"X-Grafana-Proxy-Signature": {sign("username", now)},
+				"X-Grafana-Proxy-Timestamp": {now},
+			},
+			usedSignature: sign("username", now),
+			expectedErr:   errInvalidSignature,
+		},
+		{
+			desc: "should fail when signature is replayed with different casing",
+			headers: map[string][]string{
+				"X-Username":                {"username"},
+				"X-Grafana-Proxy-Signature": {strings.ToUpper(sign("username", now))},
+				"X-Grafana-Proxy-Timestamp": {now},
+			},
+			usedSignature: sign("username", now),
+			expectedErr:   errInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := setting.NewCfg()
+			cfg.AuthProxyHeaderName = "X-Username"
+			cfg.AuthProxySignatureSecret = secret
+			cfg.AuthProxySignatureHeaderName = "X-Grafana-Proxy-Signature"
+			cfg.AuthProxyTimestampHeaderName = "X-Grafana-Proxy-Timestamp"
+			cfg.AuthProxySignatureMaxAge = time.Minute
+
+			proxyClient := authntest.MockProxyClient{AuthenticateProxyFunc: func(ctx context.Context, r *authn.Request, username string, additional map[string]string) (*authn.Identity, error) {
+				return &authn.Identity{}, nil
+			}}
+			c, err := ProvideProxy(cfg, remotecache.NewFakeMemoryStore(t, nil), usertest.NewUserServiceFake(), proxyClient)
+			require.NoError(t, err)
+
+			if tt.usedSignature != "" {
+				used := http.Header{}
+				for k, v := range tt.headers {
+					used[k] = v
+				}
+				used.Set("X-Grafana-Proxy-Signature", tt.usedSignature)
+				_, err := c.Authenticate(context.Background(), &authn.Request{HTTPRequest: &http.Request{Header: used}})
+				require.NoError(t, err)
+			}
+
+			req := &authn.Request{HTTPRequest: &http.Request{Header: tt.headers}}
+
+			identity, err := c.Authenticate(context.Background(), req)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, identity)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, identity)
+		})
+	}
+}
+
+func TestProxy_AuthenticateSignatureCacheError(t *testing.T) {
+	cfg := setting.NewCfg()
+	cfg.AuthProxyHeaderName = "X-Username"
+	cfg.AuthProxySignatureSecret = "secret"
+	cfg.AuthProxySignatureHeaderName = "X-Grafana-Proxy-Signature"
+	cfg.AuthProxyTimestampHeaderName = "X-Grafana-Proxy-Timestamp"
+	cfg.AuthProxySignatureMaxAge = time.Minute
+
+	proxyClient := authntest.MockProxyClient{AuthenticateProxyFunc: func(ctx context.Context, r *authn.Request, username string, additional map[string]string) (*authn.Identity, error) {
+		return &authn.Identity{}, nil
+	}}
+	c, err := ProvideProxy(cfg, fakeCache{expectedErr: errors.New("cache unavailable")}, usertest.NewUserServiceFake(), proxyClient)
+	require.NoError(t, err)
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	identity, err := c.Authenticate(context.Background(), &authn.Request{HTTPRequest: &http.Request{Header: map[string][]string{
+		"X-Username":                {"username"},
+		"X-Grafana-Proxy-Signature": {hex.EncodeToString(computeProxySignature("secret", "username", now))},
+		"X-Grafana-Proxy-Timestamp": {now},
+	}}})
+	// replay protection must not fail open
+	assert.Error(t, err)
+	assert.Nil(t, identity)
+}
+
 func TestProxy_Test(t *testing.T) {
 	type testCase struct {
 		desc       string
@@ -188,3 +326,7 @@ func (f fakeCache) Get(ctx context.Context, key string) (interface{}, error) {
 func (f fakeCache) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
 	return f.expectedErr
 }
+
+func (f fakeCache) SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	return f.expectedErr == nil, f.expectedErr
+}
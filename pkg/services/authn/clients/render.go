@@ -8,7 +8,6 @@ import (
 	"github.com/grafana/grafana/pkg/services/login"
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/rendering"
-	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/util/errutil"
 )
 
@@ -22,12 +21,12 @@ const (
 
 var _ authn.ContextAwareClient = new(Render)
 
-func ProvideRender(userService user.Service, renderService rendering.Service) *Render {
-	return &Render{userService, renderService}
+func ProvideRender(resolver authn.IdentityResolver, renderService rendering.Service) *Render {
+	return &Render{resolver, renderService}
 }
 
 type Render struct {
-	userService   user.Service
+	resolver      authn.IdentityResolver
 	renderService rendering.Service
 }
 
@@ -45,17 +44,16 @@ func (c *Render) Authenticate(ctx context.Context, r *authn.Request) (*authn.Ide
 	var identity *authn.Identity
 	if renderUsr.UserID <= 0 {
 		identity = &authn.Identity{
-			ID:       authn.NamespacedID(authn.NamespaceUser, 0),
+			ID:       authn.NamespacedID(authn.NamespaceRenderService, 0),
 			OrgID:    renderUsr.OrgID,
 			OrgRoles: map[int64]org.RoleType{renderUsr.OrgID: org.RoleType(renderUsr.OrgRole)},
 		}
 	} else {
-		usr, err := c.userService.GetSignedInUserWithCacheCtx(ctx, &user.GetSignedInUserQuery{UserID: renderUsr.UserID, OrgID: renderUsr.OrgID})
+		var err error
+		identity, err = c.resolver.ResolveIdentity(ctx, renderUsr.OrgID, authn.NamespacedID(authn.NamespaceUser, renderUsr.UserID))
 		if err != nil {
 			return nil, err
 		}
-
-		identity = authn.IdentityFromSignedInUser(authn.NamespacedID(authn.NamespaceUser, usr.UserID), usr, authn.ClientParams{})
 	}
 
 	identity.LastSeenAt = time.Now()
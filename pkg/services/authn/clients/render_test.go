@@ -14,7 +14,6 @@ import (
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/rendering"
 	"github.com/grafana/grafana/pkg/services/user"
-	"github.com/grafana/grafana/pkg/services/user/usertest"
 )
 
 func TestRender_Authenticate(t *testing.T) {
@@ -38,7 +37,7 @@ func TestRender_Authenticate(t *testing.T) {
 				},
 			},
 			expectedIdentity: &authn.Identity{
-				ID:         "user:0",
+				ID:         "render:0",
 				OrgID:      1,
 				OrgRoles:   map[int64]org.RoleType{1: org.RoleViewer},
 				AuthModule: login.RenderModule,
@@ -95,7 +94,7 @@ func TestRender_Authenticate(t *testing.T) {
 			renderService := rendering.NewMockService(ctrl)
 			renderService.EXPECT().GetRenderUser(gomock.Any(), tt.renderKey).Return(tt.expectedRenderUsr, tt.expectedRenderUsr != nil)
 
-			c := ProvideRender(&usertest.FakeUserService{ExpectedSignedInUser: tt.expectedUsr}, renderService)
+			c := ProvideRender(fakeIdentityResolver{usr: tt.expectedUsr}, renderService)
 			identity, err := c.Authenticate(context.Background(), tt.req)
 			if tt.expectedErr != nil {
 				assert.ErrorIs(t, tt.expectedErr, err)
@@ -139,7 +138,7 @@ func TestRender_Test(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
-			c := ProvideRender(&usertest.FakeUserService{}, &rendering.MockService{})
+			c := ProvideRender(fakeIdentityResolver{}, &rendering.MockService{})
 			assert.Equal(t, tt.expected, c.Test(context.Background(), tt.req))
 		})
 	}
@@ -3,6 +3,7 @@ package clients
 import (
 	"context"
 	"errors"
+	"math"
 	"net/url"
 	"time"
 
@@ -18,13 +19,14 @@ import (
 var _ authn.HookClient = new(Session)
 var _ authn.ContextAwareClient = new(Session)
 
-func ProvideSession(sessionService auth.UserTokenService, userService user.Service,
+func ProvideSession(sessionService auth.UserTokenService, userService user.Service, sessionOrgs *SessionOrgs,
 	cookieName string, maxLifetime time.Duration) *Session {
 	return &Session{
 		loginCookieName:  cookieName,
 		loginMaxLifetime: maxLifetime,
 		sessionService:   sessionService,
 		userService:      userService,
+		sessionOrgs:      sessionOrgs,
 		log:              log.New(authn.ClientSession),
 	}
 }
@@ -34,6 +36,7 @@ type Session struct {
 	loginMaxLifetime time.Duration // jguer: should be returned by session Service on rotate
 	sessionService   auth.UserTokenService
 	userService      user.Service
+	sessionOrgs      *SessionOrgs
 	log              log.Logger
 }
 
@@ -58,6 +61,13 @@ func (s *Session) Authenticate(ctx context.Context, r *authn.Request) (*authn.Id
 		return nil, err
 	}
 
+	// an organization targeted by the request takes precedence over the active organization of the session
+	sessionOrgID := int64(0)
+	if r.OrgID == 0 && s.sessionOrgs != nil {
+		sessionOrgID = s.sessionOrgs.Get(ctx, token.Id)
+		r.OrgID = sessionOrgID
+	}
+
 	signedInUser, err := s.userService.GetSignedInUserWithCacheCtx(
 		ctx, &user.GetSignedInUserQuery{UserID: token.UserId, OrgID: r.OrgID},
 	)
@@ -66,6 +76,17 @@ func (s *Session) Authenticate(ctx context.Context, r *authn.Request) (*authn.Id
 		return nil, err
 	}
 
+	// the user can have been removed from the active organization of the session, fall back to the default organization
+	if sessionOrgID > 0 && signedInUser.OrgRole == "" {
+		s.log.FromContext(ctx).Debug("User is not a member of the active organization of the session", "userId", token.UserId, "orgId", sessionOrgID)
+		r.OrgID = 0
+		signedInUser, err = s.userService.GetSignedInUserWithCacheCtx(ctx, &user.GetSignedInUserQuery{UserID: token.UserId})
+		if err != nil {
+			s.log.FromContext(ctx).Error("Failed to get user with id", "userId", token.UserId, "error", err)
+			return nil, err
+		}
+	}
+
 	identity := authn.IdentityFromSignedInUser(authn.NamespacedID(authn.NamespaceUser, signedInUser.UserID), signedInUser, authn.ClientParams{})
 	identity.SessionToken = token
 
@@ -118,8 +139,9 @@ func (s *Session) Hook(ctx context.Context, identity *authn.Identity, r *authn.R
 			identity.SessionToken = newToken
 			s.log.Debug("rotated session token", "user", identity.ID)
 
-			maxAge := int(s.loginMaxLifetime.Seconds())
-			if s.loginMaxLifetime <= 0 {
+			ttl := identity.SessionToken.TimeToLive(s.loginMaxLifetime)
+			maxAge := int(math.Ceil(ttl.Seconds()))
+			if ttl <= 0 {
 				maxAge = -1
 			}
 			cookies.WriteCookie(r.Resp, s.loginCookieName, url.QueryEscape(identity.SessionToken.UnhashedToken), maxAge, nil)
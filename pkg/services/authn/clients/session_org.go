@@ -0,0 +1,49 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type sessionOrgCache interface {
+	GetByteArray(ctx context.Context, key string) ([]byte, error)
+	SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error
+}
+
+func NewSessionOrgs(cache sessionOrgCache, maxLifetime time.Duration) *SessionOrgs {
+	return &SessionOrgs{cache: cache, maxLifetime: maxLifetime}
+}
+
+// SessionOrgs keeps the active organization per session, so that a session can switch organization
+// without changing the default organization of the user and with that all other sessions of the user.
+// Requests that target an organization with the org id header or query parameter are not affected.
+type SessionOrgs struct {
+	cache       sessionOrgCache
+	maxLifetime time.Duration
+}
+
+// Get returns the active organization of the session, 0 if the session has not switched organization.
+func (s *SessionOrgs) Get(ctx context.Context, sessionID int64) int64 {
+	data, err := s.cache.GetByteArray(ctx, sessionOrgKey(sessionID))
+	if err != nil {
+		return 0
+	}
+
+	orgID, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return orgID
+}
+
+// Set sets the active organization of the session.
+func (s *SessionOrgs) Set(ctx context.Context, sessionID, orgID int64) error {
+	// sessions can not outlive the max lifetime, so neither should the organization
+	return s.cache.SetByteArray(ctx, sessionOrgKey(sessionID), []byte(strconv.FormatInt(orgID, 10)), s.maxLifetime)
+}
+
+func sessionOrgKey(sessionID int64) string {
+	return fmt.Sprintf("session-org:%d", sessionID)
+}
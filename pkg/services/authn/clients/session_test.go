@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/infra/remotecache"
 	"github.com/grafana/grafana/pkg/models/roletype"
 	"github.com/grafana/grafana/pkg/models/usertoken"
 	"github.com/grafana/grafana/pkg/services/auth"
@@ -28,7 +29,7 @@ func TestSession_Test(t *testing.T) {
 	}
 	validHTTPReq.AddCookie(&http.Cookie{Name: cookieName, Value: "bob-the-high-entropy-token"})
 
-	s := ProvideSession(&authtest.FakeUserAuthTokenService{}, &usertest.FakeUserService{}, "", 20*time.Second)
+	s := ProvideSession(&authtest.FakeUserAuthTokenService{}, &usertest.FakeUserService{}, nil, "", 20*time.Second)
 
 	disabled := s.Test(context.Background(), &authn.Request{HTTPRequest: validHTTPReq})
 	assert.False(t, disabled)
@@ -111,7 +112,7 @@ func TestSession_Authenticate(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := ProvideSession(tt.fields.sessionService, tt.fields.userService, cookieName, 20*time.Second)
+			s := ProvideSession(tt.fields.sessionService, tt.fields.userService, nil, cookieName, 20*time.Second)
 
 			got, err := s.Authenticate(context.Background(), tt.args.r)
 			require.True(t, (err != nil) == tt.wantErr, err)
@@ -124,6 +125,60 @@ func TestSession_Authenticate(t *testing.T) {
 	}
 }
 
+func TestSession_AuthenticateSessionOrg(t *testing.T) {
+	cookieName := "grafana_session"
+	sampleToken := &auth.UserToken{Id: 1, UserId: 1}
+
+	type testCase struct {
+		desc          string
+		sessionOrgID  int64
+		targetOrgID   int64
+		memberOf      []int64
+		expectedOrgID int64
+	}
+
+	tests := []testCase{
+		{desc: "should use default organization when session has not switched", memberOf: []int64{1, 2}, expectedOrgID: 1},
+		{desc: "should use active organization of the session", sessionOrgID: 2, memberOf: []int64{1, 2}, expectedOrgID: 2},
+		{desc: "should prefer organization targeted by the request", sessionOrgID: 2, targetOrgID: 3, memberOf: []int64{1, 2, 3}, expectedOrgID: 3},
+		{desc: "should fall back to default organization when no longer member", sessionOrgID: 2, memberOf: []int64{1}, expectedOrgID: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			sessionOrgs := NewSessionOrgs(remotecache.NewFakeMemoryStore(t, nil), time.Hour)
+			if tt.sessionOrgID != 0 {
+				require.NoError(t, sessionOrgs.Set(context.Background(), sampleToken.Id, tt.sessionOrgID))
+			}
+
+			userService := &usertest.FakeUserService{GetSignedInUserFn: func(ctx context.Context, query *user.GetSignedInUserQuery) (*user.SignedInUser, error) {
+				orgID := query.OrgID
+				if orgID == 0 {
+					orgID = 1
+				}
+				usr := &user.SignedInUser{UserID: query.UserID, OrgID: orgID}
+				for _, id := range tt.memberOf {
+					if id == orgID {
+						usr.OrgRole = roletype.RoleViewer
+					}
+				}
+				return usr, nil
+			}}
+
+			s := ProvideSession(&authtest.FakeUserAuthTokenService{LookupTokenProvider: func(ctx context.Context, unhashedToken string) (*auth.UserToken, error) {
+				return sampleToken, nil
+			}}, userService, sessionOrgs, cookieName, time.Hour)
+
+			req := &http.Request{Header: map[string][]string{}}
+			req.AddCookie(&http.Cookie{Name: cookieName, Value: "bob-the-high-entropy-token"})
+
+			identity, err := s.Authenticate(context.Background(), &authn.Request{HTTPRequest: req, OrgID: tt.targetOrgID})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedOrgID, identity.OrgID)
+		})
+	}
+}
+
 type fakeResponseWriter struct {
 	Status      int
 	HeaderStore http.Header
@@ -147,12 +202,13 @@ func TestSession_Hook(t *testing.T) {
 			token.UnhashedToken = "new-token"
 			return true, token, nil
 		},
-	}, &usertest.FakeUserService{}, "grafana-session", 20*time.Second)
+	}, &usertest.FakeUserService{}, nil, "grafana-session", 20*time.Second)
 
 	sampleID := &authn.Identity{
 		SessionToken: &auth.UserToken{
-			Id:     1,
-			UserId: 1,
+			Id:        1,
+			UserId:    1,
+			CreatedAt: time.Now().Unix(),
 		},
 	}
 
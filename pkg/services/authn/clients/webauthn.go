@@ -0,0 +1,74 @@
+package clients
+
+import (
+	"context"
+	"io"
+
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+// maxWebAuthnBodySize limits the size of assertions read from the request body
+const maxWebAuthnBodySize = 64 * 1024
+
+var errWebAuthnBadRequest = errutil.NewBase(errutil.StatusBadRequest, "webauthn.bad-request", errutil.WithPublicMessage("Invalid WebAuthn request"))
+
+var _ authn.Client = new(WebAuthn)
+
+// WebAuthnAssertion is the result of a verified WebAuthn login assertion.
+type WebAuthnAssertion struct {
+	UserID int64
+	// UserVerified is true if the authenticator verified the user, e.g. with a PIN or biometrics
+	UserVerified bool
+}
+
+// WebAuthnVerifier verifies WebAuthn login assertions against the registered credentials.
+type WebAuthnVerifier interface {
+	VerifyAssertion(ctx context.Context, body []byte) (*WebAuthnAssertion, error)
+}
+
+func ProvideWebAuthn(verifier WebAuthnVerifier, userService user.Service) *WebAuthn {
+	return &WebAuthn{verifier: verifier, userService: userService}
+}
+
+// WebAuthn authenticates login requests using an assertion signed by a passkey or security key.
+type WebAuthn struct {
+	verifier    WebAuthnVerifier
+	userService user.Service
+}
+
+func (c *WebAuthn) Name() string {
+	return authn.ClientWebAuthn
+}
+
+func (c *WebAuthn) Authenticate(ctx context.Context, r *authn.Request) (*authn.Identity, error) {
+	if r.HTTPRequest == nil || r.HTTPRequest.Body == nil {
+		return nil, errWebAuthnBadRequest.Errorf("missing request body")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.HTTPRequest.Body, maxWebAuthnBodySize))
+	if err != nil {
+		return nil, errWebAuthnBadRequest.Errorf("failed to read request body: %w", err)
+	}
+
+	assertion, err := c.verifier.VerifyAssertion(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+
+	signedInUser, err := c.userService.GetSignedInUserWithCacheCtx(ctx, &user.GetSignedInUserQuery{OrgID: r.OrgID, UserID: assertion.UserID})
+	if err != nil {
+		return nil, err
+	}
+
+	identity := authn.IdentityFromSignedInUser(authn.NamespacedID(authn.NamespaceUser, signedInUser.UserID), signedInUser, authn.ClientParams{})
+	identity.AuthenticationMethods = []string{authn.AMRHardwareKey}
+	identity.AssuranceLevel = authn.AssuranceLevelSingleFactor
+	if assertion.UserVerified {
+		// the key is something the user has and the verification something they know or are
+		identity.AssuranceLevel = authn.AssuranceLevelMultiFactor
+	}
+
+	return identity, nil
+}
@@ -6,4 +6,5 @@ var (
 	ErrUnsupportedClient   = errutil.NewBase(errutil.StatusBadRequest, "auth.client.unsupported")
 	ErrClientNotConfigured = errutil.NewBase(errutil.StatusBadRequest, "auth.client.notConfigured")
 	ErrUnsupportedIdentity = errutil.NewBase(errutil.StatusNotImplemented, "auth.identity.unsupported")
+	ErrInvalidNamespaceID  = errutil.NewBase(errutil.StatusBadRequest, "auth.identity.invalid-namespace-id")
 )
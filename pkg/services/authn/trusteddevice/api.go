@@ -0,0 +1,92 @@
+package trusteddevice
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/services/auth"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+type deviceDTO struct {
+	*Device
+	// IsCurrent is true for the device the request was made from.
+	IsCurrent bool `json:"isCurrent"`
+}
+
+func (s *Service) registerAPIEndpoints(router routing.RouteRegister) {
+	router.Group("/api/user/devices", func(userRoute routing.RouteRegister) {
+		userRoute.Get("/", routing.Wrap(s.listDevices))
+		userRoute.Delete("/:id", routing.Wrap(s.revokeDevice))
+	}, middleware.ReqSignedInNoAnonymous)
+}
+
+func (s *Service) listDevices(c *contextmodel.ReqContext) response.Response {
+	devices, err := s.store.ListByUser(c.Req.Context(), c.UserID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to list devices", err)
+	}
+
+	result := make([]deviceDTO, 0, len(devices))
+	for _, device := range devices {
+		result = append(result, deviceDTO{
+			Device:    device,
+			IsCurrent: c.UserToken != nil && device.SessionID == c.UserToken.Id,
+		})
+	}
+
+	return response.JSON(http.StatusOK, result)
+}
+
+func (s *Service) revokeDevice(c *contextmodel.ReqContext) response.Response {
+	id, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+
+	if err := s.revoke(c.Req.Context(), c.UserID, id); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to revoke device", err)
+	}
+
+	return response.Success("Device revoked")
+}
+
+// revoke removes the device and signs out the session last created from it, the device
+// is reported as new again the next time it is used to sign in.
+func (s *Service) revoke(ctx context.Context, userID, id int64) error {
+	device, err := s.store.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if device == nil {
+		return ErrDeviceNotFound.Errorf("device %d not found", id)
+	}
+
+	if device.SessionID > 0 {
+		token, err := s.sessionService.GetUserToken(ctx, userID, device.SessionID)
+		if err != nil && !errors.Is(err, auth.ErrUserTokenNotFound) {
+			return err
+		}
+		if token != nil {
+			if err := s.sessionService.RevokeToken(ctx, token, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	deleted, err := s.store.Delete(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDeviceNotFound.Errorf("device %d not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package trusteddevice
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+var errDeviceExists = errors.New("device already exists")
+
+type store interface {
+	// GetByFingerprint returns the device or nil if the user has no device with the fingerprint.
+	GetByFingerprint(ctx context.Context, userID int64, fingerprint string) (*Device, error)
+	// Get returns the device or nil if the user has no device with the id.
+	Get(ctx context.Context, userID, id int64) (*Device, error)
+	ListByUser(ctx context.Context, userID int64) ([]*Device, error)
+	// Create returns errDeviceExists if the user already has a device with the fingerprint.
+	Create(ctx context.Context, device *Device) error
+	UpdateUsage(ctx context.Context, device *Device) error
+	Delete(ctx context.Context, userID, id int64) (bool, error)
+}
+
+type xormStore struct {
+	db db.DB
+}
+
+func (s *xormStore) GetByFingerprint(ctx context.Context, userID int64, fingerprint string) (*Device, error) {
+	return s.get(ctx, "user_id = ? AND fingerprint = ?", userID, fingerprint)
+}
+
+func (s *xormStore) Get(ctx context.Context, userID, id int64) (*Device, error) {
+	return s.get(ctx, "user_id = ? AND id = ?", userID, id)
+}
+
+func (s *xormStore) get(ctx context.Context, query string, args ...interface{}) (*Device, error) {
+	var device Device
+	var has bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		has, err = sess.Where(query, args...).Get(&device)
+		return err
+	})
+	if err != nil || !has {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (s *xormStore) ListByUser(ctx context.Context, userID int64) ([]*Device, error) {
+	devices := make([]*Device, 0)
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("user_id = ?", userID).Desc("last_seen").Find(&devices)
+	})
+	return devices, err
+}
+
+func (s *xormStore) Create(ctx context.Context, device *Device) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Insert(device)
+		if s.db.GetDialect().IsUniqueConstraintViolation(err) {
+			return errDeviceExists
+		}
+		return err
+	})
+}
+
+func (s *xormStore) UpdateUsage(ctx context.Context, device *Device) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec("UPDATE user_device SET user_agent = ?, client_ip = ?, session_id = ?, last_seen = ? WHERE id = ?",
+			device.UserAgent, device.ClientIP, device.SessionID, device.LastSeen, device.ID)
+		return err
+	})
+}
+
+func (s *xormStore) Delete(ctx context.Context, userID, id int64) (bool, error) {
+	var deleted bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("DELETE FROM user_device WHERE user_id = ? AND id = ?", userID, id)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		deleted = affected > 0
+		return err
+	})
+	return deleted, err
+}
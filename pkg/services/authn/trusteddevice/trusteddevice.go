@@ -0,0 +1,254 @@
+package trusteddevice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/network"
+	"github.com/grafana/grafana/pkg/middleware/cookies"
+	"github.com/grafana/grafana/pkg/services/anonymous"
+	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/util/errutil"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+var ErrDeviceNotFound = errutil.NewBase(errutil.StatusNotFound, "trusted-device.not-found", errutil.WithPublicMessage("Device not found"))
+
+const (
+	tmplNewDeviceLogin  = "new_device_login"
+	eventNewDeviceLogin = "new_device_login"
+
+	deviceCookieMaxAge = 365 * 24 * 60 * 60
+	// maxUserAgentLength is the length of the user_agent column
+	maxUserAgentLength = 255
+)
+
+// Device is a browser a user has signed in from, identified by a long-lived device cookie.
+type Device struct {
+	ID     int64 `xorm:"pk autoincr 'id'" json:"id"`
+	UserID int64 `xorm:"user_id" json:"-"`
+	// Fingerprint is the sha256 of the device cookie, the cookie value itself is never stored.
+	Fingerprint string `xorm:"fingerprint" json:"-"`
+	UserAgent   string `xorm:"user_agent" json:"userAgent"`
+	ClientIP    string `xorm:"client_ip" json:"clientIp"`
+	// SessionID is the id of the last session created from the device.
+	SessionID int64     `xorm:"session_id" json:"-"`
+	Created   time.Time `xorm:"created" json:"created"`
+	LastSeen  time.Time `xorm:"last_seen" json:"lastSeen"`
+}
+
+func (d Device) TableName() string { return "user_device" }
+
+type Service struct {
+	cfg                 *setting.Cfg
+	store               store
+	sessionService      auth.UserTokenService
+	notificationService notifications.Service
+	log                 log.Logger
+}
+
+func ProvideService(
+	cfg *setting.Cfg, features featuremgmt.FeatureToggles, sqlStore db.DB, router routing.RouteRegister, authnService authn.Service,
+	sessionService auth.UserTokenService, notificationService notifications.Service,
+) (*Service, error) {
+	s := &Service{
+		cfg:                 cfg,
+		store:               &xormStore{db: sqlStore},
+		sessionService:      sessionService,
+		notificationService: notificationService,
+		log:                 log.New("authn.trusted-device"),
+	}
+
+	if !cfg.TrustedDevicesEnabled {
+		return s, nil
+	}
+
+	// devices are tracked by a post login hook of the authn service, the logins
+	// handled without it would not be tracked
+	if !features.IsEnabled(featuremgmt.FlagAuthnService) {
+		return nil, errors.New("[auth.trusted_devices] requires the authnService feature toggle")
+	}
+
+	s.registerAPIEndpoints(router)
+	authnService.RegisterPostLoginHook(s.trackDeviceHook, 20)
+
+	return s, nil
+}
+
+// trackDeviceHook records the device used for a successful login and notifies
+// the user the first time a new device is used.
+func (s *Service) trackDeviceHook(ctx context.Context, identity *authn.Identity, r *authn.Request, err error) {
+	if err != nil || identity == nil || r == nil || r.HTTPRequest == nil {
+		return
+	}
+
+	namespace, userID := identity.NamespacedID()
+	if namespace != authn.NamespaceUser || userID <= 0 {
+		return
+	}
+
+	var sessionID int64
+	if identity.SessionToken != nil {
+		sessionID = identity.SessionToken.Id
+	}
+
+	device := &Device{
+		UserID:      userID,
+		Fingerprint: fingerprint(s.getDeviceID(r)),
+		UserAgent:   truncate(r.HTTPRequest.UserAgent(), maxUserAgentLength),
+		ClientIP:    clientIP(r.HTTPRequest),
+		SessionID:   sessionID,
+	}
+
+	isNew, err := s.track(ctx, device)
+	if err != nil {
+		s.log.FromContext(ctx).Warn("Failed to track login device", "id", identity.ID, "error", err)
+		return
+	}
+
+	if isNew {
+		s.notifyNewDevice(ctx, identity, device)
+	}
+}
+
+// track creates or updates the device, it returns true when the device was not known
+// and the user has signed in from other devices before.
+func (s *Service) track(ctx context.Context, device *Device) (bool, error) {
+	now := time.Now()
+	existing, err := s.store.GetByFingerprint(ctx, device.UserID, device.Fingerprint)
+	if err != nil {
+		return false, err
+	}
+
+	if existing != nil {
+		device.ID = existing.ID
+		device.Created = existing.Created
+		device.LastSeen = now
+		return false, s.store.UpdateUsage(ctx, device)
+	}
+
+	known, err := s.store.ListByUser(ctx, device.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	device.Created = now
+	device.LastSeen = now
+	if err := s.store.Create(ctx, device); err != nil {
+		// a concurrent login from the same device created it first
+		if errors.Is(err, errDeviceExists) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	// the first device of a user is not reported, otherwise every user would
+	// be notified on their first login after enabling the feature
+	return len(known) > 0, nil
+}
+
+func (s *Service) notifyNewDevice(ctx context.Context, identity *authn.Identity, device *Device) {
+	logger := s.log.FromContext(ctx)
+	name := identity.Name
+	if name == "" {
+		name = identity.Login
+	}
+
+	data := map[string]interface{}{
+		"Name":      name,
+		"Login":     identity.Login,
+		"UserAgent": device.UserAgent,
+		"IP":        device.ClientIP,
+		"Time":      device.Created.Format(time.RFC1123),
+	}
+
+	if s.cfg.TrustedDevicesNotifyEmail && identity.Email != "" {
+		if err := s.notificationService.SendEmailCommandHandler(ctx, &notifications.SendEmailCommand{
+			To:       []string{identity.Email},
+			Template: tmplNewDeviceLogin,
+			Data:     data,
+		}); err != nil {
+			logger.Warn("Failed to send new device email", "id", identity.ID, "error", err)
+		}
+	}
+
+	if s.cfg.TrustedDevicesWebhookURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"event":     eventNewDeviceLogin,
+		"userId":    device.UserID,
+		"login":     identity.Login,
+		"email":     identity.Email,
+		"deviceId":  device.ID,
+		"userAgent": device.UserAgent,
+		"clientIp":  device.ClientIP,
+		"time":      device.Created,
+	})
+	if err != nil {
+		logger.Warn("Failed to marshal new device webhook", "id", identity.ID, "error", err)
+		return
+	}
+
+	// the webhook is sent in the background so a slow receiver does not delay the login
+	go func() {
+		if err := s.notificationService.SendWebhookSync(context.Background(), &notifications.SendWebhookSync{
+			Url:         s.cfg.TrustedDevicesWebhookURL,
+			Body:        string(body),
+			HttpMethod:  http.MethodPost,
+			ContentType: "application/json",
+		}); err != nil {
+			logger.Warn("Failed to send new device webhook", "id", identity.ID, "error", err)
+		}
+	}()
+}
+
+// getDeviceID returns the device id stored in the device cookie, a new device id is
+// generated and written to the response if the cookie is missing or invalid.
+func (s *Service) getDeviceID(r *authn.Request) string {
+	if cookie, err := r.HTTPRequest.Cookie(anonymous.DeviceIDCookieName); err == nil {
+		if cookie.Value != "" && util.IsValidShortUID(cookie.Value) && !util.IsShortUIDTooLong(cookie.Value) {
+			return cookie.Value
+		}
+	}
+
+	deviceID := util.GenerateShortUID()
+	if r.Resp != nil {
+		cookies.WriteCookie(r.Resp, anonymous.DeviceIDCookieName, deviceID, deviceCookieMaxAge, nil)
+	}
+	return deviceID
+}
+
+func fingerprint(deviceID string) string {
+	sum := sha256.Sum256([]byte(deviceID))
+	return hex.EncodeToString(sum[:])
+}
+
+func clientIP(req *http.Request) string {
+	ip, err := network.GetIPFromAddress(web.RemoteAddr(req))
+	if err != nil {
+		return ""
+	}
+	return ip.String()
+}
+
+func truncate(s string, length int) string {
+	if len(s) > length {
+		return s[:length]
+	}
+	return s
+}
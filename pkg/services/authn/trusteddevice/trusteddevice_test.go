@@ -0,0 +1,216 @@
+package trusteddevice
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/anonymous"
+	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/auth/authtest"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func TestService_TrackDeviceHook(t *testing.T) {
+	s, notificationService := setupTests(t)
+	store := s.store.(*fakeStore)
+
+	login := func(deviceID string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, "/login", nil)
+		require.NoError(t, err)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("User-Agent", "test-agent")
+		if deviceID != "" {
+			req.AddCookie(&http.Cookie{Name: anonymous.DeviceIDCookieName, Value: deviceID})
+		}
+
+		resp := httptest.NewRecorder()
+		identity := &authn.Identity{ID: "user:1", Login: "test", Email: "test@example.com", SessionToken: &auth.UserToken{Id: 10}}
+		s.trackDeviceHook(context.Background(), identity, &authn.Request{HTTPRequest: req, Resp: web.NewResponseWriter(http.MethodGet, resp)}, nil)
+		return resp
+	}
+
+	// the first device of a user is recorded without a notification
+	resp := login("")
+	require.Len(t, store.devices, 1)
+	assert.Equal(t, "test-agent", store.devices[0].UserAgent)
+	assert.Equal(t, "10.0.0.1", store.devices[0].ClientIP)
+	assert.Equal(t, int64(10), store.devices[0].SessionID)
+	assert.Empty(t, notificationService.Email.To)
+
+	cookies := resp.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, anonymous.DeviceIDCookieName, cookies[0].Name)
+	assert.Equal(t, fingerprint(cookies[0].Value), store.devices[0].Fingerprint)
+
+	// a known device is only updated
+	login(cookies[0].Value)
+	require.Len(t, store.devices, 1)
+	assert.Empty(t, notificationService.Email.To)
+
+	// a new device is reported
+	login("other-device")
+	require.Len(t, store.devices, 2)
+	assert.Equal(t, []string{"test@example.com"}, notificationService.Email.To)
+	assert.Equal(t, tmplNewDeviceLogin, notificationService.Email.Template)
+	assert.Equal(t, "test-agent", notificationService.Email.Data["UserAgent"])
+}
+
+func TestService_Track_ConcurrentCreate(t *testing.T) {
+	s, _ := setupTests(t)
+	store := s.store.(*fakeStore)
+	store.devices = []*Device{{ID: 1, UserID: 1, Fingerprint: "other"}}
+
+	// the device was created by a concurrent login after the lookup
+	store.createErr = errDeviceExists
+	isNew, err := s.track(context.Background(), &Device{UserID: 1, Fingerprint: "device"})
+	require.NoError(t, err)
+	assert.False(t, isNew)
+}
+
+func TestService_TrackDeviceHook_Skip(t *testing.T) {
+	s, _ := setupTests(t)
+	req, err := http.NewRequest(http.MethodPost, "/login", nil)
+	require.NoError(t, err)
+
+	s.trackDeviceHook(context.Background(), &authn.Identity{ID: "user:1"}, &authn.Request{HTTPRequest: req}, errors.New("invalid password"))
+	s.trackDeviceHook(context.Background(), &authn.Identity{ID: "service-account:1"}, &authn.Request{HTTPRequest: req}, nil)
+	assert.Empty(t, s.store.(*fakeStore).devices)
+}
+
+func TestService_NotifyNewDevice_Webhook(t *testing.T) {
+	s, notificationService := setupTests(t)
+	s.cfg.TrustedDevicesNotifyEmail = false
+	s.cfg.TrustedDevicesWebhookURL = "http://example.com/hook"
+
+	sent := make(chan *notifications.SendWebhookSync, 1)
+	notificationService.WebhookHandler = func(ctx context.Context, cmd *notifications.SendWebhookSync) error {
+		sent <- cmd
+		return nil
+	}
+
+	s.notifyNewDevice(context.Background(), &authn.Identity{ID: "user:1", Login: "test", Email: "test@example.com"}, &Device{ID: 2, UserID: 1, Created: time.Now()})
+
+	select {
+	case cmd := <-sent:
+		assert.Equal(t, "http://example.com/hook", cmd.Url)
+		assert.Contains(t, cmd.Body, `"event":"new_device_login"`)
+		assert.Contains(t, cmd.Body, `"deviceId":2`)
+	case <-time.After(time.Second):
+		t.Fatal("webhook was not sent")
+	}
+	assert.Empty(t, notificationService.Email.To)
+}
+
+func TestService_Revoke(t *testing.T) {
+	s, _ := setupTests(t)
+	store := s.store.(*fakeStore)
+	store.devices = []*Device{{ID: 1, UserID: 1, SessionID: 10}, {ID: 2, UserID: 2, SessionID: 20}}
+
+	var revoked []int64
+	sessionService := authtest.NewFakeUserAuthTokenService()
+	sessionService.GetUserTokenProvider = func(ctx context.Context, userId, userTokenId int64) (*auth.UserToken, error) {
+		return &auth.UserToken{Id: userTokenId, UserId: userId}, nil
+	}
+	sessionService.RevokeTokenProvider = func(ctx context.Context, token *auth.UserToken, soft bool) error {
+		revoked = append(revoked, token.Id)
+		return nil
+	}
+	s.sessionService = sessionService
+
+	// users can only revoke their own devices
+	assert.ErrorIs(t, s.revoke(context.Background(), 1, 2), ErrDeviceNotFound)
+
+	require.NoError(t, s.revoke(context.Background(), 1, 1))
+	assert.Equal(t, []int64{10}, revoked)
+	require.Len(t, store.devices, 1)
+	assert.Equal(t, int64(2), store.devices[0].ID)
+}
+
+func setupTests(t *testing.T) (*Service, *notifications.NotificationServiceMock) {
+	t.Helper()
+
+	cfg := setting.NewCfg()
+	cfg.TrustedDevicesEnabled = true
+	cfg.TrustedDevicesNotifyEmail = true
+
+	notificationService := notifications.MockNotificationService()
+	return &Service{
+		cfg:                 cfg,
+		store:               &fakeStore{},
+		sessionService:      authtest.NewFakeUserAuthTokenService(),
+		notificationService: notificationService,
+		log:                 log.NewNopLogger(),
+	}, notificationService
+}
+
+type fakeStore struct {
+	devices   []*Device
+	createErr error
+}
+
+func (f *fakeStore) GetByFingerprint(ctx context.Context, userID int64, fingerprint string) (*Device, error) {
+	for _, d := range f.devices {
+		if d.UserID == userID && d.Fingerprint == fingerprint {
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeStore) Get(ctx context.Context, userID, id int64) (*Device, error) {
+	for _, d := range f.devices {
+		if d.UserID == userID && d.ID == id {
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeStore) ListByUser(ctx context.Context, userID int64) ([]*Device, error) {
+	var result []*Device
+	for _, d := range f.devices {
+		if d.UserID == userID {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeStore) Create(ctx context.Context, device *Device) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
+	device.ID = int64(len(f.devices) + 1)
+	f.devices = append(f.devices, device)
+	return nil
+}
+
+func (f *fakeStore) UpdateUsage(ctx context.Context, device *Device) error {
+	for i, d := range f.devices {
+		if d.ID == device.ID {
+			f.devices[i] = device
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) Delete(ctx context.Context, userID, id int64) (bool, error) {
+	for i, d := range f.devices {
+		if d.UserID == userID && d.ID == id {
+			f.devices = append(f.devices[:i], f.devices[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,345 @@
+package webauthn
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/middleware/cookies"
+	"github.com/grafana/grafana/pkg/services/authn"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+type assertionCommand struct {
+	ChallengeID       string `json:"challengeId"`
+	CredentialID      string `json:"credentialId"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+}
+
+type registrationCommand struct {
+	ChallengeID       string `json:"challengeId"`
+	Name              string `json:"name"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	// PublicKey is the base64url encoded result of AuthenticatorAttestationResponse.getPublicKey()
+	PublicKey          string `json:"publicKey"`
+	PublicKeyAlgorithm int64  `json:"publicKeyAlgorithm"`
+}
+
+type credentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type credentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type creationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams       []credentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []credentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection map[string]string      `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+type requestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout"`
+	AllowCredentials []credentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+type ceremonyResponse struct {
+	ChallengeID string      `json:"challengeId"`
+	PublicKey   interface{} `json:"publicKey"`
+}
+
+func (s *Service) registerAPIEndpoints(router routing.RouteRegister) {
+	router.Group("/api/user/webauthn", func(userRoute routing.RouteRegister) {
+		userRoute.Get("/credentials", routing.Wrap(s.listCredentials))
+		userRoute.Post("/credentials/begin", mfa.RequireStepUp(s.mfaService), s.requirePasskeyStepUp, routing.Wrap(s.beginRegistration))
+		userRoute.Post("/credentials", mfa.RequireStepUp(s.mfaService), s.requirePasskeyStepUp, routing.Wrap(s.finishRegistration))
+		userRoute.Delete("/credentials/:id", mfa.RequireStepUp(s.mfaService), s.requirePasskeyStepUp, routing.Wrap(s.deleteCredential))
+		userRoute.Post("/step-up/begin", routing.Wrap(s.beginStepUp))
+		userRoute.Post("/step-up", routing.Wrap(s.finishStepUp))
+	}, middleware.ReqSignedInNoAnonymous)
+
+	router.Post("/api/login/webauthn/begin", routing.Wrap(s.beginLogin))
+	router.Post("/api/login/webauthn", routing.Wrap(s.finishLogin))
+}
+
+// requirePasskeyStepUp requires users that already have credentials to verify one of them
+// for the current session before credentials can be added or removed. Users that only
+// registered passkeys are not enrolled in TOTP, so mfa.RequireStepUp lets them through.
+func (s *Service) requirePasskeyStepUp(c *contextmodel.ReqContext) {
+	ctx := c.Req.Context()
+	creds, err := s.store.ListByUser(ctx, c.UserID)
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+
+	if len(creds) == 0 {
+		return
+	}
+
+	if c.UserToken == nil {
+		c.WriteErrOrFallback(http.StatusForbidden, http.StatusText(http.StatusForbidden), mfa.ErrStepUpRequired.Errorf("managing WebAuthn credentials requires a user session"))
+		return
+	}
+
+	steppedUp, err := s.mfaService.IsSteppedUp(ctx, c.UserToken.Id)
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+
+	if !steppedUp {
+		c.WriteErrOrFallback(http.StatusForbidden, http.StatusText(http.StatusForbidden), mfa.ErrStepUpRequired.Errorf("session has not verified an existing WebAuthn credential"))
+	}
+}
+
+func (s *Service) listCredentials(c *contextmodel.ReqContext) response.Response {
+	creds, err := s.store.ListByUser(c.Req.Context(), c.UserID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to list WebAuthn credentials", err)
+	}
+	return response.JSON(http.StatusOK, creds)
+}
+
+func (s *Service) beginRegistration(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	creds, err := s.store.ListByUser(ctx, c.UserID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to list WebAuthn credentials", err)
+	}
+
+	id, challenge, err := s.newChallenge(ctx, ceremonyCreate, c.UserID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to create WebAuthn challenge", err)
+	}
+
+	opts := creationOptions{
+		Challenge:          encodeBase64URL(challenge),
+		Timeout:            s.cfg.WebAuthnChallengeTimeout.Milliseconds(),
+		ExcludeCredentials: descriptors(creds),
+		// resident keys allow the credential to be used for passwordless login without entering a username
+		AuthenticatorSelection: map[string]string{"residentKey": "preferred", "userVerification": "preferred"},
+		Attestation:            "none",
+	}
+	opts.RP.ID = s.rpID
+	opts.RP.Name = s.cfg.WebAuthnRPName
+	opts.User.ID = encodeBase64URL([]byte(strconv.FormatInt(c.UserID, 10)))
+	opts.User.Name = c.Login
+	opts.User.DisplayName = c.NameOrFallback()
+	for _, alg := range supportedAlgorithms {
+		opts.PubKeyCredParams = append(opts.PubKeyCredParams, credentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	return response.JSON(http.StatusOK, ceremonyResponse{ChallengeID: id, PublicKey: opts})
+}
+
+func (s *Service) finishRegistration(c *contextmodel.ReqContext) response.Response {
+	cmd := registrationCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	cred, err := s.register(c.Req.Context(), c.UserID, &cmd)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to register WebAuthn credential", err)
+	}
+
+	return response.JSON(http.StatusOK, cred)
+}
+
+func (s *Service) register(ctx context.Context, userID int64, cmd *registrationCommand) (*Credential, error) {
+	session, err := s.consumeChallenge(ctx, cmd.ChallengeID, ceremonyCreate)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, ErrChallengeNotFound.Errorf("challenge was issued for another user")
+	}
+
+	clientDataJSON, err := decodeBase64URL(cmd.ClientDataJSON)
+	if err != nil {
+		return nil, ErrInvalidCredential.Errorf("failed to decode client data: %w", err)
+	}
+	rawAuthData, err := decodeBase64URL(cmd.AuthenticatorData)
+	if err != nil {
+		return nil, ErrInvalidCredential.Errorf("failed to decode authenticator data: %w", err)
+	}
+	publicKey, err := decodeBase64URL(cmd.PublicKey)
+	if err != nil {
+		return nil, ErrInvalidCredential.Errorf("failed to decode public key: %w", err)
+	}
+
+	if err := verifyClientData(clientDataJSON, ceremonyCreate, session.Challenge, s.origin); err != nil {
+		return nil, err
+	}
+
+	data, err := parseAuthenticatorData(rawAuthData, s.rpID)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.credentialID) == 0 {
+		return nil, ErrInvalidCredential.Errorf("authenticator data does not contain a credential")
+	}
+
+	if _, err := parsePublicKey(publicKey, cmd.PublicKeyAlgorithm); err != nil {
+		return nil, err
+	}
+
+	name := cmd.Name
+	if name == "" {
+		name = "Security key"
+	}
+
+	now := time.Now()
+	cred := &Credential{
+		UserID:       userID,
+		CredentialID: encodeBase64URL(data.credentialID),
+		Name:         name,
+		PublicKey:    encodeBase64URL(publicKey),
+		Algorithm:    cmd.PublicKeyAlgorithm,
+		SignCount:    int64(data.signCount),
+		Created:      now,
+		LastUsed:     now,
+	}
+
+	if err := s.store.Create(ctx, cred); err != nil {
+		return nil, err
+	}
+
+	return cred, nil
+}
+
+func (s *Service) deleteCredential(c *contextmodel.ReqContext) response.Response {
+	id, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+
+	deleted, err := s.store.Delete(c.Req.Context(), c.UserID, id)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to delete WebAuthn credential", err)
+	}
+	if !deleted {
+		return response.Err(ErrCredentialNotFound.Errorf("credential %d not found", id))
+	}
+
+	return response.Success("WebAuthn credential deleted")
+}
+
+func (s *Service) beginStepUp(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	creds, err := s.store.ListByUser(ctx, c.UserID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to list WebAuthn credentials", err)
+	}
+	if len(creds) == 0 {
+		return response.Err(ErrCredentialNotFound.Errorf("user has no credentials"))
+	}
+
+	return s.requestOptionsResponse(ctx, c.UserID, descriptors(creds), "preferred")
+}
+
+func (s *Service) finishStepUp(c *contextmodel.ReqContext) response.Response {
+	cmd := assertionCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	if c.UserToken == nil {
+		return response.Err(mfa.ErrSessionRequired.Errorf("request is not authenticated with a session"))
+	}
+
+	ctx := c.Req.Context()
+	session, err := s.consumeChallenge(ctx, cmd.ChallengeID, ceremonyGet)
+	if err != nil {
+		return response.Err(err)
+	}
+	if session.UserID != c.UserID {
+		return response.Err(ErrChallengeNotFound.Errorf("challenge was issued for another user"))
+	}
+
+	if _, _, err := s.verifyAssertion(ctx, session, &cmd); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to verify WebAuthn assertion", err)
+	}
+
+	if err := s.mfaService.StepUp(ctx, c.UserToken.Id, authn.AMRHardwareKey); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to step up session", err)
+	}
+
+	return response.Success("Session verified")
+}
+
+func (s *Service) beginLogin(c *contextmodel.ReqContext) response.Response {
+	// Only discoverable credentials are supported for login so the response
+	// does not reveal which users exist or have credentials registered.
+	return s.requestOptionsResponse(c.Req.Context(), 0, []credentialDescriptor{}, "required")
+}
+
+func (s *Service) finishLogin(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	identity, err := s.authnService.Login(ctx, authn.ClientWebAuthn, &authn.Request{HTTPRequest: c.Req, Resp: c.Resp})
+	if err != nil {
+		return response.Err(err)
+	}
+
+	cookies.WriteSessionCookie(c, s.cfg, identity.SessionToken.UnhashedToken, s.cfg.LoginMaxLifetime)
+
+	// a passkey verified by the authenticator satisfies the second factor for the new session
+	if identity.AssuranceLevel == authn.AssuranceLevelMultiFactor {
+		if err := s.mfaService.StepUp(ctx, identity.SessionToken.Id, authn.AMRHardwareKey); err != nil {
+			s.log.FromContext(ctx).Warn("Failed to step up session after WebAuthn login", "error", err)
+		}
+	}
+
+	return response.JSON(http.StatusOK, map[string]interface{}{"message": "Logged in"})
+}
+
+func (s *Service) requestOptionsResponse(ctx context.Context, userID int64, allow []credentialDescriptor, userVerification string) response.Response {
+	id, challenge, err := s.newChallenge(ctx, ceremonyGet, userID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to create WebAuthn challenge", err)
+	}
+
+	return response.JSON(http.StatusOK, ceremonyResponse{ChallengeID: id, PublicKey: requestOptions{
+		Challenge:        encodeBase64URL(challenge),
+		RPID:             s.rpID,
+		Timeout:          s.cfg.WebAuthnChallengeTimeout.Milliseconds(),
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}})
+}
+
+func descriptors(creds []*Credential) []credentialDescriptor {
+	result := make([]credentialDescriptor, 0, len(creds))
+	for _, cred := range creds {
+		result = append(result, credentialDescriptor{Type: "public-key", ID: cred.CredentialID})
+	}
+	return result
+}
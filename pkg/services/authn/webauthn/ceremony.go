@@ -0,0 +1,177 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+)
+
+// COSE algorithm identifiers supported for credential public keys.
+const (
+	algES256 int64 = -7
+	algEdDSA int64 = -8
+	algRS256 int64 = -257
+)
+
+var supportedAlgorithms = []int64{algES256, algEdDSA, algRS256}
+
+// Authenticator data flags, see https://www.w3.org/TR/webauthn-2/#flags
+const (
+	flagUserPresent        byte = 0x01
+	flagUserVerified       byte = 0x04
+	flagAttestedCredential byte = 0x40
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+)
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+}
+
+func (d *authenticatorData) userPresent() bool  { return d.flags&flagUserPresent != 0 }
+func (d *authenticatorData) userVerified() bool { return d.flags&flagUserVerified != 0 }
+
+// decodeBase64URL decodes base64url with or without padding, as browsers and
+// client libraries do not agree on using padding.
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func encodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// verifyClientData checks the client data collected by the browser against the expected ceremony.
+func verifyClientData(raw []byte, ceremony string, challenge []byte, origin string) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return ErrInvalidCredential.Errorf("failed to parse client data: %w", err)
+	}
+
+	if data.Type != ceremony {
+		return ErrInvalidCredential.Errorf("unexpected ceremony type %q", data.Type)
+	}
+
+	received, err := decodeBase64URL(data.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return ErrInvalidCredential.Errorf("challenge mismatch")
+	}
+
+	if data.Origin != origin {
+		return ErrInvalidCredential.Errorf("unexpected origin %q", data.Origin)
+	}
+
+	return nil
+}
+
+// parseAuthenticatorData parses the authenticator data and verifies it is scoped to the relying party.
+func parseAuthenticatorData(raw []byte, rpID string) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, ErrInvalidCredential.Errorf("authenticator data too short")
+	}
+
+	data := &authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	expected := sha256.Sum256([]byte(rpID))
+	if subtle.ConstantTimeCompare(data.rpIDHash, expected[:]) != 1 {
+		return nil, ErrInvalidCredential.Errorf("relying party id mismatch")
+	}
+
+	if !data.userPresent() {
+		return nil, ErrInvalidCredential.Errorf("user presence flag not set")
+	}
+
+	// attested credential data: aaguid (16) | credential id length (2) | credential id | public key
+	if data.flags&flagAttestedCredential != 0 {
+		if len(raw) < 55 {
+			return nil, ErrInvalidCredential.Errorf("attested credential data too short")
+		}
+		idLen := int(binary.BigEndian.Uint16(raw[53:55]))
+		if len(raw) < 55+idLen {
+			return nil, ErrInvalidCredential.Errorf("attested credential data too short")
+		}
+		data.credentialID = raw[55 : 55+idLen]
+	}
+
+	return data, nil
+}
+
+// parsePublicKey parses a DER encoded SubjectPublicKeyInfo, as returned by
+// AuthenticatorAttestationResponse.getPublicKey(), and checks it matches the algorithm.
+func parsePublicKey(der []byte, alg int64) (crypto.PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, ErrInvalidCredential.Errorf("failed to parse public key: %w", err)
+	}
+
+	switch key.(type) {
+	case *ecdsa.PublicKey:
+		if alg == algES256 {
+			return key, nil
+		}
+	case ed25519.PublicKey:
+		if alg == algEdDSA {
+			return key, nil
+		}
+	case *rsa.PublicKey:
+		if alg == algRS256 {
+			return key, nil
+		}
+	}
+
+	return nil, ErrInvalidCredential.Errorf("unsupported public key algorithm %d", alg)
+}
+
+// verifySignature verifies an assertion signature over authenticatorData || sha256(clientDataJSON).
+func verifySignature(der []byte, alg int64, authData, clientDataJSON, signature []byte) error {
+	key, err := parsePublicKey(der, alg)
+	if err != nil {
+		return err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := make([]byte, 0, len(authData)+len(clientDataHash))
+	signed = append(signed, authData...)
+	signed = append(signed, clientDataHash[:]...)
+
+	valid := false
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		valid = ecdsa.VerifyASN1(k, digest[:], signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(k, signed, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		valid = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+	}
+
+	if !valid {
+		return ErrInvalidCredential.Errorf("invalid signature")
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package webauthn
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+type store interface {
+	// GetByCredentialID returns the credential or nil if no credential has the id.
+	GetByCredentialID(ctx context.Context, credentialID string) (*Credential, error)
+	ListByUser(ctx context.Context, userID int64) ([]*Credential, error)
+	Create(ctx context.Context, cred *Credential) error
+	UpdateUsage(ctx context.Context, id int64, signCount int64, lastUsed time.Time) error
+	Delete(ctx context.Context, userID, id int64) (bool, error)
+}
+
+type xormStore struct {
+	db db.DB
+}
+
+func (s *xormStore) GetByCredentialID(ctx context.Context, credentialID string) (*Credential, error) {
+	var cred Credential
+	var has bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		has, err = sess.Where("credential_id = ?", credentialID).Get(&cred)
+		return err
+	})
+	if err != nil || !has {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+func (s *xormStore) ListByUser(ctx context.Context, userID int64) ([]*Credential, error) {
+	creds := make([]*Credential, 0)
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("user_id = ?", userID).Asc("id").Find(&creds)
+	})
+	return creds, err
+}
+
+func (s *xormStore) Create(ctx context.Context, cred *Credential) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Insert(cred)
+		return err
+	})
+}
+
+func (s *xormStore) UpdateUsage(ctx context.Context, id int64, signCount int64, lastUsed time.Time) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec("UPDATE user_webauthn_credential SET sign_count = ?, last_used = ? WHERE id = ?", signCount, lastUsed, id)
+		return err
+	})
+}
+
+func (s *xormStore) Delete(ctx context.Context, userID, id int64) (bool, error) {
+	var deleted bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("DELETE FROM user_webauthn_credential WHERE user_id = ? AND id = ?", userID, id)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		deleted = affected > 0
+		return err
+	})
+	return deleted, err
+}
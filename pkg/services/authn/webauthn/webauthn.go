@@ -0,0 +1,261 @@
+package webauthn
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/authn/clients"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+var (
+	ErrInvalidCredential        = errutil.NewBase(errutil.StatusUnauthorized, "webauthn.invalid-credential", errutil.WithPublicMessage("Invalid WebAuthn credential"))
+	ErrChallengeNotFound        = errutil.NewBase(errutil.StatusBadRequest, "webauthn.challenge-not-found", errutil.WithPublicMessage("WebAuthn challenge expired or not found"))
+	ErrCredentialNotFound       = errutil.NewBase(errutil.StatusNotFound, "webauthn.credential-not-found", errutil.WithPublicMessage("WebAuthn credential not found"))
+	ErrUserVerificationRequired = errutil.NewBase(errutil.StatusUnauthorized, "webauthn.user-verification-required", errutil.WithPublicMessage("Passwordless login requires user verification"))
+)
+
+var _ clients.WebAuthnVerifier = new(Service)
+
+// Credential is a WebAuthn public key credential registered by a user.
+type Credential struct {
+	ID     int64 `xorm:"pk autoincr 'id'" json:"id"`
+	UserID int64 `xorm:"user_id" json:"-"`
+	// CredentialID is the base64url encoded credential id chosen by the authenticator.
+	CredentialID string `xorm:"credential_id" json:"credentialId"`
+	Name         string `xorm:"name" json:"name"`
+	// PublicKey is the base64url encoded SubjectPublicKeyInfo of the credential.
+	PublicKey string    `xorm:"public_key" json:"-"`
+	Algorithm int64     `xorm:"algorithm" json:"-"`
+	SignCount int64     `xorm:"sign_count" json:"-"`
+	Created   time.Time `xorm:"created" json:"created"`
+	LastUsed  time.Time `xorm:"last_used" json:"lastUsed"`
+}
+
+func (c Credential) TableName() string { return "user_webauthn_credential" }
+
+// challengeSession is stored in the remote cache between the start and the end of a ceremony.
+type challengeSession struct {
+	Ceremony  string `json:"ceremony"`
+	Challenge []byte `json:"challenge"`
+	// UserID is set for registrations, step-up and logins started for a known user.
+	UserID int64 `json:"userId"`
+}
+
+type cache interface {
+	GetByteArray(ctx context.Context, key string) ([]byte, error)
+	SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error
+	SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type Service struct {
+	cfg          *setting.Cfg
+	store        store
+	cache        cache
+	userService  user.Service
+	authnService authn.Service
+	mfaService   mfa.Service
+	log          log.Logger
+
+	rpID   string
+	origin string
+}
+
+func ProvideService(
+	cfg *setting.Cfg, sqlStore db.DB, remoteCache *remotecache.RemoteCache, router routing.RouteRegister,
+	authnService authn.Service, userService user.Service, mfaService mfa.Service,
+) *Service {
+	s := &Service{
+		cfg:          cfg,
+		store:        &xormStore{db: sqlStore},
+		cache:        remoteCache,
+		userService:  userService,
+		authnService: authnService,
+		mfaService:   mfaService,
+		log:          log.New("authn.webauthn"),
+	}
+
+	if !cfg.WebAuthnEnabled {
+		return s
+	}
+
+	appURL, err := url.Parse(cfg.AppURL)
+	if err != nil {
+		s.log.Error("Failed to configure WebAuthn, invalid root_url", "error", err)
+		return s
+	}
+
+	s.origin = appURL.Scheme + "://" + appURL.Host
+	s.rpID = cfg.WebAuthnRPID
+	if s.rpID == "" {
+		s.rpID = appURL.Hostname()
+	}
+
+	s.registerAPIEndpoints(router)
+	authnService.RegisterClient(clients.ProvideWebAuthn(s, userService))
+
+	return s
+}
+
+// VerifyAssertion verifies a login assertion and returns the credential used.
+// Passwordless logins require the authenticator to have verified the user.
+func (s *Service) VerifyAssertion(ctx context.Context, body []byte) (*clients.WebAuthnAssertion, error) {
+	var cmd assertionCommand
+	if err := json.Unmarshal(body, &cmd); err != nil {
+		return nil, ErrInvalidCredential.Errorf("failed to parse assertion: %w", err)
+	}
+
+	session, err := s.consumeChallenge(ctx, cmd.ChallengeID, ceremonyGet)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, data, err := s.verifyAssertion(ctx, session, &cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if !data.userVerified() {
+		return nil, ErrUserVerificationRequired.Errorf("authenticator did not verify the user")
+	}
+
+	return &clients.WebAuthnAssertion{UserID: cred.UserID, UserVerified: true}, nil
+}
+
+func (s *Service) verifyAssertion(ctx context.Context, session *challengeSession, cmd *assertionCommand) (*Credential, *authenticatorData, error) {
+	cred, err := s.store.GetByCredentialID(ctx, cmd.CredentialID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cred == nil {
+		return nil, nil, ErrInvalidCredential.Errorf("unknown credential")
+	}
+
+	// The challenge was issued for a specific user
+	if session.UserID != 0 && session.UserID != cred.UserID {
+		return nil, nil, ErrInvalidCredential.Errorf("credential does not belong to the user the challenge was issued for")
+	}
+
+	clientDataJSON, err := decodeBase64URL(cmd.ClientDataJSON)
+	if err != nil {
+		return nil, nil, ErrInvalidCredential.Errorf("failed to decode client data: %w", err)
+	}
+	rawAuthData, err := decodeBase64URL(cmd.AuthenticatorData)
+	if err != nil {
+		return nil, nil, ErrInvalidCredential.Errorf("failed to decode authenticator data: %w", err)
+	}
+	signature, err := decodeBase64URL(cmd.Signature)
+	if err != nil {
+		return nil, nil, ErrInvalidCredential.Errorf("failed to decode signature: %w", err)
+	}
+	publicKey, err := decodeBase64URL(cred.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := verifyClientData(clientDataJSON, ceremonyGet, session.Challenge, s.origin); err != nil {
+		return nil, nil, err
+	}
+
+	data, err := parseAuthenticatorData(rawAuthData, s.rpID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := verifySignature(publicKey, cred.Algorithm, rawAuthData, clientDataJSON, signature); err != nil {
+		return nil, nil, err
+	}
+
+	// Authenticators that support signature counters must always increase it,
+	// a counter that did not increase indicates a cloned authenticator.
+	if (data.signCount != 0 || cred.SignCount != 0) && int64(data.signCount) <= cred.SignCount {
+		return nil, nil, ErrInvalidCredential.Errorf("signature counter did not increase, credential may be cloned")
+	}
+
+	if err := s.store.UpdateUsage(ctx, cred.ID, int64(data.signCount), time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	return cred, data, nil
+}
+
+func (s *Service) newChallenge(ctx context.Context, ceremony string, userID int64) (string, []byte, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", nil, err
+	}
+
+	value, err := json.Marshal(challengeSession{Ceremony: ceremony, Challenge: challenge, UserID: userID})
+	if err != nil {
+		return "", nil, err
+	}
+
+	id := util.GenerateShortUID()
+	if err := s.cache.SetByteArray(ctx, challengeKey(id), value, s.cfg.WebAuthnChallengeTimeout); err != nil {
+		return "", nil, err
+	}
+
+	return id, challenge, nil
+}
+
+// consumeChallenge returns the challenge and removes it so it can only be used once.
+func (s *Service) consumeChallenge(ctx context.Context, id, ceremony string) (*challengeSession, error) {
+	if id == "" {
+		return nil, ErrChallengeNotFound.Errorf("missing challenge id")
+	}
+
+	// Reading and deleting the challenge is not atomic, so concurrent requests
+	// first have to claim it and only one of them can succeed.
+	claimed, err := s.cache.SetIfNotExists(ctx, challengeUsedKey(id), []byte{1}, s.cfg.WebAuthnChallengeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrChallengeNotFound.Errorf("challenge %s has already been used", id)
+	}
+
+	value, err := s.cache.GetByteArray(ctx, challengeKey(id))
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return nil, ErrChallengeNotFound.Errorf("challenge %s not found", id)
+		}
+		return nil, err
+	}
+
+	if err := s.cache.Delete(ctx, challengeKey(id)); err != nil {
+		return nil, err
+	}
+
+	var session challengeSession
+	if err := json.Unmarshal(value, &session); err != nil {
+		return nil, err
+	}
+
+	if session.Ceremony != ceremony {
+		return nil, ErrChallengeNotFound.Errorf("challenge %s was issued for another ceremony", id)
+	}
+
+	return &session, nil
+}
+
+func challengeKey(id string) string {
+	return "webauthn-challenge:" + id
+}
+
+func challengeUsedKey(id string) string {
+	return "webauthn-challenge-used:" + id
+}
@@ -0,0 +1,322 @@
+package webauthn
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/auth"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const (
+	testRPID   = "grafana.example.com"
+	testOrigin = "https://grafana.example.com"
+)
+
+func TestService_RegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+	s := setupTests(t)
+	authenticator := newTestAuthenticator(t)
+
+	// register
+	challengeID, challenge, err := s.newChallenge(ctx, ceremonyCreate, 1)
+	require.NoError(t, err)
+
+	cred, err := s.register(ctx, 1, authenticator.attest(t, challengeID, challenge))
+	require.NoError(t, err)
+	assert.Equal(t, encodeBase64URL(authenticator.credentialID), cred.CredentialID)
+
+	// the challenge can only be used once
+	_, err = s.register(ctx, 1, authenticator.attest(t, challengeID, challenge))
+	assert.ErrorIs(t, err, ErrChallengeNotFound)
+
+	// login
+	challengeID, challenge, err = s.newChallenge(ctx, ceremonyGet, 0)
+	require.NoError(t, err)
+
+	body, err := json.Marshal(authenticator.assert(t, challengeID, challenge, testOrigin, true))
+	require.NoError(t, err)
+
+	assertion, err := s.VerifyAssertion(ctx, body)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), assertion.UserID)
+	assert.True(t, assertion.UserVerified)
+}
+
+func TestService_ConsumeChallengeConcurrently(t *testing.T) {
+	ctx := context.Background()
+	s := setupTests(t)
+
+	challengeID, _, err := s.newChallenge(ctx, ceremonyGet, 0)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var consumed int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.consumeChallenge(ctx, challengeID, ceremonyGet); err == nil {
+				atomic.AddInt32(&consumed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), consumed)
+}
+
+func TestService_RequirePasskeyStepUp(t *testing.T) {
+	tests := []struct {
+		desc         string
+		creds        []*Credential
+		token        *auth.UserToken
+		steppedUp    bool
+		expectedCode int
+	}{
+		{desc: "user without credentials", token: &auth.UserToken{Id: 1}, expectedCode: http.StatusOK},
+		{desc: "session not stepped up", creds: []*Credential{{UserID: 1}}, token: &auth.UserToken{Id: 1}, expectedCode: http.StatusForbidden},
+		{desc: "session stepped up", creds: []*Credential{{UserID: 1}}, token: &auth.UserToken{Id: 1}, steppedUp: true, expectedCode: http.StatusOK},
+		{desc: "request without a session", creds: []*Credential{{UserID: 1}}, expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			s := setupTests(t)
+			s.store = &fakeStore{creds: tt.creds}
+			s.mfaService = fakeMFAService{steppedUp: tt.steppedUp}
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			recorder := httptest.NewRecorder()
+			c := &contextmodel.ReqContext{
+				Context:      &web.Context{Req: req, Resp: web.NewResponseWriter(req.Method, recorder)},
+				SignedInUser: &user.SignedInUser{UserID: 1},
+				UserToken:    tt.token,
+				Logger:       log.NewNopLogger(),
+			}
+
+			s.requirePasskeyStepUp(c)
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestService_ChallengeTimeout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := setupTests(t)
+	s.cache = remotecache.NewFakeMemoryStore(t, func() time.Time { return now })
+	authenticator := newTestAuthenticator(t)
+
+	challengeID, challenge, err := s.newChallenge(ctx, ceremonyCreate, 1)
+	require.NoError(t, err)
+
+	now = now.Add(s.cfg.WebAuthnChallengeTimeout)
+	_, err = s.register(ctx, 1, authenticator.attest(t, challengeID, challenge))
+	assert.ErrorIs(t, err, ErrChallengeNotFound)
+}
+
+func TestService_VerifyAssertion(t *testing.T) {
+	type testCase struct {
+		desc         string
+		origin       string
+		userVerified bool
+		signCount    uint32
+		expectedErr  error
+	}
+
+	tests := []testCase{
+		{desc: "should reject assertion from another origin", origin: "https://evil.example.com", userVerified: true, expectedErr: ErrInvalidCredential},
+		{desc: "should require user verification for login", origin: testOrigin, userVerified: false, expectedErr: ErrUserVerificationRequired},
+		{desc: "should reject assertion with a sign count that did not increase", origin: testOrigin, userVerified: true, signCount: 5, expectedErr: ErrInvalidCredential},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ctx := context.Background()
+			s := setupTests(t)
+			authenticator := newTestAuthenticator(t)
+
+			challengeID, challenge, err := s.newChallenge(ctx, ceremonyCreate, 1)
+			require.NoError(t, err)
+			_, err = s.register(ctx, 1, authenticator.attest(t, challengeID, challenge))
+			require.NoError(t, err)
+
+			if tt.signCount != 0 {
+				s.store.(*fakeStore).creds[0].SignCount = int64(tt.signCount)
+				authenticator.signCount = tt.signCount - 1
+			}
+
+			challengeID, challenge, err = s.newChallenge(ctx, ceremonyGet, 0)
+			require.NoError(t, err)
+			body, err := json.Marshal(authenticator.assert(t, challengeID, challenge, tt.origin, tt.userVerified))
+			require.NoError(t, err)
+
+			_, err = s.VerifyAssertion(ctx, body)
+			assert.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func setupTests(t *testing.T) *Service {
+	t.Helper()
+
+	cfg := setting.NewCfg()
+	cfg.WebAuthnChallengeTimeout = time.Minute
+
+	return &Service{
+		cfg:    cfg,
+		store:  &fakeStore{},
+		cache:  remotecache.NewFakeMemoryStore(t, nil),
+		log:    log.NewNopLogger(),
+		rpID:   testRPID,
+		origin: testOrigin,
+	}
+}
+
+type fakeMFAService struct {
+	mfa.Service
+	steppedUp bool
+}
+
+func (f fakeMFAService) IsSteppedUp(ctx context.Context, sessionID int64) (bool, error) {
+	return f.steppedUp, nil
+}
+
+type testAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return &testAuthenticator{key: key, credentialID: []byte("test-credential")}
+}
+
+func (a *testAuthenticator) authData(flags byte, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+	}
+	return data
+}
+
+func clientDataJSON(t *testing.T, ceremony string, challenge []byte, origin string) []byte {
+	raw, err := json.Marshal(clientData{Type: ceremony, Challenge: encodeBase64URL(challenge), Origin: origin})
+	require.NoError(t, err)
+	return raw
+}
+
+func (a *testAuthenticator) attest(t *testing.T, challengeID string, challenge []byte) *registrationCommand {
+	publicKey, err := x509.MarshalPKIXPublicKey(&a.key.PublicKey)
+	require.NoError(t, err)
+
+	return &registrationCommand{
+		ChallengeID:        challengeID,
+		Name:               "test key",
+		ClientDataJSON:     encodeBase64URL(clientDataJSON(t, ceremonyCreate, challenge, testOrigin)),
+		AuthenticatorData:  encodeBase64URL(a.authData(flagUserPresent|flagAttestedCredential, true)),
+		PublicKey:          encodeBase64URL(publicKey),
+		PublicKeyAlgorithm: algES256,
+	}
+}
+
+func (a *testAuthenticator) assert(t *testing.T, challengeID string, challenge []byte, origin string, userVerified bool) *assertionCommand {
+	a.signCount++
+	flags := flagUserPresent
+	if userVerified {
+		flags |= flagUserVerified
+	}
+
+	authData := a.authData(flags, false)
+	clientData := clientDataJSON(t, ceremonyGet, challenge, origin)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	return &assertionCommand{
+		ChallengeID:       challengeID,
+		CredentialID:      encodeBase64URL(a.credentialID),
+		ClientDataJSON:    encodeBase64URL(clientData),
+		AuthenticatorData: encodeBase64URL(authData),
+		Signature:         encodeBase64URL(signature),
+	}
+}
+
+type fakeStore struct {
+	creds []*Credential
+}
+
+func (f *fakeStore) GetByCredentialID(ctx context.Context, credentialID string) (*Credential, error) {
+	for _, c := range f.creds {
+		if c.CredentialID == credentialID {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeStore) ListByUser(ctx context.Context, userID int64) ([]*Credential, error) {
+	var result []*Credential
+	for _, c := range f.creds {
+		if c.UserID == userID {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeStore) Create(ctx context.Context, cred *Credential) error {
+	cred.ID = int64(len(f.creds) + 1)
+	f.creds = append(f.creds, cred)
+	return nil
+}
+
+func (f *fakeStore) UpdateUsage(ctx context.Context, id int64, signCount int64, lastUsed time.Time) error {
+	for _, c := range f.creds {
+		if c.ID == id {
+			c.SignCount = signCount
+			c.LastUsed = lastUsed
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) Delete(ctx context.Context, userID, id int64) (bool, error) {
+	for i, c := range f.creds {
+		if c.ID == id && c.UserID == userID {
+			f.creds = append(f.creds[:i], f.creds[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
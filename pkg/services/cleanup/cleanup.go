@@ -13,6 +13,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/serverlock"
 	"github.com/grafana/grafana/pkg/infra/tracing"
@@ -29,7 +30,7 @@ import (
 func ProvideService(cfg *setting.Cfg, serverLockService *serverlock.ServerLockService,
 	shortURLService shorturls.Service, sqlstore db.DB, queryHistoryService queryhistory.Service,
 	dashboardVersionService dashver.Service, dashSnapSvc dashboardsnapshots.Service, deleteExpiredImageService *image.DeleteExpiredService,
-	tempUserService tempuser.Service, tracer tracing.Tracer, annotationCleaner annotations.Cleaner) *CleanUpService {
+	tempUserService tempuser.Service, tracer tracing.Tracer, annotationCleaner annotations.Cleaner, kvStore kvstore.KVStore) *CleanUpService {
 	s := &CleanUpService{
 		Cfg:                       cfg,
 		ServerLockService:         serverLockService,
@@ -43,6 +44,7 @@ func ProvideService(cfg *setting.Cfg, serverLockService *serverlock.ServerLockSe
 		tempUserService:           tempUserService,
 		tracer:                    tracer,
 		annotationCleaner:         annotationCleaner,
+		kvStore:                   kvStore,
 	}
 	return s
 }
@@ -60,6 +62,7 @@ type CleanUpService struct {
 	deleteExpiredImageService *image.DeleteExpiredService
 	tempUserService           tempuser.Service
 	annotationCleaner         annotations.Cleaner
+	kvStore                   kvstore.KVStore
 }
 
 type cleanUpJob struct {
@@ -102,6 +105,7 @@ func (srv *CleanUpService) clean(ctx context.Context) {
 		{"expire old user invites", srv.expireOldUserInvites},
 		{"delete stale short URLs", srv.deleteStaleShortURLs},
 		{"delete stale query history", srv.deleteStaleQueryHistory},
+		{"delete expired kvstore items", srv.deleteExpiredKVStoreItems},
 	}
 
 	logger := srv.log.FromContext(ctx)
@@ -237,6 +241,16 @@ func (srv *CleanUpService) expireOldUserInvites(ctx context.Context) {
 	}
 }
 
+func (srv *CleanUpService) deleteExpiredKVStoreItems(ctx context.Context) {
+	logger := srv.log.FromContext(ctx)
+	deleted, err := srv.kvStore.DeleteExpired(ctx)
+	if err != nil {
+		logger.Error("Problem deleting expired kvstore items", "error", err.Error())
+	} else {
+		logger.Debug("Deleted expired kvstore items", "rows affected", deleted)
+	}
+}
+
 func (srv *CleanUpService) deleteStaleShortURLs(ctx context.Context) {
 	logger := srv.log.FromContext(ctx)
 	cmd := shorturls.DeleteShortUrlCommand{
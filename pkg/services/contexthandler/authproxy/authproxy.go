@@ -18,6 +18,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/remotecache"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/ldap"
 	"github.com/grafana/grafana/pkg/services/ldap/service"
 	"github.com/grafana/grafana/pkg/services/login"
@@ -50,7 +51,12 @@ type AuthProxy struct {
 
 func ProvideAuthProxy(cfg *setting.Cfg, remoteCache *remotecache.RemoteCache,
 	loginService login.Service, userService user.Service,
-	sqlStore db.DB, ldapService service.LDAP) *AuthProxy {
+	sqlStore db.DB, ldapService service.LDAP, features featuremgmt.FeatureToggles) (*AuthProxy, error) {
+	// signed requests are only validated by the authn proxy client, refuse to silently accept unsigned requests
+	if cfg.AuthProxyEnabled && cfg.AuthProxySignatureSecret != "" && !features.IsEnabled(featuremgmt.FlagAuthnService) {
+		return nil, errors.New("[auth.proxy] signature_secret requires the authnService feature toggle")
+	}
+
 	return &AuthProxy{
 		cfg:          cfg,
 		remoteCache:  remoteCache,
@@ -59,7 +65,7 @@ func ProvideAuthProxy(cfg *setting.Cfg, remoteCache *remotecache.RemoteCache,
 		userService:  userService,
 		logger:       log.New("auth.proxy"),
 		ldapService:  ldapService,
-	}
+	}, nil
 }
 
 // Error auth proxy specific error
@@ -12,6 +12,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/infra/remotecache"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/ldap/service"
 	"github.com/grafana/grafana/pkg/services/login"
 	"github.com/grafana/grafana/pkg/services/login/loginservice"
@@ -48,7 +49,9 @@ func prepareMiddleware(t *testing.T, remoteCache *remotecache.RemoteCache, confi
 		},
 	}
 
-	return ProvideAuthProxy(cfg, remoteCache, loginService, nil, nil, service.NewLDAPFakeService()), ctx
+	authProxy, err := ProvideAuthProxy(cfg, remoteCache, loginService, nil, nil, service.NewLDAPFakeService(), featuremgmt.WithFeatures())
+	require.NoError(t, err)
+	return authProxy, ctx
 }
 
 func TestMiddlewareContext(t *testing.T) {
@@ -166,3 +169,21 @@ func TestDecodeHeader(t *testing.T) {
 		assert.Equal(t, "München", header)
 	})
 }
+
+func TestProvideAuthProxy_SignatureSecret(t *testing.T) {
+	cfg := setting.NewCfg()
+	cfg.AuthProxyEnabled = true
+	cfg.AuthProxySignatureSecret = "secret"
+
+	t.Run("should refuse a signature secret without authnService", func(t *testing.T) {
+		authProxy, err := ProvideAuthProxy(cfg, nil, loginservice.LoginServiceMock{}, nil, nil, service.NewLDAPFakeService(), featuremgmt.WithFeatures())
+		assert.Error(t, err)
+		assert.Nil(t, authProxy)
+	})
+
+	t.Run("should accept a signature secret with authnService", func(t *testing.T) {
+		authProxy, err := ProvideAuthProxy(cfg, nil, loginservice.LoginServiceMock{}, nil, nil, service.NewLDAPFakeService(), featuremgmt.WithFeatures(featuremgmt.FlagAuthnService))
+		require.NoError(t, err)
+		assert.NotNil(t, authProxy)
+	})
+}
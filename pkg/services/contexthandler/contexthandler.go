@@ -584,7 +584,7 @@ func (h *ContextHandler) rotateEndOfRequestFunc(reqContext *contextmodel.ReqCont
 
 		if rotated {
 			reqContext.UserToken = newToken
-			cookies.WriteSessionCookie(reqContext, h.Cfg, newToken.UnhashedToken, h.Cfg.LoginMaxLifetime)
+			cookies.WriteSessionCookie(reqContext, h.Cfg, newToken.UnhashedToken, newToken.TimeToLive(h.Cfg.LoginMaxLifetime))
 		}
 	}
 }
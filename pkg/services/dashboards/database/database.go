@@ -984,7 +984,8 @@ func (d *DashboardStore) FindDashboards(ctx context.Context, query *dashboards.F
 
 	sql, params := sb.ToSQL(limit, page)
 
-	err := d.store.WithDbSession(ctx, func(sess *db.Session) error {
+	err := d.store.WithDbSession(db.WithTransientErrorRetries(db.WithReadReplica(ctx)), func(sess *db.Session) error {
+		res = res[:0]
 		return sess.SQL(sql, params...).Find(&res)
 	})
 
@@ -56,6 +56,10 @@ func (m *LDAPMock) User(login string) (*login.ExternalUserInfo, ldap.ServerConfi
 	return m.UserSearchResult, m.UserSearchConfig, m.UserSearchError
 }
 
+func (m *LDAPMock) HealthCheck() {}
+
+func (m *LDAPMock) Close() {}
+
 func setupAPITest(t *testing.T, opts ...func(a *Service)) (*Service, *webtest.Server) {
 	t.Helper()
 	router := routing.NewRouteRegister()
@@ -49,19 +49,32 @@ type IMultiLDAP interface {
 	User(login string) (
 		*login.ExternalUserInfo, ldap.ServerConfig, error,
 	)
+
+	// HealthCheck dials all configured servers and updates their health,
+	// healthy servers are tried before unhealthy ones.
+	HealthCheck()
+	// Close closes all pooled connections.
+	Close()
 }
 
 // MultiLDAP is basic struct of LDAP authorization
 type MultiLDAP struct {
 	configs []*ldap.ServerConfig
+	pools   []*connPool
 	cfg     *setting.Cfg
 	log     log.Logger
 }
 
 // New creates the new LDAP auth
 func New(configs []*ldap.ServerConfig, cfg *setting.Cfg) IMultiLDAP {
+	pools := make([]*connPool, 0, len(configs))
+	for _, config := range configs {
+		pools = append(pools, newConnPool(config, cfg))
+	}
+
 	return &MultiLDAP{
 		configs: configs,
+		pools:   pools,
 		cfg:     cfg,
 		log:     log.New("ldap"),
 	}
@@ -74,27 +87,69 @@ func (multiples *MultiLDAP) Ping() ([]*ServerStatus, error) {
 	}
 
 	serverStatuses := []*ServerStatus{}
-	for _, config := range multiples.configs {
-		status := &ServerStatus{}
+	for _, pool := range multiples.pools {
+		serverStatuses = append(serverStatuses, pool.healthCheck())
+	}
+
+	return serverStatuses, nil
+}
 
-		status.Host = config.Host
-		status.Port = config.Port
+// HealthCheck dials each of the LDAP servers and updates their health.
+func (multiples *MultiLDAP) HealthCheck() {
+	for _, pool := range multiples.pools {
+		if status := pool.healthCheck(); !status.Available {
+			logDialFailure(status.Error, pool.config)
+		}
+	}
+}
 
-		server := newLDAP(config, multiples.cfg)
-		err := server.Dial()
+// Close closes all pooled connections
+func (multiples *MultiLDAP) Close() {
+	for _, pool := range multiples.pools {
+		pool.close()
+	}
+}
 
-		if err == nil {
-			status.Available = true
-			serverStatuses = append(serverStatuses, status)
-			server.Close()
+// orderedPools returns the pools of healthy servers followed by the pools of
+// unhealthy servers, both in configuration order.
+func (multiples *MultiLDAP) orderedPools() []*connPool {
+	ordered := make([]*connPool, 0, len(multiples.pools))
+	var unhealthy []*connPool
+	for _, pool := range multiples.pools {
+		if pool.isHealthy() {
+			ordered = append(ordered, pool)
 		} else {
-			status.Available = false
-			status.Error = err
-			serverStatuses = append(serverStatuses, status)
+			unhealthy = append(unhealthy, pool)
 		}
 	}
+	return append(ordered, unhealthy...)
+}
 
-	return serverStatuses, nil
+// withServer runs fn with a connection from the pool. If a reused connection
+// fails with an unexpected error, fn is retried once with a new connection.
+// The returned bool is true if the server could not be dialed.
+func withServer[T any](pool *connPool, fn func(server ldap.IServer) (T, error)) (T, bool, error) {
+	var zero T
+
+	server, reused, err := pool.get()
+	if err != nil {
+		return zero, true, err
+	}
+
+	result, err := fn(server)
+	pool.put(server, err)
+	if err == nil || !reused || isSilentError(err) {
+		return result, false, err
+	}
+
+	server, err = pool.dial()
+	if err != nil {
+		return zero, true, err
+	}
+
+	result, err = fn(server)
+	pool.put(server, err)
+	return result, false, err
 }
 
 // Login tries to log in the user in multiples LDAP
@@ -107,29 +162,29 @@ func (multiples *MultiLDAP) Login(query *login.LoginUserQuery) (
 
 	ldapSilentErrors := []error{}
 
-	for index, config := range multiples.configs {
-		server := newLDAP(config, multiples.cfg)
+	pools := multiples.orderedPools()
+	for index, pool := range pools {
+		user, dialFailed, err := withServer(pool, func(server ldap.IServer) (*login.ExternalUserInfo, error) {
+			return server.Login(query)
+		})
 
-		if err := server.Dial(); err != nil {
-			logDialFailure(err, config)
+		if dialFailed {
+			logDialFailure(err, pool.config)
 
 			// Only return an error if it is the last server so we can try next server
-			if index == len(multiples.configs)-1 {
+			if index == len(pools)-1 {
 				return nil, err
 			}
 			continue
 		}
 
-		defer server.Close()
-
-		user, err := server.Login(query)
 		if err != nil {
 			if isSilentError(err) {
 				ldapSilentErrors = append(ldapSilentErrors, err)
 				multiples.log.Debug(
 					"unable to login with LDAP - skipping server",
-					"host", config.Host,
-					"port", config.Port,
+					"host", pool.config.Host,
+					"port", pool.config.Port,
 					"error", err,
 				)
 				continue
@@ -155,7 +210,7 @@ func (multiples *MultiLDAP) Login(query *login.LoginUserQuery) (
 }
 
 // User attempts to find an user by login/username by searching into all of the configured LDAP servers. Then, if the user is found it returns the user alongisde the server it was found.
-func (multiples *MultiLDAP) User(login string) (
+func (multiples *MultiLDAP) User(username string) (
 	*login.ExternalUserInfo,
 	ldap.ServerConfig,
 	error,
@@ -164,33 +219,32 @@ func (multiples *MultiLDAP) User(login string) (
 		return nil, ldap.ServerConfig{}, ErrNoLDAPServers
 	}
 
-	search := []string{login}
-	for index, config := range multiples.configs {
-		server := newLDAP(config, multiples.cfg)
-
-		if err := server.Dial(); err != nil {
-			logDialFailure(err, config)
-
-			// Only return an error if it is the last server so we can try next server
-			if index == len(multiples.configs)-1 {
-				return nil, *config, err
+	search := []string{username}
+	pools := multiples.orderedPools()
+	for index, pool := range pools {
+		users, dialFailed, err := withServer(pool, func(server ldap.IServer) ([]*login.ExternalUserInfo, error) {
+			if err := server.Bind(); err != nil {
+				return nil, err
 			}
-			continue
-		}
+			return server.Users(search)
+		})
 
-		defer server.Close()
+		if err != nil {
+			if dialFailed {
+				logDialFailure(err, pool.config)
 
-		if err := server.Bind(); err != nil {
-			return nil, *config, err
-		}
+				// Only return an error if it is the last server so we can try next server
+				if index == len(pools)-1 {
+					return nil, *pool.config, err
+				}
+				continue
+			}
 
-		users, err := server.Users(search)
-		if err != nil {
-			return nil, *config, err
+			return nil, *pool.config, err
 		}
 
 		if len(users) != 0 {
-			return users[0], *config, nil
+			return users[0], *pool.config, nil
 		}
 	}
 
@@ -208,29 +262,29 @@ func (multiples *MultiLDAP) Users(logins []string) (
 		return nil, ErrNoLDAPServers
 	}
 
-	for index, config := range multiples.configs {
-		server := newLDAP(config, multiples.cfg)
-
-		if err := server.Dial(); err != nil {
-			logDialFailure(err, config)
-
-			// Only return an error if it is the last server so we can try next server
-			if index == len(multiples.configs)-1 {
+	pools := multiples.orderedPools()
+	for index, pool := range pools {
+		users, dialFailed, err := withServer(pool, func(server ldap.IServer) ([]*login.ExternalUserInfo, error) {
+			if err := server.Bind(); err != nil {
 				return nil, err
 			}
-			continue
-		}
+			return server.Users(logins)
+		})
 
-		defer server.Close()
+		if err != nil {
+			if dialFailed {
+				logDialFailure(err, pool.config)
 
-		if err := server.Bind(); err != nil {
-			return nil, err
-		}
+				// Only return an error if it is the last server so we can try next server
+				if index == len(pools)-1 {
+					return nil, err
+				}
+				continue
+			}
 
-		users, err := server.Users(logins)
-		if err != nil {
 			return nil, err
 		}
+
 		result = append(result, users...)
 	}
 
@@ -522,3 +522,67 @@ func setup() *mockLDAP {
 func teardown() {
 	newLDAP = ldap.New
 }
+
+func TestMultiLDAP_ConnectionPool(t *testing.T) {
+	t.Run("Should reuse pooled connections", func(t *testing.T) {
+		mock := setup()
+		defer teardown()
+		mock.loginReturn = &login.ExternalUserInfo{Login: "killa"}
+
+		cfg := setting.NewCfg()
+		cfg.LDAPConnectionPoolSize = 2
+		multi := New([]*ldap.ServerConfig{{}}, cfg)
+
+		for i := 0; i < 3; i++ {
+			_, err := multi.Login(&login.LoginUserQuery{})
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, 1, mock.dialCalledTimes)
+		require.Equal(t, 3, mock.loginCalledTimes)
+		require.Equal(t, 0, mock.closeCalledTimes)
+
+		multi.Close()
+		require.Equal(t, 1, mock.closeCalledTimes)
+	})
+
+	t.Run("Should redial when a pooled connection fails", func(t *testing.T) {
+		mock := setup()
+		defer teardown()
+		mock.loginReturn = &login.ExternalUserInfo{Login: "killa"}
+
+		cfg := setting.NewCfg()
+		cfg.LDAPConnectionPoolSize = 2
+		multi := New([]*ldap.ServerConfig{{}}, cfg)
+
+		_, err := multi.Login(&login.LoginUserQuery{})
+		require.NoError(t, err)
+
+		mock.loginErrReturn = errors.New("connection reset")
+		_, err = multi.Login(&login.LoginUserQuery{})
+		require.Error(t, err)
+
+		require.Equal(t, 2, mock.dialCalledTimes)
+		require.Equal(t, 3, mock.loginCalledTimes)
+		require.Equal(t, 2, mock.closeCalledTimes)
+	})
+
+	t.Run("Should try unhealthy servers last", func(t *testing.T) {
+		mocks := map[string]*mockLDAP{
+			"unhealthy": {dialErrReturn: errors.New("dial error"), loginReturn: &login.ExternalUserInfo{Login: "unhealthy"}},
+			"healthy":   {loginReturn: &login.ExternalUserInfo{Login: "healthy"}},
+		}
+		newLDAP = func(config *ldap.ServerConfig, cfg *setting.Cfg) ldap.IServer {
+			return mocks[config.Host]
+		}
+		defer teardown()
+
+		multi := New([]*ldap.ServerConfig{{Host: "unhealthy"}, {Host: "healthy"}}, setting.NewCfg())
+		multi.HealthCheck()
+
+		mocks["unhealthy"].dialErrReturn = nil
+		result, err := multi.Login(&login.LoginUserQuery{})
+		require.NoError(t, err)
+		require.Equal(t, "healthy", result.Login)
+	})
+}
@@ -0,0 +1,178 @@
+package multildap
+
+import (
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/services/ldap"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+var (
+	poolIdleConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "grafana",
+		Subsystem: "ldap",
+		Name:      "pool_idle_connections",
+		Help:      "Number of idle connections in the LDAP connection pool",
+	}, []string{"server"})
+
+	poolDialsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "ldap",
+		Name:      "pool_dials_total",
+		Help:      "Number of connections dialed by the LDAP connection pool",
+	}, []string{"server", "result"})
+
+	poolReusedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "ldap",
+		Name:      "pool_connections_reused_total",
+		Help:      "Number of times a pooled LDAP connection was reused",
+	}, []string{"server"})
+
+	serverUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "grafana",
+		Subsystem: "ldap",
+		Name:      "server_up",
+		Help:      "Whether the last connection attempt to the LDAP server succeeded",
+	}, []string{"server"})
+)
+
+// connPool keeps bound-agnostic connections to a single LDAP server.
+// Every operation performed on a server starts with a bind, so connections
+// can safely be shared between operations.
+type connPool struct {
+	config *ldap.ServerConfig
+	cfg    *setting.Cfg
+	size   int
+	name   string
+
+	mu      sync.Mutex
+	idle    []ldap.IServer
+	healthy bool
+	closed  bool
+}
+
+func newConnPool(config *ldap.ServerConfig, cfg *setting.Cfg) *connPool {
+	size := 0
+	if cfg != nil {
+		size = cfg.LDAPConnectionPoolSize
+	}
+
+	return &connPool{
+		config:  config,
+		cfg:     cfg,
+		size:    size,
+		name:    net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+		healthy: true,
+	}
+}
+
+// get returns an idle connection from the pool or dials a new one.
+// The returned bool is true if the connection was reused.
+func (p *connPool) get() (ldap.IServer, bool, error) {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		server := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		poolIdleConnections.WithLabelValues(p.name).Set(float64(len(p.idle)))
+		p.mu.Unlock()
+		poolReusedTotal.WithLabelValues(p.name).Inc()
+		return server, true, nil
+	}
+	p.mu.Unlock()
+
+	server, err := p.dial()
+	return server, false, err
+}
+
+func (p *connPool) dial() (ldap.IServer, error) {
+	server := newLDAP(p.config, p.cfg)
+	err := server.Dial()
+	p.setHealthy(err == nil)
+
+	if err != nil {
+		poolDialsTotal.WithLabelValues(p.name, "failure").Inc()
+		return nil, err
+	}
+
+	poolDialsTotal.WithLabelValues(p.name, "success").Inc()
+	return server, nil
+}
+
+// put returns the connection to the pool. Connections that failed with an
+// unexpected error are closed since they may be broken.
+func (p *connPool) put(server ldap.IServer, err error) {
+	if err != nil && !isSilentError(err) {
+		server.Close()
+		return
+	}
+
+	p.mu.Lock()
+	if p.closed || len(p.idle) >= p.size {
+		p.mu.Unlock()
+		server.Close()
+		return
+	}
+	p.idle = append(p.idle, server)
+	poolIdleConnections.WithLabelValues(p.name).Set(float64(len(p.idle)))
+	p.mu.Unlock()
+}
+
+// healthCheck dials the server to update its health. Idle connections are
+// dropped when the server is unreachable.
+func (p *connPool) healthCheck() *ServerStatus {
+	status := &ServerStatus{Host: p.config.Host, Port: p.config.Port}
+
+	server, err := p.dial()
+	if err != nil {
+		status.Error = err
+		p.drain()
+		return status
+	}
+
+	status.Available = true
+	p.put(server, nil)
+	return status
+}
+
+func (p *connPool) isHealthy() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.healthy
+}
+
+func (p *connPool) setHealthy(healthy bool) {
+	p.mu.Lock()
+	p.healthy = healthy
+	p.mu.Unlock()
+
+	if healthy {
+		serverUp.WithLabelValues(p.name).Set(1)
+	} else {
+		serverUp.WithLabelValues(p.name).Set(0)
+	}
+}
+
+func (p *connPool) drain() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	poolIdleConnections.WithLabelValues(p.name).Set(0)
+	p.mu.Unlock()
+
+	for _, server := range idle {
+		server.Close()
+	}
+}
+
+func (p *connPool) close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.drain()
+}
@@ -12,6 +12,7 @@ type LDAPFakeService struct {
 	ExpectedError  error
 	ExpectedUser   *login.ExternalUserInfo
 	UserCalled     bool
+	LoginCalled    bool
 }
 
 func NewLDAPFakeService() *LDAPFakeService {
@@ -31,6 +32,7 @@ func (s *LDAPFakeService) Client() multildap.IMultiLDAP {
 }
 
 func (s *LDAPFakeService) Login(query *login.LoginUserQuery) (*login.ExternalUserInfo, error) {
+	s.LoginCalled = true
 	return s.ExpectedUser, s.ExpectedError
 }
 
@@ -1,8 +1,10 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/ldap"
@@ -29,6 +31,8 @@ type LDAP interface {
 }
 
 type LDAPImpl struct {
+	// mu guards client and ldapCfg, they are replaced on reload while logins and health checks read them.
+	mu      sync.RWMutex
 	client  multildap.IMultiLDAP
 	cfg     *setting.Cfg
 	ldapCfg *ldap.Config
@@ -80,17 +84,54 @@ func (s *LDAPImpl) ReloadConfig() error {
 		return ErrUnableToCreateLDAPClient
 	}
 
+	s.mu.Lock()
+	previous := s.client
 	s.ldapCfg = config
 	s.client = client
+	s.mu.Unlock()
+
+	// connections in use by the previous client are closed when they are returned to its pools
+	if previous != nil {
+		previous.Close()
+	}
 
 	return nil
 }
 
+// Run periodically checks the health of the configured LDAP servers so that
+// unavailable servers are tried last.
+func (s *LDAPImpl) Run(ctx context.Context) error {
+	if !s.cfg.LDAPEnabled || s.cfg.LDAPHealthCheckInterval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(s.cfg.LDAPHealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if client := s.Client(); client != nil {
+				client.HealthCheck()
+			}
+		case <-ctx.Done():
+			if client := s.Client(); client != nil {
+				client.Close()
+			}
+			return ctx.Err()
+		}
+	}
+}
+
 func (s *LDAPImpl) Client() multildap.IMultiLDAP {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.client
 }
 
 func (s *LDAPImpl) Config() *ldap.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.ldapCfg
 }
 
@@ -2,31 +2,31 @@ package database
 
 import (
 	"context"
-	"encoding/base64"
 	"time"
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/login"
 	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/sqlstore/encrypted"
 	"github.com/grafana/grafana/pkg/services/user"
 )
 
 var GetTime = time.Now
 
 type AuthInfoStore struct {
-	sqlStore       db.DB
-	secretsService secrets.Service
-	logger         log.Logger
-	userService    user.Service
+	sqlStore         db.DB
+	encryptedColumns *encrypted.Columns
+	logger           log.Logger
+	userService      user.Service
 }
 
 func ProvideAuthInfoStore(sqlStore db.DB, secretsService secrets.Service, userService user.Service) login.Store {
 	store := &AuthInfoStore{
-		sqlStore:       sqlStore,
-		secretsService: secretsService,
-		logger:         log.New("login.authinfo.store"),
-		userService:    userService,
+		sqlStore:         sqlStore,
+		encryptedColumns: encrypted.NewColumns(secretsService, secrets.WithoutScope()),
+		logger:           log.New("login.authinfo.store"),
+		userService:      userService,
 	}
 	// FIXME: disabled the metric collection for duplicate user entries
 	// due to query performance issues that is clogging the users Grafana instance
@@ -86,26 +86,9 @@ func (s *AuthInfoStore) GetAuthInfo(ctx context.Context, query *login.GetAuthInf
 		return user.ErrUserNotFound
 	}
 
-	secretAccessToken, err := s.decodeAndDecrypt(userAuth.OAuthAccessToken)
-	if err != nil {
-		return err
-	}
-	secretRefreshToken, err := s.decodeAndDecrypt(userAuth.OAuthRefreshToken)
-	if err != nil {
-		return err
-	}
-	secretTokenType, err := s.decodeAndDecrypt(userAuth.OAuthTokenType)
-	if err != nil {
-		return err
-	}
-	secretIdToken, err := s.decodeAndDecrypt(userAuth.OAuthIdToken)
-	if err != nil {
+	if err := s.encryptedColumns.Decrypt(ctx, userAuth); err != nil {
 		return err
 	}
-	userAuth.OAuthAccessToken = secretAccessToken
-	userAuth.OAuthRefreshToken = secretRefreshToken
-	userAuth.OAuthTokenType = secretTokenType
-	userAuth.OAuthIdToken = secretIdToken
 
 	query.Result = userAuth
 	return nil
@@ -144,32 +127,17 @@ func (s *AuthInfoStore) SetAuthInfo(ctx context.Context, cmd *login.SetAuthInfoC
 	}
 
 	if cmd.OAuthToken != nil {
-		secretAccessToken, err := s.encryptAndEncode(cmd.OAuthToken.AccessToken)
-		if err != nil {
-			return err
-		}
-		secretRefreshToken, err := s.encryptAndEncode(cmd.OAuthToken.RefreshToken)
-		if err != nil {
-			return err
-		}
-		secretTokenType, err := s.encryptAndEncode(cmd.OAuthToken.TokenType)
-		if err != nil {
-			return err
+		authUser.OAuthAccessToken = cmd.OAuthToken.AccessToken
+		authUser.OAuthRefreshToken = cmd.OAuthToken.RefreshToken
+		authUser.OAuthTokenType = cmd.OAuthToken.TokenType
+		if idToken, ok := cmd.OAuthToken.Extra("id_token").(string); ok {
+			authUser.OAuthIdToken = idToken
 		}
+		authUser.OAuthExpiry = cmd.OAuthToken.Expiry
 
-		var secretIdToken string
-		if idToken, ok := cmd.OAuthToken.Extra("id_token").(string); ok && idToken != "" {
-			secretIdToken, err = s.encryptAndEncode(idToken)
-			if err != nil {
-				return err
-			}
+		if err := s.encryptedColumns.Encrypt(ctx, authUser); err != nil {
+			return err
 		}
-
-		authUser.OAuthAccessToken = secretAccessToken
-		authUser.OAuthRefreshToken = secretRefreshToken
-		authUser.OAuthTokenType = secretTokenType
-		authUser.OAuthIdToken = secretIdToken
-		authUser.OAuthExpiry = cmd.OAuthToken.Expiry
 	}
 
 	return s.sqlStore.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
@@ -203,32 +171,17 @@ func (s *AuthInfoStore) UpdateAuthInfo(ctx context.Context, cmd *login.UpdateAut
 	}
 
 	if cmd.OAuthToken != nil {
-		secretAccessToken, err := s.encryptAndEncode(cmd.OAuthToken.AccessToken)
-		if err != nil {
-			return err
-		}
-		secretRefreshToken, err := s.encryptAndEncode(cmd.OAuthToken.RefreshToken)
-		if err != nil {
-			return err
-		}
-		secretTokenType, err := s.encryptAndEncode(cmd.OAuthToken.TokenType)
-		if err != nil {
-			return err
+		authUser.OAuthAccessToken = cmd.OAuthToken.AccessToken
+		authUser.OAuthRefreshToken = cmd.OAuthToken.RefreshToken
+		authUser.OAuthTokenType = cmd.OAuthToken.TokenType
+		if idToken, ok := cmd.OAuthToken.Extra("id_token").(string); ok {
+			authUser.OAuthIdToken = idToken
 		}
+		authUser.OAuthExpiry = cmd.OAuthToken.Expiry
 
-		var secretIdToken string
-		if idToken, ok := cmd.OAuthToken.Extra("id_token").(string); ok && idToken != "" {
-			secretIdToken, err = s.encryptAndEncode(idToken)
-			if err != nil {
-				return err
-			}
+		if err := s.encryptedColumns.Encrypt(ctx, authUser); err != nil {
+			return err
 		}
-
-		authUser.OAuthAccessToken = secretAccessToken
-		authUser.OAuthRefreshToken = secretRefreshToken
-		authUser.OAuthTokenType = secretTokenType
-		authUser.OAuthIdToken = secretIdToken
-		authUser.OAuthExpiry = cmd.OAuthToken.Expiry
 	}
 
 	return s.sqlStore.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
@@ -282,30 +235,3 @@ func (s *AuthInfoStore) GetUserByEmail(ctx context.Context, email string) (*user
 
 	return usr, nil
 }
-
-// decodeAndDecrypt will decode the string with the standard base64 decoder and then decrypt it
-func (s *AuthInfoStore) decodeAndDecrypt(str string) (string, error) {
-	// Bail out if empty string since it'll cause a segfault in Decrypt
-	if str == "" {
-		return "", nil
-	}
-	decoded, err := base64.StdEncoding.DecodeString(str)
-	if err != nil {
-		return "", err
-	}
-	decrypted, err := s.secretsService.Decrypt(context.Background(), decoded)
-	if err != nil {
-		return "", err
-	}
-	return string(decrypted), nil
-}
-
-// encryptAndEncode will encrypt a string with grafana's secretKey, and
-// then encode it with the standard bas64 encoder
-func (s *AuthInfoStore) encryptAndEncode(str string) (string, error) {
-	encrypted, err := s.secretsService.Encrypt(context.Background(), []byte(str), secrets.WithoutScope())
-	if err != nil {
-		return "", err
-	}
-	return base64.StdEncoding.EncodeToString(encrypted), nil
-}
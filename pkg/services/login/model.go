@@ -44,10 +44,10 @@ type UserAuth struct {
 	AuthModule        string
 	AuthId            string
 	Created           time.Time
-	OAuthAccessToken  string
-	OAuthRefreshToken string
-	OAuthIdToken      string
-	OAuthTokenType    string
+	OAuthAccessToken  string `encrypted:"true"`
+	OAuthRefreshToken string `encrypted:"true"`
+	OAuthIdToken      string `encrypted:"true,omitempty"`
+	OAuthTokenType    string `encrypted:"true"`
 	OAuthExpiry       time.Time
 }
 
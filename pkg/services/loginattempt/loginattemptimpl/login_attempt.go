@@ -2,102 +2,162 @@ package loginattemptimpl
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
-	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
 const (
 	maxInvalidLoginAttempts int64 = 5
-	loginAttemptsWindow           = time.Minute * 5
+	// maxInvalidLoginAttemptsPerIP is higher than the per user limit since
+	// many users can share an address, e.g. behind a NAT.
+	maxInvalidLoginAttemptsPerIP int64 = 50
+	loginAttemptsWindow                = time.Minute * 5
 )
 
-func ProvideService(db db.DB, cfg *setting.Cfg, lock *serverlock.ServerLockService) *Service {
+var _ loginattempt.Service = new(Service)
+
+type cache interface {
+	GetByteArray(ctx context.Context, key string) ([]byte, error)
+	Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func ProvideService(cfg *setting.Cfg, remoteCache *remotecache.RemoteCache) *Service {
 	return &Service{
-		&xormStore{db: db, now: time.Now},
-		cfg,
-		lock,
-		log.New("login_attempt"),
+		cache:  remoteCache,
+		cfg:    cfg,
+		now:    time.Now,
+		logger: log.New("login_attempt"),
 	}
 }
 
+// Service limits invalid login attempts using a sliding window counter per
+// username and per IP address. Counters are kept in the remote cache so the
+// limits are shared between all instances.
 type Service struct {
-	store  store
+	cache  cache
 	cfg    *setting.Cfg
-	lock   *serverlock.ServerLockService
+	now    func() time.Time
 	logger log.Logger
 }
 
-func (s *Service) Run(ctx context.Context) error {
-	// no need to run clean up job if it is disabled
+func (s *Service) Add(ctx context.Context, username, IPAddress string) error {
 	if s.cfg.DisableBruteForceLoginProtection {
 		return nil
 	}
 
-	ticker := time.NewTicker(time.Minute * 10)
-	for {
-		select {
-		case <-ticker.C:
-			s.cleanup(ctx)
-		case <-ctx.Done():
-			return ctx.Err()
-		}
+	if err := s.increment(ctx, userKey(username)); err != nil {
+		return err
 	}
-}
 
-func (s *Service) Add(ctx context.Context, username, IPAddress string) error {
-	if s.cfg.DisableBruteForceLoginProtection {
+	if IPAddress == "" {
 		return nil
 	}
-
-	return s.store.CreateLoginAttempt(ctx, CreateLoginAttemptCommand{
-		Username:  username,
-		IpAddress: IPAddress,
-	})
+	return s.increment(ctx, ipKey(IPAddress))
 }
 
 func (s *Service) Reset(ctx context.Context, username string) error {
-	return s.store.DeleteLoginAttempts(ctx, DeleteLoginAttemptsCommand{username})
+	current := s.bucket(s.now())
+	for _, bucket := range []int64{current, current - 1} {
+		if err := s.cache.Delete(ctx, bucketKey(userKey(username), bucket)); err != nil && !errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return err
+		}
+	}
+	return nil
 }
 
-func (s *Service) Validate(ctx context.Context, username string) (bool, error) {
+func (s *Service) Validate(ctx context.Context, username, IPAddress string) (bool, error) {
 	if s.cfg.DisableBruteForceLoginProtection {
 		return true, nil
 	}
 
-	loginAttemptCountQuery := GetUserLoginAttemptCountQuery{
-		Username: username,
-		Since:    time.Now().Add(-loginAttemptsWindow),
+	count, err := s.count(ctx, userKey(username))
+	if err != nil {
+		return false, err
+	}
+
+	if count >= float64(maxInvalidLoginAttempts) {
+		return false, nil
 	}
 
-	count, err := s.store.GetUserLoginAttemptCount(ctx, loginAttemptCountQuery)
+	if IPAddress == "" {
+		return true, nil
+	}
+
+	count, err = s.count(ctx, ipKey(IPAddress))
 	if err != nil {
 		return false, err
 	}
 
-	if count >= maxInvalidLoginAttempts {
+	if count >= float64(maxInvalidLoginAttemptsPerIP) {
+		s.logger.FromContext(ctx).Warn("Too many invalid login attempts from address", "ip", IPAddress)
 		return false, nil
 	}
 
 	return true, nil
 }
 
-func (s *Service) cleanup(ctx context.Context) {
-	err := s.lock.LockAndExecute(ctx, "delete old login attempts", time.Minute*10, func(context.Context) {
-		cmd := DeleteOldLoginAttemptsCommand{
-			OlderThan: time.Now().Add(time.Minute * -10),
-		}
-		if deletedLogs, err := s.store.DeleteOldLoginAttempts(ctx, cmd); err != nil {
-			s.logger.Error("Problem deleting expired login attempts", "error", err.Error())
-		} else {
-			s.logger.Debug("Deleted expired login attempts", "rows affected", deletedLogs)
-		}
-	})
+// count approximates the number of attempts in the sliding window by weighting
+// the previous fixed window by how much of it still overlaps the sliding window.
+func (s *Service) count(ctx context.Context, key string) (float64, error) {
+	now := s.now()
+	current := s.bucket(now)
 
+	currentCount, err := s.get(ctx, bucketKey(key, current))
 	if err != nil {
-		s.logger.Error("failed to lock and execute cleanup of old login attempts", "error", err)
+		return 0, err
 	}
+
+	previousCount, err := s.get(ctx, bucketKey(key, current-1))
+	if err != nil {
+		return 0, err
+	}
+
+	elapsed := now.Sub(time.Unix(current*int64(loginAttemptsWindow/time.Second), 0))
+	weight := 1 - float64(elapsed)/float64(loginAttemptsWindow)
+
+	return float64(currentCount) + float64(previousCount)*weight, nil
+}
+
+func (s *Service) increment(ctx context.Context, key string) error {
+	// keep the bucket while it can be the previous window
+	_, err := s.cache.Increment(ctx, bucketKey(key, s.bucket(s.now())), 1, 2*loginAttemptsWindow)
+	return err
+}
+
+func (s *Service) get(ctx context.Context, key string) (int64, error) {
+	value, err := s.cache.GetByteArray(ctx, key)
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return strconv.ParseInt(string(value), 10, 64)
+}
+
+func (s *Service) bucket(t time.Time) int64 {
+	return t.Unix() / int64(loginAttemptsWindow/time.Second)
+}
+
+func bucketKey(key string, bucket int64) string {
+	return fmt.Sprintf("%s:%d", key, bucket)
+}
+
+func userKey(username string) string {
+	// lower case so the limit cannot be bypassed by changing the case of the username
+	return "login-attempts:user:" + strings.ToLower(username)
+}
+
+func ipKey(ip string) string {
+	return "login-attempts:ip:" + ip
 }
@@ -2,10 +2,16 @@ package loginattemptimpl
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
@@ -15,89 +21,177 @@ func TestService_Validate(t *testing.T) {
 		loginAttempts int64
 		disabled      bool
 		expected      bool
-		expectedErr   error
 	}{
 		{
 			name:          "When brute force protection enabled and user login attempt count is less than max",
 			loginAttempts: maxInvalidLoginAttempts - 1,
 			expected:      true,
-			expectedErr:   nil,
 		},
 		{
 			name:          "When brute force protection enabled and user login attempt count equals max",
 			loginAttempts: maxInvalidLoginAttempts,
 			expected:      false,
-			expectedErr:   nil,
 		},
 		{
 			name:          "When brute force protection enabled and user login attempt count is greater than max",
 			loginAttempts: maxInvalidLoginAttempts + 1,
 			expected:      false,
-			expectedErr:   nil,
 		},
-
 		{
 			name:          "When brute force protection disabled and user login attempt count is less than max",
 			loginAttempts: maxInvalidLoginAttempts - 1,
 			disabled:      true,
 			expected:      true,
-			expectedErr:   nil,
 		},
 		{
 			name:          "When brute force protection disabled and user login attempt count equals max",
 			loginAttempts: maxInvalidLoginAttempts,
 			disabled:      true,
 			expected:      true,
-			expectedErr:   nil,
 		},
 		{
 			name:          "When brute force protection disabled and user login attempt count is greater than max",
 			loginAttempts: maxInvalidLoginAttempts + 1,
 			disabled:      true,
 			expected:      true,
-			expectedErr:   nil,
 		},
 	}
 
 	for _, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
-			cfg := setting.NewCfg()
-			cfg.DisableBruteForceLoginProtection = tt.disabled
-			service := &Service{
-				store: fakeStore{
-					ExpectedCount: tt.loginAttempts,
-					ExpectedErr:   tt.expectedErr,
-				},
-				cfg: cfg,
+			ctx := context.Background()
+			service := setupTests(t, time.Unix(1000, 0))
+			service.cfg.DisableBruteForceLoginProtection = tt.disabled
+
+			for i := int64(0); i < tt.loginAttempts; i++ {
+				require.NoError(t, service.Add(ctx, "test", "192.168.1.1"))
 			}
 
-			ok, err := service.Validate(context.Background(), "test")
+			ok, err := service.Validate(ctx, "test", "192.168.1.1")
+			require.NoError(t, err)
 			assert.Equal(t, tt.expected, ok)
-			assert.Equal(t, tt.expectedErr, err)
 		})
 	}
 }
 
-var _ store = new(fakeStore)
+func TestService_ValidateIP(t *testing.T) {
+	ctx := context.Background()
+	service := setupTests(t, time.Unix(1000, 0))
+
+	// attempts spread over many usernames are limited by address
+	for i := int64(0); i < maxInvalidLoginAttemptsPerIP; i++ {
+		require.NoError(t, service.Add(ctx, fmt.Sprintf("user%d", i), "192.168.1.1"))
+	}
+
+	ok, err := service.Validate(ctx, "other", "192.168.1.1")
+	require.NoError(t, err)
+	assert.False(t, ok)
 
-type fakeStore struct {
-	ExpectedErr         error
-	ExpectedCount       int64
-	ExpectedDeletedRows int64
+	ok, err = service.Validate(ctx, "other", "192.168.1.2")
+	require.NoError(t, err)
+	assert.True(t, ok)
 }
 
-func (f fakeStore) GetUserLoginAttemptCount(ctx context.Context, query GetUserLoginAttemptCountQuery) (int64, error) {
-	return f.ExpectedCount, f.ExpectedErr
+func TestService_SlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0).Add(loginAttemptsWindow * 100)
+	service := setupTests(t, now)
+
+	for i := int64(0); i < maxInvalidLoginAttempts; i++ {
+		require.NoError(t, service.Add(ctx, "test", ""))
+	}
+
+	ok, err := service.Validate(ctx, "test", "")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// half of the previous window still overlaps the sliding window
+	service.now = func() time.Time { return now.Add(loginAttemptsWindow + loginAttemptsWindow/2) }
+	ok, err = service.Validate(ctx, "test", "")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	for i := int64(0); i < maxInvalidLoginAttempts/2+1; i++ {
+		require.NoError(t, service.Add(ctx, "test", ""))
+	}
+
+	ok, err = service.Validate(ctx, "test", "")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	service.now = func() time.Time { return now.Add(3 * loginAttemptsWindow) }
+	ok, err = service.Validate(ctx, "test", "")
+	require.NoError(t, err)
+	assert.True(t, ok)
 }
 
-func (f fakeStore) CreateLoginAttempt(ctx context.Context, command CreateLoginAttemptCommand) error {
-	return f.ExpectedErr
+func TestService_Expiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0).Add(loginAttemptsWindow * 100)
+	service := setupTests(t, now)
+
+	for i := int64(0); i < maxInvalidLoginAttempts; i++ {
+		require.NoError(t, service.Add(ctx, "test", ""))
+	}
+
+	// the buckets are kept while they can be the previous window, and expire afterwards
+	service.now = func() time.Time { return now.Add(2*loginAttemptsWindow - time.Second) }
+	_, err := service.cache.GetByteArray(ctx, bucketKey(userKey("test"), service.bucket(now)))
+	require.NoError(t, err)
+
+	service.now = func() time.Time { return now.Add(2 * loginAttemptsWindow) }
+	_, err = service.cache.GetByteArray(ctx, bucketKey(userKey("test"), service.bucket(now)))
+	assert.ErrorIs(t, err, remotecache.ErrCacheItemNotFound)
+}
+
+func TestService_AddConcurrently(t *testing.T) {
+	ctx := context.Background()
+	service := setupTests(t, time.Unix(1000, 0))
+
+	var wg sync.WaitGroup
+	for i := int64(0); i < maxInvalidLoginAttempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, service.Add(ctx, "test", ""))
+		}()
+	}
+	wg.Wait()
+
+	// every concurrent attempt is counted
+	ok, err := service.Validate(ctx, "test", "")
+	require.NoError(t, err)
+	assert.False(t, ok)
 }
 
-func (f fakeStore) DeleteOldLoginAttempts(ctx context.Context, command DeleteOldLoginAttemptsCommand) (int64, error) {
-	return f.ExpectedDeletedRows, f.ExpectedErr
+func TestService_Reset(t *testing.T) {
+	ctx := context.Background()
+	service := setupTests(t, time.Unix(1000, 0))
+
+	for i := int64(0); i < maxInvalidLoginAttempts; i++ {
+		require.NoError(t, service.Add(ctx, "Test", ""))
+	}
+
+	ok, err := service.Validate(ctx, "test", "")
+	require.NoError(t, err)
+	assert.False(t, ok, "usernames should be counted case insensitive")
+
+	require.NoError(t, service.Reset(ctx, "test"))
+
+	ok, err = service.Validate(ctx, "test", "")
+	require.NoError(t, err)
+	assert.True(t, ok)
 }
 
-func (f fakeStore) DeleteLoginAttempts(ctx context.Context, cmd DeleteLoginAttemptsCommand) error {
-	return f.ExpectedErr
+func setupTests(t *testing.T, now time.Time) *Service {
+	t.Helper()
+
+	s := &Service{
+		cfg:    setting.NewCfg(),
+		now:    func() time.Time { return now },
+		logger: log.NewNopLogger(),
+	}
+	// the cache expires items according to the clock of the service
+	s.cache = remotecache.NewFakeMemoryStore(t, func() time.Time { return s.now() })
+	return s
 }
@@ -0,0 +1,84 @@
+package mfa
+
+import (
+	"context"
+	"net/http"
+
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/util/errutil"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+var (
+	ErrNotEnrolled     = errutil.NewBase(errutil.StatusBadRequest, "mfa.not-enrolled", errutil.WithPublicMessage("Multi-factor authentication is not enrolled"))
+	ErrAlreadyEnrolled = errutil.NewBase(errutil.StatusBadRequest, "mfa.already-enrolled", errutil.WithPublicMessage("Multi-factor authentication is already enrolled"))
+	ErrInvalidCode     = errutil.NewBase(errutil.StatusBadRequest, "mfa.invalid-code", errutil.WithPublicMessage("Invalid verification code"))
+	ErrTooManyAttempts = errutil.NewBase(errutil.StatusTooManyRequests, "mfa.too-many-attempts", errutil.WithPublicMessage("Too many invalid verification codes, try again later"))
+	ErrStepUpRequired  = errutil.NewBase(errutil.StatusForbidden, "mfa.step-up-required", errutil.WithPublicMessage("Multi-factor authentication is required to perform this action"))
+	ErrSessionRequired = errutil.NewBase(errutil.StatusBadRequest, "mfa.session-required", errutil.WithPublicMessage("Multi-factor authentication requires a user session"))
+	ErrFeatureDisabled = errutil.NewBase(errutil.StatusNotFound, "mfa.disabled", errutil.WithPublicMessage("Multi-factor authentication is disabled"))
+)
+
+type Service interface {
+	// Enroll generates a new TOTP secret for the user. The enrollment is pending
+	// until the first code generated from the secret has been verified.
+	Enroll(ctx context.Context, userID int64, login string) (*Enrollment, error)
+	// Verify checks the TOTP code for the user. On success the session is
+	// stepped up and a pending enrollment is activated. Users are locked out
+	// for a while after too many invalid codes.
+	Verify(ctx context.Context, userID, sessionID int64, code string) error
+	// Disable removes the enrollment for the user.
+	Disable(ctx context.Context, userID int64) error
+	// IsEnrolled returns true if the user has an active enrollment.
+	IsEnrolled(ctx context.Context, userID int64) (bool, error)
+	// IsSteppedUp returns true if a second factor has recently been verified for the session.
+	IsSteppedUp(ctx context.Context, sessionID int64) (bool, error)
+	// StepUp marks the session as having verified a second factor with the given
+	// authentication method reference, e.g. authn.AMRHardwareKey for a WebAuthn assertion.
+	StepUp(ctx context.Context, sessionID int64, method string) error
+}
+
+// Enrollment holds the TOTP secret that should be added to an authenticator app.
+type Enrollment struct {
+	Secret string `json:"secret"`
+	// URL is the otpauth:// URL that can be rendered as a QR code.
+	URL string `json:"url"`
+}
+
+// RequireStepUp returns a middleware that requires users enrolled in MFA to
+// have verified a second factor for their current session recently.
+// Users that are not enrolled, API keys and service accounts are let through. Requests of
+// enrolled users that are not authenticated by a session, ex with basic auth, are rejected
+// since they cannot complete the step up.
+func RequireStepUp(s Service) web.Handler {
+	return func(c *contextmodel.ReqContext) {
+		if s == nil || c.SignedInUser == nil || c.SignedInUser.UserID <= 0 || c.SignedInUser.IsServiceAccount {
+			return
+		}
+
+		enrolled, err := s.IsEnrolled(c.Req.Context(), c.SignedInUser.UserID)
+		if err != nil {
+			c.WriteErr(err)
+			return
+		}
+
+		if !enrolled {
+			return
+		}
+
+		if c.UserToken == nil {
+			c.WriteErrOrFallback(http.StatusForbidden, http.StatusText(http.StatusForbidden), ErrStepUpRequired.Errorf("multi-factor authentication requires a user session"))
+			return
+		}
+
+		steppedUp, err := s.IsSteppedUp(c.Req.Context(), c.UserToken.Id)
+		if err != nil {
+			c.WriteErr(err)
+			return
+		}
+
+		if !steppedUp {
+			c.WriteErrOrFallback(http.StatusForbidden, http.StatusText(http.StatusForbidden), ErrStepUpRequired.Errorf("session has not completed multi-factor authentication"))
+		}
+	}
+}
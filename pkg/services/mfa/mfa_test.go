@@ -0,0 +1,66 @@
+package mfa_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/auth"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func TestRequireStepUp(t *testing.T) {
+	tests := []struct {
+		desc         string
+		user         *user.SignedInUser
+		token        *auth.UserToken
+		enrolled     bool
+		steppedUp    bool
+		expectedCode int
+	}{
+		{desc: "user not enrolled", user: &user.SignedInUser{UserID: 1}, token: &auth.UserToken{Id: 1}, expectedCode: http.StatusOK},
+		{desc: "session not stepped up", user: &user.SignedInUser{UserID: 1}, token: &auth.UserToken{Id: 1}, enrolled: true, expectedCode: http.StatusForbidden},
+		{desc: "session stepped up", user: &user.SignedInUser{UserID: 1}, token: &auth.UserToken{Id: 1}, enrolled: true, steppedUp: true, expectedCode: http.StatusOK},
+		{desc: "enrolled user without a session", user: &user.SignedInUser{UserID: 1}, enrolled: true, expectedCode: http.StatusForbidden},
+		{desc: "service account", user: &user.SignedInUser{UserID: 2, IsServiceAccount: true}, enrolled: true, expectedCode: http.StatusOK},
+		{desc: "api key", user: &user.SignedInUser{ApiKeyID: 3}, enrolled: true, expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			recorder := httptest.NewRecorder()
+			c := &contextmodel.ReqContext{
+				Context:      &web.Context{Req: req, Resp: web.NewResponseWriter(req.Method, recorder)},
+				SignedInUser: tt.user,
+				UserToken:    tt.token,
+				Logger:       log.NewNopLogger(),
+			}
+
+			handler := mfa.RequireStepUp(fakeService{enrolled: tt.enrolled, steppedUp: tt.steppedUp}).(func(*contextmodel.ReqContext))
+			handler(c)
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+		})
+	}
+}
+
+type fakeService struct {
+	mfa.Service
+	enrolled  bool
+	steppedUp bool
+}
+
+func (f fakeService) IsEnrolled(ctx context.Context, userID int64) (bool, error) {
+	return f.enrolled, nil
+}
+
+func (f fakeService) IsSteppedUp(ctx context.Context, sessionID int64) (bool, error) {
+	return f.steppedUp, nil
+}
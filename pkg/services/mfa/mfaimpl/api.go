@@ -0,0 +1,78 @@
+package mfaimpl
+
+import (
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+type mfaStatusResponse struct {
+	Enrolled  bool `json:"enrolled"`
+	SteppedUp bool `json:"steppedUp"`
+}
+
+type verifyCodeCommand struct {
+	Code string `json:"code"`
+}
+
+func (s *Service) registerAPIEndpoints(router routing.RouteRegister) {
+	router.Group("/api/user/mfa", func(mfaRoute routing.RouteRegister) {
+		mfaRoute.Get("/", routing.Wrap(s.getStatus))
+		mfaRoute.Post("/totp", routing.Wrap(s.enrollTOTP))
+		mfaRoute.Post("/totp/verify", routing.Wrap(s.verifyTOTP))
+		mfaRoute.Delete("/totp", mfa.RequireStepUp(s), routing.Wrap(s.disableTOTP))
+	}, middleware.ReqSignedInNoAnonymous)
+}
+
+func (s *Service) getStatus(c *contextmodel.ReqContext) response.Response {
+	enrolled, err := s.IsEnrolled(c.Req.Context(), c.UserID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get mfa status", err)
+	}
+
+	steppedUp := false
+	if c.UserToken != nil {
+		steppedUp, err = s.IsSteppedUp(c.Req.Context(), c.UserToken.Id)
+		if err != nil {
+			return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get mfa status", err)
+		}
+	}
+
+	return response.JSON(http.StatusOK, mfaStatusResponse{Enrolled: enrolled, SteppedUp: steppedUp})
+}
+
+func (s *Service) enrollTOTP(c *contextmodel.ReqContext) response.Response {
+	enrollment, err := s.Enroll(c.Req.Context(), c.UserID, c.Login)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to enroll mfa", err)
+	}
+	return response.JSON(http.StatusOK, enrollment)
+}
+
+func (s *Service) verifyTOTP(c *contextmodel.ReqContext) response.Response {
+	cmd := verifyCodeCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	if c.UserToken == nil {
+		return response.Err(mfa.ErrSessionRequired.Errorf("request is not authenticated with a session"))
+	}
+
+	if err := s.Verify(c.Req.Context(), c.UserID, c.UserToken.Id, cmd.Code); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to verify mfa code", err)
+	}
+	return response.Success("Code verified")
+}
+
+func (s *Service) disableTOTP(c *contextmodel.ReqContext) response.Response {
+	if err := s.Disable(c.Req.Context(), c.UserID); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to disable mfa", err)
+	}
+	return response.Success("Multi-factor authentication disabled")
+}
@@ -0,0 +1,236 @@
+package mfaimpl
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+var _ mfa.Service = (*Service)(nil)
+
+const (
+	// maxInvalidCodes is the number of invalid codes after which a user is locked out
+	// until the window has passed, so that codes cannot be brute-forced.
+	maxInvalidCodes    int64 = 5
+	invalidCodesWindow       = 5 * time.Minute
+)
+
+type cache interface {
+	GetByteArray(ctx context.Context, key string) ([]byte, error)
+	SetByteArray(ctx context.Context, key string, value []byte, expire time.Duration) error
+	SetIfNotExists(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error)
+	Increment(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error)
+	Decrement(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type Service struct {
+	cfg     *setting.Cfg
+	store   store
+	secrets secrets.Service
+	cache   cache
+	log     log.Logger
+	now     func() time.Time
+}
+
+func ProvideService(
+	cfg *setting.Cfg, sqlStore db.DB, secretsService secrets.Service, remoteCache *remotecache.RemoteCache,
+	router routing.RouteRegister, authnService authn.Service,
+) *Service {
+	s := &Service{
+		cfg:     cfg,
+		store:   &xormStore{db: sqlStore},
+		secrets: secretsService,
+		cache:   remoteCache,
+		log:     log.New("mfa"),
+		now:     time.Now,
+	}
+
+	if cfg.MFAEnabled {
+		s.registerAPIEndpoints(router)
+		authnService.RegisterPostAuthHook(s.assuranceLevelHook, 110)
+	}
+
+	return s
+}
+
+func (s *Service) Enroll(ctx context.Context, userID int64, login string) (*mfa.Enrollment, error) {
+	if !s.cfg.MFAEnabled {
+		return nil, mfa.ErrFeatureDisabled.Errorf("mfa is disabled")
+	}
+
+	existing, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Enabled {
+		return nil, mfa.ErrAlreadyEnrolled.Errorf("user %d is already enrolled", userID)
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.secrets.Encrypt(ctx, []byte(secret), secrets.WithoutScope())
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	if err := s.store.Upsert(ctx, &userMFA{
+		UserID:  userID,
+		Secret:  base64.StdEncoding.EncodeToString(encrypted),
+		Enabled: false,
+		Created: now,
+		Updated: now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &mfa.Enrollment{
+		Secret: secret,
+		URL:    otpauthURL(s.cfg.MFAIssuer, login, secret),
+	}, nil
+}
+
+func (s *Service) Verify(ctx context.Context, userID, sessionID int64, code string) error {
+	if !s.cfg.MFAEnabled {
+		return mfa.ErrFeatureDisabled.Errorf("mfa is disabled")
+	}
+
+	m, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return mfa.ErrNotEnrolled.Errorf("user %d is not enrolled", userID)
+	}
+
+	// Attempts are counted before the code is checked so that parallel guesses cannot all
+	// pass the limit, the window starts with the first attempt and is not extended by the following ones.
+	attempts, err := s.cache.Increment(ctx, invalidCodesKey(userID), 1, invalidCodesWindow)
+	if err != nil {
+		return err
+	}
+	if attempts > maxInvalidCodes {
+		return mfa.ErrTooManyAttempts.Errorf("user %d has entered too many invalid codes", userID)
+	}
+
+	secret, err := s.decryptSecret(ctx, m.Secret)
+	if err != nil {
+		return err
+	}
+
+	counter, ok := validateCode(secret, code, s.now())
+	if !ok {
+		return mfa.ErrInvalidCode.Errorf("invalid code for user %d", userID)
+	}
+
+	// only invalid codes count towards the lockout
+	if _, err := s.cache.Decrement(ctx, invalidCodesKey(userID), 1, invalidCodesWindow); err != nil {
+		return err
+	}
+
+	// A code can only be used once
+	unused, err := s.cache.SetIfNotExists(ctx, fmt.Sprintf("mfa-totp-used:%d:%d", userID, counter), []byte{1}, totpPeriod*(2*totpSkew+1))
+	if err != nil {
+		return err
+	}
+	if !unused {
+		return mfa.ErrInvalidCode.Errorf("code has already been used by user %d", userID)
+	}
+
+	if !m.Enabled {
+		if err := s.store.Enable(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	return s.StepUp(ctx, sessionID, authn.AMROTP)
+}
+
+func (s *Service) Disable(ctx context.Context, userID int64) error {
+	return s.store.Delete(ctx, userID)
+}
+
+func (s *Service) IsEnrolled(ctx context.Context, userID int64) (bool, error) {
+	if !s.cfg.MFAEnabled {
+		return false, nil
+	}
+
+	m, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return m != nil && m.Enabled, nil
+}
+
+func (s *Service) IsSteppedUp(ctx context.Context, sessionID int64) (bool, error) {
+	_, err := s.cache.GetByteArray(ctx, stepUpKey(sessionID))
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Service) StepUp(ctx context.Context, sessionID int64, method string) error {
+	return s.cache.SetByteArray(ctx, stepUpKey(sessionID), []byte(method), s.cfg.MFAStepUpTTL)
+}
+
+// assuranceLevelHook sets the assurance level and authentication methods of
+// identities authenticated with a session.
+func (s *Service) assuranceLevelHook(ctx context.Context, identity *authn.Identity, r *authn.Request) error {
+	if identity.SessionToken == nil {
+		return nil
+	}
+
+	identity.AssuranceLevel = authn.AssuranceLevelSingleFactor
+
+	method, err := s.cache.GetByteArray(ctx, stepUpKey(identity.SessionToken.Id))
+	if err != nil {
+		if !errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			s.log.FromContext(ctx).Warn("Failed to check mfa state of session", "error", err)
+		}
+		return nil
+	}
+
+	identity.AssuranceLevel = authn.AssuranceLevelMultiFactor
+	identity.AuthenticationMethods = append(identity.AuthenticationMethods, string(method))
+	return nil
+}
+
+func (s *Service) decryptSecret(ctx context.Context, encoded string) (string, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	decrypted, err := s.secrets.Decrypt(ctx, encrypted)
+	if err != nil {
+		return "", err
+	}
+	return string(decrypted), nil
+}
+
+func stepUpKey(sessionID int64) string {
+	return fmt.Sprintf("mfa-step-up:%d", sessionID)
+}
+
+func invalidCodesKey(userID int64) string {
+	return fmt.Sprintf("mfa-totp-invalid:%d", userID)
+}
@@ -0,0 +1,187 @@
+package mfaimpl
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestService_EnrollAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := setupTests(t, &now)
+	ctx := context.Background()
+
+	enrollment, err := s.Enroll(ctx, 1, "admin")
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URL, "otpauth://totp/Grafana:admin")
+
+	enrolled, err := s.IsEnrolled(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, enrolled, "enrollment should be pending until a code is verified")
+
+	err = s.Verify(ctx, 1, 10, "000000")
+	assert.ErrorIs(t, err, mfa.ErrInvalidCode)
+
+	code := codeFor(t, enrollment.Secret, now)
+	require.NoError(t, s.Verify(ctx, 1, 10, code))
+
+	enrolled, err = s.IsEnrolled(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, enrolled)
+
+	steppedUp, err := s.IsSteppedUp(ctx, 10)
+	require.NoError(t, err)
+	assert.True(t, steppedUp)
+
+	steppedUp, err = s.IsSteppedUp(ctx, 11)
+	require.NoError(t, err)
+	assert.False(t, steppedUp)
+
+	// codes cannot be reused
+	err = s.Verify(ctx, 1, 11, code)
+	assert.ErrorIs(t, err, mfa.ErrInvalidCode)
+
+	// the step up expires
+	now = now.Add(s.cfg.MFAStepUpTTL)
+	steppedUp, err = s.IsSteppedUp(ctx, 10)
+	require.NoError(t, err)
+	assert.False(t, steppedUp)
+
+	_, err = s.Enroll(ctx, 1, "admin")
+	assert.ErrorIs(t, err, mfa.ErrAlreadyEnrolled)
+
+	require.NoError(t, s.Disable(ctx, 1))
+	enrolled, err = s.IsEnrolled(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, enrolled)
+}
+
+func TestService_VerifyLockout(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := setupTests(t, &now)
+	ctx := context.Background()
+
+	enrollment, err := s.Enroll(ctx, 1, "admin")
+	require.NoError(t, err)
+
+	for i := int64(0); i < maxInvalidCodes; i++ {
+		assert.ErrorIs(t, s.Verify(ctx, 1, 10, "000000"), mfa.ErrInvalidCode)
+	}
+
+	// valid codes are rejected while the user is locked out
+	assert.ErrorIs(t, s.Verify(ctx, 1, 10, codeFor(t, enrollment.Secret, now)), mfa.ErrTooManyAttempts)
+
+	now = now.Add(invalidCodesWindow)
+	require.NoError(t, s.Verify(ctx, 1, 10, codeFor(t, enrollment.Secret, now)))
+}
+
+func TestService_VerifyLockoutConcurrently(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := setupTests(t, &now)
+	ctx := context.Background()
+
+	_, err := s.Enroll(ctx, 1, "admin")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var invalid int32
+	for i := 0; i < 3*int(maxInvalidCodes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if errors.Is(s.Verify(ctx, 1, 10, "000000"), mfa.ErrInvalidCode) {
+				atomic.AddInt32(&invalid, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// guesses made in parallel cannot get past the limit
+	assert.Equal(t, int32(maxInvalidCodes), invalid)
+}
+
+func TestService_AssuranceLevelHook(t *testing.T) {
+	now := time.Now()
+	s := setupTests(t, &now)
+	ctx := context.Background()
+
+	identity := &authn.Identity{ID: "user:1"}
+	require.NoError(t, s.assuranceLevelHook(ctx, identity, &authn.Request{}))
+	assert.Equal(t, authn.AssuranceLevelNone, identity.AssuranceLevel)
+
+	identity = &authn.Identity{ID: "user:1", SessionToken: &auth.UserToken{Id: 10}}
+	require.NoError(t, s.assuranceLevelHook(ctx, identity, &authn.Request{}))
+	assert.Equal(t, authn.AssuranceLevelSingleFactor, identity.AssuranceLevel)
+	assert.Empty(t, identity.AuthenticationMethods)
+
+	require.NoError(t, s.StepUp(ctx, 10, authn.AMROTP))
+	identity = &authn.Identity{ID: "user:1", SessionToken: &auth.UserToken{Id: 10}}
+	require.NoError(t, s.assuranceLevelHook(ctx, identity, &authn.Request{}))
+	assert.Equal(t, authn.AssuranceLevelMultiFactor, identity.AssuranceLevel)
+	assert.Equal(t, []string{authn.AMROTP}, identity.AuthenticationMethods)
+}
+
+// setupTests returns a service whose clock and cache expiry follow now.
+func setupTests(t *testing.T, now *time.Time) *Service {
+	t.Helper()
+
+	cfg := setting.NewCfg()
+	cfg.MFAEnabled = true
+	cfg.MFAIssuer = "Grafana"
+	cfg.MFAStepUpTTL = 15 * time.Minute
+
+	return &Service{
+		cfg:     cfg,
+		store:   &fakeStore{data: map[int64]*userMFA{}},
+		secrets: fakes.NewFakeSecretsService(),
+		cache:   remotecache.NewFakeMemoryStore(t, func() time.Time { return *now }),
+		log:     log.NewNopLogger(),
+		now:     func() time.Time { return *now },
+	}
+}
+
+func codeFor(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	return generateCode(key, totpCounter(now))
+}
+
+type fakeStore struct {
+	data map[int64]*userMFA
+}
+
+func (f *fakeStore) Get(ctx context.Context, userID int64) (*userMFA, error) {
+	return f.data[userID], nil
+}
+
+func (f *fakeStore) Upsert(ctx context.Context, m *userMFA) error {
+	f.data[m.UserID] = m
+	return nil
+}
+
+func (f *fakeStore) Enable(ctx context.Context, userID int64) error {
+	if m, ok := f.data[userID]; ok {
+		m.Enabled = true
+	}
+	return nil
+}
+
+func (f *fakeStore) Delete(ctx context.Context, userID int64) error {
+	delete(f.data, userID)
+	return nil
+}
@@ -0,0 +1,71 @@
+package mfaimpl
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+type userMFA struct {
+	ID     int64 `xorm:"pk autoincr 'id'"`
+	UserID int64 `xorm:"user_id"`
+	// Secret is the encrypted and base64 encoded TOTP secret
+	Secret  string    `xorm:"secret"`
+	Enabled bool      `xorm:"enabled"`
+	Created time.Time `xorm:"created"`
+	Updated time.Time `xorm:"updated"`
+}
+
+func (m userMFA) TableName() string { return "user_mfa" }
+
+type store interface {
+	// Get returns the enrollment of the user or nil if the user has not started an enrollment.
+	Get(ctx context.Context, userID int64) (*userMFA, error)
+	// Upsert replaces the enrollment of the user.
+	Upsert(ctx context.Context, m *userMFA) error
+	Enable(ctx context.Context, userID int64) error
+	Delete(ctx context.Context, userID int64) error
+}
+
+type xormStore struct {
+	db db.DB
+}
+
+func (s *xormStore) Get(ctx context.Context, userID int64) (*userMFA, error) {
+	var m userMFA
+	var has bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		has, err = sess.Where("user_id = ?", userID).Get(&m)
+		return err
+	})
+	if err != nil || !has {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (s *xormStore) Upsert(ctx context.Context, m *userMFA) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		if _, err := sess.Exec("DELETE FROM user_mfa WHERE user_id = ?", m.UserID); err != nil {
+			return err
+		}
+		_, err := sess.Insert(m)
+		return err
+	})
+}
+
+func (s *xormStore) Enable(ctx context.Context, userID int64) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec("UPDATE user_mfa SET enabled = ?, updated = ? WHERE user_id = ?", true, time.Now(), userID)
+		return err
+	})
+}
+
+func (s *xormStore) Delete(ctx context.Context, userID int64) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec("DELETE FROM user_mfa WHERE user_id = ?", userID)
+		return err
+	})
+}
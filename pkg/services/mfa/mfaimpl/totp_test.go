@@ -0,0 +1,45 @@
+package mfaimpl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTP_GenerateCode(t *testing.T) {
+	// Test vectors from RFC 6238 appendix B, truncated to six digits
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 2000000000, expected: "279037"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, generateCode(secret, totpCounter(time.Unix(tt.unix, 0))))
+	}
+}
+
+func TestTOTP_ValidateCode(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(59, 0)
+
+	counter, ok := validateCode(secret, "287082", now)
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), counter)
+
+	_, ok = validateCode(secret, "287082", now.Add(totpPeriod))
+	assert.True(t, ok, "code from the previous period should be accepted")
+
+	_, ok = validateCode(secret, "287082", now.Add(2*totpPeriod))
+	assert.False(t, ok, "code outside the allowed skew should be rejected")
+
+	_, ok = validateCode(secret, "000000", now)
+	assert.False(t, ok)
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana/pkg/infra/kvstore"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
@@ -181,6 +182,10 @@ func (fkv *FakeKVStore) Set(_ context.Context, orgId int64, namespace string, ke
 
 	return nil
 }
+func (fkv *FakeKVStore) SetWithTTL(ctx context.Context, orgId int64, namespace string, key string, value string, _ time.Duration) error {
+	return fkv.Set(ctx, orgId, namespace, key, value)
+}
+
 func (fkv *FakeKVStore) Del(_ context.Context, orgId int64, namespace string, key string) error {
 	fkv.mtx.Lock()
 	defer fkv.mtx.Unlock()
@@ -225,6 +230,10 @@ func (fkv *FakeKVStore) GetAll(ctx context.Context, orgId int64, namespace strin
 	return nil, nil
 }
 
+func (fkv *FakeKVStore) DeleteExpired(_ context.Context) (int64, error) {
+	return 0, nil
+}
+
 type fakeState struct {
 	data string
 }
@@ -69,7 +69,7 @@ func (o *Service) GetCurrentOAuthToken(ctx context.Context, usr *user.SignedInUs
 		return nil
 	}
 
-	token, err := o.tryGetOrRefreshAccessToken(ctx, authInfoQuery.Result)
+	token, err := o.tryGetOrRefreshAccessToken(ctx, authInfoQuery.Result, false)
 	if err != nil {
 		if errors.Is(err, ErrNoRefreshTokenFound) {
 			return buildOAuthTokenFromAuthInfo(authInfoQuery.Result)
@@ -112,11 +112,17 @@ func (o *Service) HasOAuthEntry(ctx context.Context, usr *user.SignedInUser) (*l
 // TryTokenRefresh returns an error in case the OAuth token refresh was unsuccessful
 // It uses a singleflight.Group to prevent getting the Refresh Token multiple times for a given User
 func (o *Service) TryTokenRefresh(ctx context.Context, usr *login.UserAuth) error {
+	return o.refreshToken(ctx, usr, false)
+}
+
+// refreshToken gets a new access token for the user, sharing the singleflight.Group
+// with TryTokenRefresh. If force is true the token is refreshed even if it has not expired yet.
+func (o *Service) refreshToken(ctx context.Context, usr *login.UserAuth, force bool) error {
 	lockKey := fmt.Sprintf("oauth-refresh-token-%d", usr.UserId)
 	_, err, _ := o.singleFlightGroup.Do(lockKey, func() (interface{}, error) {
 		logger.Debug("singleflight request for getting a new access token", "key", lockKey)
 
-		return o.tryGetOrRefreshAccessToken(ctx, usr)
+		return o.tryGetOrRefreshAccessToken(ctx, usr, force)
 	})
 	return err
 }
@@ -166,7 +172,7 @@ func (o *Service) InvalidateOAuthTokens(ctx context.Context, usr *login.UserAuth
 	})
 }
 
-func (o *Service) tryGetOrRefreshAccessToken(ctx context.Context, usr *login.UserAuth) (*oauth2.Token, error) {
+func (o *Service) tryGetOrRefreshAccessToken(ctx context.Context, usr *login.UserAuth, force bool) (*oauth2.Token, error) {
 	if err := checkOAuthRefreshToken(usr); err != nil {
 		return nil, err
 	}
@@ -187,8 +193,16 @@ func (o *Service) tryGetOrRefreshAccessToken(ctx context.Context, usr *login.Use
 
 	persistedToken := buildOAuthTokenFromAuthInfo(usr)
 
+	sourceToken := persistedToken
+	if force {
+		// Mark the token as expired so that the TokenSource refreshes it
+		expired := *persistedToken
+		expired.Expiry = time.Now().Add(-time.Second)
+		sourceToken = &expired
+	}
+
 	// TokenSource handles refreshing the token if it has expired
-	token, err := connect.TokenSource(ctx, persistedToken).Token()
+	token, err := connect.TokenSource(ctx, sourceToken).Token()
 	if err != nil {
 		logger.Error("failed to retrieve oauth access token",
 			"provider", usr.AuthModule, "userId", usr.UserId, "error", err)
@@ -0,0 +1,186 @@
+package oauthtoken
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// TokenRenewalService refreshes the OAuth access tokens of users with an active
+// session before they expire, so requests forwarding the OAuth identity do not
+// fail when a token expires.
+type TokenRenewalService struct {
+	cfg          *setting.Cfg
+	store        renewalStore
+	tokenService *Service
+	lock         *serverlock.ServerLockService
+	log          log.Logger
+	// jitter returns the delay after the start of a run before refreshing a token
+	jitter func() time.Duration
+}
+
+type renewalCandidate struct {
+	UserID     int64  `xorm:"user_id"`
+	AuthModule string `xorm:"auth_module"`
+}
+
+// scheduledRenewal is a candidate together with the time its token is refreshed at
+type scheduledRenewal struct {
+	renewalCandidate
+	at time.Time
+}
+
+type renewalStore interface {
+	// FindExpiringTokens returns the users with an active session whose OAuth access token expires in the given time range.
+	// A session is active when it was created after createdSince and rotated after activeSince.
+	FindExpiringTokens(ctx context.Context, from, to, createdSince, activeSince time.Time) ([]renewalCandidate, error)
+}
+
+func ProvideTokenRenewalService(cfg *setting.Cfg, sqlStore db.DB, tokenService *Service, lock *serverlock.ServerLockService) *TokenRenewalService {
+	s := &TokenRenewalService{
+		cfg:          cfg,
+		store:        &xormRenewalStore{db: sqlStore},
+		tokenService: tokenService,
+		lock:         lock,
+		log:          log.New("oauthtoken.renewal"),
+	}
+
+	maxJitter := cfg.OAuthTokenRenewalInterval / 2
+	s.jitter = func() time.Duration {
+		if maxJitter <= 0 {
+			return 0
+		}
+		// nolint:gosec
+		return time.Duration(rand.Int63n(int64(maxJitter)))
+	}
+
+	return s
+}
+
+func (s *TokenRenewalService) Run(ctx context.Context) error {
+	if !s.cfg.OAuthTokenRenewalEnabled {
+		return nil
+	}
+
+	ticker := time.NewTicker(s.cfg.OAuthTokenRenewalInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := s.lock.LockAndExecute(ctx, "oauth token renewal", s.cfg.OAuthTokenRenewalInterval, func(ctx context.Context) {
+				s.renewExpiringTokens(ctx)
+			})
+			if err != nil {
+				s.log.Error("Failed to lock and execute OAuth token renewal", "error", err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *TokenRenewalService) renewExpiringTokens(ctx context.Context) {
+	now := time.Now()
+	candidates, err := s.store.FindExpiringTokens(ctx, now, now.Add(s.cfg.OAuthTokenRenewalWindow),
+		now.Add(-s.cfg.LoginMaxLifetime), now.Add(-s.cfg.LoginMaxInactiveLifetime))
+	if err != nil {
+		s.log.Error("Failed to find expiring OAuth tokens", "error", err)
+		return
+	}
+
+	if len(candidates) == 0 {
+		return
+	}
+
+	s.log.Debug("Renewing expiring OAuth tokens", "count", len(candidates))
+
+	// Group the candidates per provider so each provider gets its own concurrency limit. Each refresh is
+	// scheduled at a random time after the start of the run, so the total delay is bounded by the jitter
+	// instead of adding up for every candidate a worker handles.
+	byProvider := map[string][]scheduledRenewal{}
+	for _, c := range candidates {
+		byProvider[c.AuthModule] = append(byProvider[c.AuthModule], scheduledRenewal{renewalCandidate: c, at: now.Add(s.jitter())})
+	}
+
+	var wg sync.WaitGroup
+	for _, providerCandidates := range byProvider {
+		sort.Slice(providerCandidates, func(i, j int) bool {
+			return providerCandidates[i].at.Before(providerCandidates[j].at)
+		})
+
+		queue := make(chan scheduledRenewal, len(providerCandidates))
+		for _, c := range providerCandidates {
+			queue <- c
+		}
+		close(queue)
+
+		workers := s.cfg.OAuthTokenRenewalConcurrency
+		if workers > len(providerCandidates) {
+			workers = len(providerCandidates)
+		}
+
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for c := range queue {
+					s.renewToken(ctx, c)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func (s *TokenRenewalService) renewToken(ctx context.Context, c scheduledRenewal) {
+	select {
+	case <-time.After(time.Until(c.at)):
+	case <-ctx.Done():
+		return
+	}
+
+	query := &login.GetAuthInfoQuery{UserId: c.UserID}
+	if err := s.tokenService.AuthInfoService.GetAuthInfo(ctx, query); err != nil {
+		s.log.Warn("Failed to get auth info for OAuth token renewal", "userId", c.UserID, "error", err)
+		return
+	}
+
+	// The user may have logged in with another provider since the candidates were collected
+	if query.Result.AuthModule != c.AuthModule {
+		return
+	}
+
+	if err := s.tokenService.refreshToken(ctx, query.Result, true); err != nil {
+		if errors.Is(err, ErrNoRefreshTokenFound) {
+			return
+		}
+		s.log.Warn("Failed to renew OAuth token", "userId", c.UserID, "provider", c.AuthModule, "error", err)
+	}
+}
+
+type xormRenewalStore struct {
+	db db.DB
+}
+
+func (s *xormRenewalStore) FindExpiringTokens(ctx context.Context, from, to, createdSince, activeSince time.Time) ([]renewalCandidate, error) {
+	var candidates []renewalCandidate
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.SQL(`SELECT DISTINCT ua.user_id, ua.auth_module FROM user_auth AS ua
+			INNER JOIN user_auth_token AS uat ON uat.user_id = ua.user_id
+			WHERE ua.auth_module LIKE 'oauth%'
+			AND ua.o_auth_expiry > ? AND ua.o_auth_expiry <= ?
+			AND uat.revoked_at = 0 AND uat.created_at > ? AND uat.rotated_at > ?`,
+			from, to, createdSince.Unix(), activeSince.Unix()).Find(&candidates)
+	})
+	return candidates, err
+}
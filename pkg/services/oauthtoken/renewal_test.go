@@ -0,0 +1,131 @@
+package oauthtoken
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/login"
+)
+
+func TestTokenRenewalService_RenewExpiringTokens(t *testing.T) {
+	type testCase struct {
+		desc         string
+		candidates   []renewalCandidate
+		authModule   string
+		expectRenew  bool
+		refreshToken string
+	}
+
+	tests := []testCase{
+		{
+			desc:         "should renew token that is about to expire",
+			candidates:   []renewalCandidate{{UserID: 1, AuthModule: "oauth_generic_oauth"}},
+			authModule:   "oauth_generic_oauth",
+			refreshToken: "testrefresh",
+			expectRenew:  true,
+		},
+		{
+			desc:         "should skip user that logged in with another provider",
+			candidates:   []renewalCandidate{{UserID: 1, AuthModule: "oauth_generic_oauth"}},
+			authModule:   "oauth_github",
+			refreshToken: "testrefresh",
+		},
+		{
+			desc:       "should skip token without refresh token",
+			candidates: []renewalCandidate{{UserID: 1, AuthModule: "oauth_generic_oauth"}},
+			authModule: "oauth_generic_oauth",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			tokenService, authInfoStore, socialConnector := setupOAuthTokenService(t)
+			tokenService.Cfg.OAuthTokenRenewalWindow = 5 * time.Minute
+			tokenService.Cfg.OAuthTokenRenewalConcurrency = 2
+
+			expiry := time.Now().Add(time.Minute)
+			authInfoStore.ExpectedOAuth = &login.UserAuth{
+				UserId:            1,
+				AuthModule:        tt.authModule,
+				OAuthAccessToken:  "testaccess",
+				OAuthRefreshToken: tt.refreshToken,
+				OAuthExpiry:       expiry,
+				OAuthTokenType:    "Bearer",
+			}
+
+			newToken := &oauth2.Token{
+				AccessToken:  "testaccess_new",
+				RefreshToken: "testrefresh_new",
+				Expiry:       time.Now().Add(time.Hour),
+				TokenType:    "Bearer",
+			}
+
+			// the token passed to the token source must be expired to force a refresh
+			socialConnector.On("TokenSource", mock.Anything, mock.MatchedBy(func(token *oauth2.Token) bool {
+				return !token.Valid()
+			})).Return(oauth2.StaticTokenSource(newToken))
+
+			s := &TokenRenewalService{
+				cfg:          tokenService.Cfg,
+				store:        &fakeRenewalStore{candidates: tt.candidates},
+				tokenService: tokenService,
+				log:          log.NewNopLogger(),
+				jitter:       func() time.Duration { return 0 },
+			}
+
+			s.renewExpiringTokens(context.Background())
+
+			query := &login.GetAuthInfoQuery{UserId: 1}
+			require.NoError(t, tokenService.AuthInfoService.GetAuthInfo(context.Background(), query))
+
+			if tt.expectRenew {
+				socialConnector.AssertNumberOfCalls(t, "TokenSource", 1)
+				assert.Equal(t, newToken.AccessToken, query.Result.OAuthAccessToken)
+				assert.Equal(t, newToken.RefreshToken, query.Result.OAuthRefreshToken)
+				return
+			}
+
+			socialConnector.AssertNotCalled(t, "TokenSource")
+			assert.Equal(t, "testaccess", query.Result.OAuthAccessToken)
+		})
+	}
+}
+
+func TestTokenRenewalService_RenewExpiringTokensJitter(t *testing.T) {
+	tokenService, authInfoStore, _ := setupOAuthTokenService(t)
+	tokenService.Cfg.OAuthTokenRenewalConcurrency = 1
+	authInfoStore.ExpectedOAuth = &login.UserAuth{UserId: 1, AuthModule: "oauth_github"}
+
+	candidates := make([]renewalCandidate, 0, 5)
+	for i := int64(1); i <= 5; i++ {
+		candidates = append(candidates, renewalCandidate{UserID: i, AuthModule: "oauth_generic_oauth"})
+	}
+
+	s := &TokenRenewalService{
+		cfg:          tokenService.Cfg,
+		store:        &fakeRenewalStore{candidates: candidates},
+		tokenService: tokenService,
+		log:          log.NewNopLogger(),
+		jitter:       func() time.Duration { return 100 * time.Millisecond },
+	}
+
+	// the jitter is measured from the start of the run and does not add up per candidate
+	start := time.Now()
+	s.renewExpiringTokens(context.Background())
+	assert.Less(t, time.Since(start), 300*time.Millisecond)
+}
+
+type fakeRenewalStore struct {
+	candidates []renewalCandidate
+}
+
+func (f *fakeRenewalStore) FindExpiringTokens(ctx context.Context, from, to, createdSince, activeSince time.Time) ([]renewalCandidate, error) {
+	return f.candidates, nil
+}
@@ -106,10 +106,11 @@ func (ss *sqlStore) Insert(ctx context.Context, org *org.Org) (int64, error) {
 // InsertOrgUser adds a new membership record for a user in an organization.
 func (ss *sqlStore) InsertOrgUser(ctx context.Context, cmd *org.OrgUser) (int64, error) {
 	var err error
-	err = ss.db.WithDbSession(ctx, func(sess *db.Session) error {
+	err = ss.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
 		if _, err = sess.Insert(cmd); err != nil {
 			return err
 		}
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: cmd.UserID})
 		return nil
 	})
 	if err != nil {
@@ -119,10 +120,11 @@ func (ss *sqlStore) InsertOrgUser(ctx context.Context, cmd *org.OrgUser) (int64,
 }
 
 func (ss *sqlStore) DeleteUserFromAll(ctx context.Context, userID int64) error {
-	return ss.db.WithDbSession(ctx, func(sess *db.Session) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
 		if _, err := sess.Exec("DELETE FROM org_user WHERE user_id = ?", userID); err != nil {
 			return err
 		}
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: userID})
 		return nil
 	})
 }
@@ -242,6 +244,8 @@ func (ss *sqlStore) Delete(ctx context.Context, cmd *org.DeleteOrgCommand) error
 			}
 		}
 
+		// all members of the organization lose their role in it
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now()})
 		return nil
 	})
 }
@@ -330,6 +334,7 @@ func (ss *sqlStore) CreateWithMember(ctx context.Context, cmd *org.CreateOrgComm
 
 		_, err := sess.Insert(&user)
 
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: cmd.UserID})
 		sess.PublishAfterCommit(&events.OrgCreated{
 			Timestamp: orga.Created,
 			Id:        orga.ID,
@@ -382,6 +387,7 @@ func (ss *sqlStore) AddOrgUser(ctx context.Context, cmd *org.AddOrgUserCommand)
 		if err != nil {
 			return err
 		}
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: cmd.UserID})
 
 		var userOrgs []*org.UserOrgDTO
 		sess.Table("org_user")
@@ -497,6 +503,7 @@ func (ss *sqlStore) UpdateOrgUser(ctx context.Context, cmd *org.UpdateOrgUserCom
 			return err
 		}
 
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: cmd.UserID})
 		return validateOneAdminLeftInOrg(cmd.OrgID, sess)
 	})
 }
@@ -676,6 +683,7 @@ func (ss *sqlStore) RemoveOrgUser(ctx context.Context, cmd *org.RemoveOrgUserCom
 		if err := validateOneAdminLeftInOrg(cmd.OrgID, sess); err != nil {
 			return err
 		}
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: cmd.UserID})
 
 		// check user other orgs and update user current org
 		var userOrgs []*org.UserOrgDTO
@@ -794,6 +802,9 @@ func (ss *sqlStore) userDeletions() []string {
 		"DELETE FROM user_auth WHERE user_id = ?",
 		"DELETE FROM user_auth_token WHERE user_id = ?",
 		"DELETE FROM quota WHERE user_id = ?",
+		"DELETE FROM user_mfa WHERE user_id = ?",
+		"DELETE FROM user_webauthn_credential WHERE user_id = ?",
+		"DELETE FROM user_device WHERE user_id = ?",
 	}
 	return deletes
 }
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/grafana/grafana/pkg/events"
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/kvstore"
 	"github.com/grafana/grafana/pkg/infra/log"
@@ -155,6 +156,7 @@ func (s *ServiceAccountsStoreImpl) UpdateServiceAccount(
 			}
 		}
 
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: updateTime, UserID: serviceAccountId})
 		return nil
 	})
 
@@ -192,6 +194,7 @@ func (s *ServiceAccountsStoreImpl) deleteServiceAccount(sess *db.Session, orgId,
 			return err
 		}
 	}
+	sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: user.ID})
 	return nil
 }
 
@@ -0,0 +1,123 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// rowsAffectedDriver wraps a database driver to save the rows affected by statements in the
+// databaseQuery of the context, sqlhooks does not pass the results of statements to the hooks.
+// The connections and statements implement the same interfaces as the connections and
+// statements of sqlhooks, and fall back to the same methods if the driver does not.
+type rowsAffectedDriver struct {
+	driver.Driver
+}
+
+func (d *rowsAffectedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &rowsAffectedConn{Conn: conn}, nil
+}
+
+type rowsAffectedConn struct {
+	driver.Conn
+}
+
+func (c *rowsAffectedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rowsAffectedStmt{Stmt: stmt}, nil
+}
+
+func (c *rowsAffectedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	var (
+		result driver.Result
+		err    error
+	)
+	switch e := c.Conn.(type) {
+	case driver.ExecerContext:
+		result, err = e.ExecContext(ctx, query, args)
+	case driver.Execer: //nolint:staticcheck
+		result, err = e.Exec(query, namedValuesToValues(args))
+	default:
+		return nil, driver.ErrSkip
+	}
+	saveRowsAffected(ctx, result, err)
+	return result, err
+}
+
+func (c *rowsAffectedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	switch q := c.Conn.(type) {
+	case driver.QueryerContext:
+		return q.QueryContext(ctx, query, args)
+	case driver.Queryer: //nolint:staticcheck
+		return q.Query(query, namedValuesToValues(args))
+	default:
+		return nil, driver.ErrSkip
+	}
+}
+
+func (c *rowsAffectedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+type rowsAffectedStmt struct {
+	driver.Stmt
+}
+
+func (s *rowsAffectedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var (
+		result driver.Result
+		err    error
+	)
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else {
+		result, err = s.Stmt.Exec(namedValuesToValues(args)) //nolint:staticcheck
+	}
+	saveRowsAffected(ctx, result, err)
+	return result, err
+}
+
+func (s *rowsAffectedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return q.QueryContext(ctx, args)
+	}
+	return s.Stmt.Query(namedValuesToValues(args)) //nolint:staticcheck
+}
+
+func saveRowsAffected(ctx context.Context, result driver.Result, err error) {
+	if err != nil {
+		return
+	}
+	q, ok := ctx.Value(databaseQueryWrapperKey{}).(*databaseQuery)
+	if !ok {
+		return
+	}
+	if rows, err := result.RowsAffected(); err == nil {
+		q.rowsAffected = rows
+	}
+}
+
+func namedValuesToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for _, arg := range args {
+		values[arg.Ordinal-1] = arg.Value
+	}
+	return values
+}
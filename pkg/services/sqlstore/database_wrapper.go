@@ -6,7 +6,11 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gchaincl/sqlhooks"
 	"github.com/go-sql-driver/mysql"
@@ -22,7 +26,9 @@ import (
 )
 
 var (
-	databaseQueryHistogram *prometheus.HistogramVec
+	databaseQueryHistogram     *prometheus.HistogramVec
+	databaseStatementHistogram *prometheus.HistogramVec
+	databaseRowsAffected       *prometheus.CounterVec
 )
 
 func init() {
@@ -33,13 +39,27 @@ func init() {
 		Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
 	}, []string{"status"})
 
-	prometheus.MustRegister(databaseQueryHistogram)
+	databaseStatementHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "grafana",
+		Name:      "database_statement_duration_seconds",
+		Help:      "Histogram of the duration of database statements by the service that executed them",
+		Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+	}, []string{"service", "statement", "status"})
+
+	databaseRowsAffected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Name:      "database_rows_affected_total",
+		Help:      "The total number of rows affected by database statements by the service that executed them",
+	}, []string{"service", "statement"})
+
+	prometheus.MustRegister(databaseQueryHistogram, databaseStatementHistogram, databaseRowsAffected)
 }
 
 // WrapDatabaseDriverWithHooks creates a fake database driver that
 // executes pre and post functions which we use to gather metrics about
-// database queries. It also registers the metrics.
-func WrapDatabaseDriverWithHooks(dbType string, tracer tracing.Tracer) string {
+// database queries. It also registers the metrics. Queries that take longer
+// than slowQueryThreshold are logged, 0 disables the slow query log.
+func WrapDatabaseDriverWithHooks(dbType string, tracer tracing.Tracer, slowQueryThreshold time.Duration) string {
 	drivers := map[string]driver.Driver{
 		migrator.SQLite:   &sqlite3.SQLiteDriver{},
 		migrator.MySQL:    &mysql.MySQLDriver{},
@@ -52,7 +72,11 @@ func WrapDatabaseDriverWithHooks(dbType string, tracer tracing.Tracer) string {
 	}
 
 	driverWithHooks := dbType + "WithHooks"
-	sql.Register(driverWithHooks, sqlhooks.Wrap(d, &databaseQueryWrapper{log: log.New("sqlstore.metrics"), tracer: tracer}))
+	sql.Register(driverWithHooks, sqlhooks.Wrap(&rowsAffectedDriver{Driver: d}, &databaseQueryWrapper{
+		log:                log.New("sqlstore.metrics"),
+		tracer:             tracer,
+		slowQueryThreshold: slowQueryThreshold,
+	}))
 	core.RegisterDriver(driverWithHooks, &databaseQueryWrapperDriver{dbType: dbType})
 	return driverWithHooks
 }
@@ -60,16 +84,24 @@ func WrapDatabaseDriverWithHooks(dbType string, tracer tracing.Tracer) string {
 // databaseQueryWrapper satisfies the sqlhook.databaseQueryWrapper interface
 // which allow us to wrap all SQL queries with a `Before` & `After` hook.
 type databaseQueryWrapper struct {
-	log    log.Logger
-	tracer tracing.Tracer
+	log                log.Logger
+	tracer             tracing.Tracer
+	slowQueryThreshold time.Duration
 }
 
 // databaseQueryWrapperKey is used as key to save values in `context.Context`
 type databaseQueryWrapperKey struct{}
 
+// databaseQuery is saved in the context of a query by the Before hook
+type databaseQuery struct {
+	begin time.Time
+	// rowsAffected is set by rowsAffectedDriver for statements that modify rows
+	rowsAffected int64
+}
+
 // Before hook will print the query with its args and return the context with the timestamp
 func (h *databaseQueryWrapper) Before(ctx context.Context, query string, args ...interface{}) (context.Context, error) {
-	return context.WithValue(ctx, databaseQueryWrapperKey{}, time.Now()), nil
+	return context.WithValue(ctx, databaseQueryWrapperKey{}, &databaseQuery{begin: time.Now()}), nil
 }
 
 // After hook will get the timestamp registered on the Before hook and print the elapsed time
@@ -80,7 +112,8 @@ func (h *databaseQueryWrapper) After(ctx context.Context, query string, args ...
 }
 
 func (h *databaseQueryWrapper) instrument(ctx context.Context, status string, query string, err error) {
-	begin := ctx.Value(databaseQueryWrapperKey{}).(time.Time)
+	q := ctx.Value(databaseQueryWrapperKey{}).(*databaseQuery)
+	begin := q.begin
 	elapsed := time.Since(begin)
 
 	histogram := databaseQueryHistogram.WithLabelValues(status)
@@ -95,6 +128,12 @@ func (h *databaseQueryWrapper) instrument(ctx context.Context, status string, qu
 		histogram.Observe(elapsed.Seconds())
 	}
 
+	service, statement := callingService(), statementType(query)
+	databaseStatementHistogram.WithLabelValues(service, statement, status).Observe(elapsed.Seconds())
+	if q.rowsAffected > 0 {
+		databaseRowsAffected.WithLabelValues(service, statement).Add(float64(q.rowsAffected))
+	}
+
 	ctx = log.IncDBCallCounter(ctx)
 
 	_, span := h.tracer.Start(ctx, "database query", trace.WithTimestamp(begin))
@@ -108,6 +147,84 @@ func (h *databaseQueryWrapper) instrument(ctx context.Context, status string, qu
 
 	ctxLogger := h.log.FromContext(ctx)
 	ctxLogger.Debug("query finished", "status", status, "elapsed time", elapsed, "sql", query, "error", err)
+
+	if h.slowQueryThreshold > 0 && elapsed >= h.slowQueryThreshold {
+		ctxLogger.Warn("Slow query", "service", service, "elapsed time", elapsed, "sql", sanitizeSQL(query),
+			"rows affected", q.rowsAffected, "status", status)
+	}
+}
+
+// grafanaPackagePrefix is the prefix of the functions of Grafana in stack traces
+const grafanaPackagePrefix = "github.com/grafana/grafana/pkg/"
+
+// databasePackages are the packages of the database layer, that execute statements on behalf of other services
+var databasePackages = map[string]bool{
+	"infra/db":                  true,
+	"services/sqlstore":         true,
+	"services/sqlstore/session": true,
+}
+
+// callingService returns the package, relative to pkg/, of the first function in the stack
+// that is not part of the database layer. The statements are executed in the goroutine of
+// the caller, so the stack includes the service that started the session.
+func callingService() string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, grafanaPackagePrefix) {
+			pkg := functionPackage(strings.TrimPrefix(frame.Function, grafanaPackagePrefix))
+			if !databasePackages[pkg] {
+				return pkg
+			}
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// functionPackage returns the package of a function name such as
+// services/dashboards/database.(*DashboardStore).FindDashboards.func1
+func functionPackage(function string) string {
+	dir := ""
+	if i := strings.LastIndex(function, "/"); i >= 0 {
+		dir, function = function[:i+1], function[i+1:]
+	}
+	if i := strings.Index(function, "."); i >= 0 {
+		function = function[:i]
+	}
+	return dir + function
+}
+
+// statementType returns the lowercase verb of select, insert, update and delete statements,
+// and other for all other statements
+func statementType(query string) string {
+	query = strings.TrimSpace(query)
+	if i := strings.IndexFunc(query, unicode.IsSpace); i >= 0 {
+		query = query[:i]
+	}
+	switch verb := strings.ToLower(query); verb {
+	case "select", "insert", "update", "delete":
+		return verb
+	default:
+		return "other"
+	}
+}
+
+var (
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumberLiteral = regexp.MustCompile(`([^\w$.])\d+(?:\.\d+)?\b`)
+	sqlWhitespace    = regexp.MustCompile(`\s+`)
+)
+
+// sanitizeSQL replaces the string and number literals of query, which can contain user data,
+// with placeholders. The arguments of queries are never logged.
+func sanitizeSQL(query string) string {
+	query = sqlStringLiteral.ReplaceAllString(query, "?")
+	query = sqlNumberLiteral.ReplaceAllString(query, "${1}?")
+	return strings.TrimSpace(sqlWhitespace.ReplaceAllString(query, " "))
 }
 
 // OnError will be called if any error happens
@@ -0,0 +1,85 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/gchaincl/sqlhooks"
+	"github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	for query, expected := range map[string]string{
+		"SELECT * FROM user WHERE login = 'admin' AND id = 1":   "SELECT * FROM user WHERE login = ? AND id = ?",
+		"SELECT * FROM user WHERE login = ? AND id = $2":        "SELECT * FROM user WHERE login = ? AND id = $2",
+		"INSERT INTO t (a, b) VALUES ('it''s', -1.5)":           "INSERT INTO t (a, b) VALUES (?, -?)",
+		"SELECT v2.id FROM dashboard_v2 AS v2 WHERE x IN (1,2)": "SELECT v2.id FROM dashboard_v2 AS v2 WHERE x IN (?,?)",
+		"\n\tSELECT 1\n\t\tFROM dual\n":                         "SELECT ? FROM dual",
+	} {
+		assert.Equal(t, expected, sanitizeSQL(query), query)
+	}
+}
+
+func TestStatementType(t *testing.T) {
+	for query, expected := range map[string]string{
+		"SELECT 1":                      "select",
+		"\n  insert INTO t VALUES (1)":  "insert",
+		"UPDATE t SET a = 1":            "update",
+		"DELETE FROM t":                 "delete",
+		"CREATE TABLE t (a INT)":        "other",
+		"WITH x AS (SELECT 1) SELECT 1": "other",
+		"":                              "other",
+	} {
+		assert.Equal(t, expected, statementType(query), query)
+	}
+}
+
+func TestFunctionPackage(t *testing.T) {
+	assert.Equal(t, "services/dashboards/database", functionPackage("services/dashboards/database.(*DashboardStore).FindDashboards.func1"))
+	assert.Equal(t, "services/sqlstore", functionPackage("services/sqlstore.(*SQLStore).WithDbSession"))
+	assert.Equal(t, "server", functionPackage("server.Initialize"))
+}
+
+func TestDatabaseQueryWrapper_RowsAffected(t *testing.T) {
+	sql.Register("sqlite3RowsAffectedTest", sqlhooks.Wrap(&rowsAffectedDriver{Driver: &sqlite3.SQLiteDriver{}}, &databaseQueryWrapper{
+		log:                log.New("sqlstore.metrics"),
+		tracer:             tracing.InitializeTracerForTest(),
+		slowQueryThreshold: time.Nanosecond,
+	}))
+	db, err := sql.Open("sqlite3RowsAffectedTest", "file::memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	// statements executed by the tests are not executed by a service
+	updated := databaseRowsAffected.WithLabelValues("unknown", "update")
+	before := testutil.ToFloat64(updated)
+
+	_, err = db.ExecContext(ctx, "CREATE TABLE rows_affected (id INTEGER, name TEXT)")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "INSERT INTO rows_affected VALUES (1, 'a'), (2, 'b'), (3, 'c')")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "UPDATE rows_affected SET name = ? WHERE id > ?", "d", 1)
+	require.NoError(t, err)
+
+	stmt, err := db.PrepareContext(ctx, "UPDATE rows_affected SET name = ?")
+	require.NoError(t, err)
+	_, err = stmt.ExecContext(ctx, "e")
+	require.NoError(t, err)
+	require.NoError(t, stmt.Close())
+
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM rows_affected WHERE name = ?", "e").Scan(&count))
+	assert.Equal(t, 3, count)
+	assert.Equal(t, float64(2+3), testutil.ToFloat64(updated)-before)
+}
@@ -0,0 +1,203 @@
+// Package encrypted encrypts the columns of database rows with the secrets service.
+//
+// The encrypted columns are declared with the encrypted struct tag on the fields of the row:
+//
+//	type UserAuth struct {
+//		Id               int64
+//		OAuthAccessToken string `encrypted:"true"`
+//		OAuthIdToken     string `encrypted:"true,omitempty"`
+//	}
+//
+// Fields of type string are stored base64 encoded, fields of type []byte are stored as is. Empty
+// values are never decrypted, and are not encrypted either with the omitempty option.
+package encrypted
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/grafana/grafana/pkg/services/secrets"
+)
+
+const tagName = "encrypted"
+
+var bytesType = reflect.TypeOf([]byte(nil))
+
+// Columns encrypts the encrypted columns of rows before they are written, and decrypts them after
+// they are read.
+// Like secrets.Service.Encrypt, Encrypt must not be called within database transactions, rows must
+// be encrypted before the transaction starts.
+type Columns struct {
+	secretsService secrets.Service
+	opt            secrets.EncryptionOptions
+}
+
+// NewColumns returns Columns encrypting with the options opt, ex secrets.WithoutScope().
+func NewColumns(secretsService secrets.Service, opt secrets.EncryptionOptions) *Columns {
+	return &Columns{
+		secretsService: secretsService,
+		opt:            opt,
+	}
+}
+
+// Encrypt encrypts the encrypted columns of rows in place, rows is a pointer to a struct or a
+// slice of structs or of pointers to structs.
+func (c *Columns) Encrypt(ctx context.Context, rows interface{}) error {
+	return eachRow(rows, func(row reflect.Value) error {
+		fields, err := fieldsOf(row.Type())
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if err := c.encryptField(ctx, row.Field(f.index), f); err != nil {
+				return fmt.Errorf("failed to encrypt %s: %w", f.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Decrypt decrypts the encrypted columns of rows in place, rows is a pointer to a struct or a
+// slice of structs or of pointers to structs.
+func (c *Columns) Decrypt(ctx context.Context, rows interface{}) error {
+	return eachRow(rows, func(row reflect.Value) error {
+		fields, err := fieldsOf(row.Type())
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if err := c.decryptField(ctx, row.Field(f.index)); err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", f.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (c *Columns) encryptField(ctx context.Context, v reflect.Value, f field) error {
+	if v.Len() == 0 && f.omitEmpty {
+		return nil
+	}
+
+	if v.Kind() == reflect.String {
+		encrypted, err := c.secretsService.Encrypt(ctx, []byte(v.String()), c.opt)
+		if err != nil {
+			return err
+		}
+		v.SetString(base64.StdEncoding.EncodeToString(encrypted))
+		return nil
+	}
+
+	encrypted, err := c.secretsService.Encrypt(ctx, v.Bytes(), c.opt)
+	if err != nil {
+		return err
+	}
+	v.SetBytes(encrypted)
+	return nil
+}
+
+func (c *Columns) decryptField(ctx context.Context, v reflect.Value) error {
+	// decrypting an empty payload fails
+	if v.Len() == 0 {
+		return nil
+	}
+
+	if v.Kind() == reflect.String {
+		decoded, err := base64.StdEncoding.DecodeString(v.String())
+		if err != nil {
+			return err
+		}
+		decrypted, err := c.secretsService.Decrypt(ctx, decoded)
+		if err != nil {
+			return err
+		}
+		v.SetString(string(decrypted))
+		return nil
+	}
+
+	decrypted, err := c.secretsService.Decrypt(ctx, v.Bytes())
+	if err != nil {
+		return err
+	}
+	v.SetBytes(decrypted)
+	return nil
+}
+
+func eachRow(rows interface{}, fn func(row reflect.Value) error) error {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("rows must be a pointer, got %T", rows)
+	}
+
+	v = v.Elem()
+	switch v.Kind() {
+	case reflect.Struct:
+		return fn(v)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			row := v.Index(i)
+			if row.Kind() == reflect.Ptr {
+				if row.IsNil() {
+					continue
+				}
+				row = row.Elem()
+			}
+			if row.Kind() != reflect.Struct {
+				return fmt.Errorf("rows must be structs, got %s", row.Type())
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("rows must be a pointer to a struct or a slice, got %T", rows)
+	}
+}
+
+type field struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+// fieldsCache caches the encrypted fields by type
+var fieldsCache sync.Map
+
+func fieldsOf(t reflect.Type) ([]field, error) {
+	if fields, ok := fieldsCache.Load(t); ok {
+		return fields.([]field), nil
+	}
+
+	fields := make([]field, 0)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup(tagName)
+		if !ok {
+			continue
+		}
+
+		options := strings.Split(tag, ",")
+		if options[0] != "true" {
+			continue
+		}
+		if sf.Type.Kind() != reflect.String && sf.Type != bytesType {
+			return nil, fmt.Errorf("encrypted field %s.%s must be a string or []byte, got %s", t.Name(), sf.Name, sf.Type)
+		}
+
+		f := field{index: i, name: sf.Name}
+		for _, option := range options[1:] {
+			if option == "omitempty" {
+				f.omitEmpty = true
+			}
+		}
+		fields = append(fields, f)
+	}
+
+	fieldsCache.Store(t, fields)
+	return fields, nil
+}
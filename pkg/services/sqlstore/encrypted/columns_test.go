@@ -0,0 +1,101 @@
+package encrypted
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+)
+
+// prefixSecretsService prefixes the payloads instead of encrypting them
+type prefixSecretsService struct {
+	fakes.FakeSecretsService
+}
+
+func (prefixSecretsService) Encrypt(_ context.Context, payload []byte, _ secrets.EncryptionOptions) ([]byte, error) {
+	return append([]byte("encrypted:"), payload...), nil
+}
+
+func (prefixSecretsService) Decrypt(_ context.Context, payload []byte) ([]byte, error) {
+	if len(payload) < len("encrypted:") || string(payload[:len("encrypted:")]) != "encrypted:" {
+		return nil, errors.New("not encrypted")
+	}
+	return payload[len("encrypted:"):], nil
+}
+
+type row struct {
+	ID       int64
+	Name     string
+	Password string `encrypted:"true"`
+	Token    string `encrypted:"true,omitempty"`
+	Data     []byte `encrypted:"true"`
+	Ignored  string `encrypted:"false"`
+}
+
+func TestColumns(t *testing.T) {
+	ctx := context.Background()
+	columns := NewColumns(prefixSecretsService{}, secrets.WithoutScope())
+
+	t.Run("encrypt and decrypt a row", func(t *testing.T) {
+		r := &row{ID: 1, Name: "name", Password: "password", Data: []byte("data"), Ignored: "ignored"}
+		require.NoError(t, columns.Encrypt(ctx, r))
+
+		assert.Equal(t, "name", r.Name)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("encrypted:password")), r.Password)
+		assert.Empty(t, r.Token)
+		assert.Equal(t, []byte("encrypted:data"), r.Data)
+		assert.Equal(t, "ignored", r.Ignored)
+
+		require.NoError(t, columns.Decrypt(ctx, r))
+		assert.Equal(t, row{ID: 1, Name: "name", Password: "password", Data: []byte("data"), Ignored: "ignored"}, *r)
+	})
+
+	t.Run("empty values are encrypted without omitempty", func(t *testing.T) {
+		r := &row{}
+		require.NoError(t, columns.Encrypt(ctx, r))
+
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("encrypted:")), r.Password)
+		assert.Empty(t, r.Token)
+		assert.Equal(t, []byte("encrypted:"), r.Data)
+
+		require.NoError(t, columns.Decrypt(ctx, r))
+		assert.Empty(t, r.Password)
+		assert.Empty(t, r.Token)
+		assert.Empty(t, r.Data)
+	})
+
+	t.Run("decrypt slices of rows", func(t *testing.T) {
+		rows := []row{{Password: "first"}, {Password: "second", Token: "token"}}
+		require.NoError(t, columns.Encrypt(ctx, &rows))
+		require.NoError(t, columns.Decrypt(ctx, &rows))
+		assert.Equal(t, "first", rows[0].Password)
+		assert.Equal(t, "second", rows[1].Password)
+		assert.Equal(t, "token", rows[1].Token)
+
+		pointers := []*row{{Password: "first"}, nil}
+		require.NoError(t, columns.Encrypt(ctx, &pointers))
+		require.NoError(t, columns.Decrypt(ctx, &pointers))
+		assert.Equal(t, "first", pointers[0].Password)
+	})
+
+	t.Run("decrypting a value that is not encrypted fails", func(t *testing.T) {
+		err := columns.Decrypt(ctx, &row{Password: base64.StdEncoding.EncodeToString([]byte("password"))})
+		require.ErrorContains(t, err, "failed to decrypt Password")
+	})
+
+	t.Run("invalid rows", func(t *testing.T) {
+		require.Error(t, columns.Encrypt(ctx, row{}))
+		require.Error(t, columns.Encrypt(ctx, &[]string{"value"}))
+
+		type invalidRow struct {
+			Secret int `encrypted:"true"`
+		}
+		require.ErrorContains(t, columns.Encrypt(ctx, &invalidRow{}), "must be a string or []byte")
+	})
+}
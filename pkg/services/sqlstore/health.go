@@ -0,0 +1,150 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+// Health is the state of the database for operators, see SQLStore.Health.
+type Health struct {
+	Type string `json:"type"`
+	// PingError is set when the primary database could not be reached
+	PingError  string          `json:"pingError,omitempty"`
+	Pools      []Pool          `json:"pools"`
+	Replicas   []ReplicaHealth `json:"replicas,omitempty"`
+	Migrations MigrationStatus `json:"migrations"`
+	LastError  *DatabaseError  `json:"lastError,omitempty"`
+}
+
+// ReplicaHealth is the state of a read replica.
+type ReplicaHealth struct {
+	Name string `json:"name"`
+	// Lag is how far the replica is behind the primary database, empty if it is not known
+	Lag   string `json:"lag,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// MigrationStatus compares the migrations of this version of Grafana with the migration log.
+type MigrationStatus struct {
+	Total   int      `json:"total"`
+	Pending []string `json:"pending"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// DatabaseError is the last error returned by the database driver.
+type DatabaseError struct {
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// lastError is the last error returned by the database driver to a session.
+type lastError struct {
+	mu  sync.Mutex
+	err *DatabaseError
+}
+
+// recordError remembers err if it was returned by the database driver, errors of the callbacks
+// of the sessions and unique constraint violations, which are usually handled, are ignored.
+// It returns err.
+func (ss *SQLStore) recordError(err error) error {
+	if err == nil || ss.Dialect.IsUniqueConstraintViolation(err) {
+		return err
+	}
+	if ss.Dialect.ErrorMessage(err) == "" && ss.Dialect.TransientErrorReason(err) == "" {
+		return err
+	}
+
+	ss.lastError.mu.Lock()
+	ss.lastError.err = &DatabaseError{Message: err.Error(), Time: time.Now()}
+	ss.lastError.mu.Unlock()
+	return err
+}
+
+// Health checks the connection to the primary database, the replication lag of the read replicas and
+// the migrations, and returns them with the statistics of the connection pools and the last error
+// returned by the database driver.
+func (ss *SQLStore) Health(ctx context.Context) Health {
+	health := Health{
+		Type:       ss.Dialect.DriverName(),
+		Pools:      ss.Pools(),
+		Migrations: ss.migrationStatus(),
+	}
+
+	if err := ss.engine.DB().PingContext(ctx); err != nil {
+		health.PingError = ss.recordError(err).Error()
+	}
+
+	for _, r := range ss.replicas {
+		replica := ReplicaHealth{Name: r.name}
+		lag, err := ss.replicationLag(ctx, r.engine)
+		if err != nil {
+			replica.Error = err.Error()
+		} else if lag >= 0 {
+			replica.Lag = lag.String()
+		}
+		health.Replicas = append(health.Replicas, replica)
+	}
+
+	ss.lastError.mu.Lock()
+	if ss.lastError.err != nil {
+		lastErr := *ss.lastError.err
+		health.LastError = &lastErr
+	}
+	ss.lastError.mu.Unlock()
+
+	return health
+}
+
+func (ss *SQLStore) migrationStatus() MigrationStatus {
+	if ss.migrations == nil {
+		return MigrationStatus{Pending: []string{}}
+	}
+
+	mg := migrator.NewMigrator(ss.engine, ss.Cfg)
+	ss.migrations.AddMigration(mg)
+
+	status := MigrationStatus{Total: len(mg.GetMigrationIDs(true)), Pending: []string{}}
+	pending, err := mg.GetPendingMigrationIDs()
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Pending = pending
+	return status
+}
+
+// replicationLag returns how far the replica is behind the primary database, or -1 if the database
+// does not report it.
+func (ss *SQLStore) replicationLag(ctx context.Context, engine *xorm.Engine) (time.Duration, error) {
+	var query, column string
+	switch ss.Dialect.DriverName() {
+	case migrator.MySQL:
+		query, column = "SHOW SLAVE STATUS", "Seconds_Behind_Master"
+	case migrator.Postgres:
+		query, column = "SELECT CASE WHEN pg_is_in_recovery() THEN EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) ELSE 0 END AS lag", "lag"
+	default:
+		return -1, nil
+	}
+
+	rows, err := engine.Context(ctx).QueryString(query)
+	if err != nil {
+		return 0, err
+	}
+	// not a replica, or the replication is not running
+	if len(rows) == 0 || rows[0][column] == "" {
+		return -1, nil
+	}
+
+	seconds, err := strconv.ParseFloat(rows[0][column], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid replication lag %q: %w", rows[0][column], err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,45 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	store := InitTestDB(t)
+	ctx := context.Background()
+
+	health := store.Health(ctx)
+	assert.Equal(t, store.Dialect.DriverName(), health.Type)
+	assert.Empty(t, health.PingError)
+	require.Len(t, health.Pools, 1)
+	assert.Empty(t, health.Replicas)
+	assert.Greater(t, health.Migrations.Total, 0)
+	assert.Empty(t, health.Migrations.Pending)
+	assert.Empty(t, health.Migrations.Error)
+
+	t.Run("errors of the callbacks are not recorded", func(t *testing.T) {
+		store.lastError.err = nil
+		err := store.WithDbSession(ctx, func(sess *DBSession) error {
+			return errors.New("not a database error")
+		})
+		require.Error(t, err)
+		assert.Nil(t, store.Health(ctx).LastError)
+	})
+
+	t.Run("errors of the database are recorded", func(t *testing.T) {
+		err := store.WithDbSession(ctx, func(sess *DBSession) error {
+			_, err := sess.Exec("SELECT * FROM health_missing_table")
+			return err
+		})
+		require.Error(t, err)
+
+		lastError := store.Health(ctx).LastError
+		require.NotNil(t, lastError)
+		assert.Equal(t, err.Error(), lastError.Message)
+	})
+}
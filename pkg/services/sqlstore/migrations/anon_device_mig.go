@@ -0,0 +1,28 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addAnonDeviceMigrations(mg *Migrator) {
+	anonDeviceV1 := Table{
+		Name: "anon_device",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "device_id", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "client_ip", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "user_agent", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "created_at", Type: DB_DateTime, Nullable: false},
+			{Name: "updated_at", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "device_id"}, Type: UniqueIndex},
+			{Cols: []string{"org_id", "updated_at"}},
+			{Cols: []string{"updated_at"}},
+		},
+	}
+
+	mg.AddMigration("create anon_device table", NewAddTableMigration(anonDeviceV1))
+	mg.AddMigration("add unique index anon_device.org_id_device_id", NewAddIndexMigration(anonDeviceV1, anonDeviceV1.Indices[0]))
+	mg.AddMigration("add index anon_device.org_id_updated_at", NewAddIndexMigration(anonDeviceV1, anonDeviceV1.Indices[1]))
+	mg.AddMigration("add index anon_device.updated_at", NewAddIndexMigration(anonDeviceV1, anonDeviceV1.Indices[2]))
+}
@@ -19,4 +19,29 @@ func addCacheMigration(mg *migrator.Migrator) {
 	mg.AddMigration("create cache_data table", migrator.NewAddTableMigration(cacheDataV1))
 
 	mg.AddMigration("add unique index cache_data.cache_key", migrator.NewAddIndexMigration(cacheDataV1, cacheDataV1.Indices[0]))
+
+	var cacheMessageV1 = migrator.Table{
+		Name: "cache_message",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "channel", Type: migrator.DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "payload", Type: migrator.DB_Blob},
+			{Name: "created_at", Type: migrator.DB_BigInt, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"created_at"}},
+		},
+	}
+
+	mg.AddMigration("create cache_message table", migrator.NewAddTableMigration(cacheMessageV1))
+	mg.AddMigration("add index cache_message.created_at", migrator.NewAddIndexMigration(cacheMessageV1, cacheMessageV1.Indices[0]))
+
+	// last_accessed_at orders the rows for eviction when the size of the table is limited
+	mg.AddMigration("add column last_accessed_at to cache_data", migrator.NewAddColumnMigration(cacheDataV1, &migrator.Column{
+		Name: "last_accessed_at", Type: migrator.DB_BigInt, Nullable: false, Default: "0",
+	}))
+	mg.AddMigration("set cache_data.last_accessed_at to created_at", migrator.NewRawSQLMigration("UPDATE cache_data SET last_accessed_at = created_at"))
+	mg.AddMigration("add index cache_data.last_accessed_at", migrator.NewAddIndexMigration(cacheDataV1, &migrator.Index{
+		Cols: []string{"last_accessed_at"},
+	}))
 }
@@ -24,4 +24,13 @@ func addKVStoreMigrations(mg *Migrator) {
 	mg.AddMigration("create kv_store table v1", NewAddTableMigration(kvStoreV1))
 
 	mg.AddMigration("add index kv_store.org_id-namespace-key", NewAddIndexMigration(kvStoreV1, kvStoreV1.Indices[0]))
+
+	// unix timestamp in seconds, items without expiration have no value
+	mg.AddMigration("add expires column to kv_store", NewAddColumnMigration(kvStoreV1, &Column{
+		Name: "expires", Type: DB_BigInt, Nullable: true,
+	}))
+
+	mg.AddMigration("add index kv_store.expires", NewAddIndexMigration(kvStoreV1, &Index{
+		Cols: []string{"expires"},
+	}))
 }
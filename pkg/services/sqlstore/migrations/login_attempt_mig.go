@@ -39,4 +39,7 @@ func addLoginAttemptMigrations(mg *Migrator) {
 		"username":   "username",
 		"ip_address": "ip_address",
 	})
+
+	// login attempts are counted in the remote cache, the table is no longer used
+	mg.AddMigration("drop login_attempt table", NewDropTableMigration("login_attempt"))
 }
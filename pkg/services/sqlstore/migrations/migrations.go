@@ -105,6 +105,13 @@ func (*OSSMigrations) AddMigration(mg *Migrator) {
 	AddExternalAlertmanagerToDatasourceMigration(mg)
 
 	addFolderMigrations(mg)
+
+	addAnonDeviceMigrations(mg)
+
+	addUserMFAMigrations(mg)
+
+	addUserWebAuthnCredentialMigrations(mg)
+	addUserDeviceMigrations(mg)
 }
 
 func addMigrationLogMigrations(mg *Migrator) {
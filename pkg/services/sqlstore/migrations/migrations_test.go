@@ -8,6 +8,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
@@ -59,6 +60,64 @@ func TestMigrations(t *testing.T) {
 	checkStepsAndDatabaseMatch(t, mg, expectedMigrations)
 }
 
+func TestMigrationPlanAndVerify(t *testing.T) {
+	// a new database, the test database is not cleaned for SQLite
+	x, err := xorm.NewEngine(SQLite, "file:migration_plan?mode=memory&cache=shared")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, x.Close())
+	})
+
+	newMigrator := func() *Migrator {
+		mg := NewMigrator(x, &setting.Cfg{})
+		migrations := &OSSMigrations{}
+		migrations.AddMigration(mg)
+		return mg
+	}
+
+	mg := newMigrator()
+	plan, err := mg.Plan()
+	require.NoError(t, err)
+	planned := make([]string, 0, len(plan))
+	for _, m := range plan {
+		planned = append(planned, m.ID)
+		assert.NotEmpty(t, m.SQL, m.ID)
+	}
+	assert.Equal(t, mg.GetMigrationIDs(true), planned)
+
+	// the plan does not execute the migrations
+	exists, err := x.IsTableExist(new(MigrationLog))
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	require.NoError(t, mg.Start(false, 0))
+
+	mg = newMigrator()
+	plan, err = mg.Plan()
+	require.NoError(t, err)
+	assert.Empty(t, plan)
+
+	divergences, err := mg.Verify()
+	require.NoError(t, err)
+	assert.Empty(t, divergences)
+
+	firstID := mg.GetMigrationIDs(true)[0]
+	_, err = x.Exec("UPDATE migration_log SET sql = ? WHERE migration_id = ?", "SELECT 1", firstID)
+	require.NoError(t, err)
+	_, err = x.Insert(
+		&MigrationLog{MigrationID: "failed migration", SQL: "SELECT 1", Error: "some error", Timestamp: time.Now()},
+		&MigrationLog{MigrationID: "failed then executed migration", SQL: "SELECT 1", Error: "some error", Timestamp: time.Now()},
+		&MigrationLog{MigrationID: "failed then executed migration", SQL: "SELECT 1", Success: true, Timestamp: time.Now()},
+	)
+	require.NoError(t, err)
+
+	divergences, err = mg.Verify()
+	require.NoError(t, err)
+	require.Len(t, divergences, 2)
+	assert.Equal(t, Divergence{MigrationID: firstID, Reason: DivergenceSQLChanged, Message: divergences[0].Message}, divergences[0])
+	assert.Equal(t, Divergence{MigrationID: "failed migration", Reason: DivergenceFailed, Message: "some error"}, divergences[1])
+}
+
 func TestMigrationLock(t *testing.T) {
 	dbType := getDBType()
 	if dbType == SQLite {
@@ -0,0 +1,27 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addUserDeviceMigrations(mg *Migrator) {
+	deviceV1 := Table{
+		Name: "user_device",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "fingerprint", Type: DB_NVarchar, Length: 64, Nullable: false},
+			{Name: "user_agent", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "client_ip", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "session_id", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "last_seen", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"user_id", "fingerprint"}, Type: UniqueIndex},
+			{Cols: []string{"user_id"}},
+		},
+	}
+
+	mg.AddMigration("create user_device table", NewAddTableMigration(deviceV1))
+	mg.AddMigration("add unique index user_device.user_id_fingerprint", NewAddIndexMigration(deviceV1, deviceV1.Indices[0]))
+	mg.AddMigration("add index user_device.user_id", NewAddIndexMigration(deviceV1, deviceV1.Indices[1]))
+}
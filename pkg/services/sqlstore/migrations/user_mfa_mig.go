@@ -0,0 +1,23 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addUserMFAMigrations(mg *Migrator) {
+	userMFAV1 := Table{
+		Name: "user_mfa",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "secret", Type: DB_Text, Nullable: false},
+			{Name: "enabled", Type: DB_Bool, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"user_id"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create user_mfa table", NewAddTableMigration(userMFAV1))
+	mg.AddMigration("add unique index user_mfa.user_id", NewAddIndexMigration(userMFAV1, userMFAV1.Indices[0]))
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addUserWebAuthnCredentialMigrations(mg *Migrator) {
+	credentialV1 := Table{
+		Name: "user_webauthn_credential",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "credential_id", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "name", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "public_key", Type: DB_Text, Nullable: false},
+			{Name: "algorithm", Type: DB_BigInt, Nullable: false},
+			{Name: "sign_count", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "last_used", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"credential_id"}, Type: UniqueIndex},
+			{Cols: []string{"user_id"}},
+		},
+	}
+
+	mg.AddMigration("create user_webauthn_credential table", NewAddTableMigration(credentialV1))
+	mg.AddMigration("add unique index user_webauthn_credential.credential_id", NewAddIndexMigration(credentialV1, credentialV1.Indices[0]))
+	mg.AddMigration("add index user_webauthn_credential.user_id", NewAddIndexMigration(credentialV1, credentialV1.Indices[1]))
+}
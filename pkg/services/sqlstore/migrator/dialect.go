@@ -1,8 +1,12 @@
 package migrator
 
 import (
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
+	"syscall"
 
 	"xorm.io/xorm"
 )
@@ -68,6 +72,9 @@ type Dialect interface {
 	IsUniqueConstraintViolation(err error) bool
 	ErrorMessage(err error) string
 	IsDeadlock(err error) bool
+	// TransientErrorReason returns why err is transient, such as deadlock, or an empty string
+	// if retrying the operation cannot succeed.
+	TransientErrorReason(err error) string
 	Lock(LockCfg) error
 	Unlock(LockCfg) error
 }
@@ -103,6 +110,14 @@ type BaseDialect struct {
 	driverName string
 }
 
+// TransientErrorReason returns connection for errors of connections that were reset or closed.
+func (b *BaseDialect) TransientErrorReason(err error) string {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return "connection"
+	}
+	return ""
+}
+
 func (b *BaseDialect) DriverName() string {
 	return b.driverName
 }
@@ -100,6 +100,23 @@ func (mg *Migrator) GetMigrationLog() (map[string]MigrationLog, error) {
 	return logMap, nil
 }
 
+// GetPendingMigrationIDs returns the ids of the migrations that have not been executed successfully yet,
+// in the order they are executed. Migrations that are not logged are executed every time and are not included.
+func (mg *Migrator) GetPendingMigrationIDs() ([]string, error) {
+	logMap, err := mg.GetMigrationLog()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]string, 0)
+	for _, id := range mg.GetMigrationIDs(true) {
+		if _, exists := logMap[id]; !exists {
+			pending = append(pending, id)
+		}
+	}
+	return pending, nil
+}
+
 func (mg *Migrator) Start(isDatabaseLockingEnabled bool, lockAttemptTimeout int) (err error) {
 	if !isDatabaseLockingEnabled {
 		return mg.run()
@@ -218,6 +218,18 @@ func (db *MySQLDialect) IsDeadlock(err error) bool {
 	return db.isThisError(err, mysqlerr.ER_LOCK_DEADLOCK)
 }
 
+func (db *MySQLDialect) TransientErrorReason(err error) string {
+	switch {
+	case db.isThisError(err, mysqlerr.ER_LOCK_DEADLOCK):
+		return "deadlock"
+	case db.isThisError(err, mysqlerr.ER_LOCK_WAIT_TIMEOUT):
+		return "lock_timeout"
+	case errors.Is(err, mysql.ErrInvalidConn):
+		return "connection"
+	}
+	return db.BaseDialect.TransientErrorReason(err)
+}
+
 // UpsertSQL returns the upsert sql statement for MySQL dialect
 func (db *MySQLDialect) UpsertSQL(tableName string, keyCols, updateCols []string) string {
 	q, _ := db.UpsertMultipleSQL(tableName, keyCols, updateCols, 1)
@@ -0,0 +1,148 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PlannedMigration is a migration that has not been executed yet, see Migrator.Plan.
+type PlannedMigration struct {
+	ID string `json:"id"`
+	// SQL is the statement executed by the migration, code migrations execute statements
+	// that depend on the data and only have a placeholder.
+	SQL           string `json:"sql"`
+	CodeMigration bool   `json:"codeMigration"`
+	// Skipped is set when the condition of the migration is not fulfilled by the current schema,
+	// the migration is then only logged.
+	Skipped bool `json:"skipped"`
+}
+
+// Divergence is a difference between the migration log and the migrations, see Migrator.Verify.
+type Divergence struct {
+	MigrationID string `json:"migrationId"`
+	Reason      string `json:"reason"`
+	Message     string `json:"message"`
+}
+
+const (
+	// DivergenceFailed is a migration that has failed and was not executed successfully afterwards
+	DivergenceFailed = "failed"
+	// DivergenceSQLChanged is a migration that was executed with another statement than the one of this version
+	DivergenceSQLChanged = "sql_changed"
+)
+
+// Plan returns the migrations that would be executed by Start, in order, without executing them.
+// The conditions of the migrations are checked against the current schema, not against the schema
+// after the previous pending migrations have been executed.
+func (mg *Migrator) Plan() ([]PlannedMigration, error) {
+	logMap, err := mg.GetMigrationLog()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]PlannedMigration, 0)
+	for _, m := range mg.migrations {
+		if _, exists := logMap[m.Id()]; exists || m.SkipMigrationLog() {
+			continue
+		}
+
+		_, codeMigration := m.(CodeMigration)
+		planned := PlannedMigration{
+			ID:            m.Id(),
+			SQL:           m.SQL(mg.Dialect),
+			CodeMigration: codeMigration,
+		}
+
+		if condition := m.GetCondition(); condition != nil {
+			sql, args := condition.SQL(mg.Dialect)
+			if sql != "" {
+				results, err := mg.DBEngine.SQL(sql, args...).Query()
+				if err != nil {
+					return nil, fmt.Errorf("condition of migration %q: %w", m.Id(), err)
+				}
+				planned.Skipped = !condition.IsFulfilled(results)
+			}
+		}
+
+		plan = append(plan, planned)
+	}
+	return plan, nil
+}
+
+// Verify compares the migration log with the migrations and returns the divergences. Migrations that
+// are in the log but not in the migrations are not reported, some migrations are only added until they
+// are executed, ex the migration of the dashboard alerts. It reads the log without taking the migration
+// lock, so it never delays the migrations of other instances. It does not change the database.
+func (mg *Migrator) Verify() ([]Divergence, error) {
+	divergences := make([]Divergence, 0)
+	exists, err := mg.DBEngine.IsTableExist(new(MigrationLog))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", "failed to check table existence", err)
+	}
+	if !exists {
+		return divergences, nil
+	}
+
+	logItems := make([]MigrationLog, 0)
+	if err := mg.DBEngine.Asc("id").Find(&logItems); err != nil {
+		return nil, err
+	}
+
+	succeeded := map[string]MigrationLog{}
+	failed := map[string]MigrationLog{}
+	for _, logItem := range logItems {
+		if logItem.Success {
+			succeeded[logItem.MigrationID] = logItem
+		} else {
+			failed[logItem.MigrationID] = logItem
+		}
+	}
+
+	migrations := make(map[string]Migration, len(mg.migrations))
+	for _, m := range mg.migrations {
+		migrations[m.Id()] = m
+	}
+
+	for _, id := range sortedKeys(succeeded) {
+		m, ok := migrations[id]
+		if !ok {
+			continue
+		}
+		// the statement of a code migration is a placeholder, and the order of the columns
+		// of a copy depends on the iteration of a map
+		if _, codeMigration := m.(CodeMigration); codeMigration {
+			continue
+		}
+		if _, copyMigration := m.(*CopyTableDataMigration); copyMigration {
+			continue
+		}
+		if sql := m.SQL(mg.Dialect); sql != succeeded[id].SQL {
+			divergences = append(divergences, Divergence{
+				MigrationID: id,
+				Reason:      DivergenceSQLChanged,
+				Message:     fmt.Sprintf("the migration was executed with %q instead of %q", succeeded[id].SQL, sql),
+			})
+		}
+	}
+
+	for _, id := range sortedKeys(failed) {
+		if _, ok := succeeded[id]; ok {
+			continue
+		}
+		divergences = append(divergences, Divergence{
+			MigrationID: id,
+			Reason:      DivergenceFailed,
+			Message:     failed[id].Error,
+		})
+	}
+	return divergences, nil
+}
+
+func sortedKeys(m map[string]MigrationLog) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
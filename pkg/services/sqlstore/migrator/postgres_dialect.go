@@ -213,6 +213,23 @@ func (db *PostgresDialect) IsDeadlock(err error) bool {
 	return db.isThisError(err, "40P01")
 }
 
+func (db *PostgresDialect) TransientErrorReason(err error) string {
+	var driverErr *pq.Error
+	if errors.As(err, &driverErr) {
+		switch {
+		case driverErr.Code == "40001":
+			return "serialization_failure"
+		case driverErr.Code == "40P01":
+			return "deadlock"
+		// connection exceptions
+		case driverErr.Code.Class() == "08":
+			return "connection"
+		}
+		return ""
+	}
+	return db.BaseDialect.TransientErrorReason(err)
+}
+
 func (db *PostgresDialect) PostInsertId(table string, sess *xorm.Session) error {
 	if table != "org" {
 		return nil
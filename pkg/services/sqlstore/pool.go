@@ -0,0 +1,187 @@
+package sqlstore
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// PrimaryPool is the name of the connection pool of the primary database, the pools of the read
+// replicas are named after the replicas.
+const PrimaryPool = "primary"
+
+var ErrPoolNotFound = errors.New("connection pool not found")
+
+// PoolSettings are the settings of a connection pool that can be changed at runtime.
+type PoolSettings struct {
+	MaxOpenConn int `json:"maxOpenConn"`
+	MaxIdleConn int `json:"maxIdleConn"`
+	// ConnMaxLifetime in seconds
+	ConnMaxLifetime int `json:"connMaxLifetime"`
+}
+
+// PoolSettingsUpdate changes the settings of a connection pool, nil values are not changed.
+type PoolSettingsUpdate struct {
+	MaxOpenConn     *int `json:"maxOpenConn"`
+	MaxIdleConn     *int `json:"maxIdleConn"`
+	ConnMaxLifetime *int `json:"connMaxLifetime"`
+}
+
+// PoolStats are the statistics of a connection pool, see sql.DBStats.
+type PoolStats struct {
+	OpenConnections   int    `json:"openConnections"`
+	InUse             int    `json:"inUse"`
+	Idle              int    `json:"idle"`
+	WaitCount         int64  `json:"waitCount"`
+	WaitDuration      string `json:"waitDuration"`
+	MaxIdleClosed     int64  `json:"maxIdleClosed"`
+	MaxIdleTimeClosed int64  `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed int64  `json:"maxLifetimeClosed"`
+}
+
+// Pool is a connection pool of the primary database or of a read replica.
+type Pool struct {
+	Name     string       `json:"name"`
+	Settings PoolSettings `json:"settings"`
+	Stats    PoolStats    `json:"stats"`
+}
+
+// Pools returns the connection pools of the primary database and of the read replicas.
+func (ss *SQLStore) Pools() []Pool {
+	ss.poolMu.RLock()
+	defer ss.poolMu.RUnlock()
+
+	pools := []Pool{newPool(PrimaryPool, ss.engine, ss.poolSettings[PrimaryPool])}
+	for _, r := range ss.replicas {
+		pools = append(pools, newPool(r.name, r.engine, ss.poolSettings[r.name]))
+	}
+	return pools
+}
+
+// UpdatePool changes the settings of the connection pool named name at runtime, and returns the updated pool.
+// The settings are not persisted, they are read from the configuration again after a restart.
+func (ss *SQLStore) UpdatePool(name string, update PoolSettingsUpdate) (Pool, error) {
+	engine := ss.poolEngine(name)
+	if engine == nil {
+		return Pool{}, ErrPoolNotFound
+	}
+
+	ss.poolMu.Lock()
+	defer ss.poolMu.Unlock()
+
+	settings := ss.poolSettings[name].apply(update)
+	if err := settings.validate(); err != nil {
+		return Pool{}, err
+	}
+
+	setPoolSettings(engine, settings)
+	ss.poolSettings[name] = settings
+	ss.log.Info("Database connection pool updated", "pool", name, "maxOpenConn", settings.MaxOpenConn,
+		"maxIdleConn", settings.MaxIdleConn, "connMaxLifetime", settings.ConnMaxLifetime)
+	return newPool(name, engine, settings), nil
+}
+
+func (ss *SQLStore) poolEngine(name string) *xorm.Engine {
+	if name == PrimaryPool {
+		return ss.engine
+	}
+	for _, r := range ss.replicas {
+		if r.name == name {
+			return r.engine
+		}
+	}
+	return nil
+}
+
+// Validate validates the connection pool settings of the database section, see setting.ReloadHandler.
+func (ss *SQLStore) Validate(section setting.Section) error {
+	_, err := ss.poolSettingsUpdate(section)
+	return err
+}
+
+// Reload applies the connection pool settings of the database section to the pool of the primary
+// database, see setting.ReloadHandler.
+func (ss *SQLStore) Reload(section setting.Section) error {
+	update, err := ss.poolSettingsUpdate(section)
+	if err != nil {
+		return err
+	}
+	_, err = ss.UpdatePool(PrimaryPool, update)
+	return err
+}
+
+func (ss *SQLStore) poolSettingsUpdate(section setting.Section) (PoolSettingsUpdate, error) {
+	var update PoolSettingsUpdate
+	for key, value := range map[string]**int{
+		"max_open_conn":     &update.MaxOpenConn,
+		"max_idle_conn":     &update.MaxIdleConn,
+		"conn_max_lifetime": &update.ConnMaxLifetime,
+	} {
+		v := section.KeyValue(key).Value()
+		if v == "" {
+			continue
+		}
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return update, fmt.Errorf("invalid %s %q: %w", key, v, err)
+		}
+		*value = &i
+	}
+
+	ss.poolMu.RLock()
+	settings := ss.poolSettings[PrimaryPool].apply(update)
+	ss.poolMu.RUnlock()
+	return update, settings.validate()
+}
+
+func (s PoolSettings) apply(update PoolSettingsUpdate) PoolSettings {
+	if update.MaxOpenConn != nil {
+		s.MaxOpenConn = *update.MaxOpenConn
+	}
+	if update.MaxIdleConn != nil {
+		s.MaxIdleConn = *update.MaxIdleConn
+	}
+	if update.ConnMaxLifetime != nil {
+		s.ConnMaxLifetime = *update.ConnMaxLifetime
+	}
+	return s
+}
+
+func (s PoolSettings) validate() error {
+	if s.MaxOpenConn < 0 || s.MaxIdleConn < 0 || s.ConnMaxLifetime < 0 {
+		return errors.New("connection pool settings can not be negative")
+	}
+	if s.MaxOpenConn > 0 && s.MaxIdleConn > s.MaxOpenConn {
+		return errors.New("max_idle_conn can not be greater than max_open_conn")
+	}
+	return nil
+}
+
+func setPoolSettings(engine *xorm.Engine, settings PoolSettings) {
+	engine.SetMaxOpenConns(settings.MaxOpenConn)
+	engine.SetMaxIdleConns(settings.MaxIdleConn)
+	engine.SetConnMaxLifetime(time.Second * time.Duration(settings.ConnMaxLifetime))
+}
+
+func newPool(name string, engine *xorm.Engine, settings PoolSettings) Pool {
+	stats := engine.DB().Stats()
+	return Pool{
+		Name:     name,
+		Settings: settings,
+		Stats: PoolStats{
+			OpenConnections:   stats.OpenConnections,
+			InUse:             stats.InUse,
+			Idle:              stats.Idle,
+			WaitCount:         stats.WaitCount,
+			WaitDuration:      stats.WaitDuration.String(),
+			MaxIdleClosed:     stats.MaxIdleClosed,
+			MaxIdleTimeClosed: stats.MaxIdleTimeClosed,
+			MaxLifetimeClosed: stats.MaxLifetimeClosed,
+		},
+	}
+}
@@ -0,0 +1,72 @@
+package sqlstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestPools(t *testing.T) {
+	store := InitTestDB(t)
+
+	pools := store.Pools()
+	require.Len(t, pools, 1)
+	primary := pools[0]
+	assert.Equal(t, PrimaryPool, primary.Name)
+	t.Cleanup(func() {
+		_, err := store.UpdatePool(PrimaryPool, PoolSettingsUpdate{
+			MaxOpenConn:     &primary.Settings.MaxOpenConn,
+			MaxIdleConn:     &primary.Settings.MaxIdleConn,
+			ConnMaxLifetime: &primary.Settings.ConnMaxLifetime,
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("update the pool", func(t *testing.T) {
+		maxOpenConn, maxIdleConn := 20, 10
+		pool, err := store.UpdatePool(PrimaryPool, PoolSettingsUpdate{MaxOpenConn: &maxOpenConn, MaxIdleConn: &maxIdleConn})
+		require.NoError(t, err)
+		assert.Equal(t, PoolSettings{MaxOpenConn: 20, MaxIdleConn: 10, ConnMaxLifetime: primary.Settings.ConnMaxLifetime}, pool.Settings)
+		assert.Equal(t, 20, store.engine.DB().Stats().MaxOpenConnections)
+		assert.Equal(t, pool.Settings, store.Pools()[0].Settings)
+	})
+
+	t.Run("invalid settings are not applied", func(t *testing.T) {
+		maxIdleConn, negative := 100, -1
+		_, err := store.UpdatePool(PrimaryPool, PoolSettingsUpdate{MaxIdleConn: &maxIdleConn})
+		require.Error(t, err)
+		_, err = store.UpdatePool(PrimaryPool, PoolSettingsUpdate{ConnMaxLifetime: &negative})
+		require.Error(t, err)
+		assert.Equal(t, 10, store.Pools()[0].Settings.MaxIdleConn)
+	})
+
+	t.Run("unknown pool", func(t *testing.T) {
+		_, err := store.UpdatePool("unknown", PoolSettingsUpdate{})
+		require.ErrorIs(t, err, ErrPoolNotFound)
+	})
+
+	t.Run("reload the database section", func(t *testing.T) {
+		section := func(t *testing.T, keys map[string]string) setting.Section {
+			raw := ini.Empty()
+			sec, err := raw.NewSection("database")
+			require.NoError(t, err)
+			for key, value := range keys {
+				_, err := sec.NewKey(key, value)
+				require.NoError(t, err)
+			}
+			return setting.ProvideProvider(&setting.Cfg{Raw: raw}).Section("database")
+		}
+
+		require.Error(t, store.Validate(section(t, map[string]string{"max_open_conn": "many"})))
+		require.Error(t, store.Validate(section(t, map[string]string{"max_open_conn": "5"})))
+
+		valid := section(t, map[string]string{"max_open_conn": "5", "max_idle_conn": "5", "conn_max_lifetime": "60"})
+		require.NoError(t, store.Validate(valid))
+		require.NoError(t, store.Reload(valid))
+		assert.Equal(t, PoolSettings{MaxOpenConn: 5, MaxIdleConn: 5, ConnMaxLifetime: 60}, store.Pools()[0].Settings)
+	})
+}
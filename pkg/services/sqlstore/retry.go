@@ -0,0 +1,98 @@
+package sqlstore
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	transientErrorMinBackoff = 10 * time.Millisecond
+	transientErrorMaxBackoff = time.Second
+)
+
+var (
+	databaseRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Name:      "database_transient_error_retries_total",
+		Help:      "The total number of database operations retried because of a transient error",
+	}, []string{"reason"})
+
+	databaseRetriesExhausted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Name:      "database_transient_error_retries_exhausted_total",
+		Help:      "The total number of database operations that failed with a transient error after all retries",
+	}, []string{"reason"})
+)
+
+func init() {
+	prometheus.MustRegister(databaseRetries, databaseRetriesExhausted)
+}
+
+type transientErrorRetriesKey struct{}
+
+// WithTransientErrorRetries returns a context that retries the sessions and transactions started
+// with it when they fail with a transient error, such as a deadlock, a serialization failure or
+// a reset connection. The callback of the session is called again, so it must be idempotent.
+// Sessions that reuse the session of the context, within InTransaction, are not retried on
+// their own; mark the context of InTransaction instead.
+func WithTransientErrorRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, transientErrorRetriesKey{}, true)
+}
+
+// withTransientErrorRetries calls fn again with exponential backoff while it fails with a
+// transient error, if ctx is marked with WithTransientErrorRetries.
+func (ss *SQLStore) withTransientErrorRetries(ctx context.Context, fn func() error) error {
+	if retry, _ := ctx.Value(transientErrorRetriesKey{}).(bool); !retry || ss.dbCfg.TransientErrorRetries <= 0 {
+		return fn()
+	}
+	// a failed statement can abort the transaction of the existing session
+	if _, ok := ctx.Value(ContextSessionKey{}).(*DBSession); ok {
+		return fn()
+	}
+
+	ctxLogger := tsclogger.FromContext(ctx)
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		reason := ss.Dialect.TransientErrorReason(err)
+		if reason == "" {
+			return err
+		}
+		if attempt == ss.dbCfg.TransientErrorRetries {
+			databaseRetriesExhausted.WithLabelValues(reason).Inc()
+			return err
+		}
+
+		databaseRetries.WithLabelValues(reason).Inc()
+		backoff := transientErrorBackoff(attempt)
+		ctxLogger.Info("Transient database error, sleeping then retrying", "error", err, "reason", reason, "retry", attempt+1, "backoff", backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// transientErrorBackoff doubles the backoff for every attempt up to transientErrorMaxBackoff,
+// and picks a random duration between half of the backoff and the backoff so that the retries
+// of concurrent operations that failed together are spread out.
+func transientErrorBackoff(attempt int) time.Duration {
+	backoff := transientErrorMaxBackoff
+	if attempt < 10 {
+		backoff = transientErrorMinBackoff << attempt
+		if backoff > transientErrorMaxBackoff {
+			backoff = transientErrorMaxBackoff
+		}
+	}
+	// nolint:gosec
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
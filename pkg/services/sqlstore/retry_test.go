@@ -0,0 +1,92 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTransientErrorRetries(t *testing.T) {
+	store := InitTestDB(t)
+	store.dbCfg.TransientErrorRetries = 2
+
+	funcToTest := map[string]func(ctx context.Context, callback DBTransactionFunc) error{
+		"WithDbSession":              store.WithDbSession,
+		"WithNewDbSession":           store.WithNewDbSession,
+		"WithTransactionalDbSession": store.WithTransactionalDbSession,
+		"InTransaction": func(ctx context.Context, callback DBTransactionFunc) error {
+			return store.InTransaction(ctx, func(ctx context.Context) error {
+				return store.WithDbSession(ctx, callback)
+			})
+		},
+	}
+
+	failing := func(calls *int, errs ...error) DBTransactionFunc {
+		return func(sess *DBSession) error {
+			*calls++
+			if *calls <= len(errs) {
+				return errs[*calls-1]
+			}
+			return nil
+		}
+	}
+
+	ctx := WithTransientErrorRetries(context.Background())
+	for name, f := range funcToTest {
+		t.Run(name+" retries transient errors", func(t *testing.T) {
+			calls := 0
+			require.NoError(t, f(ctx, failing(&calls, driver.ErrBadConn, driver.ErrBadConn)))
+			require.Equal(t, 3, calls)
+		})
+
+		t.Run(name+" returns the error when the retries are exhausted", func(t *testing.T) {
+			calls := 0
+			err := f(ctx, failing(&calls, driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn))
+			require.ErrorIs(t, err, driver.ErrBadConn)
+			require.Equal(t, 3, calls)
+		})
+
+		t.Run(name+" does not retry other errors", func(t *testing.T) {
+			calls := 0
+			err := f(ctx, failing(&calls, errors.New("some error")))
+			require.Error(t, err)
+			require.Equal(t, 1, calls)
+		})
+
+		t.Run(name+" does not retry without WithTransientErrorRetries", func(t *testing.T) {
+			calls := 0
+			err := f(context.Background(), failing(&calls, driver.ErrBadConn))
+			require.ErrorIs(t, err, driver.ErrBadConn)
+			require.Equal(t, 1, calls)
+		})
+	}
+
+	t.Run("sessions of a transaction are not retried on their own", func(t *testing.T) {
+		calls := 0
+		err := store.InTransaction(context.Background(), func(ctx context.Context) error {
+			return store.WithDbSession(WithTransientErrorRetries(ctx), failing(&calls, driver.ErrBadConn))
+		})
+		require.ErrorIs(t, err, driver.ErrBadConn)
+		require.Equal(t, 1, calls)
+	})
+}
+
+func TestTransientErrorBackoff(t *testing.T) {
+	for attempt, upper := range map[int]time.Duration{
+		0:   transientErrorMinBackoff,
+		1:   2 * transientErrorMinBackoff,
+		3:   8 * transientErrorMinBackoff,
+		7:   transientErrorMaxBackoff,
+		100: transientErrorMaxBackoff,
+	} {
+		for i := 0; i < 10; i++ {
+			backoff := transientErrorBackoff(attempt)
+			require.GreaterOrEqual(t, backoff, upper/2)
+			require.LessOrEqual(t, backoff, upper)
+		}
+	}
+}
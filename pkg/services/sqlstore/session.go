@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync/atomic"
 	"time"
 
 	"github.com/mattn/go-sqlite3"
@@ -70,16 +71,43 @@ func startSessionOrUseExisting(ctx context.Context, engine *xorm.Engine, beginTr
 // A session is stored in the context if sqlstore.InTransaction() has been previously called with the same context (and it's not committed/rolledback yet).
 // In case of sqlite3.ErrLocked or sqlite3.ErrBusy failure it will be retried at most five times before giving up.
 func (ss *SQLStore) WithDbSession(ctx context.Context, callback DBTransactionFunc) error {
-	return ss.withDbSession(ctx, ss.engine, callback)
+	return ss.recordError(ss.withTransientErrorRetries(ctx, func() error {
+		return ss.withDbSession(ctx, ss.sessionEngine(ctx), callback)
+	}))
 }
 
 // WithNewDbSession calls the callback with a new session that is closed upon completion.
 // In case of sqlite3.ErrLocked or sqlite3.ErrBusy failure it will be retried at most five times before giving up.
 func (ss *SQLStore) WithNewDbSession(ctx context.Context, callback DBTransactionFunc) error {
-	sess := &DBSession{Session: ss.engine.NewSession(), transactionOpen: false}
-	defer sess.Close()
-	retry := 0
-	return retryer.Retry(ss.retryOnLocks(ctx, callback, sess, retry), ss.dbCfg.QueryRetries, time.Millisecond*time.Duration(10), time.Second)
+	return ss.recordError(ss.withTransientErrorRetries(ctx, func() error {
+		sess := &DBSession{Session: ss.sessionEngine(ctx).NewSession(), transactionOpen: false}
+		defer sess.Close()
+		retry := 0
+		return retryer.Retry(ss.retryOnLocks(ctx, callback, sess, retry), ss.dbCfg.QueryRetries, time.Millisecond*time.Duration(10), time.Second)
+	}))
+}
+
+type readReplicaKey struct{}
+
+// WithReadReplica returns a context that routes the sessions started by WithDbSession and
+// WithNewDbSession to one of the read replicas of the database, in turn. Without replicas, or
+// when the context already holds a session, the sessions use the primary database.
+// Replicas can lag behind the primary database, so only use it for reads that tolerate stale data,
+// never for lookups used for authentication or authorization.
+func WithReadReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readReplicaKey{}, true)
+}
+
+// sessionEngine returns the engine new sessions are started with for ctx.
+func (ss *SQLStore) sessionEngine(ctx context.Context) *xorm.Engine {
+	if len(ss.replicas) == 0 {
+		return ss.engine
+	}
+	if useReplica, _ := ctx.Value(readReplicaKey{}).(bool); !useReplica {
+		return ss.engine
+	}
+	next := atomic.AddUint64(&ss.nextReplica, 1)
+	return ss.replicas[next%uint64(len(ss.replicas))].engine
 }
 
 func (ss *SQLStore) retryOnLocks(ctx context.Context, callback DBTransactionFunc, sess *DBSession, retry int) func() (retryer.RetrySignal, error) {
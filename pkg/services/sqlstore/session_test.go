@@ -8,6 +8,9 @@ import (
 
 	"github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/require"
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
 )
 
 func TestRetryingOnFailures(t *testing.T) {
@@ -83,3 +86,37 @@ func TestRetryingOnFailures(t *testing.T) {
 	require.Equal(t, int64(4), val3)
 	require.False(t, rows.Next()) // no more rows
 }
+
+func TestWithReadReplica(t *testing.T) {
+	store := InitTestDB(t)
+	replicaEngine, err := xorm.NewEngine(migrator.SQLite, "file::memory:")
+	require.NoError(t, err)
+	store.replicas = []*replica{{name: "test", engine: replicaEngine}}
+	t.Cleanup(func() {
+		store.replicas = nil
+	})
+
+	usesReplica := func(ctx context.Context, f func(ctx context.Context, callback DBTransactionFunc) error) bool {
+		var replica bool
+		err := f(ctx, func(sess *DBSession) error {
+			replica = sess.DB() == replicaEngine.DB()
+			return nil
+		})
+		require.NoError(t, err)
+		return replica
+	}
+
+	ctx := context.Background()
+	require.False(t, usesReplica(ctx, store.WithDbSession))
+	require.False(t, usesReplica(ctx, store.WithNewDbSession))
+	require.True(t, usesReplica(WithReadReplica(ctx), store.WithDbSession))
+	require.True(t, usesReplica(WithReadReplica(ctx), store.WithNewDbSession))
+	require.False(t, usesReplica(WithReadReplica(ctx), store.WithTransactionalDbSession))
+
+	// sessions of a transaction are not routed to replicas
+	err = store.InTransaction(ctx, func(ctx context.Context) error {
+		require.False(t, usesReplica(WithReadReplica(ctx), store.WithDbSession))
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -52,9 +52,20 @@ type SQLStore struct {
 	skipEnsureDefaultOrgAndUser bool
 	migrations                  registry.DatabaseMigrator
 	tracer                      tracing.Tracer
+	replicas                    []*replica
+	nextReplica                 uint64
+	poolMu                      sync.RWMutex
+	poolSettings                map[string]PoolSettings
+	lastError                   lastError
 }
 
-func ProvideService(cfg *setting.Cfg, cacheService *localcache.CacheService, migrations registry.DatabaseMigrator, bus bus.Bus, tracer tracing.Tracer) (*SQLStore, error) {
+// replica is a read-only copy of the database, see WithReadReplica.
+type replica struct {
+	name   string
+	engine *xorm.Engine
+}
+
+func ProvideService(cfg *setting.Cfg, cacheService *localcache.CacheService, migrations registry.DatabaseMigrator, bus bus.Bus, tracer tracing.Tracer, settingsProvider setting.Provider) (*SQLStore, error) {
 	// This change will make xorm use an empty default schema for postgres and
 	// by that mimic the functionality of how it was functioning before
 	// xorm's changes above.
@@ -81,9 +92,19 @@ func ProvideService(cfg *setting.Cfg, cacheService *localcache.CacheService, mig
 	// TODO: deprecate/remove these metrics
 	prometheus.MustRegister(newSQLStoreMetrics(db))
 
+	// the connection pool of the primary database can be changed without restart
+	settingsProvider.RegisterReloadHandler("database", s)
+
 	return s, nil
 }
 
+// NewWithoutMigrations connects to the database without executing the migrations nor creating the
+// default organization and user, for commands that inspect the migrations.
+func NewWithoutMigrations(cfg *setting.Cfg, migrations registry.DatabaseMigrator, bus bus.Bus, tracer tracing.Tracer) (*SQLStore, error) {
+	xorm.DefaultPostgresSchema = ""
+	return newSQLStore(cfg, nil, nil, migrations, bus, tracer)
+}
+
 func ProvideServiceForTests(migrations registry.DatabaseMigrator) (*SQLStore, error) {
 	return initTestDB(migrations, InitTestDBOpt{EnsureDefaultOrgAndUser: true})
 }
@@ -139,6 +160,23 @@ func (ss *SQLStore) Migrate(isDatabaseLockingEnabled bool) error {
 	return migrator.Start(isDatabaseLockingEnabled, ss.dbCfg.MigrationLockAttemptTimeout)
 }
 
+// MigrationPlan returns the migrations that have not been executed yet without executing them.
+func (ss *SQLStore) MigrationPlan() ([]migrator.PlannedMigration, error) {
+	migrator := migrator.NewMigrator(ss.engine, ss.Cfg)
+	ss.migrations.AddMigration(migrator)
+
+	return migrator.Plan()
+}
+
+// VerifyMigrations compares the migration log with the migrations of this version without taking the
+// migration lock.
+func (ss *SQLStore) VerifyMigrations() ([]migrator.Divergence, error) {
+	migrator := migrator.NewMigrator(ss.engine, ss.Cfg)
+	ss.migrations.AddMigration(migrator)
+
+	return migrator.Verify()
+}
+
 // Sync syncs changes to the database.
 func (ss *SQLStore) Sync() error {
 	return ss.engine.Sync2()
@@ -264,36 +302,42 @@ func (ss *SQLStore) buildConnectionString() (string, error) {
 		return "", err
 	}
 
-	cnnstr := ss.dbCfg.ConnectionString
+	return ss.connectionString(&ss.dbCfg, "custom")
+}
+
+// connectionString builds the connection string for the database, tlsConfigName is the name the
+// TLS config of a MySQL database is registered with and must be unique per database.
+func (ss *SQLStore) connectionString(dbCfg *DatabaseConfig, tlsConfigName string) (string, error) {
+	cnnstr := dbCfg.ConnectionString
 
 	// special case used by integration tests
 	if cnnstr != "" {
 		return cnnstr, nil
 	}
 
-	switch ss.dbCfg.Type {
+	switch dbCfg.Type {
 	case migrator.MySQL:
 		protocol := "tcp"
-		if strings.HasPrefix(ss.dbCfg.Host, "/") {
+		if strings.HasPrefix(dbCfg.Host, "/") {
 			protocol = "unix"
 		}
 
 		cnnstr = fmt.Sprintf("%s:%s@%s(%s)/%s?collation=utf8mb4_unicode_ci&allowNativePasswords=true",
-			ss.dbCfg.User, ss.dbCfg.Pwd, protocol, ss.dbCfg.Host, ss.dbCfg.Name)
+			dbCfg.User, dbCfg.Pwd, protocol, dbCfg.Host, dbCfg.Name)
 
-		if ss.dbCfg.SslMode == "true" || ss.dbCfg.SslMode == "skip-verify" {
-			tlsCert, err := makeCert(ss.dbCfg)
+		if dbCfg.SslMode == "true" || dbCfg.SslMode == "skip-verify" {
+			tlsCert, err := makeCert(*dbCfg)
 			if err != nil {
 				return "", err
 			}
-			if err := mysql.RegisterTLSConfig("custom", tlsCert); err != nil {
+			if err := mysql.RegisterTLSConfig(tlsConfigName, tlsCert); err != nil {
 				return "", err
 			}
 
-			cnnstr += "&tls=custom"
+			cnnstr += "&tls=" + url.QueryEscape(tlsConfigName)
 		}
 
-		if isolation := ss.dbCfg.IsolationLevel; isolation != "" {
+		if isolation := dbCfg.IsolationLevel; isolation != "" {
 			val := url.QueryEscape(fmt.Sprintf("'%s'", isolation))
 			cnnstr += fmt.Sprintf("&tx_isolation=%s", val)
 		}
@@ -308,40 +352,40 @@ func (ss *SQLStore) buildConnectionString() (string, error) {
 
 		cnnstr += ss.buildExtraConnectionString('&')
 	case migrator.Postgres:
-		addr, err := util.SplitHostPortDefault(ss.dbCfg.Host, "127.0.0.1", "5432")
+		addr, err := util.SplitHostPortDefault(dbCfg.Host, "127.0.0.1", "5432")
 		if err != nil {
-			return "", fmt.Errorf("invalid host specifier '%s': %w", ss.dbCfg.Host, err)
+			return "", fmt.Errorf("invalid host specifier '%s': %w", dbCfg.Host, err)
 		}
 
-		if ss.dbCfg.Pwd == "" {
-			ss.dbCfg.Pwd = "''"
+		if dbCfg.Pwd == "" {
+			dbCfg.Pwd = "''"
 		}
-		if ss.dbCfg.User == "" {
-			ss.dbCfg.User = "''"
+		if dbCfg.User == "" {
+			dbCfg.User = "''"
 		}
 		cnnstr = fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s sslmode=%s sslcert=%s sslkey=%s sslrootcert=%s",
-			ss.dbCfg.User, ss.dbCfg.Pwd, addr.Host, addr.Port, ss.dbCfg.Name, ss.dbCfg.SslMode, ss.dbCfg.ClientCertPath,
-			ss.dbCfg.ClientKeyPath, ss.dbCfg.CaCertPath)
+			dbCfg.User, dbCfg.Pwd, addr.Host, addr.Port, dbCfg.Name, dbCfg.SslMode, dbCfg.ClientCertPath,
+			dbCfg.ClientKeyPath, dbCfg.CaCertPath)
 
 		cnnstr += ss.buildExtraConnectionString(' ')
 	case migrator.SQLite:
 		// special case for tests
-		if !filepath.IsAbs(ss.dbCfg.Path) {
-			ss.dbCfg.Path = filepath.Join(ss.Cfg.DataPath, ss.dbCfg.Path)
+		if !filepath.IsAbs(dbCfg.Path) {
+			dbCfg.Path = filepath.Join(ss.Cfg.DataPath, dbCfg.Path)
 		}
-		if err := os.MkdirAll(path.Dir(ss.dbCfg.Path), os.ModePerm); err != nil {
+		if err := os.MkdirAll(path.Dir(dbCfg.Path), os.ModePerm); err != nil {
 			return "", err
 		}
 
-		cnnstr = fmt.Sprintf("file:%s?cache=%s&mode=rwc", ss.dbCfg.Path, ss.dbCfg.CacheMode)
+		cnnstr = fmt.Sprintf("file:%s?cache=%s&mode=rwc", dbCfg.Path, dbCfg.CacheMode)
 
-		if ss.dbCfg.WALEnabled {
+		if dbCfg.WALEnabled {
 			cnnstr += "&_journal_mode=WAL"
 		}
 
 		cnnstr += ss.buildExtraConnectionString('&')
 	default:
-		return "", fmt.Errorf("unknown database type: %s", ss.dbCfg.Type)
+		return "", fmt.Errorf("unknown database type: %s", dbCfg.Type)
 	}
 
 	return cnnstr, nil
@@ -359,8 +403,21 @@ func (ss *SQLStore) initEngine(engine *xorm.Engine) error {
 		return err
 	}
 
-	if ss.Cfg.IsFeatureToggleEnabled(featuremgmt.FlagDatabaseMetrics) {
-		ss.dbCfg.Type = WrapDatabaseDriverWithHooks(ss.dbCfg.Type, ss.tracer)
+	replicas, err := ss.readReplicaConfigs()
+	if err != nil {
+		return err
+	}
+	replicaConnectionStrings := make([]string, 0, len(replicas))
+	for i := range replicas {
+		cnnstr, err := ss.connectionString(&replicas[i].DatabaseConfig, "replica-"+replicas[i].name)
+		if err != nil {
+			return fmt.Errorf("read replica %q: %w", replicas[i].name, err)
+		}
+		replicaConnectionStrings = append(replicaConnectionStrings, cnnstr)
+	}
+
+	if ss.Cfg.IsFeatureToggleEnabled(featuremgmt.FlagDatabaseMetrics) || ss.dbCfg.SlowQueryThreshold > 0 {
+		ss.dbCfg.Type = WrapDatabaseDriverWithHooks(ss.dbCfg.Type, ss.tracer, ss.dbCfg.SlowQueryThreshold)
 	}
 
 	ss.log.Info("Connecting to DB", "dbtype", ss.dbCfg.Type)
@@ -401,9 +458,54 @@ func (ss *SQLStore) initEngine(engine *xorm.Engine) error {
 		}
 	}
 
-	engine.SetMaxOpenConns(ss.dbCfg.MaxOpenConn)
-	engine.SetMaxIdleConns(ss.dbCfg.MaxIdleConn)
-	engine.SetConnMaxLifetime(time.Second * time.Duration(ss.dbCfg.ConnMaxLifetime))
+	ss.configureEngine(PrimaryPool, engine, ss.dbCfg)
+
+	// replicas use the same driver as the primary database, including the hooks registered for metrics
+	for i, cfg := range replicas {
+		ss.log.Info("Connecting to DB read replica", "name", cfg.name)
+		replicaEngine, err := xorm.NewEngine(ss.dbCfg.Type, replicaConnectionStrings[i])
+		if err != nil {
+			ss.closeReplicas()
+			return fmt.Errorf("read replica %q: %w", cfg.name, err)
+		}
+		ss.configureEngine(cfg.name, replicaEngine, cfg.DatabaseConfig)
+		ss.replicas = append(ss.replicas, &replica{name: cfg.name, engine: replicaEngine})
+	}
+
+	ss.engine = engine
+	return nil
+}
+
+// Run closes the read replicas when Grafana shuts down, queries that already started are completed.
+// The primary database stays open for the services that are still shutting down.
+func (ss *SQLStore) Run(ctx context.Context) error {
+	<-ctx.Done()
+	ss.closeReplicas()
+	return ctx.Err()
+}
+
+func (ss *SQLStore) closeReplicas() {
+	for _, r := range ss.replicas {
+		if err := r.engine.Close(); err != nil {
+			ss.log.Warn("Failed to close DB read replica", "name", r.name, "error", err)
+		}
+	}
+}
+
+// configureEngine sets the connection pool and the logger of engine.
+func (ss *SQLStore) configureEngine(name string, engine *xorm.Engine, dbCfg DatabaseConfig) {
+	settings := PoolSettings{
+		MaxOpenConn:     dbCfg.MaxOpenConn,
+		MaxIdleConn:     dbCfg.MaxIdleConn,
+		ConnMaxLifetime: dbCfg.ConnMaxLifetime,
+	}
+	setPoolSettings(engine, settings)
+	ss.poolMu.Lock()
+	if ss.poolSettings == nil {
+		ss.poolSettings = map[string]PoolSettings{}
+	}
+	ss.poolSettings[name] = settings
+	ss.poolMu.Unlock()
 
 	// configure sql logging
 	debugSQL := ss.Cfg.Raw.Section("database").Key("log_queries").MustBool(false)
@@ -415,9 +517,6 @@ func (ss *SQLStore) initEngine(engine *xorm.Engine) error {
 		engine.ShowSQL(true)
 		engine.ShowExecTime(true)
 	}
-
-	ss.engine = engine
-	return nil
 }
 
 // readConfig initializes the SQLStore from its configuration.
@@ -426,25 +525,9 @@ func (ss *SQLStore) readConfig() error {
 
 	cfgURL := sec.Key("url").String()
 	if len(cfgURL) != 0 {
-		dbURL, err := url.Parse(cfgURL)
-		if err != nil {
+		if err := readDatabaseURL(&ss.dbCfg, cfgURL); err != nil {
 			return err
 		}
-		ss.dbCfg.Type = dbURL.Scheme
-		ss.dbCfg.Host = dbURL.Host
-
-		pathSplit := strings.Split(dbURL.Path, "/")
-		if len(pathSplit) > 1 {
-			ss.dbCfg.Name = pathSplit[1]
-		}
-
-		userInfo := dbURL.User
-		if userInfo != nil {
-			ss.dbCfg.User = userInfo.Username()
-			ss.dbCfg.Pwd, _ = userInfo.Password()
-		}
-
-		ss.dbCfg.UrlQueryParams = dbURL.Query()
 	} else {
 		ss.dbCfg.Type = sec.Key("type").String()
 		ss.dbCfg.Host = sec.Key("host").String()
@@ -473,9 +556,99 @@ func (ss *SQLStore) readConfig() error {
 
 	ss.dbCfg.QueryRetries = sec.Key("query_retries").MustInt()
 	ss.dbCfg.TransactionRetries = sec.Key("transaction_retries").MustInt(5)
+	ss.dbCfg.SlowQueryThreshold = sec.Key("slow_query_threshold").MustDuration(0)
+	ss.dbCfg.TransientErrorRetries = sec.Key("transient_error_retries").MustInt(3)
+	return nil
+}
+
+func readDatabaseURL(dbCfg *DatabaseConfig, cfgURL string) error {
+	dbURL, err := url.Parse(cfgURL)
+	if err != nil {
+		return err
+	}
+	dbCfg.Type = dbURL.Scheme
+	dbCfg.Host = dbURL.Host
+
+	pathSplit := strings.Split(dbURL.Path, "/")
+	if len(pathSplit) > 1 {
+		dbCfg.Name = pathSplit[1]
+	}
+
+	userInfo := dbURL.User
+	if userInfo != nil {
+		dbCfg.User = userInfo.Username()
+		dbCfg.Pwd, _ = userInfo.Password()
+	}
+
+	dbCfg.UrlQueryParams = dbURL.Query()
 	return nil
 }
 
+type replicaConfig struct {
+	name string
+	DatabaseConfig
+}
+
+// readReplicaConfigs reads the read replicas from the [database.replica.<name>] sections.
+// Settings that are not set for a replica are the same as for the primary database, except
+// for the connection string and the server cert name, which depend on the host of the replica.
+func (ss *SQLStore) readReplicaConfigs() ([]replicaConfig, error) {
+	const sectionPrefix = "database.replica."
+
+	var replicas []replicaConfig
+	for _, sec := range ss.Cfg.Raw.Sections() {
+		if !strings.HasPrefix(sec.Name(), sectionPrefix) {
+			continue
+		}
+
+		// ini looks up the keys missing in a child section in [database], only the keys of the
+		// replica are read so the url of the primary database does not apply to the replica.
+		keys := sec.KeysHash()
+		mustString := func(name string, defaultVal string) string {
+			if _, ok := keys[name]; !ok {
+				return defaultVal
+			}
+			return sec.Key(name).MustString(defaultVal)
+		}
+		mustInt := func(name string, defaultVal int) int {
+			if _, ok := keys[name]; !ok {
+				return defaultVal
+			}
+			return sec.Key(name).MustInt(defaultVal)
+		}
+
+		cfg := replicaConfig{name: strings.TrimPrefix(sec.Name(), sectionPrefix), DatabaseConfig: ss.dbCfg}
+		if cfgURL := keys["url"]; len(cfgURL) != 0 {
+			if err := readDatabaseURL(&cfg.DatabaseConfig, cfgURL); err != nil {
+				return nil, fmt.Errorf("read replica %q: %w", cfg.name, err)
+			}
+			if cfg.Type != ss.dbCfg.Type {
+				return nil, fmt.Errorf("read replica %q: database type %q differs from the primary database", cfg.name, cfg.Type)
+			}
+		} else {
+			cfg.Host = mustString("host", cfg.Host)
+			cfg.Name = mustString("name", cfg.Name)
+			cfg.User = mustString("user", cfg.User)
+			cfg.Pwd = mustString("password", cfg.Pwd)
+			cfg.Path = mustString("path", cfg.Path)
+		}
+		cfg.ConnectionString = keys["connection_string"]
+
+		cfg.SslMode = mustString("ssl_mode", cfg.SslMode)
+		cfg.CaCertPath = mustString("ca_cert_path", cfg.CaCertPath)
+		cfg.ClientKeyPath = mustString("client_key_path", cfg.ClientKeyPath)
+		cfg.ClientCertPath = mustString("client_cert_path", cfg.ClientCertPath)
+		cfg.ServerCertName = keys["server_cert_name"]
+
+		cfg.MaxOpenConn = mustInt("max_open_conn", cfg.MaxOpenConn)
+		cfg.MaxIdleConn = mustInt("max_idle_conn", cfg.MaxIdleConn)
+		cfg.ConnMaxLifetime = mustInt("conn_max_lifetime", cfg.ConnMaxLifetime)
+
+		replicas = append(replicas, cfg)
+	}
+	return replicas, nil
+}
+
 // ITestDB is an interface of arguments for testing db
 type ITestDB interface {
 	Helper()
@@ -698,4 +871,7 @@ type DatabaseConfig struct {
 	QueryRetries int
 	// SQLite only
 	TransactionRetries int
+	SlowQueryThreshold time.Duration
+	// TransientErrorRetries applies to sessions started with WithTransientErrorRetries
+	TransientErrorRetries int
 }
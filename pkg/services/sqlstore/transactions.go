@@ -17,13 +17,17 @@ var tsclogger = log.New("sqlstore.transactions")
 
 // WithTransactionalDbSession calls the callback with a session within a transaction.
 func (ss *SQLStore) WithTransactionalDbSession(ctx context.Context, callback DBTransactionFunc) error {
-	return ss.inTransactionWithRetryCtx(ctx, ss.engine, ss.bus, callback, 0)
+	return ss.recordError(ss.withTransientErrorRetries(ctx, func() error {
+		return ss.inTransactionWithRetryCtx(ctx, ss.engine, ss.bus, callback, 0)
+	}))
 }
 
 // InTransaction starts a transaction and calls the fn
 // It stores the session in the context
 func (ss *SQLStore) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
-	return ss.inTransactionWithRetry(ctx, fn, 0)
+	return ss.recordError(ss.withTransientErrorRetries(ctx, func() error {
+		return ss.inTransactionWithRetry(ctx, fn, 0)
+	}))
 }
 
 func (ss *SQLStore) inTransactionWithRetry(ctx context.Context, fn func(ctx context.Context) error, retry int) error {
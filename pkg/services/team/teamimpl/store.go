@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/grafana/grafana/pkg/events"
 	"github.com/grafana/grafana/pkg/infra/db"
 	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/dashboards"
@@ -158,9 +159,13 @@ func (ss *xormStore) Delete(ctx context.Context, cmd *team.DeleteTeamCommand) er
 			}
 		}
 
-		_, err := sess.Exec("DELETE FROM permission WHERE scope=?", ac.Scope("teams", "id", fmt.Sprint(cmd.ID)))
+		if _, err := sess.Exec("DELETE FROM permission WHERE scope=?", ac.Scope("teams", "id", fmt.Sprint(cmd.ID))); err != nil {
+			return err
+		}
 
-		return err
+		// all members of the team lose the permissions granted to it
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now()})
+		return nil
 	})
 }
 
@@ -446,8 +451,12 @@ func addTeamMember(sess *db.Session, orgID, teamID, userID int64, isExternal boo
 		Permission: permission,
 	}
 
-	_, err := sess.Insert(&entity)
-	return err
+	if _, err := sess.Insert(&entity); err != nil {
+		return err
+	}
+
+	sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: userID})
+	return nil
 }
 
 func updateTeamMember(sess *db.Session, orgID, teamID, userID int64, permission dashboards.PermissionType) error {
@@ -461,8 +470,12 @@ func updateTeamMember(sess *db.Session, orgID, teamID, userID int64, permission
 	}
 
 	member.Permission = permission
-	_, err = sess.Cols("permission").Where("org_id=? and team_id=? and user_id=?", orgID, teamID, userID).Update(member)
-	return err
+	if _, err = sess.Cols("permission").Where("org_id=? and team_id=? and user_id=?", orgID, teamID, userID).Update(member); err != nil {
+		return err
+	}
+
+	sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: userID})
+	return nil
 }
 
 // RemoveTeamMember removes a member from a team
@@ -492,8 +505,12 @@ func removeTeamMember(sess *db.Session, cmd *team.RemoveTeamMemberCommand) error
 	if rows == 0 {
 		return team.ErrTeamMemberNotFound
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: cmd.UserID})
+	return nil
 }
 
 // GetUserTeamMemberships return a list of memberships to teams granted to a user
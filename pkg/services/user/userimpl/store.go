@@ -105,10 +105,14 @@ func (ss *sqlStore) Get(ctx context.Context, usr *user.User) (*user.User, error)
 }
 
 func (ss *sqlStore) Delete(ctx context.Context, userID int64) error {
-	err := ss.db.WithDbSession(ctx, func(sess *db.Session) error {
+	err := ss.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
 		var rawSQL = "DELETE FROM " + ss.dialect.Quote("user") + " WHERE id = ?"
-		_, err := sess.Exec(rawSQL, userID)
-		return err
+		if _, err := sess.Exec(rawSQL, userID); err != nil {
+			return err
+		}
+
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: userID})
+		return nil
 	})
 	if err != nil {
 		return err
@@ -341,6 +345,7 @@ func (ss *sqlStore) Update(ctx context.Context, cmd *user.UpdateUserCommand) err
 			Login:     user.Login,
 			Email:     user.Email,
 		})
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: cmd.UserID})
 
 		return nil
 	})
@@ -365,14 +370,21 @@ func (ss *sqlStore) UpdateLastSeenAt(ctx context.Context, cmd *user.UpdateUserLa
 			LastSeenAt: time.Now(),
 		}
 
-		_, err := sess.ID(cmd.UserID).Update(&user)
-		return err
+		if _, err := sess.ID(cmd.UserID).Update(&user); err != nil {
+			return err
+		}
+
+		// cached identities carry the last seen time, refresh them so it is not updated again on every request
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: user.LastSeenAt, UserID: cmd.UserID})
+		return nil
 	})
 }
 
 func (ss *sqlStore) GetSignedInUser(ctx context.Context, query *user.GetSignedInUserQuery) (*user.SignedInUser, error) {
 	var signedInUser user.SignedInUser
-	err := ss.db.WithDbSession(ctx, func(dbSess *db.Session) error {
+	// the signed in user is used for authentication and authorization, so it is always read
+	// from the primary database to not grant roles or access that have been revoked
+	err := ss.db.WithDbSession(db.WithTransientErrorRetries(ctx), func(dbSess *db.Session) error {
 		orgId := "u.org_id"
 		if query.OrgID > 0 {
 			orgId = strconv.FormatInt(query.OrgID, 10)
@@ -439,8 +451,12 @@ func (ss *sqlStore) GetSignedInUser(ctx context.Context, query *user.GetSignedIn
 
 func (ss *sqlStore) UpdateUser(ctx context.Context, user *user.User) error {
 	return ss.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
-		_, err := sess.ID(user.ID).Update(user)
-		return err
+		if _, err := sess.ID(user.ID).Update(user); err != nil {
+			return err
+		}
+
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: user.ID})
+		return nil
 	})
 }
 
@@ -505,6 +521,8 @@ func (ss *sqlStore) UpdatePermissions(ctx context.Context, userID int64, isAdmin
 		if err := validateOneAdminLeft(ctx, sess); err != nil {
 			return err
 		}
+
+		sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: userID})
 		return nil
 	})
 }
@@ -555,13 +573,19 @@ func (ss *sqlStore) BatchDisableUsers(ctx context.Context, cmd *user.BatchDisabl
 			disableParams = append(disableParams, v)
 		}
 
-		_, err := sess.Where(ss.notServiceAccountFilter()).Exec(disableParams...)
-		return err
+		if _, err := sess.Where(ss.notServiceAccountFilter()).Exec(disableParams...); err != nil {
+			return err
+		}
+
+		for _, id := range userIds {
+			sess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: id})
+		}
+		return nil
 	})
 }
 
 func (ss *sqlStore) Disable(ctx context.Context, cmd *user.DisableUserCommand) error {
-	return ss.db.WithDbSession(ctx, func(dbSess *db.Session) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(dbSess *db.Session) error {
 		usr := user.User{}
 		sess := dbSess.Table("user")
 
@@ -574,8 +598,12 @@ func (ss *sqlStore) Disable(ctx context.Context, cmd *user.DisableUserCommand) e
 		usr.IsDisabled = cmd.IsDisabled
 		sess.UseBool("is_disabled")
 
-		_, err := sess.ID(cmd.UserID).Update(&usr)
-		return err
+		if _, err := sess.ID(cmd.UserID).Update(&usr); err != nil {
+			return err
+		}
+
+		dbSess.PublishAfterCommit(&events.IdentityChanged{Timestamp: time.Now(), UserID: cmd.UserID})
+		return nil
 	})
 }
 
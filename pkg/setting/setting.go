@@ -270,6 +270,14 @@ type Cfg struct {
 	AdminEmail                   string
 	DisableSyncLock              bool
 	DisableLoginForm             bool
+	// AuthClientsOrder is the order authentication clients are tried in, clients not listed are tried after.
+	AuthClientsOrder []string
+	// AuthClientsDisabled are the authentication clients that should not be registered.
+	AuthClientsDisabled []string
+
+	// TokenRotationReuseDetection revokes a session when a rotated out token is used after the grace period.
+	TokenRotationReuseDetection   bool
+	TokenRotationReuseGracePeriod time.Duration
 
 	// AWS Plugin Auth
 	AWSAllowedAuthProviders []string
@@ -289,10 +297,40 @@ type Cfg struct {
 	AuthProxyHeaders          map[string]string
 	AuthProxyHeadersEncoded   bool
 	AuthProxySyncTTL          int
+	// AuthProxySignatureSecret enables HMAC signature validation of auth proxy requests when set
+	AuthProxySignatureSecret     string
+	AuthProxySignatureHeaderName string
+	AuthProxyTimestampHeaderName string
+	AuthProxySignatureMaxAge     time.Duration
+
+	// MFA
+	MFAEnabled   bool
+	MFAIssuer    string
+	MFAStepUpTTL time.Duration
+
+	// WebAuthn
+	WebAuthnEnabled          bool
+	WebAuthnRPID             string
+	WebAuthnRPName           string
+	WebAuthnChallengeTimeout time.Duration
+
+	// Trusted devices
+	TrustedDevicesEnabled     bool
+	TrustedDevicesNotifyEmail bool
+	TrustedDevicesWebhookURL  string
 
 	// OAuth
 	OAuthAutoLogin    bool
 	OAuthCookieMaxAge int
+	// OAuthTokenRenewal settings control the background job that refreshes
+	// OAuth access tokens of active sessions before they expire.
+	OAuthTokenRenewalEnabled     bool
+	OAuthTokenRenewalInterval    time.Duration
+	OAuthTokenRenewalWindow      time.Duration
+	OAuthTokenRenewalConcurrency int
+
+	// IdentityCacheTTL is how long resolved identities and their permissions are cached, 0 disables the cache.
+	IdentityCacheTTL time.Duration
 
 	// JWT Auth
 	JWTAuthEnabled                 bool
@@ -341,6 +379,8 @@ type Cfg struct {
 	AnonymousOrgName     string
 	AnonymousOrgRole     string
 	AnonymousHideVersion bool
+	// AnonymousDeviceLimit is the maximum number of active anonymous devices per organization, 0 means unlimited
+	AnonymousDeviceLimit int64
 
 	DateFormats DateFormats
 
@@ -421,6 +461,10 @@ type Cfg struct {
 	LDAPAllowSignup       bool
 	LDAPActiveSyncEnabled bool
 	LDAPSyncCron          string
+	// LDAPConnectionPoolSize is the maximum number of idle connections kept per LDAP server
+	LDAPConnectionPoolSize int
+	// LDAPHealthCheckInterval is how often LDAP servers are checked in the background, 0 disables the checks
+	LDAPHealthCheckInterval time.Duration
 
 	DefaultTheme    string
 	DefaultLanguage string
@@ -1139,10 +1183,37 @@ func (cfg *Cfg) Load(args CommandLineArgs) error {
 	encryption := cacheServer.Key("encryption").MustBool(false)
 
 	cfg.RemoteCacheOptions = &RemoteCacheOptions{
-		Name:       dbName,
-		ConnStr:    connStr,
-		Prefix:     prefix,
-		Encryption: encryption,
+		Name:                 dbName,
+		ConnStr:              connStr,
+		Prefix:               prefix,
+		Encryption:           encryption,
+		EncryptionPrefixes:   util.SplitString(valueAsString(cacheServer, "encryption_prefixes", "")),
+		Codec:                valueAsString(cacheServer, "codec", "gob"),
+		Compression:          valueAsString(cacheServer, "compression", "none"),
+		CompressionThreshold: cacheServer.Key("compression_threshold").MustInt(1024),
+		MaxItemSize:          cacheServer.Key("max_item_size").MustInt(0),
+		TLSEnabled:           cacheServer.Key("tls_enabled").MustBool(false),
+		TLSCACertPath:        valueAsString(cacheServer, "tls_ca_cert_path", ""),
+		TLSClientCertPath:    valueAsString(cacheServer, "tls_client_cert_path", ""),
+		TLSClientKeyPath:     valueAsString(cacheServer, "tls_client_key_path", ""),
+		TLSServerName:        valueAsString(cacheServer, "tls_server_name", ""),
+		TLSSkipVerify:        cacheServer.Key("tls_skip_verify").MustBool(false),
+
+		MemcachedUsername: valueAsString(cacheServer, "memcached_username", ""),
+		MemcachedPassword: valueAsString(cacheServer, "memcached_password", ""),
+
+		LocalCacheTTL:        cacheServer.Key("local_cache_ttl").MustDuration(0),
+		LocalCacheMaxEntries: cacheServer.Key("local_cache_max_entries").MustInt(10000),
+
+		StartupHealthCheck: cacheServer.Key("startup_health_check").MustBool(false),
+		OperationTimeout:   cacheServer.Key("operation_timeout").MustDuration(0),
+
+		DatabaseGCInterval:  cacheServer.Key("database_gc_interval").MustDuration(10 * time.Minute),
+		DatabaseGCBatchSize: cacheServer.Key("database_gc_batch_size").MustInt(1000),
+		DatabaseMaxRows:     cacheServer.Key("database_max_rows").MustInt64(0),
+		DatabaseMaxBytes:    cacheServer.Key("database_max_bytes").MustInt64(0),
+
+		Policies: readRemoteCachePolicies(iniFile),
 	}
 
 	geomapSection := iniFile.Section("geomap")
@@ -1180,6 +1251,89 @@ type RemoteCacheOptions struct {
 	ConnStr    string
 	Prefix     string
 	Encryption bool
+	// EncryptionPrefixes limits the encryption to keys with one of the prefixes, it is ignored when Encryption is set
+	EncryptionPrefixes []string
+	// Codec is gob or json
+	Codec string
+	// Compression is none, snappy or zstd, values smaller than CompressionThreshold bytes are not compressed
+	Compression          string
+	CompressionThreshold int
+	// MaxItemSize is the maximum size in bytes of a stored value, 0 is unlimited
+	MaxItemSize int
+
+	// TLS settings for the connections to the cache servers
+	TLSEnabled        bool
+	TLSCACertPath     string
+	TLSClientCertPath string
+	TLSClientKeyPath  string
+	// TLSServerName defaults to the host of the first address
+	TLSServerName string
+	TLSSkipVerify bool
+
+	// MemcachedUsername and MemcachedPassword enable SASL authentication to the memcached servers
+	MemcachedUsername string
+	MemcachedPassword string
+
+	// LocalCacheTTL enables a local cache in front of the remote cache when positive
+	LocalCacheTTL        time.Duration
+	LocalCacheMaxEntries int
+
+	// StartupHealthCheck fails the startup if the cache cannot be reached
+	StartupHealthCheck bool
+	// OperationTimeout limits the duration of every cache operation, 0 leaves the deadline to the caller
+	OperationTimeout time.Duration
+
+	// DatabaseGCInterval is how often the database backend deletes expired rows, DatabaseGCBatchSize rows at a time
+	DatabaseGCInterval  time.Duration
+	DatabaseGCBatchSize int
+	// DatabaseMaxRows and DatabaseMaxBytes limit the size of the database backend, 0 disables the limit
+	DatabaseMaxRows  int64
+	DatabaseMaxBytes int64
+
+	// Policies override the defaults for keys with a prefix, the longest matching prefix applies
+	Policies []RemoteCachePolicy
+}
+
+// RemoteCachePolicy configures the remote cache for the keys with the prefix
+type RemoteCachePolicy struct {
+	Prefix string
+	// DefaultTTL is used for items stored without an expiry, 0 keeps the defaults of the remote cache
+	DefaultTTL time.Duration
+	// Encrypt overrides encryption and encryption_prefixes for the keys when set
+	Encrypt *bool
+	// LocalCache keeps the items in the local cache, if the local cache is enabled
+	LocalCache bool
+}
+
+// readRemoteCachePolicies reads the [remote_cache.policy.<name>] sections, the prefix
+// defaults to the name of the policy.
+func readRemoteCachePolicies(iniFile *ini.File) []RemoteCachePolicy {
+	const sectionPrefix = "remote_cache.policy."
+
+	var policies []RemoteCachePolicy
+	for _, section := range iniFile.Sections() {
+		if !strings.HasPrefix(section.Name(), sectionPrefix) {
+			continue
+		}
+
+		// the prefix of [remote_cache] is not inherited by the policies
+		prefix := section.KeysHash()["prefix"]
+		if prefix == "" {
+			prefix = strings.TrimPrefix(section.Name(), sectionPrefix)
+		}
+
+		policy := RemoteCachePolicy{
+			Prefix:     prefix,
+			DefaultTTL: section.Key("default_ttl").MustDuration(0),
+			LocalCache: section.Key("local_cache").MustBool(true),
+		}
+		if section.HasKey("encrypt") {
+			encrypt := section.Key("encrypt").MustBool(false)
+			policy.Encrypt = &encrypt
+		}
+		policies = append(policies, policy)
+	}
+	return policies
 }
 
 func (cfg *Cfg) readSAMLConfig() {
@@ -1195,6 +1349,8 @@ func (cfg *Cfg) readLDAPConfig() {
 	cfg.LDAPSkipOrgRoleSync = ldapSec.Key("skip_org_role_sync").MustBool(false)
 	cfg.LDAPActiveSyncEnabled = ldapSec.Key("active_sync_enabled").MustBool(false)
 	cfg.LDAPAllowSignup = ldapSec.Key("allow_sign_up").MustBool(true)
+	cfg.LDAPConnectionPoolSize = ldapSec.Key("connection_pool_size").MustInt(5)
+	cfg.LDAPHealthCheckInterval = ldapSec.Key("health_check_interval").MustDuration(30 * time.Second)
 }
 
 func (cfg *Cfg) handleAWSConfig() {
@@ -1427,6 +1583,8 @@ func readAuthSettings(iniFile *ini.File, cfg *Cfg) (err error) {
 	if cfg.TokenRotationIntervalMinutes < 2 {
 		cfg.TokenRotationIntervalMinutes = 2
 	}
+	cfg.TokenRotationReuseDetection = auth.Key("token_rotation_reuse_detection").MustBool(false)
+	cfg.TokenRotationReuseGracePeriod = auth.Key("token_rotation_reuse_grace_period").MustDuration(time.Minute)
 
 	// Debug setting unlocking frontend auth sync lock. Users will still be reset on their next login.
 	cfg.DisableSyncLock = auth.Key("disable_sync_lock").MustBool(false)
@@ -1441,6 +1599,17 @@ func readAuthSettings(iniFile *ini.File, cfg *Cfg) (err error) {
 	}
 
 	cfg.OAuthCookieMaxAge = auth.Key("oauth_state_cookie_max_age").MustInt(600)
+	cfg.OAuthTokenRenewalEnabled = auth.Key("oauth_token_renewal_enabled").MustBool(false)
+	cfg.OAuthTokenRenewalInterval = auth.Key("oauth_token_renewal_interval").MustDuration(time.Minute)
+	if cfg.OAuthTokenRenewalInterval <= 0 {
+		return errors.New("the `oauth_token_renewal_interval` configuration must be greater than 0")
+	}
+	cfg.OAuthTokenRenewalWindow = auth.Key("oauth_token_renewal_window").MustDuration(5 * time.Minute)
+	cfg.OAuthTokenRenewalConcurrency = auth.Key("oauth_token_renewal_concurrency").MustInt(5)
+	if cfg.OAuthTokenRenewalConcurrency < 1 {
+		return errors.New("the minimum supported value for the `oauth_token_renewal_concurrency` configuration is 1")
+	}
+	cfg.IdentityCacheTTL = auth.Key("identity_cache_ttl").MustDuration(0)
 	SignoutRedirectUrl = valueAsString(auth, "signout_redirect_url", "")
 	// Deprecated
 	cfg.OAuthSkipOrgRoleUpdateSync = auth.Key("oauth_skip_org_role_update_sync").MustBool(false)
@@ -1477,6 +1646,7 @@ func readAuthSettings(iniFile *ini.File, cfg *Cfg) (err error) {
 	cfg.AnonymousOrgName = valueAsString(iniFile.Section("auth.anonymous"), "org_name", "")
 	cfg.AnonymousOrgRole = valueAsString(iniFile.Section("auth.anonymous"), "org_role", "")
 	cfg.AnonymousHideVersion = iniFile.Section("auth.anonymous").Key("hide_version").MustBool(false)
+	cfg.AnonymousDeviceLimit = iniFile.Section("auth.anonymous").Key("device_limit").MustInt64(0)
 
 	// basic auth
 	authBasic := iniFile.Section("auth.basic")
@@ -1524,12 +1694,101 @@ func readAuthSettings(iniFile *ini.File, cfg *Cfg) (err error) {
 
 	cfg.AuthProxyHeadersEncoded = authProxy.Key("headers_encoded").MustBool(false)
 
+	cfg.AuthProxySignatureSecret = valueAsString(authProxy, "signature_secret", "")
+	cfg.AuthProxySignatureHeaderName = valueAsString(authProxy, "signature_header_name", "X-Grafana-Proxy-Signature")
+	cfg.AuthProxyTimestampHeaderName = valueAsString(authProxy, "timestamp_header_name", "X-Grafana-Proxy-Timestamp")
+	cfg.AuthProxySignatureMaxAge = authProxy.Key("signature_max_age").MustDuration(time.Minute)
+
+	// MFA
+	mfa := iniFile.Section("auth.mfa")
+	cfg.MFAEnabled = mfa.Key("enabled").MustBool(false)
+	cfg.MFAIssuer = valueAsString(mfa, "issuer", "Grafana")
+	cfg.MFAStepUpTTL = mfa.Key("step_up_ttl").MustDuration(15 * time.Minute)
+	if cfg.MFAStepUpTTL <= 0 {
+		return errors.New("the `step_up_ttl` configuration in [auth.mfa] must be a positive duration")
+	}
+
+	// WebAuthn
+	webAuthn := iniFile.Section("auth.webauthn")
+	cfg.WebAuthnEnabled = webAuthn.Key("enabled").MustBool(false)
+	cfg.WebAuthnRPID = valueAsString(webAuthn, "rp_id", "")
+	cfg.WebAuthnRPName = valueAsString(webAuthn, "rp_name", "Grafana")
+	cfg.WebAuthnChallengeTimeout = webAuthn.Key("challenge_timeout").MustDuration(5 * time.Minute)
+
+	// Trusted devices
+	trustedDevices := iniFile.Section("auth.trusted_devices")
+	cfg.TrustedDevicesEnabled = trustedDevices.Key("enabled").MustBool(false)
+	cfg.TrustedDevicesNotifyEmail = trustedDevices.Key("notify_email").MustBool(true)
+	cfg.TrustedDevicesWebhookURL = valueAsString(trustedDevices, "webhook_url", "")
+
 	// GrafanaCom
 	readAuthGrafanaComSettings(iniFile, cfg)
 
 	// Github
 	readAuthGithubSettings(iniFile, cfg)
-	return nil
+
+	return readAuthClientsSettings(iniFile, cfg)
+}
+
+// authClients are the names of the authentication clients that can be configured in [auth.clients],
+// together with the names of the enabled oauth providers
+var authClients = []string{"render", "jwt", "api-key", "basic", "proxy", "session", "anonymous"}
+
+// interactiveAuthClients are the authentication clients that can be used by a person using a browser
+var interactiveAuthClients = []string{"session", "anonymous", "proxy", "jwt"}
+
+func readAuthClientsSettings(iniFile *ini.File, cfg *Cfg) error {
+	section := iniFile.Section("auth.clients")
+	cfg.AuthClientsOrder = util.SplitString(valueAsString(section, "order", ""))
+	cfg.AuthClientsDisabled = util.SplitString(valueAsString(section, "disabled", ""))
+
+	seen := map[string]bool{}
+	for _, name := range cfg.AuthClientsOrder {
+		if !isAuthClient(iniFile, name) {
+			return fmt.Errorf("[auth.clients] order contains unknown client: %s", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("[auth.clients] order contains duplicate client: %s", name)
+		}
+		seen[name] = true
+	}
+
+	disabled := map[string]bool{}
+	for _, name := range cfg.AuthClientsDisabled {
+		if !isAuthClient(iniFile, name) {
+			return fmt.Errorf("[auth.clients] disabled contains unknown client: %s", name)
+		}
+		disabled[name] = true
+	}
+
+	enabled := map[string]bool{
+		"session":   cfg.LoginCookieName != "",
+		"anonymous": cfg.AnonymousEnabled,
+		"proxy":     cfg.AuthProxyEnabled,
+		"jwt":       cfg.JWTAuthEnabled,
+	}
+	for _, name := range interactiveAuthClients {
+		if enabled[name] && !disabled[name] {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("[auth.clients] at least one of the interactive clients must be enabled and not disabled: %s", strings.Join(interactiveAuthClients, ", "))
+}
+
+// isAuthClient returns true if name is a known authentication client or an enabled oauth provider
+func isAuthClient(iniFile *ini.File, name string) bool {
+	for _, client := range authClients {
+		if client == name {
+			return true
+		}
+	}
+
+	section, err := iniFile.GetSection("auth." + name)
+	if err != nil {
+		return false
+	}
+	return section.Key("enabled").MustBool(false)
 }
 
 func readAccessControlSettings(iniFile *ini.File, cfg *Cfg) {
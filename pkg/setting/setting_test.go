@@ -375,6 +375,70 @@ func TestAuthDurationSettings(t *testing.T) {
 	require.Equal(t, maxLifetimeDurationTest, cfg.LoginMaxLifetime)
 }
 
+func TestAuthClientsSettings(t *testing.T) {
+	f := ini.Empty()
+	cfg := NewCfg()
+	sec, err := f.NewSection("auth.clients")
+	require.NoError(t, err)
+	_, err = sec.NewKey("order", "jwt, api-key")
+	require.NoError(t, err)
+	_, err = sec.NewKey("disabled", "basic,render")
+	require.NoError(t, err)
+	err = readAuthSettings(f, cfg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"jwt", "api-key"}, cfg.AuthClientsOrder)
+	require.Equal(t, []string{"basic", "render"}, cfg.AuthClientsDisabled)
+
+	f = ini.Empty()
+	sec, err = f.NewSection("auth.clients")
+	require.NoError(t, err)
+	_, err = sec.NewKey("order", "jwt, session, jwt")
+	require.NoError(t, err)
+	err = readAuthSettings(f, cfg)
+	require.Error(t, err)
+
+	f = ini.Empty()
+	sec, err = f.NewSection("auth.clients")
+	require.NoError(t, err)
+	_, err = sec.NewKey("disabled", "session, anonymous, proxy, jwt")
+	require.NoError(t, err)
+	err = readAuthSettings(f, cfg)
+	require.Error(t, err)
+
+	// anonymous is not enabled, so no interactive client remains
+	f = ini.Empty()
+	sec, err = f.NewSection("auth.clients")
+	require.NoError(t, err)
+	_, err = sec.NewKey("disabled", "session, proxy, jwt")
+	require.NoError(t, err)
+	err = readAuthSettings(f, cfg)
+	require.Error(t, err)
+
+	f = ini.Empty()
+	sec, err = f.NewSection("auth.clients")
+	require.NoError(t, err)
+	_, err = sec.NewKey("disabled", "basic, sesion")
+	require.NoError(t, err)
+	err = readAuthSettings(f, cfg)
+	require.Error(t, err)
+
+	f = ini.Empty()
+	sec, err = f.NewSection("auth.clients")
+	require.NoError(t, err)
+	_, err = sec.NewKey("order", "github, session")
+	require.NoError(t, err)
+	err = readAuthSettings(f, cfg)
+	require.Error(t, err)
+
+	sec, err = f.NewSection("auth.github")
+	require.NoError(t, err)
+	_, err = sec.NewKey("enabled", "true")
+	require.NoError(t, err)
+	err = readAuthSettings(f, cfg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"github", "session"}, cfg.AuthClientsOrder)
+}
+
 func TestGetCDNPath(t *testing.T) {
 	var err error
 	cfg := NewCfg()
@@ -767,3 +831,25 @@ func TestAlertingEnabled(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoteCachePolicies(t *testing.T) {
+	f, err := ini.Load([]byte(`
+[remote_cache]
+prefix = grafana-
+
+[remote_cache.policy.authn]
+prefix = authn/
+default_ttl = 1h
+encrypt = false
+
+[remote_cache.policy.session/]
+local_cache = false
+`))
+	require.NoError(t, err)
+
+	encrypt := false
+	require.Equal(t, []RemoteCachePolicy{
+		{Prefix: "authn/", DefaultTTL: time.Hour, Encrypt: &encrypt, LocalCache: true},
+		{Prefix: "session/"},
+	}, readRemoteCachePolicies(f))
+}